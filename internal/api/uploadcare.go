@@ -1,15 +1,19 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	// "net/http"
 	"os"
+	"strings"
 
+	"github.com/uploadcare/uploadcare-go/file"
 	"github.com/uploadcare/uploadcare-go/ucare"
 	"github.com/uploadcare/uploadcare-go/upload"
 )
 
+const cdnURL = "https://ucarecdn.com/"
+
 func CreateUCClient() (ucare.Client, error) {
 	creds := ucare.APICreds{
 		SecretKey: os.Getenv("UPLOAD_CARE_SECRET_KEY"),
@@ -29,30 +33,14 @@ func CreateUCClient() (ucare.Client, error) {
 	return client, nil
 }
 
-func UploadImage(client ucare.Client, file string) (string, string, error) {
-	return uploadFile(client, file)
-}
-
-func uploadFile(client ucare.Client, file string) (string, string, error) {
+// UploadData uploads a file that is already in memory.
+func UploadData(client ucare.Client, name, contentType string, data []byte) (string, string, error) {
 	uploadService := upload.NewService(client)
 
-	f, err := os.Open(file)
-	if err != nil {
-		return "", "", fmt.Errorf("could not open file: %v", err)
-	}
-
-	defer f.Close()
-
-	// Check the file type
-	buffer := make([]byte, 512)
-	_, err = f.Read(buffer)
-	if err != nil {
-		return "", "", fmt.Errorf("could not read file: %v", err)
-	}
-
 	param := upload.FileParams{
-		Data: f,
-		Name: f.Name(),
+		Data:        bytes.NewReader(data),
+		Name:        name,
+		ContentType: contentType,
 	}
 
 	fileID, err := uploadService.File(context.Background(), param)
@@ -60,15 +48,28 @@ func uploadFile(client ucare.Client, file string) (string, string, error) {
 		return "", "", fmt.Errorf("could not upload file: %v", err)
 	}
 
-	fileURL := constructFileURL(fileID)
+	return FileURL(fileID), fileID, nil
+}
 
-	return fileURL, fileID, nil
+// FileURL returns the CDN URL of a file.
+func FileURL(fileID string) string {
+	return cdnURL + fileID + "/"
 }
 
-func UploadFilePDF(client ucare.Client, file string) (string, string, error) {
-	return uploadFile(client, file)
+// FileIDFromURL returns the ID of a file from its CDN URL, and false when the
+// URL is not an Uploadcare one.
+func FileIDFromURL(fileURL string) (string, bool) {
+	if !strings.HasPrefix(fileURL, cdnURL) {
+		return "", false
+	}
+	fileID := strings.SplitN(strings.TrimPrefix(fileURL, cdnURL), "/", 2)[0]
+	return fileID, fileID != ""
 }
 
-func constructFileURL(fileID string) string {
-	return "https://ucarecdn.com/" + fileID + "/"
+// DeleteFile removes a file from the Uploadcare storage.
+func DeleteFile(client ucare.Client, fileID string) error {
+	if _, err := file.NewService(client).Delete(context.Background(), fileID); err != nil {
+		return fmt.Errorf("could not delete file: %v", err)
+	}
+	return nil
 }
@@ -0,0 +1,146 @@
+// Package care computes when the vaccinations and treatments of a cat are
+// due, from its medical record and the care protocols.
+package care
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go-challenge/internal/models"
+)
+
+// defaultReminderDaysBefore applies to the due dates set on medical entries
+// that no protocol covers.
+const defaultReminderDaysBefore = 7
+
+type Status string
+
+const (
+	StatusUpcoming    Status = "upcoming"
+	StatusDueSoon     Status = "due_soon"
+	StatusOverdue     Status = "overdue"
+	StatusNotRecorded Status = "not_recorded"
+)
+
+// DefaultProtocols are the protocols a new installation starts with.
+var DefaultProtocols = []models.CareProtocol{
+	{Type: models.MedicalVaccination, Name: "typhus", Label: "Typhus", IntervalDays: 365, ReminderDaysBefore: 14},
+	{Type: models.MedicalVaccination, Name: "coryza", Label: "Coryza", IntervalDays: 365, ReminderDaysBefore: 14},
+	{Type: models.MedicalVaccination, Name: "leucose", Label: "Leucose (FeLV)", IntervalDays: 365, ReminderDaysBefore: 14},
+	{Type: models.MedicalVaccination, Name: "rage", Label: "Rage", IntervalDays: 365, ReminderDaysBefore: 14},
+	{Type: models.MedicalDeworming, Label: "Vermifuge", IntervalDays: 90, ReminderDaysBefore: 7},
+	{Type: models.MedicalFleaTreatment, Label: "Antiparasitaire", IntervalDays: 30, ReminderDaysBefore: 3},
+	{Type: models.MedicalVetVisit, Label: "Visite annuelle", IntervalDays: 365, ReminderDaysBefore: 14},
+}
+
+// Item is one care of a cat and when it is due. DueDate is nil when the care
+// was never recorded.
+type Item struct {
+	Key        string                  `json:"key"`
+	ProtocolID uint                    `json:"protocolId,omitempty"`
+	Type       models.MedicalEntryType `json:"type"`
+	Name       string                  `json:"name"`
+	LastDate   *time.Time              `json:"lastDate,omitempty"`
+	DueDate    *time.Time              `json:"dueDate,omitempty"`
+	RemindFrom *time.Time              `json:"remindFrom,omitempty"`
+	Status     Status                  `json:"status"`
+}
+
+// NeedsReminder tells whether the owner should be reminded of the care.
+func (i Item) NeedsReminder() bool {
+	return i.Status == StatusDueSoon || i.Status == StatusOverdue
+}
+
+// Matches reports whether a medical entry is covered by a protocol.
+func Matches(protocol *models.CareProtocol, entry *models.MedicalEntry) bool {
+	if entry.Type != protocol.Type {
+		return false
+	}
+	return protocol.Name == "" || strings.Contains(strings.ToLower(entry.Name), strings.ToLower(protocol.Name))
+}
+
+// Schedule returns the cares of a cat, soonest due first. Each protocol gives
+// one item from its latest matching entry; the due date set on that entry wins
+// over the interval of the protocol. The due dates set on entries that no
+// protocol covers give an item too.
+func Schedule(entries []models.MedicalEntry, protocols []models.CareProtocol, now time.Time) []Item {
+	items := []Item{}
+	covered := map[uint]bool{}
+
+	for i := range protocols {
+		protocol := &protocols[i]
+		var latest *models.MedicalEntry
+		for j := range entries {
+			entry := &entries[j]
+			if !Matches(protocol, entry) {
+				continue
+			}
+			covered[entry.ID] = true
+			if latest == nil || entry.Date.After(latest.Date) {
+				latest = entry
+			}
+		}
+
+		item := Item{
+			Key:        fmt.Sprintf("protocol:%d", protocol.ID),
+			ProtocolID: protocol.ID,
+			Type:       protocol.Type,
+			Name:       protocol.Label,
+			Status:     StatusNotRecorded,
+		}
+		if latest != nil {
+			dueDate := latest.Date.AddDate(0, 0, protocol.IntervalDays)
+			if latest.NextDueDate != nil {
+				dueDate = *latest.NextDueDate
+			}
+			item.schedule(latest.Date, dueDate, protocol.ReminderDaysBefore, now)
+		}
+		items = append(items, item)
+	}
+
+	latestUncovered := map[string]*models.MedicalEntry{}
+	for j := range entries {
+		entry := &entries[j]
+		if covered[entry.ID] || entry.NextDueDate == nil {
+			continue
+		}
+		key := fmt.Sprintf("entry:%s:%s", entry.Type, strings.ToLower(entry.Name))
+		if latest, ok := latestUncovered[key]; !ok || entry.Date.After(latest.Date) {
+			latestUncovered[key] = entry
+		}
+	}
+	for key, entry := range latestUncovered {
+		item := Item{Key: key, Type: entry.Type, Name: entry.Name}
+		item.schedule(entry.Date, *entry.NextDueDate, defaultReminderDaysBefore, now)
+		items = append(items, item)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].DueDate == nil || items[j].DueDate == nil {
+			return items[j].DueDate == nil && items[i].DueDate != nil
+		}
+		if !items[i].DueDate.Equal(*items[j].DueDate) {
+			return items[i].DueDate.Before(*items[j].DueDate)
+		}
+		return items[i].Key < items[j].Key
+	})
+	return items
+}
+
+func (i *Item) schedule(lastDate, dueDate time.Time, reminderDaysBefore int, now time.Time) {
+	remindFrom := dueDate.AddDate(0, 0, -reminderDaysBefore)
+	i.LastDate = &lastDate
+	i.DueDate = &dueDate
+	i.RemindFrom = &remindFrom
+
+	switch {
+	case now.After(dueDate):
+		i.Status = StatusOverdue
+	case !now.Before(remindFrom):
+		i.Status = StatusDueSoon
+	default:
+		i.Status = StatusUpcoming
+	}
+}
@@ -0,0 +1,67 @@
+package care
+
+import (
+	"testing"
+	"time"
+
+	"go-challenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+
+func entry(id uint, entryType models.MedicalEntryType, name string, date time.Time) models.MedicalEntry {
+	e := models.MedicalEntry{Type: entryType, Name: name, Date: date}
+	e.ID = id
+	return e
+}
+
+func TestScheduleUsesLatestMatchingEntry(t *testing.T) {
+	protocols := []models.CareProtocol{
+		{Type: models.MedicalVaccination, Name: "typhus", Label: "Typhus", IntervalDays: 365, ReminderDaysBefore: 14},
+	}
+	entries := []models.MedicalEntry{
+		entry(1, models.MedicalVaccination, "Typhus-Coryza", now.AddDate(-2, 0, 0)),
+		entry(2, models.MedicalVaccination, "Typhus-Coryza", now.AddDate(0, 0, -360)),
+	}
+
+	items := Schedule(entries, protocols, now)
+
+	assert.Len(t, items, 1)
+	assert.Equal(t, StatusDueSoon, items[0].Status)
+	assert.Equal(t, now.AddDate(0, 0, 5), *items[0].DueDate)
+	assert.True(t, items[0].NeedsReminder())
+}
+
+func TestScheduleEntryDueDateWinsOverProtocol(t *testing.T) {
+	protocols := []models.CareProtocol{
+		{Type: models.MedicalDeworming, Label: "Vermifuge", IntervalDays: 90, ReminderDaysBefore: 7},
+	}
+	nextDueDate := now.AddDate(0, 2, 0)
+	deworming := entry(1, models.MedicalDeworming, "Milbemax", now.AddDate(0, 0, -100))
+	deworming.NextDueDate = &nextDueDate
+
+	items := Schedule([]models.MedicalEntry{deworming}, protocols, now)
+
+	assert.Equal(t, StatusUpcoming, items[0].Status)
+	assert.Equal(t, nextDueDate, *items[0].DueDate)
+}
+
+func TestScheduleListsUncoveredDueDatesAndMissingCares(t *testing.T) {
+	protocols := []models.CareProtocol{
+		{Type: models.MedicalFleaTreatment, Label: "Antiparasitaire", IntervalDays: 30, ReminderDaysBefore: 3},
+	}
+	nextDueDate := now.AddDate(0, 0, -1)
+	checkup := entry(1, models.MedicalVetVisit, "Contrôle dentaire", now.AddDate(0, -6, 0))
+	checkup.NextDueDate = &nextDueDate
+
+	items := Schedule([]models.MedicalEntry{checkup}, protocols, now)
+
+	assert.Len(t, items, 2)
+	assert.Equal(t, "Contrôle dentaire", items[0].Name)
+	assert.Equal(t, StatusOverdue, items[0].Status)
+	assert.Equal(t, StatusNotRecorded, items[1].Status)
+	assert.Nil(t, items[1].DueDate)
+	assert.False(t, items[1].NeedsReminder())
+}
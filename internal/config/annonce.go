@@ -0,0 +1,32 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAnnonceLifetimeDays      = 60
+	defaultAnnonceRenewalNoticeDays = 7
+)
+
+// AnnonceLifetime is how long an annonce stays published before it expires,
+// unless renewed. It is set in days by ANNONCE_LIFETIME_DAYS.
+func AnnonceLifetime() time.Duration {
+	return envDays("ANNONCE_LIFETIME_DAYS", defaultAnnonceLifetimeDays)
+}
+
+// AnnonceRenewalNotice is how long before an annonce expires its owner is
+// reminded to renew it. It is set in days by ANNONCE_RENEWAL_NOTICE_DAYS.
+func AnnonceRenewalNotice() time.Duration {
+	return envDays("ANNONCE_RENEWAL_NOTICE_DAYS", defaultAnnonceRenewalNoticeDays)
+}
+
+func envDays(name string, fallback int) time.Duration {
+	days, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || days <= 0 {
+		days = fallback
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
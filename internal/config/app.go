@@ -0,0 +1,24 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+const defaultAndroidPackage = "com.example.purrfectmatch"
+
+// PublicURL is the address the server is reached at, set by SERVER_URL,
+// without a trailing slash.
+func PublicURL() string {
+	return strings.TrimSuffix(os.Getenv("SERVER_URL"), "/")
+}
+
+// AndroidPackage is the package name of the Android app, set by
+// ANDROID_APP_PACKAGE. It must match assets/assetlinks.json for links to
+// the public pages to open the app.
+func AndroidPackage() string {
+	if name := os.Getenv("ANDROID_APP_PACKAGE"); name != "" {
+		return name
+	}
+	return defaultAndroidPackage
+}
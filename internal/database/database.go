@@ -5,9 +5,13 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	//"go-challenge/internal/fixtures"
 
+	"go-challenge/internal/care"
+	"go-challenge/internal/config"
+	"go-challenge/internal/geo"
 	"go-challenge/internal/models"
 	"go-challenge/internal/utils"
 
@@ -15,6 +19,7 @@ import (
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
 	"github.com/joho/godotenv"
+	"github.com/lib/pq"
 )
 
 type Database interface {
@@ -179,7 +184,48 @@ func createDbIfNotExists(db *gorm.DB, dbName string) error {
 	return nil
 }
 
+// legacyRaceTemperaments are the temperaments matching used to assume for
+// common breeds, keyed by a lowercase fragment of the race name.
+var legacyRaceTemperaments = map[string]pq.StringArray{
+	"maine coon": {models.TemperamentSociable, models.TemperamentGoodWithChildren, models.TemperamentGoodWithPets},
+	"ragdoll":    {models.TemperamentCalm, models.TemperamentSociable, models.TemperamentGoodWithChildren},
+	"persan":     {models.TemperamentCalm},
+	"persian":    {models.TemperamentCalm},
+	"siamois":    {models.TemperamentEnergetic, models.TemperamentNeedsCompany},
+	"siamese":    {models.TemperamentEnergetic, models.TemperamentNeedsCompany},
+	"bengal":     {models.TemperamentEnergetic},
+	"abyssin":    {models.TemperamentEnergetic},
+	"sphynx":     {models.TemperamentSociable, models.TemperamentNeedsCompany},
+	"british":    {models.TemperamentCalm},
+	"birman":     {models.TemperamentCalm, models.TemperamentSociable, models.TemperamentGoodWithChildren},
+	"chartreux":  {models.TemperamentCalm, models.TemperamentGoodWithChildren},
+}
+
 func migrateAllModels(db *gorm.DB) error {
+	// Association members used to be a join table of users, move it aside
+	// for the membership table to take its name
+	if db.HasTable("association_members") && !db.Dialect().HasColumn("association_members", "role") {
+		if err := db.Exec("ALTER TABLE association_members RENAME TO legacy_association_members").Error; err != nil {
+			utils.Logger("debug", "Migrate Association Members:", "Failed to rename the join table", fmt.Sprintf("Error: %v", err))
+			return err
+		}
+	}
+
+	// Documents of associations used to keep the public URL of their file,
+	// they keep its key now
+	if db.Dialect().HasColumn("association_documents", "url") {
+		if err := db.Exec("ALTER TABLE association_documents RENAME COLUMN url TO file_key").Error; err != nil {
+			utils.Logger("debug", "Migrate Association Documents:", "Failed to rename the url column", fmt.Sprintf("Error: %v", err))
+			return err
+		}
+	}
+
+	// The gallery of cats is made out of their pictures when it is created
+	migrateCatPictures := !db.HasTable(&models.CatPhoto{})
+	// Breeds get their legacy temperament when the column is added only, so
+	// a temperament cleared in the catalogue stays cleared
+	migrateRaceTemperaments := !db.Dialect().HasColumn("races", "temperament")
+
 	err := db.AutoMigrate(
 		&models.Annonce{},
 		&models.Association{},
@@ -196,6 +242,31 @@ func migrateAllModels(db *gorm.DB) error {
 		&models.ReportReason{},
 		&models.ReportedAnnonce{},
 		&models.ReportedMessage{},
+		&models.NotificationAudience{},
+		&models.NotificationCampaign{},
+		&models.NotificationDelivery{},
+		&models.InAppNotification{},
+		&models.SavedSearch{},
+		&models.SavedSearchMatch{},
+		&models.LifestyleProfile{},
+		&models.CatDismissal{},
+		&models.MedicalEntry{},
+		&models.CatOwnershipTransfer{},
+		&models.CareProtocol{},
+		&models.CareReminder{},
+		&models.CatStatusChange{},
+		&models.CatPhoto{},
+		&models.Litter{},
+		&models.AdoptionApplication{},
+		&models.AnnonceEvent{},
+		&models.AnnoncePhotoMatch{},
+		&models.FavoriteCollection{},
+		&models.AssociationMember{},
+		&models.AssociationInvitation{},
+		&models.AssociationVerificationChange{},
+		&models.AssociationDocument{},
+		&models.FosterProfile{},
+		&models.FosterPlacement{},
 	).Error
 	if err != nil {
 		utils.Logger("debug", "AutoMigrate:", "Failed to migrate models", fmt.Sprintf("Error: %v", err))
@@ -203,6 +274,197 @@ func migrateAllModels(db *gorm.DB) error {
 		return err
 	}
 
+	// Cats used to only have a reserved flag, carry it over to their status
+	if db.Dialect().HasColumn("cats", "reserved") {
+		if err := db.Exec("UPDATE cats SET status = ? WHERE reserved = ?", models.CatReserved, true).Error; err != nil {
+			utils.Logger("debug", "Migrate Cat Status:", "Failed to migrate reserved cats", fmt.Sprintf("Error: %v", err))
+			return err
+		}
+		if err := db.Model(&models.Cats{}).DropColumn("reserved").Error; err != nil {
+			return err
+		}
+	}
+
+	// Cats used to only have a list of pictures, make a gallery out of it
+	if migrateCatPictures {
+		err = db.Exec(`INSERT INTO cat_photos (created_at, updated_at, cat_id, url, position, cover)
+			SELECT NOW(), NOW(), cats.id, pictures.url, pictures.ord - 1, pictures.ord = 1
+			FROM cats, UNNEST(cats.pictures_url) WITH ORDINALITY AS pictures(url, ord)
+			WHERE cats.deleted_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM cat_photos WHERE cat_photos.cat_id = cats.id)`).Error
+		if err != nil {
+			utils.Logger("debug", "Migrate Cat Photos:", "Failed to migrate cat pictures", fmt.Sprintf("Error: %v", err))
+			return err
+		}
+	}
+
+	// Photos uploaded before variants existed use their full size picture
+	err = db.Exec(`UPDATE cat_photos SET medium_url = url, thumbnail_url = url
+		WHERE COALESCE(medium_url, '') = '' OR COALESCE(thumbnail_url, '') = ''`).Error
+	if err != nil {
+		utils.Logger("debug", "Migrate Cat Photos:", "Failed to fill in photo variants", fmt.Sprintf("Error: %v", err))
+		return err
+	}
+
+	// Annonces used to stay up forever, give the published ones a lifetime
+	err = db.Model(&models.Annonce{}).
+		Where("status = ? AND expires_at IS NULL", models.AnnoncePublished).
+		Updates(map[string]interface{}{
+			"published_at": gorm.Expr("COALESCE(published_at, created_at)"),
+			"expires_at":   time.Now().Add(config.AnnonceLifetime()),
+		}).Error
+	if err != nil {
+		utils.Logger("debug", "Migrate Annonces:", "Failed to set annonce expiry dates", fmt.Sprintf("Error: %v", err))
+		return err
+	}
+
+	// Annonces used to have no public page, give them a slug
+	var unnamed []models.Annonce
+	if err := db.Where("slug IS NULL OR slug = ''").Find(&unnamed).Error; err != nil {
+		return err
+	}
+	for _, annonce := range unnamed {
+		if err := db.Model(&annonce).UpdateColumn("slug", models.NewAnnonceSlug(annonce.Title)).Error; err != nil {
+			utils.Logger("debug", "Migrate Annonces:", "Failed to set annonce slugs", fmt.Sprintf("Error: %v", err))
+			return err
+		}
+	}
+
+	// Favorites used to outlive their annonce and could be made twice, drop
+	// the dangling ones and the duplicates before making them unique
+	err = db.Exec(`UPDATE favorites SET deleted_at = NOW()
+		WHERE deleted_at IS NULL AND NOT EXISTS (SELECT 1 FROM annonces
+			WHERE CAST(annonces.id AS text) = favorites.annonce_id AND annonces.deleted_at IS NULL)`).Error
+	if err != nil {
+		utils.Logger("debug", "Migrate Favorites:", "Failed to remove dangling favorites", fmt.Sprintf("Error: %v", err))
+		return err
+	}
+	err = db.Exec(`UPDATE favorites SET deleted_at = NOW()
+		WHERE deleted_at IS NULL AND EXISTS (SELECT 1 FROM favorites AS others
+			WHERE others.user_id = favorites.user_id AND others.annonce_id = favorites.annonce_id
+			AND others.deleted_at IS NULL AND others.id < favorites.id)`).Error
+	if err != nil {
+		utils.Logger("debug", "Migrate Favorites:", "Failed to remove duplicate favorites", fmt.Sprintf("Error: %v", err))
+		return err
+	}
+	err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_favorite_user_annonce
+		ON favorites (user_id, annonce_id) WHERE deleted_at IS NULL`).Error
+	if err != nil {
+		utils.Logger("debug", "Migrate Favorites:", "Failed to make favorites unique", fmt.Sprintf("Error: %v", err))
+		return err
+	}
+
+	// Associations used to list the IDs of their members, carry them over to
+	// the membership table. They could publish cats as the association, so
+	// they become its admins.
+	if db.Dialect().HasColumn("associations", "members") {
+		err = db.Exec(`INSERT INTO association_members (created_at, updated_at, association_id, user_id, role)
+			SELECT NOW(), NOW(), associations.id, users.id, ?
+			FROM associations CROSS JOIN UNNEST(associations.members) AS members(user_id)
+			JOIN users ON CAST(users.id AS text) = members.user_id
+			WHERE associations.deleted_at IS NULL
+			ON CONFLICT DO NOTHING`, models.AssociationAdmin).Error
+		if err != nil {
+			utils.Logger("debug", "Migrate Association Members:", "Failed to migrate association members", fmt.Sprintf("Error: %v", err))
+			return err
+		}
+		if err := db.Model(&models.Association{}).DropColumn("members").Error; err != nil {
+			return err
+		}
+	}
+	if db.HasTable("legacy_association_members") {
+		err = db.Exec(`INSERT INTO association_members (created_at, updated_at, association_id, user_id, role)
+			SELECT NOW(), NOW(), association_id, user_id, ?
+			FROM legacy_association_members
+			ON CONFLICT DO NOTHING`, models.AssociationAdmin).Error
+		if err != nil {
+			utils.Logger("debug", "Migrate Association Members:", "Failed to migrate association members", fmt.Sprintf("Error: %v", err))
+			return err
+		}
+		if err := db.DropTable("legacy_association_members").Error; err != nil {
+			return err
+		}
+	}
+	// Owners are members of their association too
+	err = db.Exec(`INSERT INTO association_members (created_at, updated_at, association_id, user_id, role)
+		SELECT NOW(), NOW(), id, owner_id, ? FROM associations WHERE deleted_at IS NULL
+		ON CONFLICT (association_id, user_id) DO UPDATE SET role = EXCLUDED.role`, models.AssociationOwner).Error
+	if err != nil {
+		utils.Logger("debug", "Migrate Association Members:", "Failed to add association owners", fmt.Sprintf("Error: %v", err))
+		return err
+	}
+
+	// Associations used to only have a verified flag, carry it over to their
+	// verification status, and their KBIS over to their documents
+	err = db.Exec(`UPDATE associations SET verification_status = ?, verified_at = COALESCE(verified_at, updated_at)
+		WHERE verified = ? AND verification_status <> ?`, models.AssociationVerified, true, models.AssociationVerified).Error
+	if err != nil {
+		utils.Logger("debug", "Migrate Association Verification:", "Failed to migrate verified associations", fmt.Sprintf("Error: %v", err))
+		return err
+	}
+	err = db.Exec(`INSERT INTO association_documents (created_at, updated_at, association_id, kind, name, file_key, uploaded_by)
+		SELECT NOW(), NOW(), associations.id, ?, 'kbis.pdf', associations.kbis_file, associations.owner_id
+		FROM associations
+		WHERE associations.deleted_at IS NULL AND COALESCE(associations.kbis_file, '') <> ''
+		AND NOT EXISTS (SELECT 1 FROM association_documents WHERE association_documents.association_id = associations.id
+			AND association_documents.file_key = associations.kbis_file)`, models.DocumentKbis).Error
+	if err != nil {
+		utils.Logger("debug", "Migrate Association Verification:", "Failed to migrate association KBIS", fmt.Sprintf("Error: %v", err))
+		return err
+	}
+
+	// Users and associations used to have no location, locate the ones still
+	// missing one by their postal code and copy it to their cats
+	for _, table := range []string{"users", "associations"} {
+		var cps []string
+		if err := db.Table(table).Where("latitude IS NULL AND COALESCE(cp, '') <> ''").Pluck("DISTINCT cp", &cps).Error; err != nil {
+			utils.Logger("debug", "Migrate Locations:", "Failed to list postal codes", fmt.Sprintf("Error: %v", err))
+			return err
+		}
+		for _, cp := range cps {
+			latitude, longitude := geo.Locate(cp)
+			if latitude == nil {
+				continue
+			}
+			err = db.Table(table).Where("latitude IS NULL AND cp = ?", cp).
+				Updates(map[string]interface{}{"latitude": latitude, "longitude": longitude}).Error
+			if err != nil {
+				utils.Logger("debug", "Migrate Locations:", "Failed to locate "+table, fmt.Sprintf("Error: %v", err))
+				return err
+			}
+		}
+	}
+	err = db.Exec(`UPDATE cats SET latitude = associations.latitude, longitude = associations.longitude
+		FROM associations
+		WHERE cats.latitude IS NULL AND cats.published_as = CAST(associations.id AS text)
+		AND associations.latitude IS NOT NULL`).Error
+	if err != nil {
+		utils.Logger("debug", "Migrate Locations:", "Failed to locate association cats", fmt.Sprintf("Error: %v", err))
+		return err
+	}
+	err = db.Exec(`UPDATE cats SET latitude = users.latitude, longitude = users.longitude
+		FROM users
+		WHERE cats.latitude IS NULL AND COALESCE(cats.published_as, '') = '' AND cats.user_id = CAST(users.id AS text)
+		AND users.latitude IS NOT NULL`).Error
+	if err != nil {
+		utils.Logger("debug", "Migrate Locations:", "Failed to locate user cats", fmt.Sprintf("Error: %v", err))
+		return err
+	}
+
+	// Breeds used to get their temperament from a list in the matching
+	// package, carry it over to the catalogue
+	if migrateRaceTemperaments {
+		for fragment, temperament := range legacyRaceTemperaments {
+			err = db.Model(&models.Races{}).
+				Where("LOWER(race_name) LIKE ? AND COALESCE(array_length(temperament, 1), 0) = 0", "%"+fragment+"%").
+				Update("temperament", temperament).Error
+			if err != nil {
+				utils.Logger("debug", "Migrate Races:", "Failed to fill in race temperaments", fmt.Sprintf("Error: %v", err))
+				return err
+			}
+		}
+	}
+
 	// Insert roles
 	roles := []models.Roles{
 		{Name: models.AdminRole},
@@ -221,6 +483,20 @@ func migrateAllModels(db *gorm.DB) error {
 		}
 	}
 
+	// Insert the default care protocols on first start, admins edit them after
+	var protocolCount int
+	if err := db.Model(&models.CareProtocol{}).Count(&protocolCount).Error; err != nil {
+		return err
+	}
+	if protocolCount == 0 {
+		for _, protocol := range care.DefaultProtocols {
+			if err := db.Create(&protocol).Error; err != nil {
+				utils.Logger("debug", "Create Care Protocol:", "Failed to create care protocol", fmt.Sprintf("Error: %v", err))
+				return err
+			}
+		}
+	}
+
 	fmt.Println("Migrated models and inserted roles successfully")
 	return nil
 }
@@ -0,0 +1,231 @@
+// Package dbtest runs the queries of the app against a scripted database, for
+// the tests that need to check the SQL a query sends or what it makes of the
+// rows it gets back.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+// Result is what the database answers to a statement: rows for a query,
+// a number of affected rows for an exec, or an error for both.
+type Result struct {
+	Columns      []string
+	Values       [][]driver.Value
+	RowsAffected int64
+	Err          error
+}
+
+// Query is a statement the database received, with its arguments.
+type Query struct {
+	SQL  string
+	Args []driver.Value
+}
+
+type stub struct {
+	fragment string
+	result   Result
+	once     bool
+}
+
+// DB is a scripted database. A statement gets the result of the first stub
+// whose fragment it contains. Without a stub, queries return no rows, except
+// inserts returning an ID which get a new one, and execs affect one row.
+type DB struct {
+	mu      sync.Mutex
+	stubs   []stub
+	queries []Query
+	lastID  int64
+}
+
+// New returns a gorm connection to a new scripted database.
+func New() (*gorm.DB, *DB) {
+	d := &DB{}
+	db, err := gorm.Open("postgres", sql.OpenDB(connector{d}))
+	if err != nil {
+		panic(err)
+	}
+	db.LogMode(false)
+	return db, d
+}
+
+// On answers result to every statement containing fragment.
+func (d *DB) On(fragment string, result Result) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stubs = append(d.stubs, stub{fragment: fragment, result: result})
+}
+
+// Once answers result to the next statement containing fragment only.
+func (d *DB) Once(fragment string, result Result) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stubs = append(d.stubs, stub{fragment: fragment, result: result, once: true})
+}
+
+// Queries returns the statements received so far containing fragment, or all
+// of them for an empty fragment. Transactions show up as BEGIN, COMMIT and
+// ROLLBACK.
+func (d *DB) Queries(fragment string) []Query {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var queries []Query
+	for _, query := range d.queries {
+		if strings.Contains(query.SQL, fragment) {
+			queries = append(queries, query)
+		}
+	}
+	return queries
+}
+
+// Inserted returns the value an INSERT statement gives to a column, and false
+// when the statement leaves the column out.
+func (q Query) Inserted(column string) (driver.Value, bool) {
+	start := strings.Index(q.SQL, "(")
+	end := strings.Index(q.SQL, ")")
+	if !strings.HasPrefix(q.SQL, "INSERT") || start < 0 || end < start {
+		return nil, false
+	}
+	for i, name := range strings.Split(q.SQL[start+1:end], ",") {
+		if strings.Trim(strings.TrimSpace(name), `"`) == column && i < len(q.Args) {
+			return q.Args[i], true
+		}
+	}
+	return nil, false
+}
+
+func (d *DB) record(statement string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, Query{SQL: statement})
+}
+
+func (d *DB) answer(query string, args []driver.NamedValue) (Result, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	d.queries = append(d.queries, Query{SQL: query, Args: values})
+	for i, s := range d.stubs {
+		if strings.Contains(query, s.fragment) {
+			if s.once {
+				d.stubs = append(d.stubs[:i:i], d.stubs[i+1:]...)
+			}
+			return s.result, true
+		}
+	}
+	return Result{}, false
+}
+
+type connector struct{ d *DB }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return &conn{c.d}, nil }
+func (c connector) Driver() driver.Driver                        { return c }
+func (c connector) Open(string) (driver.Conn, error)             { return &conn{c.d}, nil }
+
+type conn struct{ d *DB }
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) { return &stmt{c, query}, nil }
+func (c *conn) Close() error                              { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	c.d.record("BEGIN")
+	return tx{c.d}, nil
+}
+
+// CheckNamedValue converts arguments the way database/sql does, and keeps
+// the ones it cannot convert as they are.
+func (c *conn) CheckNamedValue(value *driver.NamedValue) error {
+	if v, err := driver.DefaultParameterConverter.ConvertValue(value.Value); err == nil {
+		value.Value = v
+	}
+	return nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, ok := c.d.answer(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	if !ok && strings.Contains(query, "RETURNING") {
+		c.d.mu.Lock()
+		c.d.lastID++
+		result = Result{Columns: []string{"id"}, Values: [][]driver.Value{{c.d.lastID}}}
+		c.d.mu.Unlock()
+	}
+	return &rows{columns: result.Columns, values: result.Values}, nil
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, ok := c.d.answer(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	if !ok {
+		result.RowsAffected = 1
+	}
+	return driver.RowsAffected(result.RowsAffected), nil
+}
+
+type stmt struct {
+	c     *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.c.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.c.QueryContext(context.Background(), s.query, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	values := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		values[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return values
+}
+
+type tx struct{ d *DB }
+
+func (t tx) Commit() error {
+	t.d.record("COMMIT")
+	return nil
+}
+
+func (t tx) Rollback() error {
+	t.d.record("ROLLBACK")
+	return nil
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,176 @@
+package queries
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go-challenge/internal/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrDuplicateApplication is returned when a user applies for a cat their
+	// pending application already targets.
+	ErrDuplicateApplication = errors.New("you already applied to adopt this cat")
+	// ErrApplicationDecided is returned when an application was decided on
+	// in the meantime.
+	ErrApplicationDecided = errors.New("the application was already decided")
+	// ErrCatNotAdoptable is returned when a cat of an application is no
+	// longer up for adoption.
+	ErrCatNotAdoptable = errors.New("a cat is no longer up for adoption")
+)
+
+// CreateAdoptionApplication sends an application, unless its applicant has a
+// pending one for one of its cats. The cats are locked so that two
+// applications sent at once cannot both get through.
+func (s *DatabaseService) CreateAdoptionApplication(application *models.AdoptionApplication) error {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	if _, err := lockCats(tx, application.CatIDs); err != nil {
+		tx.Rollback()
+		return err
+	}
+	var pending []models.AdoptionApplication
+	err := tx.Where("applicant_id = ? AND status = ? AND cat_ids && CAST(? AS varchar(100)[])",
+		application.ApplicantID, models.ApplicationPending, application.CatIDs).
+		Limit(1).Find(&pending).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(pending) > 0 {
+		tx.Rollback()
+		return ErrDuplicateApplication
+	}
+	if err := tx.Create(application).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (s *DatabaseService) FindAdoptionApplicationByID(id string) (*models.AdoptionApplication, error) {
+	db := s.s.DB()
+	var application models.AdoptionApplication
+	if err := db.Where("id = ?", id).First(&application).Error; err != nil {
+		return nil, err
+	}
+	return &application, nil
+}
+
+// FindApplicantAdoptionApplications returns the applications sent by a user,
+// most recent first.
+func (s *DatabaseService) FindApplicantAdoptionApplications(userID string) ([]models.AdoptionApplication, error) {
+	db := s.s.DB()
+	var applications []models.AdoptionApplication
+	if err := db.Where("applicant_id = ?", userID).Order("created_at DESC").Find(&applications).Error; err != nil {
+		return nil, err
+	}
+	return applications, nil
+}
+
+// FindCatAdoptionApplications returns the applications targeting a cat, alone
+// or with its siblings, most recent first.
+func (s *DatabaseService) FindCatAdoptionApplications(catID string) ([]models.AdoptionApplication, error) {
+	db := s.s.DB()
+	var applications []models.AdoptionApplication
+	if err := db.Where("? = ANY(cat_ids)", catID).Order("created_at DESC").Find(&applications).Error; err != nil {
+		return nil, err
+	}
+	return applications, nil
+}
+
+// AcceptAdoptionApplication accepts an application and reserves its cats,
+// all or none of them, and rejects the other pending applications for any of
+// them, which it returns. The application and its cats are locked and checked
+// again, so that a cat is never reserved twice.
+func (s *DatabaseService) AcceptAdoptionApplication(application *models.AdoptionApplication, cats []models.Cats, decidedBy string) ([]models.AdoptionApplication, error) {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	var current models.AdoptionApplication
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("id = ?", application.ID).First(&current).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if current.Decided() {
+		tx.Rollback()
+		return nil, ErrApplicationDecided
+	}
+	locked, err := lockCats(tx, application.CatIDs)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for i := range cats {
+		status, ok := locked[cats[i].ID]
+		if !ok || !status.Adoptable() {
+			tx.Rollback()
+			return nil, ErrCatNotAdoptable
+		}
+		cats[i].Status = status
+		note := fmt.Sprintf("adoption application %d accepted", application.ID)
+		if _, err := changeCatStatus(tx, &cats[i], models.CatReserved, decidedBy, note); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := decideAdoptionApplication(tx, application, models.ApplicationAccepted, decidedBy); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var rejected []models.AdoptionApplication
+	err = tx.Set("gorm:query_option", "FOR UPDATE").
+		Where("id <> ? AND status = ? AND cat_ids && CAST(? AS varchar(100)[])", application.ID, models.ApplicationPending, application.CatIDs).
+		Find(&rejected).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for i := range rejected {
+		if err := decideAdoptionApplication(tx, &rejected[i], models.ApplicationRejected, decidedBy); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return rejected, nil
+}
+
+// lockCats locks the cats of an application until the end of the transaction
+// and returns their current status.
+func lockCats(tx *gorm.DB, catIDs []string) (map[uint]models.CatStatus, error) {
+	var cats []models.Cats
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").Select("id, status").Where("id IN (?)", catIDs).Order("id").Find(&cats).Error; err != nil {
+		return nil, err
+	}
+	statuses := make(map[uint]models.CatStatus, len(cats))
+	for _, cat := range cats {
+		statuses[cat.ID] = cat.Status
+	}
+	return statuses, nil
+}
+
+// DecideAdoptionApplication rejects or withdraws an application.
+func (s *DatabaseService) DecideAdoptionApplication(application *models.AdoptionApplication, status models.AdoptionApplicationStatus, decidedBy string) error {
+	db := s.s.DB()
+	return decideAdoptionApplication(db, application, status, decidedBy)
+}
+
+func decideAdoptionApplication(tx *gorm.DB, application *models.AdoptionApplication, status models.AdoptionApplicationStatus, decidedBy string) error {
+	now := time.Now()
+	updates := map[string]interface{}{"status": status, "decided_by": decidedBy, "decided_at": now}
+	if err := tx.Model(application).Updates(updates).Error; err != nil {
+		return err
+	}
+	application.Status = status
+	application.DecidedBy = decidedBy
+	application.DecidedAt = &now
+	return nil
+}
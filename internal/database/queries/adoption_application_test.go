@@ -0,0 +1,85 @@
+package queries
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var applicationColumns = []string{"id", "applicant_id", "cat_ids", "status"}
+
+func testApplication() *models.AdoptionApplication {
+	application := &models.AdoptionApplication{ApplicantID: "applicant", CatIDs: pq.StringArray{"7"}, Status: models.ApplicationPending}
+	application.ID = 1
+	return application
+}
+
+func testCats() []models.Cats {
+	cat := models.Cats{Name: "Félix", Status: models.CatAvailable}
+	cat.ID = 7
+	return []models.Cats{cat}
+}
+
+func TestAcceptAdoptionApplicationRejectsOthers(t *testing.T) {
+	s, db := newTestService()
+	db.Once(`FROM "adoption_applications"`, dbtest.Result{Columns: applicationColumns, Values: [][]driver.Value{{int64(1), "applicant", "{7}", "pending"}}})
+	db.Once(`FROM "adoption_applications"`, dbtest.Result{Columns: applicationColumns, Values: [][]driver.Value{{int64(2), "other", "{7,8}", "pending"}}})
+	db.On(`FROM "cats"`, dbtest.Result{Columns: []string{"id", "status"}, Values: [][]driver.Value{{int64(7), "available"}}})
+
+	application, cats := testApplication(), testCats()
+	rejected, err := s.AcceptAdoptionApplication(application, cats, "owner")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.ApplicationAccepted, application.Status)
+	assert.Equal(t, models.CatReserved, cats[0].Status)
+	require.Len(t, rejected, 1)
+	assert.Equal(t, "other", rejected[0].ApplicantID)
+	assert.Equal(t, models.ApplicationRejected, rejected[0].Status)
+	assert.Len(t, db.Queries("FOR UPDATE"), 3)
+	assert.Len(t, db.Queries("COMMIT"), 1)
+}
+
+func TestAcceptAdoptionApplicationChecksCatsAgain(t *testing.T) {
+	s, db := newTestService()
+	db.On(`FROM "adoption_applications"`, dbtest.Result{Columns: applicationColumns, Values: [][]driver.Value{{int64(1), "applicant", "{7}", "pending"}}})
+	db.On(`FROM "cats"`, dbtest.Result{Columns: []string{"id", "status"}, Values: [][]driver.Value{{int64(7), "reserved"}}})
+
+	_, err := s.AcceptAdoptionApplication(testApplication(), testCats(), "owner")
+
+	assert.ErrorIs(t, err, ErrCatNotAdoptable)
+	assert.Len(t, db.Queries("ROLLBACK"), 1)
+	assert.Empty(t, db.Queries("INSERT"))
+}
+
+func TestAcceptAdoptionApplicationAlreadyDecided(t *testing.T) {
+	s, db := newTestService()
+	db.On(`FROM "adoption_applications"`, dbtest.Result{Columns: applicationColumns, Values: [][]driver.Value{{int64(1), "applicant", "{7}", "withdrawn"}}})
+
+	_, err := s.AcceptAdoptionApplication(testApplication(), testCats(), "owner")
+
+	assert.ErrorIs(t, err, ErrApplicationDecided)
+	assert.Len(t, db.Queries("ROLLBACK"), 1)
+}
+
+func TestCreateAdoptionApplicationRejectsDuplicates(t *testing.T) {
+	s, db := newTestService()
+	db.On(`FROM "adoption_applications"`, dbtest.Result{Columns: applicationColumns, Values: [][]driver.Value{{int64(1), "applicant", "{7}", "pending"}}})
+
+	err := s.CreateAdoptionApplication(&models.AdoptionApplication{ApplicantID: "applicant", CatIDs: pq.StringArray{"7"}})
+
+	assert.ErrorIs(t, err, ErrDuplicateApplication)
+	assert.Empty(t, db.Queries("INSERT"))
+
+	s, db = newTestService()
+	err = s.CreateAdoptionApplication(&models.AdoptionApplication{ApplicantID: "applicant", CatIDs: pq.StringArray{"7"}})
+
+	assert.NoError(t, err)
+	assert.Len(t, db.Queries(`INSERT INTO "adoption_applications"`), 1)
+	assert.Contains(t, db.Queries(`FROM "cats"`)[0].SQL, "FOR UPDATE")
+}
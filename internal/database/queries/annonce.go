@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"go-challenge/internal/models"
+	"strconv"
 
 	"gorm.io/gorm"
 )
@@ -25,6 +26,15 @@ func (s *DatabaseService) FindAnnonceByID(id string) (*models.Annonce, error) {
 	return &annonce, nil
 }
 
+func (s *DatabaseService) FindAnnonceBySlug(slug string) (*models.Annonce, error) {
+	db := s.s.DB()
+	var annonce models.Annonce
+	if err := db.Where("slug = ?", slug).First(&annonce).Error; err != nil {
+		return nil, err
+	}
+	return &annonce, nil
+}
+
 func (s *DatabaseService) UpdateAnnonceDescription(id string, description string) (*models.Annonce, error) {
 	db := s.s.DB()
 
@@ -53,17 +63,30 @@ func (s *DatabaseService) DeleteAnnonce(id string) error {
 		return err
 	}
 
-	if err := db.Delete(&annonce).Error; err != nil {
+	// Favorites of a deleted annonce would lead nowhere
+	tx := db.Begin()
+	if err := tx.Where("annonce_id = ?", strconv.FormatUint(uint64(annonce.ID), 10)).Delete(&models.Favorite{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(&annonce).Error; err != nil {
+		tx.Rollback()
 		return err
 	}
 
-	return nil
+	return tx.Commit().Error
 }
 
+// GetAllAnnonces returns the published annonces of the cats up for adoption.
+// The annonce of a litter stays up while one of its kittens is.
 func (s *DatabaseService) GetAllAnnonces() ([]models.Annonce, error) {
 	db := s.s.DB()
 	var annonces []models.Annonce
-	if err := db.Find(&annonces).Error; err != nil {
+	err := db.
+		Where("status = ?", models.AnnoncePublished).
+		Where("cat_id IN (SELECT CAST(id AS text) FROM cats WHERE status = ? AND deleted_at IS NULL) OR litter_id IN (SELECT litter_id FROM cats WHERE status = ? AND deleted_at IS NULL)", models.CatAvailable, models.CatAvailable).
+		Find(&annonces).Error
+	if err != nil {
 		return nil, err
 	}
 	return annonces, nil
@@ -88,6 +111,21 @@ func (s *DatabaseService) FindAnnonceByCatID(catID string) (*models.Annonce, err
 	return &annonce, nil
 }
 
+// FindCatAnnonce returns the latest annonce of a cat, or of the litter it
+// belongs to.
+func (s *DatabaseService) FindCatAnnonce(cat *models.Cats) (*models.Annonce, error) {
+	db := s.s.DB()
+	query := db.Where("cat_id = ?", strconv.FormatUint(uint64(cat.ID), 10))
+	if cat.LitterID != nil {
+		query = db.Where("cat_id = ? OR litter_id = ?", strconv.FormatUint(uint64(cat.ID), 10), *cat.LitterID)
+	}
+	var annonce models.Annonce
+	if err := query.Order("created_at DESC").First(&annonce).Error; err != nil {
+		return nil, err
+	}
+	return &annonce, nil
+}
+
 func (s *DatabaseService) GetUserIDByAnnonceID(annonceID string) (id string, err error) {
 	db := s.s.DB()
 	var user models.User
@@ -0,0 +1,216 @@
+package queries
+
+import (
+	"strconv"
+	"time"
+
+	"go-challenge/internal/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// AnnonceAnalyticsFilter selects the events of an annonce, or of all the
+// annonces of an association, from the day Since up to the day Until
+// excluded.
+type AnnonceAnalyticsFilter struct {
+	AnnonceID     uint
+	AssociationID string
+	Since         time.Time
+	Until         time.Time
+}
+
+// AnnonceDailyStats counts the users behind each kind of event on one day.
+type AnnonceDailyStats struct {
+	Day    time.Time                       `json:"day"`
+	Counts map[models.AnnonceEventKind]int `json:"counts"`
+}
+
+// AnnonceFunnelStep counts the users who went as far as a kind of event over
+// a period. Rate is their share of the users of the previous step, and
+// ViewRate their share of the users who viewed the annonce.
+type AnnonceFunnelStep struct {
+	Kind     models.AnnonceEventKind `json:"kind"`
+	Users    int                     `json:"users"`
+	Rate     float64                 `json:"rate"`
+	ViewRate float64                 `json:"viewRate"`
+}
+
+// AnnonceAnalytics is the activity on annonces over a period, day by day and
+// as a conversion funnel. Every day of the period is listed, even without
+// events.
+type AnnonceAnalytics struct {
+	Since  time.Time           `json:"since"`
+	Until  time.Time           `json:"until"`
+	Daily  []AnnonceDailyStats `json:"daily"`
+	Funnel []AnnonceFunnelStep `json:"funnel"`
+}
+
+type annonceEventCount struct {
+	Day   time.Time
+	Kind  models.AnnonceEventKind
+	Count int
+}
+
+// RecordAnnonceEvent records that a user did something on an annonce. It is
+// counted once per day, recording it again is not an error.
+func (s *DatabaseService) RecordAnnonceEvent(annonceID uint, kind models.AnnonceEventKind, userID string, now time.Time) error {
+	db := s.s.DB()
+	return recordAnnonceEvents(db, kind, userID, now, "annonces.id = ?", annonceID)
+}
+
+// RecordCatAnnonceEvent records that a user did something on the annonces
+// of a cat that are up, its own and the one of its litter.
+func (s *DatabaseService) RecordCatAnnonceEvent(cat *models.Cats, kind models.AnnonceEventKind, userID string, now time.Time) error {
+	db := s.s.DB()
+	return recordCatAnnonceEvents(db, cat, kind, userID, now)
+}
+
+// RecordChatOpened records that the chat about the annonce of a room started,
+// when a message is the first one sent in the room. The event goes to the
+// user interested in the annonce, whoever wrote first.
+func (s *DatabaseService) RecordChatOpened(message *models.Message, now time.Time) error {
+	db := s.s.DB()
+	var earlier int
+	if err := db.Model(&models.Message{}).Where("room_id = ? AND id < ?", message.RoomID, message.ID).Count(&earlier).Error; err != nil {
+		return err
+	}
+	if earlier > 0 {
+		return nil
+	}
+	var room models.Room
+	if err := db.Where("id = ?", message.RoomID).First(&room).Error; err != nil {
+		return err
+	}
+	return recordAnnonceEvents(db, models.AnnonceChatOpened, room.User2ID, now, "CAST(annonces.id AS text) = ?", room.AnnonceID)
+}
+
+// catAdopterID returns who adopted a cat: the user it was last transferred
+// to, or the applicant whose application for it was last accepted, whichever
+// came last. It is empty when neither is known.
+func catAdopterID(tx *gorm.DB, cat *models.Cats) (string, error) {
+	var transfers []models.CatOwnershipTransfer
+	if err := tx.Where("cat_id = ?", cat.ID).Order("created_at DESC").Limit(1).Find(&transfers).Error; err != nil {
+		return "", err
+	}
+	var applications []models.AdoptionApplication
+	err := tx.Where("status = ? AND ? = ANY(cat_ids)", models.ApplicationAccepted, strconv.FormatUint(uint64(cat.ID), 10)).
+		Order("decided_at DESC").Limit(1).Find(&applications).Error
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case len(transfers) > 0 && len(applications) > 0:
+		if applications[0].DecidedAt != nil && applications[0].DecidedAt.After(transfers[0].CreatedAt) {
+			return applications[0].ApplicantID, nil
+		}
+		return transfers[0].ToUserID, nil
+	case len(transfers) > 0:
+		return transfers[0].ToUserID, nil
+	case len(applications) > 0:
+		return applications[0].ApplicantID, nil
+	}
+	return "", nil
+}
+
+func recordCatAnnonceEvents(db *gorm.DB, cat *models.Cats, kind models.AnnonceEventKind, userID string, now time.Time) error {
+	where := "annonces.cat_id = ?"
+	args := []interface{}{strconv.FormatUint(uint64(cat.ID), 10)}
+	if cat.LitterID != nil {
+		where += " OR annonces.litter_id = ?"
+		args = append(args, *cat.LitterID)
+	}
+	statuses := []models.AnnonceStatus{models.AnnoncePublished, models.AnnoncePaused, models.AnnonceExpired}
+	return recordAnnonceEvents(db, kind, userID, now, "annonces.status IN (?) AND ("+where+")", append([]interface{}{statuses}, args...)...)
+}
+
+// recordAnnonceEvents records an event on every annonce matching a
+// condition, along with the association its cat is published as.
+func recordAnnonceEvents(db *gorm.DB, kind models.AnnonceEventKind, userID string, now time.Time, where string, args ...interface{}) error {
+	values := append([]interface{}{now, kind, userID, models.EventDay(now)}, args...)
+	return db.Exec(`INSERT INTO annonce_events (created_at, annonce_id, kind, user_id, day, association_id)
+		SELECT ?, annonces.id, ?, ?, ?, COALESCE(cats.published_as, '')
+		FROM annonces LEFT JOIN cats ON CAST(cats.id AS text) = annonces.cat_id
+		WHERE annonces.deleted_at IS NULL AND (`+where+`)
+		ON CONFLICT DO NOTHING`, values...).Error
+}
+
+// FindAnnonceAnalytics returns the activity on an annonce, or on the
+// annonces of an association, over a period.
+func (s *DatabaseService) FindAnnonceAnalytics(filter AnnonceAnalyticsFilter) (*AnnonceAnalytics, error) {
+	db := s.s.DB()
+	query := db.Table("annonce_events").Where("day >= ? AND day < ?", filter.Since, filter.Until)
+	if filter.AnnonceID != 0 {
+		query = query.Where("annonce_id = ?", filter.AnnonceID)
+	}
+	if filter.AssociationID != "" {
+		query = query.Where("association_id = ?", filter.AssociationID)
+	}
+
+	var daily []annonceEventCount
+	if err := query.Select("day, kind, COUNT(*) AS count").Group("day, kind").Scan(&daily).Error; err != nil {
+		return nil, err
+	}
+	// A user going through the funnel of several annonces counts once for
+	// each of them
+	var totals []annonceEventCount
+	err := query.Select("kind, COUNT(DISTINCT CAST(annonce_id AS text) || '/' || user_id) AS count").Group("kind").Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	users := map[models.AnnonceEventKind]int{}
+	for _, total := range totals {
+		users[total.Kind] = total.Count
+	}
+	return &AnnonceAnalytics{
+		Since:  filter.Since,
+		Until:  filter.Until,
+		Daily:  annonceDailyStats(daily, filter.Since, filter.Until),
+		Funnel: annonceFunnel(users),
+	}, nil
+}
+
+// annonceDailyStats spreads event counts over every day of a period.
+func annonceDailyStats(counts []annonceEventCount, since, until time.Time) []AnnonceDailyStats {
+	byDay := map[time.Time]map[models.AnnonceEventKind]int{}
+	for _, count := range counts {
+		day := models.EventDay(count.Day)
+		if byDay[day] == nil {
+			byDay[day] = map[models.AnnonceEventKind]int{}
+		}
+		byDay[day][count.Kind] += count.Count
+	}
+
+	daily := []AnnonceDailyStats{}
+	for day := models.EventDay(since); day.Before(until); day = day.AddDate(0, 0, 1) {
+		stats := AnnonceDailyStats{Day: day, Counts: map[models.AnnonceEventKind]int{}}
+		for _, kind := range models.AnnonceFunnel {
+			stats.Counts[kind] = byDay[day][kind]
+		}
+		daily = append(daily, stats)
+	}
+	return daily
+}
+
+// annonceFunnel orders the users of each kind of event along the funnel and
+// computes the conversion between its steps.
+func annonceFunnel(users map[models.AnnonceEventKind]int) []AnnonceFunnelStep {
+	funnel := make([]AnnonceFunnelStep, len(models.AnnonceFunnel))
+	views := users[models.AnnonceViewed]
+	for i, kind := range models.AnnonceFunnel {
+		step := AnnonceFunnelStep{Kind: kind, Users: users[kind]}
+		previous := views
+		if i > 0 {
+			previous = funnel[i-1].Users
+		}
+		if previous > 0 {
+			step.Rate = float64(step.Users) / float64(previous)
+		}
+		if views > 0 {
+			step.ViewRate = float64(step.Users) / float64(views)
+		}
+		funnel[i] = step
+	}
+	return funnel
+}
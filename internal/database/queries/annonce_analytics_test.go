@@ -0,0 +1,103 @@
+package queries
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnonceDailyStatsFillsEveryDay(t *testing.T) {
+	since := time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC)
+	until := since.AddDate(0, 0, 3)
+	counts := []annonceEventCount{
+		{Day: since, Kind: models.AnnonceViewed, Count: 4},
+		{Day: since, Kind: models.AnnonceFavorited, Count: 1},
+		{Day: since.AddDate(0, 0, 2), Kind: models.AnnonceViewed, Count: 2},
+	}
+
+	daily := annonceDailyStats(counts, since, until)
+
+	require.Len(t, daily, 3)
+	assert.Equal(t, since, daily[0].Day)
+	assert.Equal(t, 4, daily[0].Counts[models.AnnonceViewed])
+	assert.Equal(t, 1, daily[0].Counts[models.AnnonceFavorited])
+	assert.Len(t, daily[1].Counts, len(models.AnnonceFunnel))
+	assert.Equal(t, 0, daily[1].Counts[models.AnnonceViewed])
+	assert.Equal(t, 2, daily[2].Counts[models.AnnonceViewed])
+}
+
+func TestAnnonceFunnel(t *testing.T) {
+	funnel := annonceFunnel(map[models.AnnonceEventKind]int{
+		models.AnnonceViewed:     200,
+		models.AnnonceFavorited:  50,
+		models.AnnonceChatOpened: 20,
+		models.AnnonceApplied:    5,
+	})
+
+	require.Len(t, funnel, len(models.AnnonceFunnel))
+	assert.Equal(t, models.AnnonceViewed, funnel[0].Kind)
+	assert.Equal(t, 1.0, funnel[0].Rate)
+	assert.Equal(t, 0.25, funnel[1].Rate)
+	assert.Equal(t, 0.4, funnel[2].Rate)
+	assert.Equal(t, 0.1, funnel[2].ViewRate)
+	assert.Equal(t, 0.25, funnel[3].Rate)
+	assert.Equal(t, 0, funnel[4].Users)
+	assert.Equal(t, 0.0, funnel[4].Rate)
+
+	empty := annonceFunnel(map[models.AnnonceEventKind]int{})
+	assert.Equal(t, 0.0, empty[0].Rate)
+}
+
+func TestRecordChatOpenedOnFirstMessage(t *testing.T) {
+	message := &models.Message{RoomID: 4}
+	message.ID = 30
+
+	s, db := newTestService()
+	db.On(`FROM "messages"`, dbtest.Result{Columns: []string{"count"}, Values: [][]driver.Value{{int64(0)}}})
+	db.On(`FROM "rooms"`, dbtest.Result{Columns: []string{"id", "user1_id", "user2_id", "annonce_id"}, Values: [][]driver.Value{{int64(4), "author", "adopter", "12"}}})
+
+	require.NoError(t, s.RecordChatOpened(message, time.Now()))
+	events := db.Queries("INSERT INTO annonce_events")
+	require.Len(t, events, 1)
+	assert.Equal(t, "chat", events[0].Args[1])
+	assert.Equal(t, "adopter", events[0].Args[2])
+	assert.Equal(t, "12", events[0].Args[4])
+
+	s, db = newTestService()
+	db.On(`FROM "messages"`, dbtest.Result{Columns: []string{"count"}, Values: [][]driver.Value{{int64(1)}}})
+
+	require.NoError(t, s.RecordChatOpened(message, time.Now()))
+	assert.Empty(t, db.Queries("INSERT INTO annonce_events"))
+}
+
+func TestAdoptionEventGoesToAdopter(t *testing.T) {
+	decidedAt := time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC)
+	cat := &models.Cats{Status: models.CatReserved}
+	cat.ID = 7
+
+	s, db := newTestService()
+	db.On(`FROM "cat_ownership_transfers"`, dbtest.Result{Columns: []string{"id", "to_user_id", "created_at"}, Values: [][]driver.Value{{int64(1), "old-adopter", decidedAt.AddDate(-1, 0, 0)}}})
+	db.On(`FROM "adoption_applications"`, dbtest.Result{Columns: []string{"id", "applicant_id", "decided_at"}, Values: [][]driver.Value{{int64(2), "applicant", decidedAt}}})
+
+	_, err := s.ChangeCatStatus(cat, models.CatAdopted, "owner", "")
+
+	require.NoError(t, err)
+	events := db.Queries("INSERT INTO annonce_events")
+	require.Len(t, events, 1)
+	assert.Equal(t, "adoption", events[0].Args[1])
+	assert.Equal(t, "applicant", events[0].Args[2])
+
+	// Without a known adopter, no adoption is recorded
+	cat.Status = models.CatReserved
+	s, db = newTestService()
+	_, err = s.ChangeCatStatus(cat, models.CatAdopted, "owner", "")
+
+	require.NoError(t, err)
+	assert.Empty(t, db.Queries("INSERT INTO annonce_events"))
+}
@@ -0,0 +1,89 @@
+package queries
+
+import (
+	"go-challenge/internal/models"
+)
+
+// MaxPhotoHashDistance is the number of bits the perceptual hashes of two
+// photos may differ by for them to be taken as the same picture.
+const MaxPhotoHashDistance = 5
+
+const photoHashDistanceSQL = "LENGTH(REPLACE(CAST(CAST(photos.hash # others.hash AS bit(64)) AS text), '0', ''))"
+
+// FindDuplicatePhotos returns the photos of cats of other owners that look
+// like the photos of some cats, the closest first. Cats published by the same
+// association belong to the same owner. When photoIDs is not empty, only
+// these photos of the cats are compared.
+func (s *DatabaseService) FindDuplicatePhotos(catIDs, photoIDs []uint, userID, publishedAs string) ([]models.AnnoncePhotoMatch, error) {
+	db := s.s.DB()
+	allPhotos := len(photoIDs) == 0
+	if allPhotos {
+		photoIDs = []uint{0}
+	}
+	var matches []models.AnnoncePhotoMatch
+	err := db.Raw(`SELECT photos.id AS photo_id, photos.url AS photo_url,
+			others.id AS matched_photo_id, others.url AS matched_photo_url,
+			others.cat_id AS matched_cat_id, cats.user_id AS matched_user_id,
+			(SELECT annonces.id FROM annonces WHERE annonces.cat_id = CAST(cats.id AS text) AND annonces.deleted_at IS NULL
+				ORDER BY annonces.created_at DESC LIMIT 1) AS matched_annonce_id,
+			`+photoHashDistanceSQL+` AS distance
+		FROM cat_photos AS photos
+		JOIN cat_photos AS others ON others.cat_id <> photos.cat_id AND others.hash IS NOT NULL AND others.deleted_at IS NULL
+		JOIN cats ON cats.id = others.cat_id AND cats.deleted_at IS NULL
+		WHERE photos.cat_id IN (?) AND (? OR photos.id IN (?)) AND photos.hash IS NOT NULL AND photos.deleted_at IS NULL
+		AND cats.user_id <> ? AND (? = '' OR COALESCE(cats.published_as, '') <> ?)
+		AND `+photoHashDistanceSQL+` <= ?
+		ORDER BY distance, others.id`,
+		catIDs, allPhotos, photoIDs, userID, publishedAs, publishedAs, MaxPhotoHashDistance).Scan(&matches).Error
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// ReportDuplicateAnnonce makes the automatic report of an annonce whose
+// photos were already published by other owners, with the matches as
+// evidence.
+func (s *DatabaseService) ReportDuplicateAnnonce(annonce *models.Annonce, matches []models.AnnoncePhotoMatch) (*models.ReportedAnnonce, error) {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	var reason models.ReportReason
+	if err := tx.Where(models.ReportReason{Reason: models.ReasonDuplicatePhotos}).FirstOrCreate(&reason).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	report := &models.ReportedAnnonce{
+		AnnonceID:      annonce.ID,
+		ReportedUserID: annonce.UserID,
+		ReasonID:       reason.ID,
+		Automatic:      true,
+	}
+	if err := tx.Create(report).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for _, match := range matches {
+		match.ReportID = report.ID
+		if err := tx.Create(&match).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// FindAnnoncePhotoMatches returns the evidence of an automatic report.
+func (s *DatabaseService) FindAnnoncePhotoMatches(reportID uint) ([]models.AnnoncePhotoMatch, error) {
+	db := s.s.DB()
+	var matches []models.AnnoncePhotoMatch
+	if err := db.Where("report_id = ?", reportID).Order("distance, id").Find(&matches).Error; err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
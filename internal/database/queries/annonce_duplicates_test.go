@@ -0,0 +1,65 @@
+package queries
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var photoMatchColumns = []string{"photo_id", "photo_url", "matched_photo_id", "matched_photo_url", "matched_cat_id", "matched_user_id", "matched_annonce_id", "distance"}
+
+func TestFindDuplicatePhotos(t *testing.T) {
+	s, db := newTestService()
+	db.On("FROM cat_photos AS photos", dbtest.Result{Columns: photoMatchColumns, Values: [][]driver.Value{
+		{int64(1), "https://cdn/felix.jpg", int64(9), "https://cdn/other.jpg", int64(4), "other", int64(12), int64(2)},
+		{int64(1), "https://cdn/felix.jpg", int64(10), "https://cdn/copy.jpg", int64(5), "copier", nil, int64(3)},
+	}})
+
+	matches, err := s.FindDuplicatePhotos([]uint{7, 8}, nil, "owner", "asso")
+
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, uint(9), matches[0].MatchedPhotoID)
+	assert.Equal(t, "other", matches[0].MatchedUserID)
+	require.NotNil(t, matches[0].MatchedAnnonceID)
+	assert.Equal(t, uint(12), *matches[0].MatchedAnnonceID)
+	assert.Nil(t, matches[1].MatchedAnnonceID)
+	assert.Equal(t, 3, matches[1].Distance)
+
+	queries := db.Queries("FROM cat_photos AS photos")
+	require.Len(t, queries, 1)
+	assert.Contains(t, queries[0].SQL, "photos.cat_id IN ($1,$2) AND ($3 OR photos.id IN ($4))")
+	assert.Contains(t, queries[0].SQL, "cats.user_id <> $5")
+	assert.Equal(t, []driver.Value{int64(7), int64(8), true, int64(0), "owner", "asso", "asso", int64(MaxPhotoHashDistance)}, queries[0].Args)
+}
+
+func TestFindDuplicatePhotosOfSomePhotos(t *testing.T) {
+	s, db := newTestService()
+
+	matches, err := s.FindDuplicatePhotos([]uint{7}, []uint{3, 4}, "owner", "")
+
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+	queries := db.Queries("FROM cat_photos AS photos")
+	require.Len(t, queries, 1)
+	assert.Equal(t, []driver.Value{int64(7), false, int64(3), int64(4), "owner", "", "", int64(MaxPhotoHashDistance)}, queries[0].Args)
+}
+
+func TestFindUnhashedCatPhotos(t *testing.T) {
+	s, db := newTestService()
+	db.On(`FROM "cat_photos"`, dbtest.Result{Columns: []string{"id", "cat_id", "url"}, Values: [][]driver.Value{{int64(5), int64(7), "https://cdn/felix.jpg"}}})
+
+	photos, err := s.FindUnhashedCatPhotos(4, 100)
+
+	require.NoError(t, err)
+	require.Len(t, photos, 1)
+	assert.Equal(t, uint(5), photos[0].ID)
+	queries := db.Queries(`FROM "cat_photos"`)
+	require.Len(t, queries, 1)
+	assert.Contains(t, queries[0].SQL, "hash IS NULL AND id > $1")
+	assert.Contains(t, queries[0].SQL, "LIMIT 100")
+}
@@ -0,0 +1,164 @@
+package queries
+
+import (
+	"strconv"
+	"time"
+
+	"go-challenge/internal/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ChangeAnnonceStatus moves an annonce to a new status. The transition is
+// expected to be checked by the caller. Publishing, which also renews, sets
+// the expiry date and rearms the renewal reminder; closing records a reason.
+func (s *DatabaseService) ChangeAnnonceStatus(annonce *models.Annonce, to models.AnnonceStatus, reason models.AnnonceCloseReason, expiresAt *time.Time) error {
+	db := s.s.DB()
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": to}
+	switch to {
+	case models.AnnoncePublished:
+		if annonce.PublishedAt == nil {
+			updates["published_at"] = now
+		}
+		updates["expires_at"] = expiresAt
+		updates["renewal_reminded_at"] = gorm.Expr("NULL")
+	case models.AnnonceClosed:
+		updates["close_reason"] = reason
+	}
+	if err := db.Model(annonce).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	annonce.Status = to
+	switch to {
+	case models.AnnoncePublished:
+		if annonce.PublishedAt == nil {
+			annonce.PublishedAt = &now
+		}
+		annonce.ExpiresAt = expiresAt
+		annonce.RenewalRemindedAt = nil
+	case models.AnnonceClosed:
+		annonce.CloseReason = reason
+	}
+	return nil
+}
+
+// ExpireAnnonces expires the published annonces whose expiry date has passed
+// and returns them.
+func (s *DatabaseService) ExpireAnnonces(now time.Time) ([]models.Annonce, error) {
+	db := s.s.DB()
+
+	var annonces []models.Annonce
+	if err := db.Where("status = ? AND expires_at <= ?", models.AnnoncePublished, now).Find(&annonces).Error; err != nil {
+		return nil, err
+	}
+	if len(annonces) == 0 {
+		return annonces, nil
+	}
+
+	ids := make([]uint, len(annonces))
+	for i := range annonces {
+		ids[i] = annonces[i].ID
+		annonces[i].Status = models.AnnonceExpired
+	}
+	err := db.Model(&models.Annonce{}).
+		Where("id IN (?) AND status = ?", ids, models.AnnoncePublished).
+		Update("status", models.AnnonceExpired).Error
+	if err != nil {
+		return nil, err
+	}
+	return annonces, nil
+}
+
+// FindAnnoncesToRemind returns the published annonces expiring before a date
+// whose owner was not reminded to renew them yet.
+func (s *DatabaseService) FindAnnoncesToRemind(until time.Time) ([]models.Annonce, error) {
+	db := s.s.DB()
+	var annonces []models.Annonce
+	err := db.
+		Where("status = ? AND expires_at <= ? AND renewal_reminded_at IS NULL", models.AnnoncePublished, until).
+		Order("expires_at").
+		Find(&annonces).Error
+	if err != nil {
+		return nil, err
+	}
+	return annonces, nil
+}
+
+func (s *DatabaseService) MarkAnnonceRenewalReminded(annonce *models.Annonce, now time.Time) error {
+	db := s.s.DB()
+	if err := db.Model(annonce).Update("renewal_reminded_at", now).Error; err != nil {
+		return err
+	}
+	annonce.RenewalRemindedAt = &now
+	return nil
+}
+
+// FindPublishedAnnonceByCatID returns the published annonce of a cat, the
+// most recent one if the cat has several.
+func (s *DatabaseService) FindPublishedAnnonceByCatID(catID string) (*models.Annonce, error) {
+	db := s.s.DB()
+	var annonce models.Annonce
+	err := db.Where("cat_id = ? AND status = ?", catID, models.AnnoncePublished).Order("created_at DESC").First(&annonce).Error
+	if err != nil {
+		return nil, err
+	}
+	return &annonce, nil
+}
+
+// FindPublishedAnnoncesByCatIDs returns the published annonces of cats keyed
+// by cat, the most recent one for a cat that has several.
+func (s *DatabaseService) FindPublishedAnnoncesByCatIDs(catIDs []uint) (map[uint]*models.Annonce, error) {
+	annonces := make(map[uint]*models.Annonce, len(catIDs))
+	if len(catIDs) == 0 {
+		return annonces, nil
+	}
+	ids := make([]string, len(catIDs))
+	for i, catID := range catIDs {
+		ids[i] = strconv.FormatUint(uint64(catID), 10)
+	}
+
+	db := s.s.DB()
+	var found []models.Annonce
+	err := db.Where("cat_id IN (?) AND status = ?", ids, models.AnnoncePublished).Order("created_at DESC").Find(&found).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range found {
+		catID, err := strconv.ParseUint(found[i].CatID, 10, 64)
+		if err != nil {
+			continue
+		}
+		if _, ok := annonces[uint(catID)]; !ok {
+			annonces[uint(catID)] = &found[i]
+		}
+	}
+	return annonces, nil
+}
+
+// closeCatAnnonces closes the annonces of a cat. The annonce of a litter is
+// left up while its other kittens are looking for a home, and closed along
+// once none of them is adoptable any more. The status of the cat is expected
+// to be changed already.
+func closeCatAnnonces(tx *gorm.DB, cat *models.Cats, reason models.AnnonceCloseReason) error {
+	closed := map[string]interface{}{"status": models.AnnonceClosed, "close_reason": reason}
+	err := tx.Model(&models.Annonce{}).
+		Where("cat_id = ? AND litter_id IS NULL AND status <> ?", cat.ID, models.AnnonceClosed).
+		Updates(closed).Error
+	if err != nil || cat.LitterID == nil {
+		return err
+	}
+
+	var adoptable int
+	if err := tx.Model(&models.Cats{}).Where("litter_id = ? AND status = ?", *cat.LitterID, models.CatAvailable).Count(&adoptable).Error; err != nil {
+		return err
+	}
+	if adoptable > 0 {
+		return nil
+	}
+	return tx.Model(&models.Annonce{}).
+		Where("litter_id = ? AND status <> ?", *cat.LitterID, models.AnnonceClosed).
+		Updates(closed).Error
+}
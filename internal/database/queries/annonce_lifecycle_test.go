@@ -0,0 +1,59 @@
+package queries
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKitten() *models.Cats {
+	litterID := uint(4)
+	cat := &models.Cats{Name: "Félix", Status: models.CatAdopted, LitterID: &litterID}
+	cat.ID = 7
+	return cat
+}
+
+func TestCloseCatAnnoncesKeepsLitterWithAdoptableKittens(t *testing.T) {
+	s, db := newTestService()
+	db.On("count(*)", dbtest.Result{Columns: []string{"count"}, Values: [][]driver.Value{{int64(1)}}})
+
+	err := closeCatAnnonces(s.s.DB(), testKitten(), models.CloseAdopted)
+
+	require.NoError(t, err)
+	updates := db.Queries(`UPDATE "annonces"`)
+	require.Len(t, updates, 1)
+	assert.Contains(t, updates[0].SQL, "litter_id IS NULL")
+}
+
+func TestCloseCatAnnoncesClosesLitterOfLastKitten(t *testing.T) {
+	s, db := newTestService()
+	db.On("count(*)", dbtest.Result{Columns: []string{"count"}, Values: [][]driver.Value{{int64(0)}}})
+
+	err := closeCatAnnonces(s.s.DB(), testKitten(), models.CloseAdopted)
+
+	require.NoError(t, err)
+	counts := db.Queries("count(*)")
+	require.Len(t, counts, 1)
+	assert.Equal(t, []driver.Value{int64(4), string(models.CatAvailable)}, counts[0].Args)
+	updates := db.Queries(`UPDATE "annonces"`)
+	require.Len(t, updates, 2)
+	assert.Contains(t, updates[1].SQL, "litter_id = ")
+	assert.Contains(t, updates[1].Args, int64(4))
+	assert.Contains(t, updates[1].Args, string(models.CloseAdopted))
+}
+
+func TestCloseCatAnnoncesOutsideLitter(t *testing.T) {
+	s, db := newTestService()
+	cat := testKitten()
+	cat.LitterID = nil
+
+	require.NoError(t, closeCatAnnonces(s.s.DB(), cat, models.CloseDeceased))
+
+	assert.Len(t, db.Queries(`UPDATE "annonces"`), 1)
+	assert.Empty(t, db.Queries("count(*)"))
+}
@@ -0,0 +1,220 @@
+package queries
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"go-challenge/internal/geo"
+	"go-challenge/internal/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+type AnnonceSearchSort string
+
+const (
+	AnnonceSortNewest    AnnonceSearchSort = "newest"
+	AnnonceSortNearest   AnnonceSearchSort = "nearest"
+	AnnonceSortFavorites AnnonceSearchSort = "favorites"
+)
+
+var ErrInvalidAnnonceSearchSort = errors.New("sort must be newest, nearest or favorites")
+
+// AnnonceSearch holds the filters of an annonce listing. Cat filters the cats
+// the annonces are about with the same rules as a cat search, apart from its
+// status, sort, cursor and limit: only the published annonces of cats up for
+// adoption are listed.
+type AnnonceSearch struct {
+	Cat    CatSearch
+	Sort   AnnonceSearchSort
+	Cursor string
+	Limit  int
+}
+
+// AnnonceSearchResult is an annonce found by a listing, along with its cat
+// and where and by whom it is published. The association is set when the cat
+// is published by one, the name and town are then those of the association.
+type AnnonceSearchResult struct {
+	models.Annonce
+	Cat            *models.Cats `json:"cat" gorm:"-"`
+	RaceName       string       `json:"raceName"`
+	PublisherName  string       `json:"publisherName"`
+	AssociationID  *uint        `json:"associationId,omitempty"`
+	Ville          string       `json:"ville"`
+	Cp             string       `json:"cp"`
+	Latitude       *float64     `json:"latitude"`
+	Longitude      *float64     `json:"longitude"`
+	DistanceKm     *float64     `json:"distanceKm,omitempty"`
+	FavoritesCount int          `json:"favoritesCount"`
+}
+
+// AnnonceSearchPage is one page of an annonce listing. Total counts the
+// annonces matching the filters across all pages. NextCursor is empty on the
+// last page.
+type AnnonceSearchPage struct {
+	Annonces   []AnnonceSearchResult `json:"annonces"`
+	Total      int                   `json:"total"`
+	NextCursor string                `json:"nextCursor,omitempty"`
+}
+
+const annonceFavoritesSQL = "(SELECT COUNT(*) FROM favorites WHERE favorites.annonce_id = CAST(annonces.id AS text) AND favorites.deleted_at IS NULL)"
+
+// annonceSearchOrderFor returns the order of a sort. Sorting by distance only
+// keeps the annonces of cats that have a location.
+func annonceSearchOrderFor(search AnnonceSearch) (catSearchOrder, error) {
+	switch search.Sort {
+	case AnnonceSortNewest:
+		return catSearchOrder{
+			expr: "COALESCE(annonces.published_at, annonces.created_at)",
+			desc: true,
+			kind: catSearchKeyTime,
+		}, nil
+	case AnnonceSortFavorites:
+		return catSearchOrder{
+			expr: annonceFavoritesSQL,
+			desc: true,
+			kind: catSearchKeyFloat,
+		}, nil
+	case AnnonceSortNearest:
+		if search.Cat.Near == nil {
+			return catSearchOrder{}, ErrMissingSearchOrigin
+		}
+		return catSearchOrder{
+			expr: geo.DistanceSQL(*search.Cat.Near, "cats.latitude", "cats.longitude"),
+			kind: catSearchKeyFloat,
+		}, nil
+	}
+	return catSearchOrder{}, ErrInvalidAnnonceSearchSort
+}
+
+// annonceSearchKey returns the sort key of an annonce, to be used as the
+// cursor of the next page.
+func annonceSearchKey(sort AnnonceSearchSort, result *AnnonceSearchResult) interface{} {
+	switch sort {
+	case AnnonceSortFavorites:
+		return float64(result.FavoritesCount)
+	case AnnonceSortNearest:
+		return *result.DistanceKm
+	}
+	if result.PublishedAt != nil {
+		return *result.PublishedAt
+	}
+	return result.CreatedAt
+}
+
+// SearchAnnonces returns one page of the published annonces of cats up for
+// adoption matching every filter, with their cat.
+func (s *DatabaseService) SearchAnnonces(search AnnonceSearch, now time.Time) (*AnnonceSearchPage, error) {
+	db := s.s.DB()
+
+	if search.Sort == "" {
+		search.Sort = AnnonceSortNewest
+	}
+	order, err := annonceSearchOrderFor(search)
+	if err != nil {
+		return nil, err
+	}
+	if search.Cat.RadiusKm != nil && search.Cat.Near == nil {
+		return nil, ErrMissingSearchOrigin
+	}
+	if search.Limit <= 0 {
+		search.Limit = DefaultCatSearchLimit
+	}
+	if search.Limit > MaxCatSearchLimit {
+		search.Limit = MaxCatSearchLimit
+	}
+
+	query := db.Table("annonces").
+		Joins("JOIN cats ON CAST(cats.id AS text) = annonces.cat_id AND cats.deleted_at IS NULL").
+		Joins("LEFT JOIN races ON CAST(races.id AS text) = cats.race_id AND races.deleted_at IS NULL").
+		Joins("LEFT JOIN associations ON CAST(associations.id AS text) = cats.published_as AND associations.deleted_at IS NULL").
+		Joins("LEFT JOIN users ON CAST(users.id AS text) = cats.user_id").
+		Where("annonces.deleted_at IS NULL AND annonces.status = ?", models.AnnoncePublished).
+		Where("cats.status = ? OR annonces.litter_id IN (SELECT litter_id FROM cats WHERE status = ? AND deleted_at IS NULL)", models.CatAvailable, models.CatAvailable)
+
+	catFilters := search.Cat
+	catFilters.Status = ""
+	catFilters.Near, catFilters.RadiusKm = nil, nil
+	// The annonce of a litter matches when any of its kittens up for adoption
+	// does, not only the one it is attached to.
+	cats := applyCatSearchFilters(query.New().Table("cats"), catFilters, now).Where("deleted_at IS NULL")
+	kittens := cats.Where("litter_id IS NOT NULL AND status = ?", models.CatAvailable).Select("litter_id")
+	query = query.Where("annonces.cat_id IN (?) OR annonces.litter_id IN (?)", cats.Select("CAST(id AS text)").SubQuery(), kittens.SubQuery())
+
+	selects := "annonces.*, races.race_name AS race_name, COALESCE(associations.name, users.name) AS publisher_name, associations.id AS association_id, " +
+		"COALESCE(associations.ville, users.ville) AS ville, COALESCE(associations.cp, users.cp) AS cp, cats.latitude AS latitude, cats.longitude AS longitude, " +
+		annonceFavoritesSQL + " AS favorites_count"
+	if search.Cat.Near != nil {
+		distance := geo.DistanceSQL(*search.Cat.Near, "cats.latitude", "cats.longitude")
+		selects += ", " + distance + " AS distance_km"
+		if search.Cat.RadiusKm != nil || search.Sort == AnnonceSortNearest {
+			query = query.Where("cats.latitude IS NOT NULL AND cats.longitude IS NOT NULL")
+		}
+		if search.Cat.RadiusKm != nil {
+			query = query.Where(distance+" <= ?", *search.Cat.RadiusKm)
+		}
+	}
+
+	page := &AnnonceSearchPage{Annonces: []AnnonceSearchResult{}}
+	if err := query.Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	direction, comparator := "ASC", ">"
+	if order.desc {
+		direction, comparator = "DESC", "<"
+	}
+	if search.Cursor != "" {
+		key, id, err := decodeSearchCursor(order.kind, search.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("("+order.expr+", annonces.id) "+comparator+" (?, ?)", key, id)
+	}
+
+	var results []AnnonceSearchResult
+	err = query.
+		Select(selects).
+		Order(order.expr + " " + direction).
+		Order("annonces.id " + direction).
+		Limit(search.Limit + 1).
+		Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > search.Limit {
+		results = results[:search.Limit]
+		last := &results[search.Limit-1]
+		page.NextCursor = encodeSearchCursor(annonceSearchKey(search.Sort, last), last.ID)
+	}
+
+	if err := withAnnonceSearchCats(db, results); err != nil {
+		return nil, err
+	}
+	page.Annonces = append(page.Annonces, results...)
+	return page, nil
+}
+
+func withAnnonceSearchCats(db *gorm.DB, results []AnnonceSearchResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	catIDs := make([]string, len(results))
+	for i := range results {
+		catIDs[i] = results[i].CatID
+	}
+
+	var cats []models.Cats
+	if err := db.Where("CAST(id AS text) IN (?)", catIDs).Find(&cats).Error; err != nil {
+		return err
+	}
+	byID := map[string]*models.Cats{}
+	for i := range cats {
+		byID[strconv.FormatUint(uint64(cats[i].ID), 10)] = &cats[i]
+	}
+	for i := range results {
+		results[i].Cat = byID[results[i].CatID]
+	}
+	return nil
+}
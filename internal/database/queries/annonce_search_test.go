@@ -0,0 +1,68 @@
+package queries
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/geo"
+	"go-challenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnonceSearchCursorRoundTrip(t *testing.T) {
+	publishedAt := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	result := &AnnonceSearchResult{Annonce: models.Annonce{PublishedAt: &publishedAt}, FavoritesCount: 4}
+	result.ID = 12
+
+	for _, sort := range []AnnonceSearchSort{AnnonceSortNewest, AnnonceSortFavorites} {
+		order, err := annonceSearchOrderFor(AnnonceSearch{Sort: sort})
+		require.NoError(t, err)
+
+		key, id, err := decodeSearchCursor(order.kind, encodeSearchCursor(annonceSearchKey(sort, result), result.ID))
+		require.NoError(t, err)
+		assert.Equal(t, annonceSearchKey(sort, result), key)
+		assert.Equal(t, uint(12), id)
+	}
+}
+
+func TestAnnonceSearchOrder(t *testing.T) {
+	_, err := annonceSearchOrderFor(AnnonceSearch{Sort: AnnonceSortNearest})
+	assert.ErrorIs(t, err, ErrMissingSearchOrigin)
+
+	_, err = annonceSearchOrderFor(AnnonceSearch{Sort: AnnonceSortNearest, Cat: CatSearch{Near: &geo.Point{Latitude: 48.85, Longitude: 2.35}}})
+	assert.NoError(t, err)
+
+	_, err = annonceSearchOrderFor(AnnonceSearch{Sort: "name"})
+	assert.ErrorIs(t, err, ErrInvalidAnnonceSearchSort)
+}
+
+func TestSearchAnnoncesMatchesLitterKittens(t *testing.T) {
+	s, db := newTestService()
+	db.On("count(*)", dbtest.Result{Columns: []string{"count"}, Values: [][]driver.Value{{int64(0)}}})
+
+	_, err := s.SearchAnnonces(AnnonceSearch{Cat: CatSearch{Sexe: "female"}}, time.Now())
+
+	require.NoError(t, err)
+	searches := db.Queries(`FROM "annonces"`)
+	require.Len(t, searches, 2)
+	for _, search := range searches {
+		assert.Contains(t, search.SQL, `annonces.cat_id IN ((SELECT CAST(id AS text) FROM "cats"`)
+		assert.Contains(t, search.SQL, `OR annonces.litter_id IN ((SELECT litter_id FROM "cats"`)
+		assert.Contains(t, search.SQL, "litter_id IS NOT NULL AND status = ")
+		assert.Equal(t, 2, countArg(search.Args, "female"))
+	}
+}
+
+func countArg(args []driver.Value, value driver.Value) int {
+	count := 0
+	for _, arg := range args {
+		if arg == value {
+			count++
+		}
+	}
+	return count
+}
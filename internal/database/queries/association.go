@@ -3,8 +3,6 @@ package queries
 import (
 	"go-challenge/internal/database"
 	"go-challenge/internal/models"
-
-	"github.com/jinzhu/gorm"
 )
 
 type DatabaseService struct {
@@ -17,18 +15,37 @@ func NewQueriesService(s *database.Service) *DatabaseService {
 	}
 }
 
+// CreateAssociation creates an association with its owner as its only
+// member, submitted for verification along with its KBIS when it has one.
 func (s *DatabaseService) CreateAssociation(association *models.Association) error {
 	db := s.s.DB()
-	if err := db.Create(association).Error; err != nil {
+	association.Members = []models.AssociationMember{{UserID: association.OwnerID, Role: models.AssociationOwner}}
+	association.VerificationStatus = models.AssociationSubmitted
+
+	tx := db.Begin()
+	if err := tx.Create(association).Error; err != nil {
+		tx.Rollback()
 		return err
 	}
-	return nil
+	if association.KbisFile != "" {
+		document := &models.AssociationDocument{
+			AssociationID: association.ID,
+			Kind:          models.DocumentKbis,
+			FileKey:       association.KbisFile,
+			UploadedBy:    association.OwnerID,
+		}
+		if err := tx.Create(document).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
 }
 
 func (s *DatabaseService) GetAllAssociations() ([]models.Association, error) {
 	db := s.s.DB()
 	var associations []models.Association
-	if err := db.Order("verified ASC").Find(&associations).Error; err != nil {
+	if err := db.Preload("Members").Order("verified ASC").Find(&associations).Error; err != nil {
 		return nil, err
 	}
 	return associations, nil
@@ -36,7 +53,8 @@ func (s *DatabaseService) GetAllAssociations() ([]models.Association, error) {
 
 func (s *DatabaseService) UpdateAssociation(association *models.Association) error {
 	db := s.s.DB()
-	if err := db.Save(association).Error; err != nil {
+	// Members change through their own queries
+	if err := db.Set("gorm:save_associations", false).Save(association).Error; err != nil {
 		return err
 	}
 	return nil
@@ -45,7 +63,7 @@ func (s *DatabaseService) UpdateAssociation(association *models.Association) err
 func (s *DatabaseService) FindAssociationById(id int) (*models.Association, error) {
 	db := s.s.DB()
 	var association models.Association
-	if err := db.First(&association, id).Error; err != nil {
+	if err := db.Preload("Members").First(&association, id).Error; err != nil {
 		return nil, err
 	}
 	return &association, nil
@@ -68,7 +86,8 @@ func (s *DatabaseService) FindUserByAssociationID(id int) (*models.User, error)
 func (s *DatabaseService) FindAssociationsByUserId(userId string) ([]models.Association, error) {
 	db := s.s.DB()
 	var associations []models.Association
-	if err := db.Where("owner_id = ? OR ? = ANY(members)", userId, userId).Find(&associations).Error; err != nil {
+	members := db.Table("association_members").Select("association_id").Where("user_id = ?", userId)
+	if err := db.Preload("Members").Where("owner_id = ? OR id IN (?)", userId, members.SubQuery()).Find(&associations).Error; err != nil {
 		return nil, err
 	}
 	return associations, nil
@@ -81,27 +100,3 @@ func (s *DatabaseService) DeleteAssociation(id int) error {
 	}
 	return nil
 }
-
-func (s *DatabaseService) UpdateAssociationMembers(associationID uint, memberIDs []string) error {
-	db := s.s.DB()
-	tx := db.Begin()
-
-	if err := tx.Model(&models.Association{Model: gorm.Model{ID: associationID}}).Association("Members").Clear().Error; err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	for _, memberID := range memberIDs {
-		user := models.User{}
-		if err := tx.Where("id = ?", memberID).First(&user).Error; err != nil {
-			tx.Rollback()
-			return err
-		}
-		if err := tx.Model(&models.Association{Model: gorm.Model{ID: associationID}}).Association("Members").Append(&user).Error; err != nil {
-			tx.Rollback()
-			return err
-		}
-	}
-
-	return tx.Commit().Error
-}
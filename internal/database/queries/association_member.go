@@ -0,0 +1,181 @@
+package queries
+
+import (
+	"time"
+
+	"go-challenge/internal/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// AssociationMemberDetails is a member of an association along with who they
+// are.
+type AssociationMemberDetails struct {
+	models.AssociationMember
+	Name                   string `json:"name"`
+	Email                  string `json:"email"`
+	ProfilePicThumbnailURL string `json:"profilePicThumbnailUrl"`
+}
+
+// AssociationInvitationDetails is an invitation along with the name of the
+// association it is to.
+type AssociationInvitationDetails struct {
+	models.AssociationInvitation
+	AssociationName string `json:"associationName"`
+}
+
+// FindAssociationMembers returns the members of an association, its owner
+// first, then its admins and volunteers by name.
+func (s *DatabaseService) FindAssociationMembers(associationID uint) ([]AssociationMemberDetails, error) {
+	db := s.s.DB()
+	members := []AssociationMemberDetails{}
+	err := db.Table("association_members").
+		Select("association_members.*, users.name, users.email, users.profile_pic_thumbnail_url").
+		Joins("JOIN users ON users.id = association_members.user_id AND users.deleted_at IS NULL").
+		Where("association_members.association_id = ?", associationID).
+		Order("CASE association_members.role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 ELSE 2 END, users.name").
+		Scan(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (s *DatabaseService) FindAssociationMember(associationID uint, userID string) (*models.AssociationMember, error) {
+	db := s.s.DB()
+	var member models.AssociationMember
+	if err := db.Where("association_id = ? AND user_id = ?", associationID, userID).First(&member).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (s *DatabaseService) UpdateAssociationMemberRole(member *models.AssociationMember, role models.AssociationRole) error {
+	db := s.s.DB()
+	if err := db.Model(member).Update("role", role).Error; err != nil {
+		return err
+	}
+	member.Role = role
+	return nil
+}
+
+func (s *DatabaseService) RemoveAssociationMember(member *models.AssociationMember) error {
+	db := s.s.DB()
+	return db.Delete(member).Error
+}
+
+// TransferAssociationOwnership hands an association over to one of its
+// members. The previous owner stays on as an admin.
+func (s *DatabaseService) TransferAssociationOwnership(association *models.Association, member *models.AssociationMember) error {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	err := tx.Exec(`INSERT INTO association_members (created_at, updated_at, association_id, user_id, role)
+		VALUES (NOW(), NOW(), ?, ?, ?)
+		ON CONFLICT (association_id, user_id) DO UPDATE SET role = EXCLUDED.role, updated_at = NOW()`,
+		association.ID, association.OwnerID, models.AssociationAdmin).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Model(member).Update("role", models.AssociationOwner).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Model(association).UpdateColumn("owner_id", member.UserID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	member.Role = models.AssociationOwner
+	association.OwnerID = member.UserID
+	return nil
+}
+
+func (s *DatabaseService) CreateAssociationInvitation(invitation *models.AssociationInvitation) error {
+	db := s.s.DB()
+	return db.Create(invitation).Error
+}
+
+func (s *DatabaseService) FindAssociationInvitationByID(id string) (*models.AssociationInvitation, error) {
+	db := s.s.DB()
+	var invitation models.AssociationInvitation
+	if err := db.Where("id = ?", id).First(&invitation).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// FindOpenAssociationInvitations returns the invitations to an association
+// that may still be accepted, the most recent first. With an email, only the
+// ones sent to it are returned.
+func (s *DatabaseService) FindOpenAssociationInvitations(associationID uint, email string, now time.Time) ([]models.AssociationInvitation, error) {
+	db := s.s.DB()
+	query := db.Where("association_id = ? AND status = ? AND expires_at > ?", associationID, models.InvitationPending, now)
+	if email != "" {
+		query = query.Where("LOWER(email) = LOWER(?)", email)
+	}
+	invitations := []models.AssociationInvitation{}
+	if err := query.Order("created_at DESC").Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// FindUserAssociationInvitations returns the invitations sent to an email
+// address that may still be accepted, the most recent first.
+func (s *DatabaseService) FindUserAssociationInvitations(email string, now time.Time) ([]AssociationInvitationDetails, error) {
+	db := s.s.DB()
+	invitations := []AssociationInvitationDetails{}
+	err := db.Table("association_invitations").
+		Select("association_invitations.*, associations.name AS association_name").
+		Joins("JOIN associations ON associations.id = association_invitations.association_id AND associations.deleted_at IS NULL").
+		Where("association_invitations.deleted_at IS NULL AND LOWER(association_invitations.email) = LOWER(?)", email).
+		Where("association_invitations.status = ? AND association_invitations.expires_at > ?", models.InvitationPending, now).
+		Order("association_invitations.created_at DESC").
+		Scan(&invitations).Error
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// AcceptAssociationInvitation makes a user a member of the association they
+// were invited to, with the role of the invitation.
+func (s *DatabaseService) AcceptAssociationInvitation(invitation *models.AssociationInvitation, userID string) (*models.AssociationMember, error) {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	member := &models.AssociationMember{AssociationID: invitation.AssociationID, UserID: userID, Role: invitation.Role}
+	if err := tx.Create(member).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := respondAssociationInvitation(tx, invitation, models.InvitationAccepted); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RespondAssociationInvitation declines or revokes an invitation.
+func (s *DatabaseService) RespondAssociationInvitation(invitation *models.AssociationInvitation, status models.InvitationStatus) error {
+	db := s.s.DB()
+	return respondAssociationInvitation(db, invitation, status)
+}
+
+func respondAssociationInvitation(tx *gorm.DB, invitation *models.AssociationInvitation, status models.InvitationStatus) error {
+	now := time.Now()
+	if err := tx.Model(invitation).Updates(map[string]interface{}{"status": status, "responded_at": now}).Error; err != nil {
+		return err
+	}
+	invitation.Status = status
+	invitation.RespondedAt = &now
+	return nil
+}
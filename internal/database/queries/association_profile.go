@@ -0,0 +1,76 @@
+package queries
+
+import (
+	"math"
+	"strconv"
+
+	"go-challenge/internal/models"
+)
+
+// AssociationReputation sums up what adopters think of an association: the
+// marks its members received, out of 5, and the number of cats it had
+// adopted. Average is nil until it is rated.
+type AssociationReputation struct {
+	Average   *float64    `json:"average"`
+	Count     int         `json:"count"`
+	Marks     map[int]int `json:"marks"`
+	Adoptions int         `json:"adoptions"`
+}
+
+// newAssociationReputation builds a reputation from the number of ratings
+// with each mark. The average is rounded to one decimal.
+func newAssociationReputation(marks map[int]int, adoptions int) *AssociationReputation {
+	reputation := &AssociationReputation{Marks: map[int]int{}, Adoptions: adoptions}
+	total := 0
+	for mark := 1; mark <= 5; mark++ {
+		reputation.Marks[mark] = marks[mark]
+		reputation.Count += marks[mark]
+		total += mark * marks[mark]
+	}
+	if reputation.Count > 0 {
+		average := math.Round(float64(total)/float64(reputation.Count)*10) / 10
+		reputation.Average = &average
+	}
+	return reputation
+}
+
+// FindAssociationReputation returns the reputation of an association, from
+// the adoptions of its cats and the ratings its members received from the
+// adopters of these cats. Ratings given for anything else, such as a cat a
+// member placed on their own, are left out.
+func (s *DatabaseService) FindAssociationReputation(associationID uint) (*AssociationReputation, error) {
+	db := s.s.DB()
+
+	var rows []struct {
+		Mark  int
+		Count int
+	}
+	err := db.Table("ratings").
+		Select("mark, COUNT(*) AS count").
+		Where("deleted_at IS NULL").
+		Where("user_id IN (SELECT CAST(user_id AS text) FROM association_members WHERE association_id = ?)", associationID).
+		Where(`author_id IN (
+			SELECT applicant_id FROM adoption_applications
+			WHERE deleted_at IS NULL AND status = ? AND cat_ids && ARRAY(
+				SELECT CAST(cat_id AS varchar) FROM cat_status_changes
+				WHERE deleted_at IS NULL AND association_id = ? AND to_status = ?))`,
+			models.ApplicationAccepted, strconv.FormatUint(uint64(associationID), 10), models.CatAdopted).
+		Group("mark").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	marks := map[int]int{}
+	for _, row := range rows {
+		marks[row.Mark] = row.Count
+	}
+
+	var adoptions int
+	err = db.Model(&models.CatStatusChange{}).
+		Where("association_id = ? AND to_status = ?", strconv.FormatUint(uint64(associationID), 10), models.CatAdopted).
+		Count(&adoptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return newAssociationReputation(marks, adoptions), nil
+}
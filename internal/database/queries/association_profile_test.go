@@ -0,0 +1,47 @@
+package queries
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAssociationReputation(t *testing.T) {
+	reputation := newAssociationReputation(map[int]int{5: 2, 4: 1, 0: 3}, 12)
+
+	require.NotNil(t, reputation.Average)
+	assert.Equal(t, 4.7, *reputation.Average)
+	assert.Equal(t, 3, reputation.Count)
+	assert.Equal(t, map[int]int{1: 0, 2: 0, 3: 0, 4: 1, 5: 2}, reputation.Marks)
+	assert.Equal(t, 12, reputation.Adoptions)
+}
+
+func TestNewAssociationReputationWithoutRatings(t *testing.T) {
+	reputation := newAssociationReputation(map[int]int{}, 0)
+
+	assert.Nil(t, reputation.Average)
+	assert.Equal(t, 0, reputation.Count)
+	assert.Len(t, reputation.Marks, 5)
+}
+
+func TestFindAssociationReputationRatingsOfAdopters(t *testing.T) {
+	s, db := newTestService()
+	db.On(`FROM "ratings"`, dbtest.Result{Columns: []string{"mark", "count"}, Values: [][]driver.Value{{int64(5), int64(2)}, {int64(3), int64(1)}}})
+	db.On("count(*)", dbtest.Result{Columns: []string{"count"}, Values: [][]driver.Value{{int64(4)}}})
+
+	reputation, err := s.FindAssociationReputation(3)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, reputation.Count)
+	assert.Equal(t, 4, reputation.Adoptions)
+	ratings := db.Queries(`FROM "ratings"`)
+	require.Len(t, ratings, 1)
+	assert.Contains(t, ratings[0].SQL, "author_id IN")
+	assert.Contains(t, ratings[0].SQL, "FROM adoption_applications")
+	assert.Equal(t, []driver.Value{int64(3), string(models.ApplicationAccepted), "3", string(models.CatAdopted)}, ratings[0].Args)
+}
@@ -0,0 +1,114 @@
+package queries
+
+import (
+	"go-challenge/internal/models"
+)
+
+// ChangeAssociationVerification moves an association to a new verification
+// status and records the change. The transition is expected to be checked
+// by the caller.
+func (s *DatabaseService) ChangeAssociationVerification(association *models.Association, to models.AssociationVerificationStatus, changedBy, note string) (*models.AssociationVerificationChange, error) {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	change := &models.AssociationVerificationChange{
+		AssociationID: association.ID,
+		FromStatus:    association.VerificationStatus,
+		ToStatus:      to,
+		ChangedBy:     changedBy,
+		Note:          note,
+	}
+	if err := tx.Create(change).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	verified := to == models.AssociationVerified
+	updates := map[string]interface{}{"verification_status": to, "verified": verified}
+	if verified {
+		updates["verified_at"] = change.CreatedAt
+	}
+	if err := tx.Model(association).UpdateColumns(updates).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	association.VerificationStatus = to
+	association.Verified = &verified
+	if verified {
+		association.VerifiedAt = &change.CreatedAt
+	}
+	return change, nil
+}
+
+// FindAssociationVerificationChanges returns the verification history of an
+// association, most recent first.
+func (s *DatabaseService) FindAssociationVerificationChanges(associationID uint) ([]models.AssociationVerificationChange, error) {
+	db := s.s.DB()
+	changes := []models.AssociationVerificationChange{}
+	if err := db.Where("association_id = ?", associationID).Order("created_at DESC, id DESC").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// FindAssociationsByVerificationStatus returns the associations with a
+// verification status, the ones waiting the longest first.
+func (s *DatabaseService) FindAssociationsByVerificationStatus(status models.AssociationVerificationStatus) ([]models.Association, error) {
+	db := s.s.DB()
+	associations := []models.Association{}
+	if err := db.Where("verification_status = ?", status).Order("updated_at, id").Find(&associations).Error; err != nil {
+		return nil, err
+	}
+	return associations, nil
+}
+
+func (s *DatabaseService) CreateAssociationDocument(document *models.AssociationDocument) error {
+	db := s.s.DB()
+	return db.Create(document).Error
+}
+
+func (s *DatabaseService) FindAssociationDocuments(associationID uint) ([]models.AssociationDocument, error) {
+	db := s.s.DB()
+	documents := []models.AssociationDocument{}
+	if err := db.Where("association_id = ?", associationID).Order("kind, created_at DESC").Find(&documents).Error; err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+func (s *DatabaseService) FindAssociationDocument(associationID uint, id string) (*models.AssociationDocument, error) {
+	db := s.s.DB()
+	var document models.AssociationDocument
+	if err := db.Where("association_id = ? AND id = ?", associationID, id).First(&document).Error; err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+func (s *DatabaseService) DeleteAssociationDocument(document *models.AssociationDocument) error {
+	db := s.s.DB()
+	return db.Delete(document).Error
+}
+
+// CountAssociationDocuments counts the documents of an association by kind.
+func (s *DatabaseService) CountAssociationDocuments(associationID uint) (map[models.AssociationDocumentKind]int, error) {
+	db := s.s.DB()
+	var rows []struct {
+		Kind  models.AssociationDocumentKind
+		Count int
+	}
+	err := db.Model(&models.AssociationDocument{}).Select("kind, COUNT(*) AS count").
+		Where("association_id = ?", associationID).Group("kind").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := map[models.AssociationDocumentKind]int{}
+	for _, row := range rows {
+		counts[row.Kind] = row.Count
+	}
+	return counts, nil
+}
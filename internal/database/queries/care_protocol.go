@@ -0,0 +1,63 @@
+package queries
+
+import (
+	"go-challenge/internal/models"
+)
+
+func (s *DatabaseService) GetCareProtocols() ([]models.CareProtocol, error) {
+	db := s.s.DB()
+	var protocols []models.CareProtocol
+	if err := db.Order("type, label").Find(&protocols).Error; err != nil {
+		return nil, err
+	}
+	return protocols, nil
+}
+
+func (s *DatabaseService) FindCareProtocolByID(id string) (*models.CareProtocol, error) {
+	db := s.s.DB()
+	var protocol models.CareProtocol
+	if err := db.Where("id = ?", id).First(&protocol).Error; err != nil {
+		return nil, err
+	}
+	return &protocol, nil
+}
+
+func (s *DatabaseService) CreateCareProtocol(protocol *models.CareProtocol) error {
+	db := s.s.DB()
+	return db.Create(protocol).Error
+}
+
+func (s *DatabaseService) UpdateCareProtocol(protocol *models.CareProtocol) error {
+	db := s.s.DB()
+	return db.Save(protocol).Error
+}
+
+func (s *DatabaseService) DeleteCareProtocol(protocol *models.CareProtocol) error {
+	db := s.s.DB()
+	return db.Delete(protocol).Error
+}
+
+// GetCatIDsWithMedicalEntries returns the cats that have a medical record.
+func (s *DatabaseService) GetCatIDsWithMedicalEntries() ([]uint, error) {
+	db := s.s.DB()
+	var catIDs []uint
+	if err := db.Model(&models.MedicalEntry{}).Select("DISTINCT cat_id").Pluck("cat_id", &catIDs).Error; err != nil {
+		return nil, err
+	}
+	return catIDs, nil
+}
+
+// CreateCareReminder stores a reminder and reports whether it is new. A care
+// is reminded only once per due date: the unique index on the cat, the care
+// and the due date turns down the same reminder stored twice, even by two runs
+// at once.
+func (s *DatabaseService) CreateCareReminder(reminder *models.CareReminder) (bool, error) {
+	db := s.s.DB()
+	if err := db.Create(reminder).Error; err != nil {
+		if isUniqueViolation(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,54 @@
+package queries
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateCareReminder(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		created bool
+		fails   bool
+	}{
+		{name: "new", created: true},
+		{name: "already reminded", err: &pq.Error{Code: "23505"}},
+		{name: "database down", err: errors.New("connection refused"), fails: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, db := newTestService()
+			if tt.err != nil {
+				db.On(`INSERT INTO "care_reminders"`, dbtest.Result{Err: tt.err})
+			}
+
+			created, err := s.CreateCareReminder(&models.CareReminder{CatID: 7, Key: "vaccine-typhus", DueDate: time.Now()})
+
+			assert.Equal(t, tt.fails, err != nil)
+			assert.Equal(t, tt.created, created)
+			assert.Empty(t, db.Queries(`SELECT`))
+		})
+	}
+}
+
+func TestCreateCareProtocolStoresReminderOnDueDay(t *testing.T) {
+	s, db := newTestService()
+	protocol := &models.CareProtocol{Type: models.MedicalDeworming, Label: "Vermifuge", IntervalDays: 90, ReminderDaysBefore: 0}
+
+	require.NoError(t, s.CreateCareProtocol(protocol))
+
+	inserts := db.Queries(`INSERT INTO "care_protocols"`)
+	require.Len(t, inserts, 1)
+	reminderDaysBefore, ok := inserts[0].Inserted("reminder_days_before")
+	require.True(t, ok)
+	assert.EqualValues(t, 0, reminderDaysBefore)
+}
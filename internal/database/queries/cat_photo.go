@@ -0,0 +1,283 @@
+package queries
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go-challenge/internal/models"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+// ErrInvalidPhotoOrder is returned when a new order does not list every photo
+// of a gallery exactly once.
+var ErrInvalidPhotoOrder = errors.New("the order must list every photo of the cat exactly once")
+
+func (s *DatabaseService) FindCatPhotos(catID uint) ([]models.CatPhoto, error) {
+	db := s.s.DB()
+	var photos []models.CatPhoto
+	if err := db.Where("cat_id = ?", catID).Order("position, id").Find(&photos).Error; err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+func (s *DatabaseService) FindCatPhoto(catID uint, id string) (*models.CatPhoto, error) {
+	db := s.s.DB()
+	var photo models.CatPhoto
+	if err := db.Where("cat_id = ? AND id = ?", catID, id).First(&photo).Error; err != nil {
+		return nil, err
+	}
+	return &photo, nil
+}
+
+// FindUnhashedCatPhotos returns photos without a perceptual hash, in the
+// order of their IDs from afterID.
+func (s *DatabaseService) FindUnhashedCatPhotos(afterID uint, limit int) ([]models.CatPhoto, error) {
+	db := s.s.DB()
+	var photos []models.CatPhoto
+	if err := db.Where("hash IS NULL AND id > ?", afterID).Order("id").Limit(limit).Find(&photos).Error; err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+func (s *DatabaseService) UpdateCatPhotoHash(photo *models.CatPhoto) error {
+	db := s.s.DB()
+	return db.Model(photo).UpdateColumn("hash", photo.Hash).Error
+}
+
+func (s *DatabaseService) UpdateCatPhotoCaption(photo *models.CatPhoto) error {
+	db := s.s.DB()
+	return db.Model(photo).Update("caption", photo.Caption).Error
+}
+
+// AddCatPhotos appends pictures to the gallery of a cat. The first picture of
+// an empty gallery becomes its cover. A picture without variants uses its
+// full size URL for them.
+func (s *DatabaseService) AddCatPhotos(catID uint, pictures []models.CatPhoto) ([]models.CatPhoto, error) {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	existing, err := findCatPhotos(tx, catID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var photos []models.CatPhoto
+	for i, picture := range pictures {
+		photo := models.CatPhoto{
+			CatID:        catID,
+			URL:          picture.URL,
+			MediumURL:    picture.MediumURL,
+			ThumbnailURL: picture.ThumbnailURL,
+			Hash:         picture.Hash,
+			Position:     len(existing) + i,
+			Cover:        len(existing) == 0 && i == 0,
+		}
+		if photo.MediumURL == "" {
+			photo.MediumURL = photo.URL
+		}
+		if photo.ThumbnailURL == "" {
+			photo.ThumbnailURL = photo.URL
+		}
+		if err := tx.Create(&photo).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		photos = append(photos, photo)
+	}
+
+	if err := syncCatPictures(tx, catID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+// ReorderCatPhotos sets the position of the photos of a cat to the order of
+// the given IDs.
+func (s *DatabaseService) ReorderCatPhotos(catID uint, photoIDs []uint) error {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	existing, err := findCatPhotos(tx, catID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if !samePhotos(existing, photoIDs) {
+		tx.Rollback()
+		return ErrInvalidPhotoOrder
+	}
+
+	for position, photoID := range photoIDs {
+		if err := tx.Model(&models.CatPhoto{}).Where("id = ?", photoID).Update("position", position).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := syncCatPictures(tx, catID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// SetCatCoverPhoto makes a photo the cover of the gallery of its cat.
+func (s *DatabaseService) SetCatCoverPhoto(photo *models.CatPhoto) error {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	if err := tx.Model(&models.CatPhoto{}).Where("cat_id = ? AND id <> ?", photo.CatID, photo.ID).Update("cover", false).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Model(photo).Update("cover", true).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := syncCatPictures(tx, photo.CatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// DeleteCatPhoto removes a photo from the gallery of its cat. The photos after
+// it move up, and the first remaining photo becomes the cover when the cover
+// is deleted.
+func (s *DatabaseService) DeleteCatPhoto(photo *models.CatPhoto) error {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	if err := tx.Delete(photo).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	remaining, err := findCatPhotos(tx, photo.CatID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for position, other := range remaining {
+		updates := map[string]interface{}{"position": position}
+		if photo.Cover && position == 0 {
+			updates["cover"] = true
+		}
+		if err := tx.Model(&other).Updates(updates).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := syncCatPictures(tx, photo.CatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// FindCatCovers returns the cover photo of each of the given cats, keyed by
+// cat ID.
+func (s *DatabaseService) FindCatCovers(catIDs []string) (map[string]models.CatPhoto, error) {
+	db := s.s.DB()
+	covers := map[string]models.CatPhoto{}
+	if len(catIDs) == 0 {
+		return covers, nil
+	}
+
+	var photos []models.CatPhoto
+	if err := db.Where("CAST(cat_id AS text) IN (?) AND cover = ?", catIDs, true).Find(&photos).Error; err != nil {
+		return nil, err
+	}
+	for _, photo := range photos {
+		covers[strconv.FormatUint(uint64(photo.CatID), 10)] = photo
+	}
+	return covers, nil
+}
+
+func findCatPhotos(tx *gorm.DB, catID uint) ([]models.CatPhoto, error) {
+	var photos []models.CatPhoto
+	if err := tx.Where("cat_id = ?", catID).Order("position, id").Find(&photos).Error; err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+// syncCatPictures copies the gallery of a cat to its PicturesURL, cover first,
+// for the clients that only read the list of pictures.
+func syncCatPictures(tx *gorm.DB, catID uint) error {
+	photos, err := findCatPhotos(tx, catID)
+	if err != nil {
+		return err
+	}
+
+	pictures := pq.StringArray{}
+	for _, photo := range photos {
+		if photo.Cover {
+			pictures = append(pq.StringArray{photo.URL}, pictures...)
+		} else {
+			pictures = append(pictures, photo.URL)
+		}
+	}
+	return tx.Model(&models.Cats{}).Where("id = ?", catID).Update("pictures_url", pictures).Error
+}
+
+func samePhotos(photos []models.CatPhoto, photoIDs []uint) bool {
+	if len(photos) != len(photoIDs) {
+		return false
+	}
+	seen := map[uint]bool{}
+	for _, photoID := range photoIDs {
+		seen[photoID] = true
+	}
+	for _, photo := range photos {
+		if !seen[photo.ID] {
+			return false
+		}
+	}
+	return true
+}
+
+// FindReferencedImageURLs returns which of the given URLs are still used by a
+// cat photo, a breed photo, the logo of an association or a profile picture.
+func (s *DatabaseService) FindReferencedImageURLs(urls []string) (map[string]bool, error) {
+	db := s.s.DB()
+	referenced := map[string]bool{}
+	if len(urls) == 0 {
+		return referenced, nil
+	}
+
+	columns := []struct{ table, column string }{
+		{"cat_photos", "url"}, {"cat_photos", "medium_url"}, {"cat_photos", "thumbnail_url"},
+		{"races", "photo_url"}, {"races", "photo_medium_url"}, {"races", "photo_thumbnail_url"},
+		{"associations", "logo_url"}, {"associations", "logo_thumbnail_url"},
+		{"users", "profile_pic_url"}, {"users", "profile_pic_medium_url"}, {"users", "profile_pic_thumbnail_url"},
+	}
+	var selects []string
+	var args []interface{}
+	for _, c := range columns {
+		selects = append(selects, fmt.Sprintf("SELECT %s AS url FROM %s WHERE deleted_at IS NULL AND %s IN (?)", c.column, c.table, c.column))
+		args = append(args, urls)
+	}
+
+	var rows []struct{ URL string }
+	if err := db.Raw(strings.Join(selects, " UNION "), args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		referenced[row.URL] = true
+	}
+	return referenced, nil
+}
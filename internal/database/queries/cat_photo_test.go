@@ -0,0 +1,56 @@
+package queries
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSamePhotos(t *testing.T) {
+	photos := []models.CatPhoto{{Model: gorm.Model{ID: 1}}, {Model: gorm.Model{ID: 2}}, {Model: gorm.Model{ID: 3}}}
+
+	assert.True(t, samePhotos(photos, []uint{3, 1, 2}))
+	assert.False(t, samePhotos(photos, []uint{3, 1}))
+	assert.False(t, samePhotos(photos, []uint{3, 1, 1}))
+	assert.False(t, samePhotos(photos, []uint{3, 1, 4}))
+	assert.True(t, samePhotos(nil, nil))
+}
+
+func TestDeleteCatByIDDeletesGallery(t *testing.T) {
+	s, db := newTestService()
+	db.On(`FROM "cats"`, dbtest.Result{Columns: []string{"id", "name"}, Values: [][]driver.Value{{int64(7), "Félix"}}})
+	db.On(`FROM "cat_photos"`, dbtest.Result{Columns: []string{"id", "cat_id", "url"}, Values: [][]driver.Value{
+		{int64(1), int64(7), "http://localhost:8080/uploads/felix.png"},
+		{int64(2), int64(7), "http://localhost:8080/uploads/felix-2.png"},
+	}})
+
+	photos, err := s.DeleteCatByID("7")
+
+	require.NoError(t, err)
+	require.Len(t, photos, 2)
+	assert.Equal(t, "http://localhost:8080/uploads/felix.png", photos[0].URL)
+	deletes := db.Queries(`UPDATE "cat_photos" SET "deleted_at"`)
+	require.Len(t, deletes, 1)
+	assert.Contains(t, deletes[0].Args, int64(7))
+	assert.Len(t, db.Queries(`UPDATE "cats" SET "deleted_at"`), 1)
+	assert.Len(t, db.Queries("COMMIT"), 1)
+}
+
+func TestDeleteCatByIDRollsBack(t *testing.T) {
+	s, db := newTestService()
+	db.On(`FROM "cats"`, dbtest.Result{Columns: []string{"id", "name"}, Values: [][]driver.Value{{int64(7), "Félix"}}})
+	db.On(`UPDATE "cats" SET "deleted_at"`, dbtest.Result{Err: errors.New("connection lost")})
+
+	_, err := s.DeleteCatByID("7")
+
+	require.Error(t, err)
+	assert.Len(t, db.Queries("ROLLBACK"), 1)
+	assert.Empty(t, db.Queries("COMMIT"))
+}
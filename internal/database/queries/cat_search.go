@@ -0,0 +1,326 @@
+package queries
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"go-challenge/internal/geo"
+	"go-challenge/internal/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+type CatSearchSort string
+
+const (
+	CatSortNewest   CatSearchSort = "newest"
+	CatSortName     CatSearchSort = "name"
+	CatSortYoungest CatSearchSort = "youngest"
+	CatSortEldest   CatSearchSort = "eldest"
+	CatSortNearest  CatSearchSort = "nearest"
+)
+
+const (
+	DefaultCatSearchLimit = 20
+	MaxCatSearchLimit     = 100
+)
+
+var (
+	ErrInvalidCatSearchSort = errors.New("invalid sort")
+	ErrInvalidCursor        = errors.New("invalid cursor")
+	ErrMissingSearchOrigin  = errors.New("a location is required to search by distance")
+)
+
+// CatSearch holds the filters of a cat search. Every filter that is set must
+// match, unset filters are ignored. Ages are expressed in full months.
+// RadiusKm and the nearest sort measure distances from Near. Breed filters on
+// the traits of the race of the cats.
+type CatSearch struct {
+	RaceID          string
+	Breed           RaceFilter
+	Sexe            string
+	MinAgeMonths    *int
+	MaxAgeMonths    *int
+	Color           string
+	Behavior        string
+	Sterilized      *bool
+	VaccinatedSince *time.Time
+	Status          models.CatStatus
+	AssociationID   string
+	Near            *geo.Point
+	RadiusKm        *float64
+	Sort            CatSearchSort
+	Cursor          string
+	Limit           int
+	// Published only keeps the cats with a published annonce.
+	Published bool
+}
+
+// CatSearchResult is a cat found by a search, along with its distance from
+// the location of the search when one was given.
+type CatSearchResult struct {
+	models.Cats
+	DistanceKm *float64 `json:"distanceKm,omitempty"`
+}
+
+// CatSearchPage is one page of search results. NextCursor is empty on the
+// last page.
+type CatSearchPage struct {
+	Cats       []CatSearchResult `json:"cats"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+type catSearchKey int
+
+const (
+	catSearchKeyTime catSearchKey = iota
+	catSearchKeyString
+	catSearchKeyFloat
+)
+
+// catSearchOrder describes how a sort orders the cats, so that the sort key
+// of the last cat of a page can be used as the cursor of the next one.
+type catSearchOrder struct {
+	expr string
+	desc bool
+	kind catSearchKey
+	key  func(result *CatSearchResult) interface{}
+}
+
+// Cats without a birth date always come last when sorting by age.
+var catSearchOrders = map[CatSearchSort]catSearchOrder{
+	CatSortNewest: {
+		expr: "created_at",
+		desc: true,
+		kind: catSearchKeyTime,
+		key:  func(result *CatSearchResult) interface{} { return result.CreatedAt },
+	},
+	CatSortName: {
+		expr: "name",
+		kind: catSearchKeyString,
+		key:  func(result *CatSearchResult) interface{} { return result.Name },
+	},
+	CatSortYoungest: {
+		expr: "COALESCE(birth_date, '0001-01-01')",
+		desc: true,
+		kind: catSearchKeyTime,
+		key: func(result *CatSearchResult) interface{} {
+			if result.BirthDate == nil {
+				return time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+			}
+			return *result.BirthDate
+		},
+	},
+	CatSortEldest: {
+		expr: "COALESCE(birth_date, '9999-12-31')",
+		kind: catSearchKeyTime,
+		key: func(result *CatSearchResult) interface{} {
+			if result.BirthDate == nil {
+				return time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+			}
+			return *result.BirthDate
+		},
+	},
+}
+
+// catSearchOrderFor returns the order of a sort. Sorting by distance only
+// keeps the cats that have a location.
+func catSearchOrderFor(search CatSearch) (catSearchOrder, error) {
+	if search.Sort != CatSortNearest {
+		order, ok := catSearchOrders[search.Sort]
+		if !ok {
+			return catSearchOrder{}, ErrInvalidCatSearchSort
+		}
+		return order, nil
+	}
+
+	if search.Near == nil {
+		return catSearchOrder{}, ErrMissingSearchOrigin
+	}
+	return catSearchOrder{
+		expr: geo.DistanceSQL(*search.Near, "latitude", "longitude"),
+		kind: catSearchKeyFloat,
+		key:  func(result *CatSearchResult) interface{} { return *result.DistanceKm },
+	}, nil
+}
+
+type searchCursor struct {
+	Key string `json:"k"`
+	ID  uint   `json:"id"`
+}
+
+func encodeCatSearchCursor(order catSearchOrder, result *CatSearchResult) string {
+	return encodeSearchCursor(order.key(result), result.ID)
+}
+
+func decodeCatSearchCursor(order catSearchOrder, raw string) (interface{}, uint, error) {
+	return decodeSearchCursor(order.kind, raw)
+}
+
+// encodeSearchCursor makes an opaque cursor out of the sort key and ID of the
+// last row of a page.
+func encodeSearchCursor(key interface{}, id uint) string {
+	cursor := searchCursor{ID: id}
+	switch key := key.(type) {
+	case time.Time:
+		cursor.Key = key.UTC().Format(time.RFC3339Nano)
+	case string:
+		cursor.Key = key
+	case float64:
+		cursor.Key = strconv.FormatFloat(key, 'g', -1, 64)
+	}
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func decodeSearchCursor(kind catSearchKey, raw string) (interface{}, uint, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	var cursor searchCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil || cursor.ID == 0 {
+		return nil, 0, ErrInvalidCursor
+	}
+	switch kind {
+	case catSearchKeyTime:
+		key, err := time.Parse(time.RFC3339Nano, cursor.Key)
+		if err != nil {
+			return nil, 0, ErrInvalidCursor
+		}
+		return key, cursor.ID, nil
+	case catSearchKeyFloat:
+		key, err := strconv.ParseFloat(cursor.Key, 64)
+		if err != nil {
+			return nil, 0, ErrInvalidCursor
+		}
+		return key, cursor.ID, nil
+	}
+	return cursor.Key, cursor.ID, nil
+}
+
+// birthDateBounds converts an age range in months into the range of birth
+// dates it covers: a cat is MaxAgeMonths old until the day before it turns one
+// month older.
+func birthDateBounds(minAgeMonths, maxAgeMonths *int, now time.Time) (bornBefore, bornAfter *time.Time) {
+	if minAgeMonths != nil {
+		t := now.AddDate(0, -*minAgeMonths, 0)
+		bornBefore = &t
+	}
+	if maxAgeMonths != nil {
+		t := now.AddDate(0, -(*maxAgeMonths + 1), 0)
+		bornAfter = &t
+	}
+	return bornBefore, bornAfter
+}
+
+// SearchCats returns one page of the cats matching every filter of the search.
+func (s *DatabaseService) SearchCats(search CatSearch, now time.Time) (*CatSearchPage, error) {
+	db := s.s.DB()
+
+	if search.Sort == "" {
+		search.Sort = CatSortNewest
+	}
+	order, err := catSearchOrderFor(search)
+	if err != nil {
+		return nil, err
+	}
+	if search.RadiusKm != nil && search.Near == nil {
+		return nil, ErrMissingSearchOrigin
+	}
+	if search.Limit <= 0 {
+		search.Limit = DefaultCatSearchLimit
+	}
+	if search.Limit > MaxCatSearchLimit {
+		search.Limit = MaxCatSearchLimit
+	}
+
+	query := applyCatSearchFilters(db.Table("cats"), search, now)
+	if search.Near != nil {
+		distance := geo.DistanceSQL(*search.Near, "latitude", "longitude")
+		query = query.Select("cats.*, " + distance + " AS distance_km")
+		if search.RadiusKm != nil || search.Sort == CatSortNearest {
+			query = query.Where("latitude IS NOT NULL AND longitude IS NOT NULL")
+		}
+		if search.RadiusKm != nil {
+			query = query.Where(distance+" <= ?", *search.RadiusKm)
+		}
+	}
+
+	direction, comparator := "ASC", ">"
+	if order.desc {
+		direction, comparator = "DESC", "<"
+	}
+	if search.Cursor != "" {
+		key, id, err := decodeCatSearchCursor(order, search.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("("+order.expr+", id) "+comparator+" (?, ?)", key, id)
+	}
+
+	var cats []CatSearchResult
+	err = query.
+		Order(order.expr + " " + direction).
+		Order("id " + direction).
+		Limit(search.Limit + 1).
+		Find(&cats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	page := &CatSearchPage{Cats: cats}
+	if len(cats) > search.Limit {
+		page.Cats = cats[:search.Limit]
+		page.NextCursor = encodeCatSearchCursor(order, &page.Cats[search.Limit-1])
+	}
+	return page, nil
+}
+
+func applyCatSearchFilters(query *gorm.DB, search CatSearch, now time.Time) *gorm.DB {
+	if search.RaceID != "" {
+		query = query.Where("race_id = ?", search.RaceID)
+	}
+	if !search.Breed.IsZero() {
+		races := applyRaceFilters(query.New().Model(&models.Races{}), search.Breed).Select("CAST(id AS text)")
+		query = query.Where("race_id IN (?)", races.SubQuery())
+	}
+	if search.Sexe != "" {
+		query = query.Where("LOWER(sexe) = LOWER(?)", search.Sexe)
+	}
+	if search.Color != "" {
+		query = query.Where("LOWER(color) = LOWER(?)", search.Color)
+	}
+	if search.Behavior != "" {
+		query = query.Where("LOWER(behavior) = LOWER(?)", search.Behavior)
+	}
+	if search.Sterilized != nil {
+		query = query.Where("sterilized = ?", *search.Sterilized)
+	}
+	if search.Status != "" {
+		query = query.Where("status = ?", search.Status)
+	}
+	if search.VaccinatedSince != nil {
+		query = query.Where("last_vaccine >= ?", *search.VaccinatedSince)
+	}
+	if search.AssociationID != "" {
+		query = query.Where("published_as = ?", search.AssociationID)
+	}
+	if search.Published {
+		query = query.Where(`EXISTS (SELECT 1 FROM annonces WHERE annonces.cat_id = CAST(cats.id AS text)
+			AND annonces.status = ? AND annonces.deleted_at IS NULL)`, models.AnnoncePublished)
+	}
+
+	bornBefore, bornAfter := birthDateBounds(search.MinAgeMonths, search.MaxAgeMonths, now)
+	if bornBefore != nil {
+		query = query.Where("birth_date <= ?", *bornBefore)
+	}
+	if bornAfter != nil {
+		query = query.Where("birth_date > ?", *bornAfter)
+	}
+
+	return query
+}
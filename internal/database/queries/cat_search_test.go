@@ -0,0 +1,68 @@
+package queries
+
+import (
+	"go-challenge/internal/geo"
+	"go-challenge/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatSearchCursorRoundTrip(t *testing.T) {
+	birthDate := time.Date(2023, 5, 10, 0, 0, 0, 0, time.UTC)
+	cat := &CatSearchResult{Cats: models.Cats{Name: "Moustache", BirthDate: &birthDate}}
+	cat.ID = 42
+
+	order := catSearchOrders[CatSortYoungest]
+	key, id, err := decodeCatSearchCursor(order, encodeCatSearchCursor(order, cat))
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(42), id)
+	assert.True(t, birthDate.Equal(key.(time.Time)))
+
+	order = catSearchOrders[CatSortName]
+	key, _, err = decodeCatSearchCursor(order, encodeCatSearchCursor(order, cat))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Moustache", key)
+}
+
+func TestNearestCatSearchCursor(t *testing.T) {
+	distance := 12.345678
+	cat := &CatSearchResult{DistanceKm: &distance}
+	cat.ID = 7
+
+	order, err := catSearchOrderFor(CatSearch{Sort: CatSortNearest, Near: &geo.Point{Latitude: 45.76, Longitude: 4.83}})
+	assert.NoError(t, err)
+
+	key, id, err := decodeCatSearchCursor(order, encodeCatSearchCursor(order, cat))
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), id)
+	assert.Equal(t, distance, key)
+
+	_, err = catSearchOrderFor(CatSearch{Sort: CatSortNearest})
+	assert.ErrorIs(t, err, ErrMissingSearchOrigin)
+}
+
+func TestCatSearchInvalidCursor(t *testing.T) {
+	_, _, err := decodeCatSearchCursor(catSearchOrders[CatSortNewest], "not-a-cursor")
+
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestBirthDateBounds(t *testing.T) {
+	now := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	minAge, maxAge := 6, 12
+
+	bornBefore, bornAfter := birthDateBounds(&minAge, &maxAge, now)
+
+	assert.Equal(t, time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC), *bornBefore)
+	assert.Equal(t, time.Date(2023, 5, 15, 0, 0, 0, 0, time.UTC), *bornAfter)
+
+	bornBefore, bornAfter = birthDateBounds(nil, nil, now)
+
+	assert.Nil(t, bornBefore)
+	assert.Nil(t, bornAfter)
+}
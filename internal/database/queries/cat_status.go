@@ -0,0 +1,122 @@
+package queries
+
+import (
+	"errors"
+	"time"
+
+	"go-challenge/internal/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrCatStatusChanged is returned when the status of a cat changed since it
+// was read, so the transition checked against it may no longer be allowed.
+var ErrCatStatusChanged = errors.New("the status of the cat changed in the meantime")
+
+// CatStatusHistoryFilter narrows the status changes of an association. Zero
+// values do not filter.
+type CatStatusHistoryFilter struct {
+	AssociationID string
+	Status        models.CatStatus
+	Since         *time.Time
+	Until         *time.Time
+}
+
+// ChangeCatStatus moves a cat to a new status and records the change. The
+// transition is expected to be checked by the caller, against the status of
+// the cat which must still be the current one. The annonces of a cat that is
+// adopted or deceased are closed.
+func (s *DatabaseService) ChangeCatStatus(cat *models.Cats, to models.CatStatus, changedBy, note string) (*models.CatStatusChange, error) {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	change, err := changeCatStatus(tx, cat, to, changedBy, note)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func changeCatStatus(tx *gorm.DB, cat *models.Cats, to models.CatStatus, changedBy, note string) (*models.CatStatusChange, error) {
+	change := &models.CatStatusChange{
+		CatID:         cat.ID,
+		AssociationID: cat.PublishedAs,
+		FromStatus:    cat.Status,
+		ToStatus:      to,
+		ChangedBy:     changedBy,
+		Note:          note,
+	}
+	if err := tx.Create(change).Error; err != nil {
+		return nil, err
+	}
+
+	// Only the first of concurrent changes from the same status goes through
+	now := change.CreatedAt
+	result := tx.Model(&models.Cats{}).Where("id = ? AND status = ?", cat.ID, cat.Status).
+		Updates(map[string]interface{}{"status": to, "status_changed_at": now})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrCatStatusChanged
+	}
+	cat.Status = to
+	cat.StatusChangedAt = &now
+
+	if err := closeCatFosterPlacements(tx, cat, to, changedBy, now); err != nil {
+		return nil, err
+	}
+	if to == models.CatAdopted {
+		// The adoption counts for the adopter, the last step of their funnel
+		adopterID, err := catAdopterID(tx, cat)
+		if err != nil {
+			return nil, err
+		}
+		if adopterID != "" {
+			if err := recordCatAnnonceEvents(tx, cat, models.AnnonceCatAdopted, adopterID, now); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if reason, ok := models.AnnonceCloseReasonFor(to); ok {
+		if err := closeCatAnnonces(tx, cat, reason); err != nil {
+			return nil, err
+		}
+	}
+	return change, nil
+}
+
+func (s *DatabaseService) FindCatStatusChanges(catID uint) ([]models.CatStatusChange, error) {
+	db := s.s.DB()
+	var changes []models.CatStatusChange
+	if err := db.Where("cat_id = ?", catID).Order("created_at DESC").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// FindAssociationCatStatusChanges returns the status changes of the cats of
+// an association, most recent first.
+func (s *DatabaseService) FindAssociationCatStatusChanges(filter CatStatusHistoryFilter) ([]models.CatStatusChange, error) {
+	db := s.s.DB()
+	query := db.Where("association_id = ?", filter.AssociationID)
+	if filter.Status != "" {
+		query = query.Where("to_status = ?", filter.Status)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+
+	var changes []models.CatStatusChange
+	if err := query.Order("created_at DESC").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,31 @@
+package queries
+
+import (
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeCatStatusFromStaleStatus(t *testing.T) {
+	cat := &models.Cats{Status: models.CatReserved}
+	cat.ID = 7
+	s, db := newTestService()
+	// A concurrent change already moved the cat out of the reserved status
+	db.On(`UPDATE "cats"`, dbtest.Result{RowsAffected: 0})
+
+	_, err := s.ChangeCatStatus(cat, models.CatAdopted, "owner", "")
+
+	assert.ErrorIs(t, err, ErrCatStatusChanged)
+	assert.Equal(t, models.CatReserved, cat.Status)
+	updates := db.Queries(`UPDATE "cats"`)
+	require.Len(t, updates, 1)
+	assert.Contains(t, updates[0].SQL, "status = $")
+	assert.Contains(t, updates[0].Args, "reserved")
+	assert.Len(t, db.Queries("ROLLBACK"), 1)
+	assert.Empty(t, db.Queries("COMMIT"))
+	assert.Empty(t, db.Queries(`UPDATE "annonces"`))
+}
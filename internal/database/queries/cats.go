@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"go-challenge/internal/models"
-	"time"
 
 	"gorm.io/gorm"
 )
@@ -56,22 +55,37 @@ func (s *DatabaseService) GetAllCats() ([]models.Cats, error) {
 	return cats, nil
 }
 
-func (s *DatabaseService) DeleteCatByID(id string) error {
+// DeleteCatByID deletes a cat along with its gallery, and returns the photos
+// it had for their files to be removed.
+func (s *DatabaseService) DeleteCatByID(id string) ([]models.CatPhoto, error) {
 	db := s.s.DB()
 
 	var cat models.Cats
 	if err := db.Where("id = ?", id).First(&cat).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("cat with ID %s not found", id)
+			return nil, fmt.Errorf("cat with ID %s not found", id)
 		}
-		return err
+		return nil, err
 	}
 
-	if err := db.Delete(&cat).Error; err != nil {
-		return err
+	tx := db.Begin()
+	photos, err := findCatPhotos(tx, cat.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
 	}
-
-	return nil
+	if err := tx.Where("cat_id = ?", cat.ID).Delete(&models.CatPhoto{}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Delete(&cat).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return photos, nil
 }
 
 func (s *DatabaseService) UpdateCat(cat *models.Cats) error {
@@ -92,29 +106,29 @@ func (s *DatabaseService) UpdateCat(cat *models.Cats) error {
 	return nil
 }
 
-func (s *DatabaseService) GetCatByFilters(raceId string, age int, sex string, assoId string) ([]models.Cats, error) {
+func (s *DatabaseService) FindCatsByUserID(userID string) ([]models.Cats, error) {
 	var cats []models.Cats
-	var birthDate time.Time
 	db := s.s.DB()
-
-	birthDate = time.Now().AddDate(-age, 0, 0)
-
-	if err := db.Where("sexe = ?", sex).Or("race_id = ?", raceId).Or("birth_date >= ?", birthDate).Or("published_as = ?", assoId).Find(&cats).Error; err != nil {
+	if err := db.Where("user_id = ?", userID).Find(&cats).Error; err != nil {
 		return nil, err
 	}
-
-	if len(cats) == 0 {
-		return nil, gorm.ErrRecordNotFound
-	}
-
 	return cats, nil
 }
 
-func (s *DatabaseService) FindCatsByUserID(userID string) ([]models.Cats, error) {
-	var cats []models.Cats
+// UpdateUserCatsLocation copies the location of a user to the cats they
+// publish in their own name.
+func (s *DatabaseService) UpdateUserCatsLocation(user *models.User) error {
 	db := s.s.DB()
-	if err := db.Where("user_id = ?", userID).Find(&cats).Error; err != nil {
-		return nil, err
-	}
-	return cats, nil
+	return db.Model(&models.Cats{}).
+		Where("user_id = ? AND (published_as = '' OR published_as IS NULL)", user.ID).
+		Updates(map[string]interface{}{"latitude": user.Latitude, "longitude": user.Longitude}).Error
+}
+
+// UpdateAssociationCatsLocation copies the location of an association to the
+// cats published as the association.
+func (s *DatabaseService) UpdateAssociationCatsLocation(association *models.Association) error {
+	db := s.s.DB()
+	return db.Model(&models.Cats{}).
+		Where("published_as = ?", fmt.Sprintf("%d", association.ID)).
+		Updates(map[string]interface{}{"latitude": association.Latitude, "longitude": association.Longitude}).Error
 }
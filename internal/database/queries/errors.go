@@ -0,0 +1,17 @@
+package queries
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the Postgres error code of a row breaking a unique index.
+const uniqueViolation = "23505"
+
+// isUniqueViolation reports whether an error comes from a row breaking a
+// unique index, such as when two requests insert the same row at once.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
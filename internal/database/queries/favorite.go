@@ -1,12 +1,24 @@
 package queries
 
 import (
+	"errors"
+	"strconv"
+
 	"go-challenge/internal/models"
 )
 
+// ErrFavoriteExists is returned when a user favorites an annonce twice.
+var ErrFavoriteExists = errors.New("the annonce is already in your favorites")
+
 func (s *DatabaseService) CreateFavorite(favorite *models.Favorite) error {
 	db := s.s.DB()
-	return db.Create(favorite).Error
+	if err := db.Create(favorite).Error; err != nil {
+		if isUniqueViolation(err) {
+			return ErrFavoriteExists
+		}
+		return err
+	}
+	return nil
 }
 
 func (s *DatabaseService) UpdateFavorite(favorite *models.Favorite) error {
@@ -54,3 +66,159 @@ func (s *DatabaseService) FindFavoriteByUserAndAnnonceID(userID, annonceID strin
 	}
 	return &favorite, nil
 }
+
+// FavoriteDetails is a favorite along with its annonce and the cat of the
+// annonce. Available tells whether the annonce is still published and its
+// cat still up for adoption.
+type FavoriteDetails struct {
+	models.Favorite
+	Annonce   *models.Annonce `json:"annonce"`
+	Cat       *models.Cats    `json:"cat"`
+	Available bool            `json:"available"`
+}
+
+// FavoriteCollectionSummary is a collection along with the number of
+// favorites sorted in it.
+type FavoriteCollectionSummary struct {
+	models.FavoriteCollection
+	Count int `json:"count"`
+}
+
+// FindFavoriteDetails returns the favorites of a user, the most recent first,
+// with their annonce and cat. A collection ID narrows them to one of their
+// collections.
+func (s *DatabaseService) FindFavoriteDetails(userID string, collectionID *uint) ([]FavoriteDetails, error) {
+	db := s.s.DB()
+	query := db.Where("user_id = ?", userID)
+	if collectionID != nil {
+		query = query.Where("collection_id = ?", *collectionID)
+	}
+	var favorites []models.Favorite
+	if err := query.Order("created_at DESC").Find(&favorites).Error; err != nil {
+		return nil, err
+	}
+	if len(favorites) == 0 {
+		return []FavoriteDetails{}, nil
+	}
+
+	annonceIDs := make([]string, len(favorites))
+	for i := range favorites {
+		annonceIDs[i] = favorites[i].AnnonceID
+	}
+	var annonces []models.Annonce
+	if err := db.Where("CAST(id AS text) IN (?)", annonceIDs).Find(&annonces).Error; err != nil {
+		return nil, err
+	}
+	catIDs := make([]string, len(annonces))
+	for i := range annonces {
+		catIDs[i] = annonces[i].CatID
+	}
+	var cats []models.Cats
+	if err := db.Where("CAST(id AS text) IN (?)", catIDs).Find(&cats).Error; err != nil {
+		return nil, err
+	}
+
+	annoncesByID := map[string]*models.Annonce{}
+	for i := range annonces {
+		annoncesByID[strconv.FormatUint(uint64(annonces[i].ID), 10)] = &annonces[i]
+	}
+	catsByID := map[string]*models.Cats{}
+	for i := range cats {
+		catsByID[strconv.FormatUint(uint64(cats[i].ID), 10)] = &cats[i]
+	}
+
+	details := make([]FavoriteDetails, 0, len(favorites))
+	for _, favorite := range favorites {
+		detail := FavoriteDetails{Favorite: favorite, Annonce: annoncesByID[favorite.AnnonceID]}
+		if detail.Annonce != nil {
+			detail.Cat = catsByID[detail.Annonce.CatID]
+		}
+		detail.Available = detail.Annonce != nil && detail.Annonce.Status == models.AnnoncePublished &&
+			detail.Cat != nil && detail.Cat.Status.Adoptable()
+		details = append(details, detail)
+	}
+	return details, nil
+}
+
+// MoveFavorite sorts a favorite in a collection, or out of any with a nil
+// collection ID.
+func (s *DatabaseService) MoveFavorite(favorite *models.Favorite, collectionID *uint) error {
+	db := s.s.DB()
+	if err := db.Model(favorite).Update("collection_id", collectionID).Error; err != nil {
+		return err
+	}
+	favorite.CollectionID = collectionID
+	return nil
+}
+
+// FindCatFavoriteUserIDs returns the users who favorited an annonce of a
+// cat, its own or the one of its litter.
+func (s *DatabaseService) FindCatFavoriteUserIDs(cat *models.Cats) ([]string, error) {
+	db := s.s.DB()
+	annonces := db.Table("annonces").Select("CAST(id AS text)").Where("deleted_at IS NULL")
+	if cat.LitterID != nil {
+		annonces = annonces.Where("cat_id = ? OR litter_id = ?", strconv.FormatUint(uint64(cat.ID), 10), *cat.LitterID)
+	} else {
+		annonces = annonces.Where("cat_id = ?", strconv.FormatUint(uint64(cat.ID), 10))
+	}
+
+	var userIDs []string
+	err := db.Model(&models.Favorite{}).Where("annonce_id IN (?)", annonces.SubQuery()).Pluck("DISTINCT user_id", &userIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+func (s *DatabaseService) CreateFavoriteCollection(collection *models.FavoriteCollection) error {
+	db := s.s.DB()
+	return db.Create(collection).Error
+}
+
+func (s *DatabaseService) UpdateFavoriteCollection(collection *models.FavoriteCollection) error {
+	db := s.s.DB()
+	return db.Save(collection).Error
+}
+
+// FindFavoriteCollection returns a collection of a user.
+func (s *DatabaseService) FindFavoriteCollection(userID, id string) (*models.FavoriteCollection, error) {
+	db := s.s.DB()
+	var collection models.FavoriteCollection
+	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&collection).Error; err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// FindFavoriteCollections returns the collections of a user by name, with
+// the number of favorites in each.
+func (s *DatabaseService) FindFavoriteCollections(userID string) ([]FavoriteCollectionSummary, error) {
+	db := s.s.DB()
+	var collections []FavoriteCollectionSummary
+	err := db.Table("favorite_collections").
+		Select("favorite_collections.*, (SELECT COUNT(*) FROM favorites WHERE favorites.collection_id = favorite_collections.id AND favorites.deleted_at IS NULL) AS count").
+		Where("favorite_collections.user_id = ? AND favorite_collections.deleted_at IS NULL", userID).
+		Order("LOWER(favorite_collections.name)").
+		Scan(&collections).Error
+	if err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// DeleteFavoriteCollection deletes a collection. Its favorites are kept, out
+// of any collection.
+func (s *DatabaseService) DeleteFavoriteCollection(collection *models.FavoriteCollection) error {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	if err := tx.Model(&models.Favorite{}).Where("collection_id = ?", collection.ID).Update("collection_id", nil).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(collection).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
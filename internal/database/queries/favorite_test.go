@@ -0,0 +1,56 @@
+package queries
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindFavoriteDetailsAvailability(t *testing.T) {
+	s, db := newTestService()
+	db.On(`FROM "favorites"`, dbtest.Result{Columns: []string{"id", "user_id", "annonce_id"}, Values: [][]driver.Value{
+		{int64(1), "user", "10"},
+		{int64(2), "user", "11"},
+		{int64(3), "user", "12"},
+		{int64(4), "user", "13"},
+	}})
+	db.On(`FROM "annonces"`, dbtest.Result{Columns: []string{"id", "cat_id", "status"}, Values: [][]driver.Value{
+		{int64(10), "20", "published"},
+		{int64(11), "21", "closed"},
+		{int64(12), "22", "published"},
+	}})
+	db.On(`FROM "cats"`, dbtest.Result{Columns: []string{"id", "status"}, Values: [][]driver.Value{
+		{int64(20), "available"},
+		{int64(21), "available"},
+		{int64(22), "reserved"},
+	}})
+
+	details, err := s.FindFavoriteDetails("user", nil)
+
+	require.NoError(t, err)
+	require.Len(t, details, 4)
+	assert.True(t, details[0].Available)
+	// A closed annonce, a reserved cat, a deleted annonce
+	assert.False(t, details[1].Available)
+	assert.False(t, details[2].Available)
+	assert.False(t, details[3].Available)
+	assert.Nil(t, details[3].Annonce)
+}
+
+func TestFindFavoriteDetailsInCollection(t *testing.T) {
+	s, db := newTestService()
+	collectionID := uint(5)
+
+	details, err := s.FindFavoriteDetails("user", &collectionID)
+
+	require.NoError(t, err)
+	assert.Empty(t, details)
+	favorites := db.Queries(`FROM "favorites"`)
+	require.Len(t, favorites, 1)
+	assert.Contains(t, favorites[0].SQL, "collection_id = $2")
+	assert.Equal(t, []driver.Value{"user", int64(5)}, favorites[0].Args)
+}
@@ -0,0 +1,390 @@
+package queries
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go-challenge/internal/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// FosterProfileFilter narrows the foster profiles associations look through.
+// Zero values do not filter.
+type FosterProfileFilter struct {
+	Profile     models.FosterCatProfile
+	AvailableOn *time.Time
+	Cp          string
+}
+
+// FosterProfileDetails is a foster profile along with who the foster is and
+// how many cats they hold. Email is only filled in once the foster accepted a
+// placement of the association looking.
+type FosterProfileDetails struct {
+	models.FosterProfile
+	Name                   string `json:"name"`
+	Email                  string `json:"email,omitempty"`
+	ProfilePicThumbnailURL string `json:"profilePicThumbnailUrl"`
+	CatsHeld               int    `json:"catsHeld"`
+}
+
+// FosterPlacementDetails is a placement along with the names of its cat and
+// of its foster.
+type FosterPlacementDetails struct {
+	models.FosterPlacement
+	CatName    string `json:"catName"`
+	FosterName string `json:"fosterName"`
+}
+
+// fosterHolds are the statuses of the placements that take up room at a
+// foster.
+var fosterHolds = []models.FosterPlacementStatus{models.FosterAccepted, models.FosterActive}
+
+// FosterFullError is returned when a foster has no room left for a placement
+// over its period.
+type FosterFullError struct {
+	Held     int
+	Capacity int
+}
+
+func (e *FosterFullError) Error() string {
+	return fmt.Sprintf("this foster already holds %d cats out of %d", e.Held, e.Capacity)
+}
+
+func (s *DatabaseService) FindFosterProfile(userID string) (*models.FosterProfile, error) {
+	db := s.s.DB()
+	var profile models.FosterProfile
+	if err := db.Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (s *DatabaseService) SaveFosterProfile(profile *models.FosterProfile) error {
+	db := s.s.DB()
+	return db.Save(profile).Error
+}
+
+// DeleteFosterProfile removes a foster profile for good, so that its user can
+// create another one later.
+func (s *DatabaseService) DeleteFosterProfile(profile *models.FosterProfile) error {
+	db := s.s.DB()
+	return db.Unscoped().Delete(profile).Error
+}
+
+// FindFosterProfiles returns the foster profiles matching a filter, the ones
+// with the most room left first.
+func (s *DatabaseService) FindFosterProfiles(filter FosterProfileFilter) ([]FosterProfileDetails, error) {
+	db := s.s.DB()
+	query := db.Table("foster_profiles").
+		Select(`foster_profiles.*, users.name, users.email, users.profile_pic_thumbnail_url,
+			(SELECT COUNT(*) FROM foster_placements WHERE foster_placements.deleted_at IS NULL
+				AND foster_placements.foster_id = foster_profiles.user_id AND foster_placements.status IN (?)) AS cats_held`, fosterHolds).
+		Joins("JOIN users ON CAST(users.id AS text) = foster_profiles.user_id AND users.deleted_at IS NULL").
+		Where("foster_profiles.deleted_at IS NULL")
+	if filter.Profile != "" {
+		query = query.Where("? = ANY(foster_profiles.accepted_profiles)", filter.Profile)
+	}
+	if filter.AvailableOn != nil {
+		query = query.Where("foster_profiles.available = ?", true).
+			Where("foster_profiles.available_from IS NULL OR foster_profiles.available_from <= ?", *filter.AvailableOn).
+			Where("foster_profiles.available_until IS NULL OR foster_profiles.available_until > ?", *filter.AvailableOn)
+	}
+	if filter.Cp != "" {
+		query = query.Where("foster_profiles.cp LIKE ?", filter.Cp+"%")
+	}
+
+	profiles := []FosterProfileDetails{}
+	if err := query.Order("users.name").Scan(&profiles).Error; err != nil {
+		return nil, err
+	}
+	sort.SliceStable(profiles, func(i, j int) bool {
+		return profiles[i].Capacity-profiles[i].CatsHeld > profiles[j].Capacity-profiles[j].CatsHeld
+	})
+	return profiles, nil
+}
+
+// FindFosterIDsInTouch returns the fosters who accepted a placement of one of
+// the given associations, or of any association when associationIDs is nil.
+func (s *DatabaseService) FindFosterIDsInTouch(associationIDs []uint) (map[string]bool, error) {
+	db := s.s.DB()
+	query := db.Model(&models.FosterPlacement{}).Where("status IN (?)", []models.FosterPlacementStatus{models.FosterAccepted, models.FosterActive, models.FosterEnded})
+	if associationIDs != nil {
+		if len(associationIDs) == 0 {
+			return map[string]bool{}, nil
+		}
+		query = query.Where("association_id IN (?)", associationIDs)
+	}
+	var fosterIDs []string
+	if err := query.Pluck("DISTINCT foster_id", &fosterIDs).Error; err != nil {
+		return nil, err
+	}
+	inTouch := make(map[string]bool, len(fosterIDs))
+	for _, fosterID := range fosterIDs {
+		inTouch[fosterID] = true
+	}
+	return inTouch, nil
+}
+
+// CountFosterHolds returns the number of cats a foster holds, or has agreed
+// to hold, at some point between start and end. A nil end is open-ended. The
+// placement excludeID is left out.
+func (s *DatabaseService) CountFosterHolds(fosterID string, start time.Time, end *time.Time, excludeID uint) (int, error) {
+	db := s.s.DB()
+	return countFosterHolds(db, fosterID, start, end, excludeID)
+}
+
+func countFosterHolds(db *gorm.DB, fosterID string, start time.Time, end *time.Time, excludeID uint) (int, error) {
+	query := db.Model(&models.FosterPlacement{}).
+		Where("foster_id = ? AND status IN (?) AND id <> ?", fosterID, fosterHolds, excludeID).
+		Where("end_date IS NULL OR end_date > ?", start)
+	if end != nil {
+		query = query.Where("start_date < ?", *end)
+	}
+	var count int
+	if err := query.Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// lockFosterRoom locks the profile of the foster of a placement until the end
+// of the transaction, and makes sure the foster has room for the placement.
+// Placements changing at once for the same foster wait for each other, so
+// that they cannot take up more room than the foster has.
+func lockFosterRoom(tx *gorm.DB, placement *models.FosterPlacement) error {
+	var profile models.FosterProfile
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("user_id = ?", placement.FosterID).First(&profile).Error; err != nil {
+		return err
+	}
+	held, err := countFosterHolds(tx, placement.FosterID, placement.StartDate, placement.EndDate, placement.ID)
+	if err != nil {
+		return err
+	}
+	if held >= profile.Capacity {
+		return &FosterFullError{Held: held, Capacity: profile.Capacity}
+	}
+	return nil
+}
+
+// CreateFosterPlacement requests a placement, provided its foster has room
+// for it.
+func (s *DatabaseService) CreateFosterPlacement(placement *models.FosterPlacement) error {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	if err := lockFosterRoom(tx, placement); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Create(placement).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (s *DatabaseService) FindFosterPlacementByID(id string) (*models.FosterPlacement, error) {
+	db := s.s.DB()
+	var placement models.FosterPlacement
+	if err := db.Where("id = ?", id).First(&placement).Error; err != nil {
+		return nil, err
+	}
+	return &placement, nil
+}
+
+// FindOpenCatFosterPlacement returns the placement of a cat that is still to
+// be answered, to start or under way, if any.
+func (s *DatabaseService) FindOpenCatFosterPlacement(catID uint) (*models.FosterPlacement, error) {
+	db := s.s.DB()
+	var placement models.FosterPlacement
+	err := db.Where("cat_id = ? AND status IN (?)", catID, []models.FosterPlacementStatus{models.FosterRequested, models.FosterAccepted, models.FosterActive}).
+		First(&placement).Error
+	if err != nil {
+		return nil, err
+	}
+	return &placement, nil
+}
+
+// FindCatFosterPlacements returns the placements of a cat, most recent first.
+func (s *DatabaseService) FindCatFosterPlacements(catID uint) ([]FosterPlacementDetails, error) {
+	return s.findFosterPlacements("foster_placements.cat_id = ?", catID)
+}
+
+// FindFosterPlacementsByFoster returns the placements offered to a foster,
+// most recent first.
+func (s *DatabaseService) FindFosterPlacementsByFoster(fosterID string) ([]FosterPlacementDetails, error) {
+	return s.findFosterPlacements("foster_placements.foster_id = ?", fosterID)
+}
+
+// FindAssociationFosterPlacements returns the placements of the cats of an
+// association with one of the given statuses, most recent first.
+func (s *DatabaseService) FindAssociationFosterPlacements(associationID uint, statuses []models.FosterPlacementStatus) ([]FosterPlacementDetails, error) {
+	return s.findFosterPlacements("foster_placements.association_id = ? AND foster_placements.status IN (?)", associationID, statuses)
+}
+
+func (s *DatabaseService) findFosterPlacements(where string, args ...interface{}) ([]FosterPlacementDetails, error) {
+	db := s.s.DB()
+	placements := []FosterPlacementDetails{}
+	err := db.Table("foster_placements").
+		Select("foster_placements.*, cats.name AS cat_name, users.name AS foster_name").
+		Joins("JOIN cats ON cats.id = foster_placements.cat_id").
+		Joins("LEFT JOIN users ON CAST(users.id AS text) = foster_placements.foster_id").
+		Where("foster_placements.deleted_at IS NULL").
+		Where(where, args...).
+		Order("foster_placements.start_date DESC, foster_placements.created_at DESC").
+		Scan(&placements).Error
+	if err != nil {
+		return nil, err
+	}
+	return placements, nil
+}
+
+// FindDueFosterPlacements returns the accepted placements whose start date
+// has come.
+func (s *DatabaseService) FindDueFosterPlacements(now time.Time) ([]models.FosterPlacement, error) {
+	db := s.s.DB()
+	var placements []models.FosterPlacement
+	if err := db.Where("status = ? AND start_date <= ?", models.FosterAccepted, now).Find(&placements).Error; err != nil {
+		return nil, err
+	}
+	return placements, nil
+}
+
+// FindOverFosterPlacements returns the active placements whose end date has
+// passed.
+func (s *DatabaseService) FindOverFosterPlacements(now time.Time) ([]models.FosterPlacement, error) {
+	db := s.s.DB()
+	var placements []models.FosterPlacement
+	if err := db.Where("status = ? AND end_date <= ?", models.FosterActive, now).Find(&placements).Error; err != nil {
+		return nil, err
+	}
+	return placements, nil
+}
+
+// RespondFosterPlacement accepts, declines or cancels a placement that has
+// not started. A placement is only accepted when its foster has room for it.
+func (s *DatabaseService) RespondFosterPlacement(placement *models.FosterPlacement, status models.FosterPlacementStatus, respondedBy string) error {
+	db := s.s.DB()
+	now := time.Now()
+	updates := map[string]interface{}{"status": status}
+	if status == models.FosterCancelled {
+		updates["ended_by"] = respondedBy
+		updates["ended_at"] = now
+	} else {
+		updates["responded_at"] = now
+	}
+
+	tx := db.Begin()
+	if status == models.FosterAccepted {
+		if err := lockFosterRoom(tx, placement); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Model(placement).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	if status == models.FosterCancelled {
+		placement.EndedBy = respondedBy
+		placement.EndedAt = &now
+	} else {
+		placement.RespondedAt = &now
+	}
+	placement.Status = status
+	return nil
+}
+
+// StartFosterPlacement makes a placement active and moves its cat to the
+// foster status, provided its foster has room for it. The transition of the
+// cat is expected to be checked by the caller.
+func (s *DatabaseService) StartFosterPlacement(placement *models.FosterPlacement, cat *models.Cats, changedBy string) error {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	if err := lockFosterRoom(tx, placement); err != nil {
+		tx.Rollback()
+		return err
+	}
+	now := time.Now()
+	updates := map[string]interface{}{"status": models.FosterActive}
+	if placement.Status == models.FosterRequested {
+		updates["responded_at"] = now
+	}
+	if err := tx.Model(placement).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := changeCatStatus(tx, cat, models.CatFoster, changedBy, "placed with a foster family"); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	if placement.Status == models.FosterRequested {
+		placement.RespondedAt = &now
+	}
+	placement.Status = models.FosterActive
+	return nil
+}
+
+// EndFosterPlacement ends an active placement. A cat still with the foster
+// status moves to the given status.
+func (s *DatabaseService) EndFosterPlacement(placement *models.FosterPlacement, cat *models.Cats, to models.CatStatus, endedBy, note string) error {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	now := time.Now()
+	if err := endFosterPlacements(tx.Where("id = ?", placement.ID), endedBy, now); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if cat.Status == models.CatFoster {
+		if note == "" {
+			note = "back from a foster family"
+		}
+		if _, err := changeCatStatus(tx, cat, to, endedBy, note); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	placement.Status = models.FosterEnded
+	placement.EndedBy = endedBy
+	placement.EndedAt = &now
+	return nil
+}
+
+// closeCatFosterPlacements keeps the placements of a cat in line with its
+// status: a cat leaving the foster status is no longer placed, and the
+// placements still to come of an adopted or deceased cat are cancelled.
+func closeCatFosterPlacements(tx *gorm.DB, cat *models.Cats, to models.CatStatus, changedBy string, now time.Time) error {
+	if to == models.CatFoster {
+		return nil
+	}
+	if err := endFosterPlacements(tx.Where("cat_id = ?", cat.ID), changedBy, now); err != nil {
+		return err
+	}
+	if to != models.CatAdopted && to != models.CatDeceased {
+		return nil
+	}
+	return tx.Model(&models.FosterPlacement{}).
+		Where("cat_id = ? AND status IN (?)", cat.ID, []models.FosterPlacementStatus{models.FosterRequested, models.FosterAccepted}).
+		Updates(map[string]interface{}{"status": models.FosterCancelled, "ended_by": changedBy, "ended_at": now}).Error
+}
+
+func endFosterPlacements(scope *gorm.DB, endedBy string, now time.Time) error {
+	return scope.Model(&models.FosterPlacement{}).
+		Where("status = ?", models.FosterActive).
+		Updates(map[string]interface{}{"status": models.FosterEnded, "ended_by": endedBy, "ended_at": now}).Error
+}
@@ -0,0 +1,167 @@
+package queries
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPlacement(status models.FosterPlacementStatus) *models.FosterPlacement {
+	placement := &models.FosterPlacement{CatID: 7, AssociationID: 3, FosterID: "foster", Status: status, StartDate: time.Now()}
+	placement.ID = 1
+	return placement
+}
+
+func testFosterCat(status models.CatStatus) *models.Cats {
+	cat := &models.Cats{Name: "Félix", Status: status}
+	cat.ID = 7
+	return cat
+}
+
+func stubFosterRoom(db *dbtest.DB, capacity, held int64) {
+	db.On(`FROM "foster_profiles"`, dbtest.Result{Columns: []string{"id", "user_id", "capacity"}, Values: [][]driver.Value{{int64(1), "foster", capacity}}})
+	db.On(`count(*)`, dbtest.Result{Columns: []string{"count"}, Values: [][]driver.Value{{held}}})
+}
+
+func TestCloseCatFosterPlacements(t *testing.T) {
+	tests := []struct {
+		to        models.CatStatus
+		ended     bool
+		cancelled bool
+	}{
+		{to: models.CatFoster},
+		{to: models.CatAvailable, ended: true},
+		{to: models.CatAdopted, ended: true, cancelled: true},
+		{to: models.CatDeceased, ended: true, cancelled: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.to), func(t *testing.T) {
+			s, db := newTestService()
+
+			err := closeCatFosterPlacements(s.s.DB(), testFosterCat(models.CatFoster), tt.to, "owner", time.Now())
+
+			require.NoError(t, err)
+			updates := db.Queries(`UPDATE "foster_placements"`)
+			var ended, cancelled bool
+			for _, update := range updates {
+				assert.Contains(t, update.Args, int64(7))
+				for _, arg := range update.Args {
+					ended = ended || arg == string(models.FosterEnded)
+					cancelled = cancelled || arg == string(models.FosterCancelled)
+				}
+			}
+			assert.Equal(t, tt.ended, ended)
+			assert.Equal(t, tt.cancelled, cancelled)
+		})
+	}
+}
+
+func TestRespondFosterPlacementChecksRoom(t *testing.T) {
+	s, db := newTestService()
+	stubFosterRoom(db, 2, 2)
+
+	err := s.RespondFosterPlacement(testPlacement(models.FosterRequested), models.FosterAccepted, "foster")
+
+	var full *FosterFullError
+	require.ErrorAs(t, err, &full)
+	assert.Equal(t, 2, full.Held)
+	assert.Equal(t, 2, full.Capacity)
+	assert.Len(t, db.Queries("FOR UPDATE"), 1)
+	assert.Len(t, db.Queries("ROLLBACK"), 1)
+	assert.Empty(t, db.Queries(`UPDATE "foster_placements"`))
+}
+
+func TestRespondFosterPlacementAccepts(t *testing.T) {
+	s, db := newTestService()
+	stubFosterRoom(db, 2, 1)
+	placement := testPlacement(models.FosterRequested)
+
+	err := s.RespondFosterPlacement(placement, models.FosterAccepted, "foster")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.FosterAccepted, placement.Status)
+	assert.NotNil(t, placement.RespondedAt)
+	assert.Len(t, db.Queries(`UPDATE "foster_placements"`), 1)
+	assert.Len(t, db.Queries("COMMIT"), 1)
+}
+
+func TestRespondFosterPlacementDeclineSkipsRoom(t *testing.T) {
+	s, db := newTestService()
+	placement := testPlacement(models.FosterRequested)
+
+	err := s.RespondFosterPlacement(placement, models.FosterDeclined, "foster")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.FosterDeclined, placement.Status)
+	assert.Empty(t, db.Queries("FOR UPDATE"))
+}
+
+func TestCreateFosterPlacementChecksRoom(t *testing.T) {
+	s, db := newTestService()
+	stubFosterRoom(db, 1, 1)
+
+	err := s.CreateFosterPlacement(testPlacement(models.FosterRequested))
+
+	var full *FosterFullError
+	assert.ErrorAs(t, err, &full)
+	assert.Empty(t, db.Queries("INSERT"))
+}
+
+func TestStartFosterPlacement(t *testing.T) {
+	s, db := newTestService()
+	stubFosterRoom(db, 1, 0)
+	placement, cat := testPlacement(models.FosterAccepted), testFosterCat(models.CatAvailable)
+
+	err := s.StartFosterPlacement(placement, cat, "foster")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.FosterActive, placement.Status)
+	assert.Equal(t, models.CatFoster, cat.Status)
+	assert.Len(t, db.Queries(`INSERT INTO "cat_status_changes"`), 1)
+	assert.Len(t, db.Queries("COMMIT"), 1)
+}
+
+func TestEndFosterPlacement(t *testing.T) {
+	s, db := newTestService()
+	placement, cat := testPlacement(models.FosterActive), testFosterCat(models.CatFoster)
+
+	err := s.EndFosterPlacement(placement, cat, models.CatAvailable, "owner", "")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.FosterEnded, placement.Status)
+	assert.Equal(t, "owner", placement.EndedBy)
+	assert.Equal(t, models.CatAvailable, cat.Status)
+	changes := db.Queries(`INSERT INTO "cat_status_changes"`)
+	require.Len(t, changes, 1)
+	assert.Contains(t, changes[0].Args, "back from a foster family")
+}
+
+func TestEndFosterPlacementLeavesOtherStatuses(t *testing.T) {
+	s, db := newTestService()
+	cat := testFosterCat(models.CatAdopted)
+
+	err := s.EndFosterPlacement(testPlacement(models.FosterActive), cat, models.CatAvailable, "owner", "")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.CatAdopted, cat.Status)
+	assert.Empty(t, db.Queries(`INSERT INTO "cat_status_changes"`))
+}
+
+func TestSaveFosterProfileStoresUnavailable(t *testing.T) {
+	s, db := newTestService()
+	profile := &models.FosterProfile{UserID: "foster", Capacity: 2, Available: false}
+
+	require.NoError(t, s.SaveFosterProfile(profile))
+
+	inserts := db.Queries(`INSERT INTO "foster_profiles"`)
+	require.Len(t, inserts, 1)
+	available, ok := inserts[0].Inserted("available")
+	require.True(t, ok)
+	assert.Equal(t, false, available)
+}
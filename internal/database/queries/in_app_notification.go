@@ -0,0 +1,39 @@
+package queries
+
+import (
+	"time"
+
+	"go-challenge/internal/models"
+)
+
+func (s *DatabaseService) CreateInAppNotification(notification *models.InAppNotification) error {
+	db := s.s.DB()
+	return db.Create(notification).Error
+}
+
+func (s *DatabaseService) FindInAppNotificationsByUserID(userID string, unreadOnly bool) ([]models.InAppNotification, error) {
+	db := s.s.DB()
+	var notifications []models.InAppNotification
+	query := db.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if err := query.Order("created_at DESC").Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (s *DatabaseService) MarkInAppNotificationAsRead(id string, userID string) error {
+	db := s.s.DB()
+	result := db.Model(&models.InAppNotification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", id, userID).
+		Update("read_at", time.Now())
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	// Nothing updated: the notification was already read, or it is not one
+	// of the user's, which gives gorm.ErrRecordNotFound.
+	var notification models.InAppNotification
+	return db.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error
+}
@@ -0,0 +1,207 @@
+package queries
+
+import (
+	"strings"
+
+	"go-challenge/internal/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// maxAncestorDepth bounds the walk up a family tree; real pedigrees are far
+// shallower.
+const maxAncestorDepth = 50
+
+func (s *DatabaseService) CreateLitter(litter *models.Litter) error {
+	db := s.s.DB()
+	return db.Create(litter).Error
+}
+
+func (s *DatabaseService) FindLitterByID(id string) (*models.Litter, error) {
+	db := s.s.DB()
+	var litter models.Litter
+	if err := db.Where("id = ?", id).First(&litter).Error; err != nil {
+		return nil, err
+	}
+	return &litter, nil
+}
+
+func (s *DatabaseService) UpdateLitter(litter *models.Litter) error {
+	db := s.s.DB()
+	return db.Save(litter).Error
+}
+
+// DeleteLitter removes a litter. Its kittens stay, with their parents, but no
+// longer belong to it.
+func (s *DatabaseService) DeleteLitter(litter *models.Litter) error {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	if err := tx.Model(&models.Cats{}).Where("litter_id = ?", litter.ID).Update("litter_id", gorm.Expr("NULL")).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(litter).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (s *DatabaseService) FindLitterKittens(litterID uint) ([]models.Cats, error) {
+	db := s.s.DB()
+	var kittens []models.Cats
+	if err := db.Where("litter_id = ?", litterID).Order("id").Find(&kittens).Error; err != nil {
+		return nil, err
+	}
+	return kittens, nil
+}
+
+// SetLitterKittens makes the given cats the kittens of a litter, in place of
+// the previous ones. The parents of the litter, when known, become theirs.
+func (s *DatabaseService) SetLitterKittens(litter *models.Litter, catIDs []uint) error {
+	db := s.s.DB()
+
+	tx := db.Begin()
+	if err := tx.Model(&models.Cats{}).Where("litter_id = ?", litter.ID).Update("litter_id", gorm.Expr("NULL")).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(catIDs) > 0 {
+		updates := map[string]interface{}{"litter_id": litter.ID}
+		if litter.MotherID != nil {
+			updates["mother_id"] = *litter.MotherID
+		}
+		if litter.FatherID != nil {
+			updates["father_id"] = *litter.FatherID
+		}
+		if err := tx.Model(&models.Cats{}).Where("id IN (?)", catIDs).Updates(updates).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+// FindAvailableLitters returns the litters with at least one kitten up for
+// adoption, most recent first.
+func (s *DatabaseService) FindAvailableLitters() ([]models.Litter, error) {
+	db := s.s.DB()
+	var litters []models.Litter
+	err := db.
+		Where("id IN (SELECT litter_id FROM cats WHERE status = ? AND deleted_at IS NULL)", models.CatAvailable).
+		Order("created_at DESC").
+		Find(&litters).Error
+	if err != nil {
+		return nil, err
+	}
+	return litters, nil
+}
+
+// SetCatParents sets or clears the parents of a cat.
+func (s *DatabaseService) SetCatParents(cat *models.Cats, motherID, fatherID *uint) error {
+	db := s.s.DB()
+	updates := map[string]interface{}{"mother_id": gorm.Expr("NULL"), "father_id": gorm.Expr("NULL")}
+	if motherID != nil {
+		updates["mother_id"] = *motherID
+	}
+	if fatherID != nil {
+		updates["father_id"] = *fatherID
+	}
+	if err := db.Model(cat).Updates(updates).Error; err != nil {
+		return err
+	}
+	cat.MotherID = motherID
+	cat.FatherID = fatherID
+	return nil
+}
+
+// IsCatAncestor reports whether a cat is among the ancestors of another one,
+// which would make a loop of setting it as one of its kittens.
+func (s *DatabaseService) IsCatAncestor(ancestorID, catID uint) (bool, error) {
+	db := s.s.DB()
+
+	current := []uint{catID}
+	for depth := 0; depth < maxAncestorDepth && len(current) > 0; depth++ {
+		var parents []models.Cats
+		if err := db.Unscoped().Where("id IN (?)", current).Find(&parents).Error; err != nil {
+			return false, err
+		}
+		current = nil
+		for _, parent := range parents {
+			for _, id := range []*uint{parent.MotherID, parent.FatherID} {
+				if id == nil {
+					continue
+				}
+				if *id == ancestorID {
+					return true, nil
+				}
+				current = append(current, *id)
+			}
+		}
+	}
+	return false, nil
+}
+
+// FindCatFamily returns the parents, litter, siblings and kittens of a cat.
+// Siblings include half-siblings sharing only one parent.
+func (s *DatabaseService) FindCatFamily(cat *models.Cats) (*models.CatFamily, error) {
+	db := s.s.DB()
+	family := &models.CatFamily{Siblings: []models.Cats{}, Kittens: []models.Cats{}}
+
+	if cat.MotherID != nil {
+		var mother models.Cats
+		if err := db.Where("id = ?", *cat.MotherID).First(&mother).Error; err == nil {
+			family.Mother = &mother
+		} else if !gorm.IsRecordNotFoundError(err) {
+			return nil, err
+		}
+	}
+	if cat.FatherID != nil {
+		var father models.Cats
+		if err := db.Where("id = ?", *cat.FatherID).First(&father).Error; err == nil {
+			family.Father = &father
+		} else if !gorm.IsRecordNotFoundError(err) {
+			return nil, err
+		}
+	}
+	if cat.LitterID != nil {
+		var litter models.Litter
+		if err := db.Where("id = ?", *cat.LitterID).First(&litter).Error; err == nil {
+			family.Litter = &litter
+		} else if !gorm.IsRecordNotFoundError(err) {
+			return nil, err
+		}
+	}
+
+	var conditions []string
+	args := []interface{}{cat.ID}
+	if cat.LitterID != nil {
+		conditions = append(conditions, "litter_id = ?")
+		args = append(args, *cat.LitterID)
+	}
+	if cat.MotherID != nil {
+		conditions = append(conditions, "mother_id = ?")
+		args = append(args, *cat.MotherID)
+	}
+	if cat.FatherID != nil {
+		conditions = append(conditions, "father_id = ?")
+		args = append(args, *cat.FatherID)
+	}
+	var siblings []models.Cats
+	if len(conditions) > 0 {
+		err := db.Where("id <> ? AND ("+strings.Join(conditions, " OR ")+")", args...).Order("id").Find(&siblings).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	family.Siblings = append(family.Siblings, siblings...)
+
+	var kittens []models.Cats
+	if err := db.Where("mother_id = ? OR father_id = ?", cat.ID, cat.ID).Order("id").Find(&kittens).Error; err != nil {
+		return nil, err
+	}
+	family.Kittens = append(family.Kittens, kittens...)
+
+	return family, nil
+}
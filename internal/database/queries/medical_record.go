@@ -0,0 +1,115 @@
+package queries
+
+import (
+	"go-challenge/internal/models"
+)
+
+func (s *DatabaseService) CreateMedicalEntry(entry *models.MedicalEntry) error {
+	db := s.s.DB()
+	return db.Create(entry).Error
+}
+
+func (s *DatabaseService) UpdateMedicalEntry(entry *models.MedicalEntry) error {
+	db := s.s.DB()
+	return db.Save(entry).Error
+}
+
+func (s *DatabaseService) DeleteMedicalEntry(entry *models.MedicalEntry) error {
+	db := s.s.DB()
+	return db.Delete(entry).Error
+}
+
+func (s *DatabaseService) FindMedicalEntry(catID uint, id string) (*models.MedicalEntry, error) {
+	db := s.s.DB()
+	var entry models.MedicalEntry
+	if err := db.Where("cat_id = ? AND id = ?", catID, id).First(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// FindMedicalEntriesByCatID returns the medical record of a cat, most recent
+// entries first.
+func (s *DatabaseService) FindMedicalEntriesByCatID(catID uint) ([]models.MedicalEntry, error) {
+	db := s.s.DB()
+	var entries []models.MedicalEntry
+	if err := db.Where("cat_id = ?", catID).Order("date DESC, id DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// SyncCatLastVaccine copies the latest vaccination of the medical record to
+// the LastVaccine fields of the cat, which older clients still read.
+func (s *DatabaseService) SyncCatLastVaccine(catID uint) error {
+	db := s.s.DB()
+	var latest models.MedicalEntry
+	result := db.Where("cat_id = ? AND type = ?", catID, models.MedicalVaccination).Order("date DESC, id DESC").First(&latest)
+	if result.RecordNotFound() {
+		return nil
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	return db.Model(&models.Cats{}).Where("id = ?", catID).
+		Updates(map[string]interface{}{"last_vaccine": latest.Date, "last_vaccine_name": latest.Name}).Error
+}
+
+func (s *DatabaseService) UpdateCatIdentificationNumber(cat *models.Cats) error {
+	db := s.s.DB()
+	return db.Model(cat).Update("identification_number", cat.IdentificationNumber).Error
+}
+
+// TransferCat gives a cat to its adopter. The cat is marked as adopted,
+// leaves the association it was published as, takes the location of its
+// adopter and its annonces are closed. The medical record follows the cat.
+func (s *DatabaseService) TransferCat(cat *models.Cats, adopter *models.User, transferredBy string) (*models.CatOwnershipTransfer, error) {
+	db := s.s.DB()
+	transfer := &models.CatOwnershipTransfer{
+		CatID:             cat.ID,
+		FromUserID:        cat.UserID,
+		FromAssociationID: cat.PublishedAs,
+		ToUserID:          adopter.ID,
+		TransferredBy:     transferredBy,
+	}
+
+	tx := db.Begin()
+	if err := tx.Create(transfer).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if cat.Status != models.CatAdopted {
+		if _, err := changeCatStatus(tx, cat, models.CatAdopted, transferredBy, ""); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	cat.UserID = adopter.ID
+	cat.PublishedAs = ""
+	cat.Latitude, cat.Longitude = adopter.Latitude, adopter.Longitude
+	if err := tx.Save(cat).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := closeCatAnnonces(tx, cat, models.CloseAdopted); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+func (s *DatabaseService) FindCatOwnershipTransfers(catID uint) ([]models.CatOwnershipTransfer, error) {
+	db := s.s.DB()
+	var transfers []models.CatOwnershipTransfer
+	if err := db.Where("cat_id = ?", catID).Order("created_at DESC").Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
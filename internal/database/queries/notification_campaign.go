@@ -0,0 +1,171 @@
+package queries
+
+import (
+	"go-challenge/internal/models"
+)
+
+func (s *DatabaseService) CreateNotificationAudience(audience *models.NotificationAudience) error {
+	db := s.s.DB()
+	return db.Create(audience).Error
+}
+
+func (s *DatabaseService) GetNotificationAudiences() ([]models.NotificationAudience, error) {
+	db := s.s.DB()
+	var audiences []models.NotificationAudience
+	if err := db.Order("name").Find(&audiences).Error; err != nil {
+		return nil, err
+	}
+	return audiences, nil
+}
+
+func (s *DatabaseService) FindNotificationAudienceByID(id uint) (*models.NotificationAudience, error) {
+	db := s.s.DB()
+	var audience models.NotificationAudience
+	if err := db.First(&audience, id).Error; err != nil {
+		return nil, err
+	}
+	return &audience, nil
+}
+
+func (s *DatabaseService) DeleteNotificationAudience(id uint) error {
+	db := s.s.DB()
+	return db.Delete(&models.NotificationAudience{}, id).Error
+}
+
+// ResolveNotificationRecipients returns the users matching any criterion of
+// the target, each user appearing once.
+func (s *DatabaseService) ResolveNotificationRecipients(target models.NotificationTarget) ([]models.User, error) {
+	db := s.s.DB()
+	ids := make(map[string]bool)
+
+	for _, id := range target.UserIDs {
+		ids[id] = true
+	}
+
+	if len(target.Roles) > 0 {
+		var roleUserIDs []string
+		if err := db.Table("user_roles").
+			Joins("JOIN roles ON roles.id = user_roles.roles_id").
+			Where("roles.name IN (?)", []string(target.Roles)).
+			Pluck("user_roles.user_id", &roleUserIDs).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range roleUserIDs {
+			ids[id] = true
+		}
+	}
+
+	if len(target.AssociationIDs) > 0 {
+		var associations []models.Association
+		if err := db.Preload("Members").Where("id IN (?)", []string(target.AssociationIDs)).Find(&associations).Error; err != nil {
+			return nil, err
+		}
+		for _, association := range associations {
+			ids[association.OwnerID] = true
+			for _, member := range association.Members {
+				ids[member.UserID] = true
+			}
+		}
+	}
+
+	users := []models.User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	userIDs := make([]string, 0, len(ids))
+	for id := range ids {
+		userIDs = append(userIDs, id)
+	}
+	if err := db.Where("id IN (?)", userIDs).Order("name").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (s *DatabaseService) CreateNotificationCampaign(campaign *models.NotificationCampaign) error {
+	db := s.s.DB()
+	return db.Create(campaign).Error
+}
+
+func (s *DatabaseService) UpdateNotificationCampaign(campaign *models.NotificationCampaign) error {
+	db := s.s.DB()
+	return db.Save(campaign).Error
+}
+
+func (s *DatabaseService) GetNotificationCampaigns() ([]models.NotificationCampaign, error) {
+	db := s.s.DB()
+	var campaigns []models.NotificationCampaign
+	if err := db.Order("created_at DESC").Find(&campaigns).Error; err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+func (s *DatabaseService) FindNotificationCampaignByID(id uint) (*models.NotificationCampaign, error) {
+	db := s.s.DB()
+	var campaign models.NotificationCampaign
+	if err := db.First(&campaign, id).Error; err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+func (s *DatabaseService) CreateNotificationDelivery(delivery *models.NotificationDelivery) error {
+	db := s.s.DB()
+	return db.Create(delivery).Error
+}
+
+func (s *DatabaseService) GetNotificationDeliveriesByCampaignID(campaignID uint) ([]models.NotificationDelivery, error) {
+	db := s.s.DB()
+	var deliveries []models.NotificationDelivery
+	if err := db.Where("campaign_id = ?", campaignID).Order("id").Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// FindSendingNotificationCampaigns returns the campaigns whose delivery is
+// not over, the oldest first.
+func (s *DatabaseService) FindSendingNotificationCampaigns() ([]models.NotificationCampaign, error) {
+	db := s.s.DB()
+	var campaigns []models.NotificationCampaign
+	if err := db.Where("status = ?", models.CampaignSending).Order("id").Find(&campaigns).Error; err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// FindDeliveredUserIDs returns the users a campaign already went to,
+// whatever the outcome.
+func (s *DatabaseService) FindDeliveredUserIDs(campaignID uint) (map[string]bool, error) {
+	db := s.s.DB()
+	var userIDs []string
+	if err := db.Model(&models.NotificationDelivery{}).Where("campaign_id = ?", campaignID).Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	delivered := make(map[string]bool, len(userIDs))
+	for _, userID := range userIDs {
+		delivered[userID] = true
+	}
+	return delivered, nil
+}
+
+// CountNotificationDeliveries counts the deliveries of a campaign by status.
+func (s *DatabaseService) CountNotificationDeliveries(campaignID uint) (map[models.DeliveryStatus]int, error) {
+	db := s.s.DB()
+	var rows []struct {
+		Status models.DeliveryStatus
+		Count  int
+	}
+	err := db.Model(&models.NotificationDelivery{}).Select("status, COUNT(*) AS count").
+		Where("campaign_id = ?", campaignID).Group("status").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := map[models.DeliveryStatus]int{}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,117 @@
+package queries
+
+import (
+	"strings"
+
+	"go-challenge/internal/models"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+// RaceFilter holds the filters of a breed listing. Every filter that is set
+// must match. Query matches part of the name or of an alias, and a breed must
+// be known for every temperament listed.
+type RaceFilter struct {
+	Query          string
+	CoatLength     models.CoatLength
+	Size           models.BreedSize
+	ActivityLevel  models.ActivityLevel
+	Hypoallergenic *bool
+	Temperament    []string
+}
+
+// IsZero reports whether no filter is set.
+func (f RaceFilter) IsZero() bool {
+	return f.Query == "" && f.CoatLength == "" && f.Size == "" && f.ActivityLevel == "" &&
+		f.Hypoallergenic == nil && len(f.Temperament) == 0
+}
+
+// RaceImport counts the breeds created and updated by an import.
+type RaceImport struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+// SearchRaces returns the breeds matching a filter, by name.
+func (s *DatabaseService) SearchRaces(filter RaceFilter) ([]models.Races, error) {
+	db := s.s.DB()
+	var races []models.Races
+	if err := applyRaceFilters(db.Model(&models.Races{}), filter).Order("race_name").Find(&races).Error; err != nil {
+		return nil, err
+	}
+	return races, nil
+}
+
+// ImportRaces creates the breeds of a catalogue, or updates the ones already
+// known under the same name. Breeds missing from the catalogue are kept.
+func (s *DatabaseService) ImportRaces(races []models.Races) (*RaceImport, error) {
+	db := s.s.DB()
+	result := &RaceImport{}
+
+	tx := db.Begin()
+	var existing []models.Races
+	if err := tx.Find(&existing).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, race := range races {
+		known := -1
+		for i := range existing {
+			if strings.EqualFold(existing[i].RaceName, race.RaceName) {
+				known = i
+				break
+			}
+		}
+
+		if known < 0 {
+			if err := tx.Create(&race).Error; err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			existing = append(existing, race)
+			result.Created++
+			continue
+		}
+
+		race.Model = existing[known].Model
+		if err := tx.Save(&race).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		existing[known] = race
+		result.Updated++
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func applyRaceFilters(query *gorm.DB, filter RaceFilter) *gorm.DB {
+	if filter.Query != "" {
+		pattern := "%" + strings.ToLower(filter.Query) + "%"
+		query = query.Where(
+			"LOWER(race_name) LIKE ? OR EXISTS (SELECT 1 FROM UNNEST(aliases_fr || aliases_en) AS alias WHERE LOWER(alias) LIKE ?)",
+			pattern, pattern,
+		)
+	}
+	if filter.CoatLength != "" {
+		query = query.Where("coat_length = ?", filter.CoatLength)
+	}
+	if filter.Size != "" {
+		query = query.Where("size = ?", filter.Size)
+	}
+	if filter.ActivityLevel != "" {
+		query = query.Where("activity_level = ?", filter.ActivityLevel)
+	}
+	if filter.Hypoallergenic != nil {
+		query = query.Where("hypoallergenic = ?", *filter.Hypoallergenic)
+	}
+	if len(filter.Temperament) > 0 {
+		query = query.Where("temperament @> CAST(? AS varchar[])", pq.StringArray(filter.Temperament))
+	}
+	return query
+}
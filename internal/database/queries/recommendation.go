@@ -0,0 +1,76 @@
+package queries
+
+import (
+	"go-challenge/internal/models"
+)
+
+func (s *DatabaseService) FindLifestyleProfileByUserID(userID string) (*models.LifestyleProfile, error) {
+	db := s.s.DB()
+	var profile models.LifestyleProfile
+	if err := db.Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (s *DatabaseService) SaveLifestyleProfile(profile *models.LifestyleProfile) error {
+	db := s.s.DB()
+	return db.Save(profile).Error
+}
+
+// CreateCatDismissal stores a dismissal unless the user already dismissed
+// the cat.
+func (s *DatabaseService) CreateCatDismissal(dismissal *models.CatDismissal) error {
+	db := s.s.DB()
+	return db.Where(models.CatDismissal{UserID: dismissal.UserID, CatID: dismissal.CatID}).FirstOrCreate(dismissal).Error
+}
+
+func (s *DatabaseService) DeleteCatDismissal(userID string, catID string) error {
+	db := s.s.DB()
+	return db.Where("user_id = ? AND cat_id = ?", userID, catID).Delete(&models.CatDismissal{}).Error
+}
+
+// FindDismissedCats returns the cats a user dismissed.
+func (s *DatabaseService) FindDismissedCats(userID string) ([]models.Cats, error) {
+	db := s.s.DB()
+	var cats []models.Cats
+	err := db.Select("cats.*").
+		Joins("JOIN cat_dismissals ON cat_dismissals.cat_id = cats.id AND cat_dismissals.deleted_at IS NULL").
+		Where("cat_dismissals.user_id = ?", userID).
+		Find(&cats).Error
+	if err != nil {
+		return nil, err
+	}
+	return cats, nil
+}
+
+// FindFavoriteCats returns the cats of the annonces a user favorited, once
+// per favorite.
+func (s *DatabaseService) FindFavoriteCats(userID string) ([]models.Cats, error) {
+	db := s.s.DB()
+	var cats []models.Cats
+	err := db.Select("cats.*").
+		Joins("JOIN annonces ON annonces.cat_id = CAST(cats.id AS text) AND annonces.deleted_at IS NULL").
+		Joins("JOIN favorites ON favorites.annonce_id = CAST(annonces.id AS text) AND favorites.deleted_at IS NULL").
+		Where("favorites.user_id = ?", userID).
+		Find(&cats).Error
+	if err != nil {
+		return nil, err
+	}
+	return cats, nil
+}
+
+// GetAdoptableCats returns the available cats with a published annonce,
+// leaving out the cats of the given user.
+func (s *DatabaseService) GetAdoptableCats(excludedUserID string) ([]models.Cats, error) {
+	db := s.s.DB()
+	var cats []models.Cats
+	err := db.
+		Where("status = ? AND user_id <> ?", models.CatAvailable, excludedUserID).
+		Where("CAST(id AS text) IN (SELECT cat_id FROM annonces WHERE status = ? AND deleted_at IS NULL)", models.AnnoncePublished).
+		Find(&cats).Error
+	if err != nil {
+		return nil, err
+	}
+	return cats, nil
+}
@@ -0,0 +1,39 @@
+package queries
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindFavoriteCatsInOneQuery(t *testing.T) {
+	s, db := newTestService()
+	db.On(`FROM "cats"`, dbtest.Result{Columns: []string{"id", "race_id", "color"}, Values: [][]driver.Value{
+		{int64(7), "1", "Black"},
+		{int64(7), "1", "Black"},
+	}})
+
+	cats, err := s.FindFavoriteCats("adopter")
+
+	require.NoError(t, err)
+	assert.Len(t, cats, 2)
+	queries := db.Queries("")
+	require.Len(t, queries, 1)
+	assert.Contains(t, queries[0].SQL, "JOIN favorites ON favorites.annonce_id = CAST(annonces.id AS text)")
+	assert.Equal(t, []driver.Value{"adopter"}, queries[0].Args)
+}
+
+func TestFindDismissedCatsInOneQuery(t *testing.T) {
+	s, db := newTestService()
+
+	_, err := s.FindDismissedCats("adopter")
+
+	require.NoError(t, err)
+	queries := db.Queries("")
+	require.Len(t, queries, 1)
+	assert.Contains(t, queries[0].SQL, "JOIN cat_dismissals ON cat_dismissals.cat_id = cats.id")
+}
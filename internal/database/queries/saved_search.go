@@ -0,0 +1,106 @@
+package queries
+
+import (
+	"time"
+
+	"go-challenge/internal/models"
+)
+
+func (s *DatabaseService) CreateSavedSearch(search *models.SavedSearch) error {
+	db := s.s.DB()
+	return db.Create(search).Error
+}
+
+func (s *DatabaseService) UpdateSavedSearch(search *models.SavedSearch) error {
+	db := s.s.DB()
+	return db.Save(search).Error
+}
+
+func (s *DatabaseService) DeleteSavedSearch(search *models.SavedSearch) error {
+	db := s.s.DB()
+	return db.Delete(search).Error
+}
+
+func (s *DatabaseService) FindSavedSearchByID(id string) (*models.SavedSearch, error) {
+	db := s.s.DB()
+	var search models.SavedSearch
+	if err := db.Where("id = ?", id).First(&search).Error; err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+func (s *DatabaseService) FindSavedSearchesByUserID(userID string) ([]models.SavedSearch, error) {
+	db := s.s.DB()
+	var searches []models.SavedSearch
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&searches).Error; err != nil {
+		return nil, err
+	}
+	return searches, nil
+}
+
+// GetActiveSavedSearches returns the searches of every user that still want
+// to be alerted, except the ones of the given user.
+func (s *DatabaseService) GetActiveSavedSearches(excludedUserID string) ([]models.SavedSearch, error) {
+	db := s.s.DB()
+	var searches []models.SavedSearch
+	if err := db.Where("active = ? AND user_id <> ?", true, excludedUserID).Find(&searches).Error; err != nil {
+		return nil, err
+	}
+	return searches, nil
+}
+
+// CreateSavedSearchMatch stores a match and reports whether it is new. A cat
+// already matched by the search is not stored twice.
+func (s *DatabaseService) CreateSavedSearchMatch(match *models.SavedSearchMatch) (bool, error) {
+	db := s.s.DB()
+	var existing models.SavedSearchMatch
+	result := db.Where("saved_search_id = ? AND cat_id = ?", match.SavedSearchID, match.CatID).First(&existing)
+	if result.Error == nil {
+		return false, nil
+	}
+	if !result.RecordNotFound() {
+		return false, result.Error
+	}
+	if err := db.Create(match).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *DatabaseService) FindSavedSearchMatches(searchID uint) ([]models.SavedSearchMatch, error) {
+	db := s.s.DB()
+	var matches []models.SavedSearchMatch
+	if err := db.Where("saved_search_id = ?", searchID).Order("created_at DESC").Find(&matches).Error; err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+func (s *DatabaseService) FindPendingSavedSearchMatches(searchID uint) ([]models.SavedSearchMatch, error) {
+	db := s.s.DB()
+	var matches []models.SavedSearchMatch
+	if err := db.Where("saved_search_id = ? AND notified_at IS NULL", searchID).Order("created_at").Find(&matches).Error; err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+func (s *DatabaseService) MarkSavedSearchMatchesNotified(searchID uint, notifiedAt time.Time) error {
+	db := s.s.DB()
+	return db.Model(&models.SavedSearchMatch{}).
+		Where("saved_search_id = ? AND notified_at IS NULL", searchID).
+		Update("notified_at", notifiedAt).Error
+}
+
+// GetDigestSavedSearches returns the active searches of a frequency whose last
+// alert is older than the given time.
+func (s *DatabaseService) GetDigestSavedSearches(frequency models.SearchFrequency, notifiedBefore time.Time) ([]models.SavedSearch, error) {
+	db := s.s.DB()
+	var searches []models.SavedSearch
+	if err := db.Where("active = ? AND frequency = ? AND (last_notified IS NULL OR last_notified < ?)", true, frequency, notifiedBefore).
+		Find(&searches).Error; err != nil {
+		return nil, err
+	}
+	return searches, nil
+}
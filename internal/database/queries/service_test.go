@@ -0,0 +1,13 @@
+package queries
+
+import (
+	"go-challenge/internal/database"
+	"go-challenge/internal/database/dbtest"
+)
+
+// newTestService returns a service running its queries against a scripted
+// database.
+func newTestService() (*DatabaseService, *dbtest.DB) {
+	db, scripted := dbtest.New()
+	return &DatabaseService{s: database.Service{Db: db}}, scripted
+}
@@ -31,6 +31,9 @@ func NewCatFixture(userID string, pictureIndex int, race models.Races) *models.C
 		"https://d2zp5xs5cp8zlg.cloudfront.net/image-53920-800.jpg",
 	}
 
+	// Most fixture cats are up for adoption
+	statuses := []string{string(models.CatAvailable), string(models.CatAvailable), string(models.CatFoster), string(models.CatReserved)}
+
 	birthDate := time.Now().AddDate(-rand.Intn(10), 0, 0)
 	lastVaccineDate := time.Now().AddDate(-rand.Intn(5), 0, 0)
 	description := randomChoice(descriptions)
@@ -46,7 +49,7 @@ func NewCatFixture(userID string, pictureIndex int, race models.Races) *models.C
 		Sterilized:      randomBool(),
 		RaceID:          strconv.FormatUint(uint64(race.ID), 10),
 		Description:     &description,
-		Reserved:        randomBool(),
+		Status:          models.CatStatus(randomChoice(statuses)),
 		PicturesURL:     pq.StringArray{picturesURL[pictureIndex%len(picturesURL)]},
 		UserID:          userID,
 	}
@@ -59,8 +62,13 @@ func CreateCatFixturesForUser(db *gorm.DB, count int, userID string) ([]*models.
 
 		return nil, err
 	}
+	var owner models.User
+	if err := db.Where("id = ?", userID).First(&owner).Error; err != nil {
+		return nil, err
+	}
 	for i := 0; i < count; i++ {
 		cat := NewCatFixture(userID, i, races[i])
+		cat.Latitude, cat.Longitude = owner.Latitude, owner.Longitude
 		if err := db.Create(cat).Error; err != nil {
 			return nil, err
 		}
@@ -3,9 +3,11 @@ package fixtures
 import (
 	"fmt"
 	"log"
+	"math/rand"
 	"time"
 
 	"go-challenge/internal/auth"
+	"go-challenge/internal/geo"
 	"go-challenge/internal/models"
 
 	"github.com/google/uuid"
@@ -20,8 +22,10 @@ func NewUserFixture(i int) *models.User {
 		log.Fatalf("failed to hash password: %v", err)
 	}
 	addressRues := []string{"123 Main St", "456 Elm St", "789 Oak St", "101 Maple St", "202 Pine St"}
-	cps := []string{"75001", "75002", "75003", "75004", "75005"}
+	cps := []string{"75001", "69001", "13001", "31000", "06000"}
 	villes := []string{"Paris", "Lyon", "Marseille", "Toulouse", "Nice"}
+	city := rand.Intn(len(cps))
+	latitude, longitude := geo.Locate(cps[city])
 
 	return &models.User{
 		ID:            uuid.New().String(),
@@ -31,9 +35,10 @@ func NewUserFixture(i int) *models.User {
 		Email:         email,
 		Password:      password,
 		AddressRue:    randomChoice(addressRues),
-		Cp:            randomChoice(cps),
-		Ville:         randomChoice(villes),
-		Associations:  []models.Association{},
+		Cp:            cps[city],
+		Ville:         villes[city],
+		Latitude:      latitude,
+		Longitude:     longitude,
 		Roles:         []models.Roles{},
 		GoogleID:      "",
 		ProfilePicURL: "default",
@@ -76,7 +81,6 @@ func CreateUserFixtures(db *gorm.DB, count int, userRole *models.Roles) ([]*mode
 		AddressRue:    "",
 		Cp:            "",
 		Ville:         "",
-		Associations:  []models.Association{},
 		Roles:         []models.Roles{adminRole},
 		GoogleID:      "",
 		ProfilePicURL: "default",
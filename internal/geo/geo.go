@@ -0,0 +1,132 @@
+// Package geo locates French postal codes and computes distances between
+// them, using an embedded dataset so that no external service is needed.
+//
+// The dataset holds the coordinates of the main cities by postal code and of
+// the prefecture of every department. A postal code missing from the dataset
+// is located at the prefecture of its department, which can be tens of
+// kilometers away: distances are only reliable to the scale of a department,
+// and searches within a small radius can miss or include cats around its
+// edge.
+package geo
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//go:embed postal_codes.csv
+var postalCodesCSV string
+
+const earthRadiusKm = 6371.0
+
+var ErrUnknownPostalCode = errors.New("unknown postal code")
+
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+var postalCodes = mustLoadPostalCodes(postalCodesCSV)
+
+func mustLoadPostalCodes(data string) map[string]Point {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("geo: invalid postal code dataset: %v", err))
+	}
+
+	points := make(map[string]Point, len(records))
+	for _, record := range records[1:] {
+		latitude, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			panic(fmt.Sprintf("geo: invalid latitude for %s: %v", record[0], err))
+		}
+		longitude, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			panic(fmt.Sprintf("geo: invalid longitude for %s: %v", record[0], err))
+		}
+		points[record[0]] = Point{Latitude: latitude, Longitude: longitude}
+	}
+	return points
+}
+
+// Department returns the department code of a postal code: two digits for
+// metropolitan France, 2A or 2B for Corsica and three digits overseas.
+func Department(cp string) (string, error) {
+	cp = strings.TrimSpace(cp)
+	if len(cp) != 5 {
+		return "", ErrUnknownPostalCode
+	}
+	if _, err := strconv.Atoi(cp); err != nil {
+		return "", ErrUnknownPostalCode
+	}
+
+	switch {
+	case strings.HasPrefix(cp, "97"):
+		return cp[:3], nil
+	case strings.HasPrefix(cp, "20"):
+		if cp[2] <= '1' {
+			return "2A", nil
+		}
+		return "2B", nil
+	}
+	return cp[:2], nil
+}
+
+// Lookup returns the coordinates of a postal code, or those of the
+// prefecture of its department when it is missing from the dataset.
+func Lookup(cp string) (Point, error) {
+	cp = strings.TrimSpace(cp)
+	department, err := Department(cp)
+	if err != nil {
+		return Point{}, err
+	}
+
+	if point, ok := postalCodes[cp]; ok {
+		return point, nil
+	}
+	if point, ok := postalCodes[department]; ok {
+		return point, nil
+	}
+	return Point{}, ErrUnknownPostalCode
+}
+
+// Locate returns the coordinates of a postal code as nullable columns, both
+// nil when the postal code is unknown.
+func Locate(cp string) (latitude, longitude *float64) {
+	point, err := Lookup(cp)
+	if err != nil {
+		return nil, nil
+	}
+	return &point.Latitude, &point.Longitude
+}
+
+// Distance returns the great-circle distance between two points in
+// kilometers.
+func Distance(a, b Point) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLat := lat2 - lat1
+	dLng := radians(b.Longitude - a.Longitude)
+
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLng/2), 2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// DistanceSQL returns a SQL expression computing the distance in kilometers
+// between a point and the latitude and longitude columns of a row.
+func DistanceSQL(p Point, latitudeColumn, longitudeColumn string) string {
+	lat := strconv.FormatFloat(p.Latitude, 'f', -1, 64)
+	lng := strconv.FormatFloat(p.Longitude, 'f', -1, 64)
+	return fmt.Sprintf(
+		"(2 * %g * ASIN(LEAST(1, SQRT(POWER(SIN(RADIANS(%s - (%s)) / 2), 2) + COS(RADIANS(%s)) * COS(RADIANS(%s)) * POWER(SIN(RADIANS(%s - (%s)) / 2), 2)))))",
+		earthRadiusKm, latitudeColumn, lat, lat, latitudeColumn, longitudeColumn, lng,
+	)
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
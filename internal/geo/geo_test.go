@@ -0,0 +1,44 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDepartment(t *testing.T) {
+	for cp, expected := range map[string]string{
+		"69003": "69",
+		"20000": "2A",
+		"20200": "2B",
+		"97400": "974",
+	} {
+		department, err := Department(cp)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, department, cp)
+	}
+
+	_, err := Department("6900")
+	assert.ErrorIs(t, err, ErrUnknownPostalCode)
+}
+
+func TestLookupFallsBackToDepartment(t *testing.T) {
+	city, err := Lookup("69003")
+	assert.NoError(t, err)
+	assert.Equal(t, Point{Latitude: 45.7597, Longitude: 4.8486}, city)
+
+	village, err := Lookup("69380")
+	assert.NoError(t, err)
+	assert.Equal(t, postalCodes["69"], village)
+
+	_, err = Lookup("ABCDE")
+	assert.ErrorIs(t, err, ErrUnknownPostalCode)
+}
+
+func TestDistance(t *testing.T) {
+	lyon, _ := Lookup("69001")
+	marseille, _ := Lookup("13001")
+
+	assert.InDelta(t, 278, Distance(lyon, marseille), 5)
+	assert.Zero(t, Distance(lyon, lyon))
+}
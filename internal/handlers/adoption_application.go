@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
+	"go-challenge/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+const (
+	maxApplicationCats       = 6
+	maxApplicationMessageLen = 2000
+)
+
+type AdoptionApplicationHandler struct {
+	applicationQueries *queries.DatabaseService
+}
+
+func NewAdoptionApplicationHandler(applicationQueries *queries.DatabaseService) *AdoptionApplicationHandler {
+	return &AdoptionApplicationHandler{applicationQueries: applicationQueries}
+}
+
+// CreateAdoptionApplicationHandler godoc
+// @Summary Apply to adopt cats
+// @Description Apply to adopt a cat, or several siblings together. Every cat must be up for adoption.
+// @Tags adoption
+// @Accept json
+// @Produce json
+// @Success 201 {object} models.AdoptionApplication "Application sent"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 404 {string} string "cat not found"
+// @Failure 409 {string} string "a cat is not up for adoption or was already applied for"
+// @Failure 500 {string} string "error creating application"
+// @Router /adoption-applications [post]
+func (h *AdoptionApplicationHandler) CreateAdoptionApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	var body struct {
+		CatIDs  []uint `json:"catIds"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if len(body.CatIDs) == 0 || len(body.CatIDs) > maxApplicationCats {
+		http.Error(w, fmt.Sprintf("an application targets between 1 and %d cats", maxApplicationCats), http.StatusBadRequest)
+		return
+	}
+	if len(body.Message) > maxApplicationMessageLen {
+		http.Error(w, fmt.Sprintf("message must be at most %d characters", maxApplicationMessageLen), http.StatusBadRequest)
+		return
+	}
+
+	catIDs := pq.StringArray{}
+	seen := map[uint]bool{}
+	var cats []models.Cats
+	for _, catID := range body.CatIDs {
+		if seen[catID] {
+			http.Error(w, "catIds must not repeat a cat", http.StatusBadRequest)
+			return
+		}
+		seen[catID] = true
+
+		id := strconv.FormatUint(uint64(catID), 10)
+		cat, err := h.applicationQueries.FindCatByID(id)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("cat %d not found", catID), http.StatusNotFound)
+			return
+		}
+		if !cat.Status.Adoptable() {
+			http.Error(w, fmt.Sprintf("%s is not up for adoption", cat.Name), http.StatusConflict)
+			return
+		}
+		if canManageCat(h.applicationQueries, cat, subject) {
+			http.Error(w, "you cannot apply to adopt your own cats", http.StatusBadRequest)
+			return
+		}
+		cats = append(cats, *cat)
+		catIDs = append(catIDs, id)
+	}
+	if !models.AllSiblings(cats) {
+		http.Error(w, "only siblings can be adopted together", http.StatusBadRequest)
+		return
+	}
+
+	application := &models.AdoptionApplication{
+		ApplicantID: subject.UserID,
+		CatIDs:      catIDs,
+		Message:     body.Message,
+		Status:      models.ApplicationPending,
+	}
+	if err := h.applicationQueries.CreateAdoptionApplication(application); err != nil {
+		if errors.Is(err, queries.ErrDuplicateApplication) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "error creating application", http.StatusInternalServerError)
+		return
+	}
+	for i := range cats {
+		recordCatAnnonceEvent(h.applicationQueries, &cats[i], models.AnnonceApplied, subject.UserID)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(application)
+}
+
+// GetMyAdoptionApplicationsHandler godoc
+// @Summary Get my adoption applications
+// @Description Retrieve the adoption applications sent by the current user, most recent first
+// @Tags adoption
+// @Produce json
+// @Success 200 {array} models.AdoptionApplication "Applications"
+// @Failure 500 {string} string "error fetching applications"
+// @Router /adoption-applications [get]
+func (h *AdoptionApplicationHandler) GetMyAdoptionApplicationsHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	applications, err := h.applicationQueries.FindApplicantAdoptionApplications(subject.UserID)
+	if err != nil {
+		http.Error(w, "error fetching applications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(applications)
+}
+
+// GetCatAdoptionApplicationsHandler godoc
+// @Summary Get the adoption applications for a cat
+// @Description Retrieve the applications targeting a cat, alone or with its siblings, for its owner or association
+// @Tags adoption
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Success 200 {array} models.AdoptionApplication "Applications"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "cat not found"
+// @Failure 500 {string} string "error fetching applications"
+// @Router /cats/{id}/adoption-applications [get]
+func (h *AdoptionApplicationHandler) GetCatAdoptionApplicationsHandler(w http.ResponseWriter, r *http.Request) {
+	cat, _, ok := findManagedCat(h.applicationQueries, w, r)
+	if !ok {
+		return
+	}
+
+	applications, err := h.applicationQueries.FindCatAdoptionApplications(strconv.FormatUint(uint64(cat.ID), 10))
+	if err != nil {
+		http.Error(w, "error fetching applications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(applications)
+}
+
+// DecideAdoptionApplicationHandler godoc
+// @Summary Decide on an adoption application
+// @Description Accept or reject an application, as whoever manages its cats, or withdraw it, as its applicant. Accepting reserves all of its cats and rejects the other pending applications for them. Applicants are notified of the decision.
+// @Tags adoption
+// @Accept json
+// @Produce json
+// @Param id path string true "Application ID"
+// @Success 200 {object} models.AdoptionApplication "Application updated"
+// @Failure 400 {string} string "status must be accepted, rejected or withdrawn"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "application not found"
+// @Failure 409 {string} string "the application was already decided or a cat is no longer up for adoption"
+// @Failure 500 {string} string "error updating application"
+// @Router /adoption-applications/{id}/status [put]
+func (h *AdoptionApplicationHandler) DecideAdoptionApplicationHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	application, err := h.applicationQueries.FindAdoptionApplicationByID(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "application not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "error fetching application", http.StatusInternalServerError)
+		return
+	}
+
+	var body struct {
+		Status models.AdoptionApplicationStatus `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	switch body.Status {
+	case models.ApplicationAccepted, models.ApplicationRejected, models.ApplicationWithdrawn:
+	default:
+		http.Error(w, "status must be accepted, rejected or withdrawn", http.StatusBadRequest)
+		return
+	}
+
+	var cats []models.Cats
+	if body.Status == models.ApplicationWithdrawn {
+		if application.ApplicantID != subject.UserID {
+			http.Error(w, "only the applicant can withdraw an application", http.StatusForbidden)
+			return
+		}
+	} else {
+		for _, catID := range application.CatIDs {
+			cat, err := h.applicationQueries.FindCatByID(catID)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("cat %s not found", catID), http.StatusNotFound)
+				return
+			}
+			if !canManageCat(h.applicationQueries, cat, subject) {
+				http.Error(w, "only the owner of the cats or their association can decide on an application", http.StatusForbidden)
+				return
+			}
+			cats = append(cats, *cat)
+		}
+	}
+
+	if application.Decided() {
+		http.Error(w, fmt.Sprintf("the application was already %s", application.Status), http.StatusConflict)
+		return
+	}
+
+	var rejected []models.AdoptionApplication
+	if body.Status == models.ApplicationAccepted {
+		for _, cat := range cats {
+			if !cat.Status.Adoptable() {
+				http.Error(w, fmt.Sprintf("%s is no longer up for adoption", cat.Name), http.StatusConflict)
+				return
+			}
+		}
+		rejected, err = h.applicationQueries.AcceptAdoptionApplication(application, cats, subject.UserID)
+	} else {
+		err = h.applicationQueries.DecideAdoptionApplication(application, body.Status, subject.UserID)
+	}
+	if errors.Is(err, queries.ErrApplicationDecided) || errors.Is(err, queries.ErrCatNotAdoptable) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "error updating application", http.StatusInternalServerError)
+		return
+	}
+	if body.Status == models.ApplicationAccepted {
+		for i := range cats {
+			go NotifyCatFavorites(h.applicationQueries, &cats[i])
+		}
+	}
+	if body.Status != models.ApplicationWithdrawn {
+		go notifyAdoptionApplication(h.applicationQueries, *application, false)
+	}
+	for _, other := range rejected {
+		go notifyAdoptionApplication(h.applicationQueries, other, true)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(application)
+}
+
+// notifyAdoptionApplication lets an applicant know that their application was
+// accepted or rejected, because another family was chosen when otherFamily
+// is set. It is meant to run in its own goroutine.
+func notifyAdoptionApplication(q *queries.DatabaseService, application models.AdoptionApplication, otherFamily bool) {
+	catNames := make([]string, 0, len(application.CatIDs))
+	for _, catID := range application.CatIDs {
+		if cat, err := q.FindCatByID(catID); err == nil {
+			catNames = append(catNames, cat.Name)
+		}
+	}
+
+	vars := map[string]string{
+		"CatNames": strings.Join(catNames, ", "),
+		"Status":   string(application.Status),
+	}
+	if otherFamily {
+		vars["OtherFamily"] = "true"
+	}
+	data := map[string]string{
+		"ApplicationID": strconv.FormatUint(uint64(application.ID), 10),
+		"Status":        string(application.Status),
+	}
+	if _, err := NotifyUserInApp(q, application.ApplicantID, notifications.EventAdoptionApplication, vars, data); err != nil {
+		utils.Logger("error", "Adoption Application:", "Failed to store in-app notification", fmt.Sprintf("Error: %v", err))
+	}
+	if _, err := NotifyUser(q, application.ApplicantID, notifications.EventAdoptionApplication, vars, data); err != nil {
+		utils.Logger("error", "Adoption Application:", "Failed to push notification", fmt.Sprintf("Error: %v", err))
+	}
+}
@@ -6,16 +6,24 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"go-challenge/internal/database/queries"
 	"go-challenge/internal/models"
+	"go-challenge/internal/utils"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/jwtauth/v5"
 	"github.com/jinzhu/gorm"
 )
 
+var (
+	errCatNotInLitter    = errors.New("the cat does not belong to the litter")
+	errNoAdoptableKitten = errors.New("the litter has no kitten up for adoption")
+)
+
 type AnnonceHandler struct {
 	annonceQueries *queries.DatabaseService
 	userQueries    *queries.DatabaseService
@@ -34,20 +42,27 @@ func NewAnnonceHandler(annonceQueries, userQueries, catQueries *queries.Database
 // @Produce  json
 // @Param title formData string true "Title of the annonce"
 // @Param description formData string true "Description of the annonce"
-// @Param catID formData string true "Cat ID"
+// @Param catID formData string false "Cat ID, required without litterID"
+// @Param litterID formData string false "Litter ID, for an annonce covering all of its kittens"
+// @Param status formData string false "draft, or published (default)"
+// @Param expiresAt formData string false "Last day the annonce is published (DD-MM-YYYY), at most the annonce lifetime from now"
 // @Success 201 {object} models.Annonce "Annonce created successfully"
 // @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 403 {string} string "User is not authorized to publish this cat"
 // @Failure 500 {string} string "Internal server error"
 // @Router /annonces [post]
 func (h *AnnonceHandler) AnnonceCreationHandler(w http.ResponseWriter, r *http.Request) {
 	contentType := r.Header.Get("Content-Type")
-	var title, description, catID string
+	var title, description, catID, litterID, status, expiresAt string
 
 	if strings.Contains(contentType, "application/json") {
 		var data struct {
 			Title       string `json:"title"`
 			Description string `json:"description"`
 			CatID       string `json:"catID"`
+			LitterID    string `json:"litterID"`
+			Status      string `json:"status"`
+			ExpiresAt   string `json:"expiresAt"`
 		}
 		err := json.NewDecoder(r.Body).Decode(&data)
 		if err != nil {
@@ -57,42 +72,99 @@ func (h *AnnonceHandler) AnnonceCreationHandler(w http.ResponseWriter, r *http.R
 		title = data.Title
 		description = data.Description
 		catID = data.CatID
+		litterID = data.LitterID
+		status = data.Status
+		expiresAt = data.ExpiresAt
 	} else {
 		r.ParseForm()
 		title = r.FormValue("title")
 		description = r.FormValue("description")
 		catID = r.FormValue("catID")
+		litterID = r.FormValue("litterID")
+		status = r.FormValue("status")
+		expiresAt = r.FormValue("expiresAt")
 	}
 
-	if title == "" || description == "" || catID == "" {
-		http.Error(w, "title, description, and catID are required", http.StatusBadRequest)
+	if title == "" || description == "" || (catID == "" && litterID == "") {
+		http.Error(w, "title, description, and catID or litterID are required", http.StatusBadRequest)
 		return
 	}
 
-	_, claims, err := jwtauth.FromContext(r.Context())
+	now := time.Now()
+	annonceStatus := models.AnnoncePublished
+	var publishedAt *time.Time
+	switch models.AnnonceStatus(status) {
+	case "", models.AnnoncePublished:
+		publishedAt = &now
+	case models.AnnonceDraft:
+		annonceStatus = models.AnnonceDraft
+	default:
+		http.Error(w, "status must be draft or published", http.StatusBadRequest)
+		return
+	}
+	// Drafts get their expiry date when they are published
+	var expiry *time.Time
+	if annonceStatus == models.AnnoncePublished {
+		var err error
+		if expiry, err = annonceExpiry(expiresAt, now); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	subject, err := requestSubject(r)
 	if err != nil {
 		http.Error(w, "error getting claims", http.StatusInternalServerError)
 		return
 	}
-	userID := claims["id"].(string)
 
-	user, err := h.userQueries.FindUserByID(userID)
+	user, err := h.userQueries.FindUserByID(subject.UserID)
 	if err != nil {
 		http.Error(w, "error finding user", http.StatusInternalServerError)
 		return
 	}
 
+	var litter *models.Litter
+	if litterID != "" {
+		if litter, err = h.catQueries.FindLitterByID(litterID); err != nil {
+			http.Error(w, "error finding litter", http.StatusInternalServerError)
+			return
+		}
+		if !canManageLitter(h.catQueries, litter, subject) {
+			http.Error(w, "User is not authorized to publish this litter", http.StatusForbidden)
+			return
+		}
+		if catID, err = litterAnnonceCatID(h.catQueries, litter, catID); err != nil {
+			if errors.Is(err, errCatNotInLitter) || errors.Is(err, errNoAdoptableKitten) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "error finding litter kittens", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	cat, err := h.catQueries.FindCatByID(catID)
 	if err != nil {
 		http.Error(w, "error finding cat", http.StatusInternalServerError)
 		return
 	}
+	if !canManageCat(h.catQueries, cat, subject) {
+		http.Error(w, "User is not authorized to publish this cat", http.StatusForbidden)
+		return
+	}
 
 	annonce := &models.Annonce{
 		Title:       title,
 		Description: &description,
 		UserID:      user.ID,
 		CatID:       fmt.Sprintf("%d", cat.ID),
+		Status:      annonceStatus,
+		PublishedAt: publishedAt,
+		ExpiresAt:   expiry,
+	}
+	if litter != nil {
+		annonce.LitterID = &litter.ID
 	}
 
 	annonceID, err := h.annonceQueries.CreateAnnonce(annonce)
@@ -106,6 +178,12 @@ func (h *AnnonceHandler) AnnonceCreationHandler(w http.ResponseWriter, r *http.R
 		http.Error(w, "error retrieving created annonce", http.StatusInternalServerError)
 		return
 	}
+	withAnnonceDetails(h.annonceQueries, createdAnnonce)
+
+	if createdAnnonce.Status == models.AnnoncePublished {
+		go MatchSavedSearches(h.annonceQueries, catID)
+	}
+	go CheckDuplicatePhotos(h.annonceQueries, createdAnnonce)
 
 	response := struct {
 		Success string          `json:"success"`
@@ -122,7 +200,7 @@ func (h *AnnonceHandler) AnnonceCreationHandler(w http.ResponseWriter, r *http.R
 
 // GetAllAnnoncesHandler godoc
 // @Summary Get all annonces
-// @Description Retrieve all annonces from the database
+// @Description Retrieve the published annonces of the cats up for adoption
 // @Tags annonces
 // @Produce json
 // @Success 200 {array} models.Annonce "List of annonces"
@@ -134,15 +212,99 @@ func (h *AnnonceHandler) GetAllAnnoncesHandler(w http.ResponseWriter, r *http.Re
 		http.Error(w, "error getting annonces", http.StatusInternalServerError)
 		return
 	}
+	withAnnonceDetails(h.annonceQueries, annoncePointers(annonces)...)
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(annonces)
 }
 
+// SearchAnnoncesHandler godoc
+// @Summary Search annonces
+// @Description List the published annonces of the cats up for adoption, with their cat, race, publisher and location, one page at a time. The links to the first and next pages are given in the Link header, the number of matching annonces in X-Total-Count.
+// @Tags annonces
+// @Param raceId query string false "Race ID"
+// @Param breed query string false "Part of the name or of an alias of the breed"
+// @Param coatLength query string false "Coat length of the breed (hairless, short, medium, long)"
+// @Param size query string false "Size of the breed (small, medium, large)"
+// @Param activityLevel query string false "Activity level of the breed (low, moderate, high)"
+// @Param hypoallergenic query bool false "Hypoallergenic breed"
+// @Param temperament query string false "Comma separated temperaments the breed must be known for"
+// @Param sexe query string false "Sexe"
+// @Param minAge query int false "Minimum age in months"
+// @Param maxAge query int false "Maximum age in months"
+// @Param color query string false "Color"
+// @Param behavior query string false "Behavior"
+// @Param sterilized query bool false "Sterilized"
+// @Param vaccinatedSince query string false "Vaccinated since (YYYY-MM-DD)"
+// @Param associationId query string false "Association ID"
+// @Param cp query string false "Postal code to search around"
+// @Param lat query number false "Latitude to search around"
+// @Param lng query number false "Longitude to search around"
+// @Param radiusKm query number false "Maximum distance in kilometers. Most postal codes are located at the prefecture of their department, so a radius of a few kilometers is not reliable"
+// @Param sort query string false "newest, nearest or favorites"
+// @Param cursor query string false "Cursor of the next page"
+// @Param limit query int false "Page size (max 100)"
+// @Produce json
+// @Success 200 {object} queries.AnnonceSearchPage "Page of annonces"
+// @Failure 400 {string} string "Invalid filters"
+// @Failure 500 {string} string "error searching annonces"
+// @Router /annonces/search [get]
+func (h *AnnonceHandler) SearchAnnoncesHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	catSearch, err := parseCatSearch(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	search := queries.AnnonceSearch{
+		Cat:    catSearch,
+		Sort:   queries.AnnonceSearchSort(params.Get("sort")),
+		Cursor: catSearch.Cursor,
+		Limit:  catSearch.Limit,
+	}
+
+	page, err := h.annonceQueries.SearchAnnonces(search, time.Now())
+	if err != nil {
+		if errors.Is(err, queries.ErrInvalidCursor) || errors.Is(err, queries.ErrInvalidAnnonceSearchSort) || errors.Is(err, queries.ErrMissingSearchOrigin) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "error searching annonces", http.StatusInternalServerError)
+		return
+	}
+	annonces := make([]*models.Annonce, len(page.Annonces))
+	for i := range page.Annonces {
+		annonces[i] = &page.Annonces[i].Annonce
+	}
+	withAnnonceDetails(h.annonceQueries, annonces...)
+
+	writePageLinks(w, r, page.NextCursor)
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(page)
+}
+
+// writePageLinks sets the Link header of a page of results to the first page
+// and, unless it is the last one, to the next page.
+func writePageLinks(w http.ResponseWriter, r *http.Request, nextCursor string) {
+	link := *r.URL
+	params := link.Query()
+	params.Del("cursor")
+	link.RawQuery = params.Encode()
+	links := []string{fmt.Sprintf("<%s>; rel=\"first\"", link.RequestURI())}
+
+	if nextCursor != "" {
+		params.Set("cursor", nextCursor)
+		link.RawQuery = params.Encode()
+		links = append(links, fmt.Sprintf("<%s>; rel=\"next\"", link.RequestURI()))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
 // GetUserAnnoncesHandler godoc
 // @Summary Get user's annonces
-// @Description Retrieve all annonces for a specific user from the database
+// @Description Retrieve the annonces of a user: all of them for whoever manages them, only the published ones otherwise
 // @Tags annonces
 // @Produce json
 // @Param id query string true "User ID"
@@ -171,11 +333,18 @@ func (h *AnnonceHandler) GetUserAnnoncesHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	annonces, err := h.annonceQueries.GetUserAnnonces(userID)
+	userAnnonces, err := h.annonceQueries.GetUserAnnonces(userID)
 	if err != nil {
 		http.Error(w, "error getting user's annonces", http.StatusInternalServerError)
 		return
 	}
+	annonces := []models.Annonce{}
+	for i := range userAnnonces {
+		if canSeeAnnonce(h.annonceQueries, &userAnnonces[i], r) {
+			annonces = append(annonces, userAnnonces[i])
+		}
+	}
+	withAnnonceDetails(h.annonceQueries, annoncePointers(annonces)...)
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	err = json.NewEncoder(w).Encode(annonces)
@@ -214,6 +383,15 @@ func (h *AnnonceHandler) GetAnnonceByIDHandler(w http.ResponseWriter, r *http.Re
 		}
 		return
 	}
+	if !canSeeAnnonce(h.annonceQueries, annonce, r) {
+		http.Error(w, "Annonce not found", http.StatusNotFound)
+		return
+	}
+	if subject, err := requestSubject(r); err == nil && !canManageAnnonce(h.annonceQueries, annonce, subject) {
+		recordAnnonceEvent(h.annonceQueries, annonce.ID, models.AnnonceViewed, subject.UserID)
+	}
+
+	withAnnonceDetails(h.annonceQueries, annonce)
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
@@ -280,16 +458,11 @@ func (h *AnnonceHandler) ModifyAnnonceHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	_, claims, err := jwtauth.FromContext(r.Context())
+	subject, err := requestSubject(r)
 	if err != nil {
 		http.Error(w, "Error getting claims", http.StatusInternalServerError)
 		return
 	}
-	userID, ok := claims["id"].(string)
-	if !ok {
-		http.Error(w, "Invalid user ID in claims", http.StatusInternalServerError)
-		return
-	}
 
 	existingAnnonce, err := h.annonceQueries.FindAnnonceByID(annonceID)
 	if err != nil {
@@ -301,10 +474,21 @@ func (h *AnnonceHandler) ModifyAnnonceHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if existingAnnonce.UserID != userID {
+	if !canManageAnnonce(h.annonceQueries, existingAnnonce, subject) {
 		http.Error(w, "User is not authorized to modify this annonce", http.StatusForbidden)
 		return
 	}
+	if catID != "" && catID != existingAnnonce.CatID {
+		cat, err := h.catQueries.FindCatByID(catID)
+		if err != nil {
+			http.Error(w, "cat not found", http.StatusNotFound)
+			return
+		}
+		if !canManageCat(h.catQueries, cat, subject) {
+			http.Error(w, "User is not authorized to publish this cat", http.StatusForbidden)
+			return
+		}
+	}
 
 	// Update the fields if provided
 	if title != "" {
@@ -323,6 +507,8 @@ func (h *AnnonceHandler) ModifyAnnonceHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	withAnnonceDetails(h.annonceQueries, existingAnnonce)
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(existingAnnonce)
@@ -335,6 +521,7 @@ func (h *AnnonceHandler) ModifyAnnonceHandler(w http.ResponseWriter, r *http.Req
 // @Param id path string true "Annonce ID"
 // @Success 204 "No Content"
 // @Failure 400 {string} string "Annonce ID is required"
+// @Failure 403 {string} string "User is not authorized to delete this annonce"
 // @Failure 404 {string} string "Annonce not found"
 // @Failure 500 {string} string "Error deleting annonce"
 // @Router /annonces/{id} [delete]
@@ -346,7 +533,23 @@ func (h *AnnonceHandler) DeleteAnnonceHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	err := h.annonceQueries.DeleteRoomByAnnonceID(id)
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	annonce, err := h.annonceQueries.FindAnnonceByID(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("annonce with ID %s not found", id), http.StatusNotFound)
+		return
+	}
+	if !canManageAnnonce(h.annonceQueries, annonce, subject) {
+		http.Error(w, "User is not authorized to delete this annonce", http.StatusForbidden)
+		return
+	}
+
+	err = h.annonceQueries.DeleteRoomByAnnonceID(id)
 	if err != nil {
 		http.Error(w, "error deleting room", http.StatusInternalServerError)
 		return
@@ -392,6 +595,12 @@ func (h *AnnonceHandler) FetchAnnonceByCatIDHandler(w http.ResponseWriter, r *ht
 		}
 		return
 	}
+	if !canSeeAnnonce(h.annonceQueries, annonce, r) {
+		http.Error(w, "Annonce not found", http.StatusNotFound)
+		return
+	}
+
+	withAnnonceDetails(h.annonceQueries, annonce)
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
@@ -419,3 +628,69 @@ func (h *AnnonceHandler) GetAddressFromUserID(w http.ResponseWriter, r *http.Req
 	address, err := h.annonceQueries.GetAddressFromAnnonceID(userID)
 }
 */
+
+// withAnnonceDetails fills in the cover photo of the cat of each annonce, and
+// the kittens of the annonces of a litter. Missing details are not an error,
+// the annonce is shown without them.
+func withAnnonceDetails(q *queries.DatabaseService, annonces ...*models.Annonce) {
+	for _, annonce := range annonces {
+		if annonce.LitterID == nil {
+			continue
+		}
+		kittens, err := q.FindLitterKittens(*annonce.LitterID)
+		if err != nil {
+			utils.Logger("error", "Annonce Litter:", "Failed to get litter kittens", fmt.Sprintf("Error: %v", err))
+			continue
+		}
+		annonce.LitterCats = kittens
+	}
+
+	catIDs := make([]string, 0, len(annonces))
+	for _, annonce := range annonces {
+		catIDs = append(catIDs, annonce.CatID)
+	}
+
+	covers, err := q.FindCatCovers(catIDs)
+	if err != nil {
+		utils.Logger("error", "Annonce Cover:", "Failed to get cover photos", fmt.Sprintf("Error: %v", err))
+		return
+	}
+	for _, annonce := range annonces {
+		cover := covers[annonce.CatID]
+		annonce.CoverImage = cover.URL
+		annonce.CoverMediumImage = cover.MediumURL
+		annonce.CoverThumbnailImage = cover.ThumbnailURL
+	}
+}
+
+// litterAnnonceCatID picks the cat the annonce of a litter is attached to: the
+// requested one when it belongs to the litter, else its first kitten up for
+// adoption.
+func litterAnnonceCatID(q *queries.DatabaseService, litter *models.Litter, catID string) (string, error) {
+	kittens, err := q.FindLitterKittens(litter.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, kitten := range kittens {
+		if catID != "" && fmt.Sprintf("%d", kitten.ID) == catID {
+			return catID, nil
+		}
+	}
+	if catID != "" {
+		return "", errCatNotInLitter
+	}
+	for _, kitten := range kittens {
+		if kitten.Status.Adoptable() {
+			return fmt.Sprintf("%d", kitten.ID), nil
+		}
+	}
+	return "", errNoAdoptableKitten
+}
+
+func annoncePointers(annonces []models.Annonce) []*models.Annonce {
+	pointers := make([]*models.Annonce, len(annonces))
+	for i := range annonces {
+		pointers[i] = &annonces[i]
+	}
+	return pointers
+}
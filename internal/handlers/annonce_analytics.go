@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+	"go-challenge/internal/policy"
+	"go-challenge/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 366
+)
+
+// recordAnnonceEvent records an event on an annonce for its analytics. A
+// failure is only logged, it must not fail the request it comes with.
+func recordAnnonceEvent(q *queries.DatabaseService, annonceID uint, kind models.AnnonceEventKind, userID string) {
+	if err := q.RecordAnnonceEvent(annonceID, kind, userID, time.Now()); err != nil {
+		utils.Logger("error", "Annonce Analytics:", "Failed to record event", fmt.Sprintf("Error: %v", err))
+	}
+}
+
+// recordCatAnnonceEvent records an event on the annonces of a cat, with the
+// same rules as recordAnnonceEvent.
+func recordCatAnnonceEvent(q *queries.DatabaseService, cat *models.Cats, kind models.AnnonceEventKind, userID string) {
+	if err := q.RecordCatAnnonceEvent(cat, kind, userID, time.Now()); err != nil {
+		utils.Logger("error", "Annonce Analytics:", "Failed to record event", fmt.Sprintf("Error: %v", err))
+	}
+}
+
+// parseAnalyticsPeriod reads the since and until days of an analytics
+// request. The period defaults to the last 30 days, today included.
+func parseAnalyticsPeriod(params url.Values, now time.Time) (since, until time.Time, err error) {
+	until = models.EventDay(now).AddDate(0, 0, 1)
+	if params.Get("until") != "" {
+		if until, err = time.Parse("2006-01-02", params.Get("until")); err != nil {
+			return since, until, errors.New("until must be formatted as YYYY-MM-DD")
+		}
+	}
+	since = until.AddDate(0, 0, -defaultAnalyticsDays)
+	if params.Get("since") != "" {
+		if since, err = time.Parse("2006-01-02", params.Get("since")); err != nil {
+			return since, until, errors.New("since must be formatted as YYYY-MM-DD")
+		}
+	}
+	if !since.Before(until) {
+		return since, until, errors.New("since must be before until")
+	}
+	if until.Sub(since) > maxAnalyticsDays*24*time.Hour {
+		return since, until, fmt.Errorf("the period must be at most %d days", maxAnalyticsDays)
+	}
+	return since, until, nil
+}
+
+// GetAnnonceAnalyticsHandler godoc
+// @Summary Get the analytics of an annonce
+// @Description Retrieve the views, favorites, chats, applications and adoptions of an annonce day by day, and the conversion funnel from view to adoption, for whoever manages it
+// @Tags annonces
+// @Produce json
+// @Param id path string true "Annonce ID"
+// @Param since query string false "From this day (YYYY-MM-DD), 30 days before until by default"
+// @Param until query string false "Before this day (YYYY-MM-DD), tomorrow by default"
+// @Success 200 {object} queries.AnnonceAnalytics "Analytics"
+// @Failure 400 {string} string "Invalid period"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "annonce not found"
+// @Failure 500 {string} string "error fetching analytics"
+// @Router /annonces/{id}/analytics [get]
+func (h *AnnonceHandler) GetAnnonceAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	annonce, err := h.annonceQueries.FindAnnonceByID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "annonce not found", http.StatusNotFound)
+		return
+	}
+	if !canManageAnnonce(h.annonceQueries, annonce, subject) {
+		http.Error(w, "only the author of the annonce or the association of its cat can access its analytics", http.StatusForbidden)
+		return
+	}
+
+	since, until, err := parseAnalyticsPeriod(r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	analytics, err := h.annonceQueries.FindAnnonceAnalytics(queries.AnnonceAnalyticsFilter{
+		AnnonceID: annonce.ID,
+		Since:     since,
+		Until:     until,
+	})
+	if err != nil {
+		http.Error(w, "error fetching analytics", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(analytics)
+}
+
+// GetAssociationAnalyticsHandler godoc
+// @Summary Get the analytics of the annonces of an association
+// @Description Retrieve the activity on the annonces of the cats published by an association day by day, and its conversion funnel from view to adoption, for its owner and members
+// @Tags associations
+// @Produce json
+// @Param id path string true "Association ID"
+// @Param since query string false "From this day (YYYY-MM-DD), 30 days before until by default"
+// @Param until query string false "Before this day (YYYY-MM-DD), tomorrow by default"
+// @Success 200 {object} queries.AnnonceAnalytics "Analytics"
+// @Failure 400 {string} string "Invalid period"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "association not found"
+// @Failure 500 {string} string "error fetching analytics"
+// @Router /associations/{id}/analytics [get]
+func (h *AnnonceHandler) GetAssociationAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	associationID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid association ID", http.StatusBadRequest)
+		return
+	}
+	association, err := h.annonceQueries.FindAssociationById(associationID)
+	if err != nil {
+		http.Error(w, "association not found", http.StatusNotFound)
+		return
+	}
+	if !subject.IsAdmin() && !policy.IsAssociationMember(association, subject.UserID) {
+		http.Error(w, "only the owner and members of the association can access its reports", http.StatusForbidden)
+		return
+	}
+
+	since, until, err := parseAnalyticsPeriod(r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	analytics, err := h.annonceQueries.FindAnnonceAnalytics(queries.AnnonceAnalyticsFilter{
+		AssociationID: strconv.Itoa(associationID),
+		Since:         since,
+		Until:         until,
+	})
+	if err != nil {
+		http.Error(w, "error fetching analytics", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(analytics)
+}
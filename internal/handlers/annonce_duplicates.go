@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+	"go-challenge/internal/storage"
+	"go-challenge/internal/utils"
+)
+
+// photoHashBatch is the number of photos hashed at once by the backfill.
+const photoHashBatch = 100
+
+// CheckDuplicatePhotos looks for the photos of the cat of a new annonce, or
+// of the kittens of its litter, among the photos of other owners. A match is
+// reported to the admins with the photos as evidence. It is meant to run in
+// its own goroutine after an annonce is created.
+func CheckDuplicatePhotos(q *queries.DatabaseService, annonce *models.Annonce) {
+	cat, err := q.FindCatByID(annonce.CatID)
+	if err != nil {
+		utils.Logger("error", "Duplicate Photos:", "Failed to find cat", fmt.Sprintf("Error: %v", err))
+		return
+	}
+	catIDs := []uint{cat.ID}
+	for _, kitten := range annonce.LitterCats {
+		if kitten.ID != cat.ID {
+			catIDs = append(catIDs, kitten.ID)
+		}
+	}
+
+	reportDuplicatePhotos(q, annonce, cat, catIDs, nil)
+}
+
+// CheckAddedPhotos looks for photos added to the gallery of a cat among the
+// photos of other owners, when the cat already has an annonce. It is meant to
+// run in its own goroutine after the photos are added.
+func CheckAddedPhotos(q *queries.DatabaseService, cat *models.Cats, photos []models.CatPhoto) {
+	annonce, err := q.FindCatAnnonce(cat)
+	if err != nil {
+		return
+	}
+	var photoIDs []uint
+	for _, photo := range photos {
+		if photo.Hash != nil {
+			photoIDs = append(photoIDs, photo.ID)
+		}
+	}
+	if len(photoIDs) == 0 {
+		return
+	}
+	reportDuplicatePhotos(q, annonce, cat, []uint{cat.ID}, photoIDs)
+}
+
+// reportDuplicatePhotos reports an annonce when photos of its cats, or only
+// the given photos of them, were already published by other owners.
+func reportDuplicatePhotos(q *queries.DatabaseService, annonce *models.Annonce, cat *models.Cats, catIDs, photoIDs []uint) {
+	matches, err := q.FindDuplicatePhotos(catIDs, photoIDs, cat.UserID, cat.PublishedAs)
+	if err != nil {
+		utils.Logger("error", "Duplicate Photos:", "Failed to compare photos", fmt.Sprintf("Error: %v", err))
+		return
+	}
+	if len(matches) == 0 {
+		return
+	}
+
+	report, err := q.ReportDuplicateAnnonce(annonce, matches)
+	if err != nil {
+		utils.Logger("error", "Duplicate Photos:", "Failed to report annonce", fmt.Sprintf("Error: %v", err))
+		return
+	}
+	entry, err := annonceReportEntry(q, report)
+	if err != nil {
+		utils.Logger("error", "Duplicate Photos:", "Failed to describe report", fmt.Sprintf("Error: %v", err))
+		return
+	}
+	reportToJSON, err := json.Marshal(ModifiedReport{entry})
+	if err != nil {
+		return
+	}
+	utils.Logger("warn", "Reported Annonce", "An annonce reuses the photos of another owner", string(reportToJSON))
+}
+
+// NewPhotoHashBackfill returns a job hashing the photos stored without a
+// perceptual hash, such as the ones uploaded before hashes existed. Each run
+// picks up after the last photo it went through, so that the photos which
+// cannot be hashed are not tried again until the server restarts.
+func NewPhotoHashBackfill(q *queries.DatabaseService, store storage.BlobStore) func(now time.Time) {
+	var lastID uint
+	return func(now time.Time) {
+		for {
+			photos, err := q.FindUnhashedCatPhotos(lastID, photoHashBatch)
+			if err != nil {
+				utils.Logger("error", "Photo Hashes:", "Failed to get photos", fmt.Sprintf("Error: %v", err))
+				return
+			}
+			for i := range photos {
+				photo := &photos[i]
+				lastID = photo.ID
+				// The thumbnail is the cheapest variant to decode
+				url := photo.ThumbnailURL
+				if url == "" {
+					url = photo.URL
+				}
+				if photo.Hash = storedImageHash(context.Background(), store, url); photo.Hash == nil {
+					continue
+				}
+				if err := q.UpdateCatPhotoHash(photo); err != nil {
+					utils.Logger("error", "Photo Hashes:", "Failed to save hash", fmt.Sprintf("Error: %v", err))
+				}
+			}
+			if len(photos) < photoHashBatch {
+				return
+			}
+		}
+	}
+}
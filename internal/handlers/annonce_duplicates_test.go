@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhotoHashBackfill(t *testing.T) {
+	store, err := storage.NewLocal(t.TempDir(), "http://localhost:8080/uploads", []byte("secret"))
+	require.NoError(t, err)
+	key, err := store.Put(context.Background(), "felix.png", "image/png", pictureData(t))
+	require.NoError(t, err)
+
+	q, db := newTestQueries()
+	db.Once(`FROM "cat_photos"`, dbtest.Result{Columns: []string{"id", "cat_id", "url", "thumbnail_url"}, Values: [][]driver.Value{
+		{int64(5), int64(7), store.URL(key), store.URL(key)},
+		{int64(6), int64(7), store.URL("missing.png"), ""},
+	}})
+
+	backfill := NewPhotoHashBackfill(q, store)
+	backfill(time.Now())
+
+	updates := db.Queries(`UPDATE "cat_photos"`)
+	require.Len(t, updates, 1)
+	assert.Contains(t, updates[0].Args, int64(5))
+
+	// The next run picks up after the photos already gone through
+	backfill(time.Now())
+	selects := db.Queries(`FROM "cat_photos"`)
+	require.Len(t, selects, 2)
+	assert.Equal(t, int64(6), selects[1].Args[0])
+}
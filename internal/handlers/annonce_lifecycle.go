@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-challenge/internal/config"
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
+	"go-challenge/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const annonceDateLayout = "02-01-2006"
+
+// annonceExpiry returns the expiry date of an annonce being published: the
+// day asked for, no later than the end of the annonce lifetime, or else the
+// end of its lifetime. An annonce expires at the end of its last day.
+func annonceExpiry(requested string, now time.Time) (*time.Time, error) {
+	latest := now.Add(config.AnnonceLifetime())
+	if requested == "" {
+		return &latest, nil
+	}
+
+	day, err := time.ParseInLocation(annonceDateLayout, requested, now.Location())
+	if err != nil {
+		return nil, errors.New("expiresAt must be formatted as DD-MM-YYYY")
+	}
+	// Days are compared rather than instants, so that the last day of the
+	// lifetime can be asked for whatever the time
+	if day.Before(startOfDay(now)) {
+		return nil, errors.New("expiresAt must not be in the past")
+	}
+	if day.After(startOfDay(latest)) {
+		return nil, fmt.Errorf("expiresAt must be at most %d days from now", int(config.AnnonceLifetime().Hours()/24))
+	}
+	expiresAt := day.AddDate(0, 0, 1)
+	return &expiresAt, nil
+}
+
+// startOfDay returns the midnight starting the day of a time, in its
+// location.
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// ChangeAnnonceStatusHandler godoc
+// @Summary Change the status of an annonce
+// @Description Publish, pause or close an annonce. Publishing a draft, paused or expired annonce sets its expiry date, closing it requires a reason (adopted, deceased, withdrawn or other).
+// @Tags annonces
+// @Accept json
+// @Produce json
+// @Param id path string true "Annonce ID"
+// @Success 200 {object} models.Annonce "Status changed"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 403 {string} string "User is not authorized to modify this annonce"
+// @Failure 404 {string} string "Annonce not found"
+// @Failure 409 {string} string "the transition is not allowed"
+// @Failure 500 {string} string "error changing annonce status"
+// @Router /annonces/{id}/status [put]
+func (h *AnnonceHandler) ChangeAnnonceStatusHandler(w http.ResponseWriter, r *http.Request) {
+	annonce, ok := h.findManagedAnnonce(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Status    models.AnnonceStatus      `json:"status"`
+		Reason    models.AnnonceCloseReason `json:"reason"`
+		ExpiresAt string                    `json:"expiresAt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if !body.Status.Valid() {
+		http.Error(w, "status must be draft, published, paused, expired or closed", http.StatusBadRequest)
+		return
+	}
+	if body.Status == models.AnnonceClosed && !body.Reason.Valid() {
+		http.Error(w, "reason must be adopted, deceased, withdrawn or other", http.StatusBadRequest)
+		return
+	}
+	if !annonce.Status.CanTransitionTo(body.Status) {
+		http.Error(w, fmt.Sprintf("cannot change the status of an annonce from %s to %s", annonce.Status, body.Status), http.StatusConflict)
+		return
+	}
+
+	var expiresAt *time.Time
+	if body.Status == models.AnnoncePublished {
+		now := time.Now()
+		if body.ExpiresAt == "" && annonce.Status == models.AnnoncePaused && annonce.ExpiresAt != nil && annonce.ExpiresAt.After(now) {
+			expiresAt = annonce.ExpiresAt
+		} else {
+			var err error
+			if expiresAt, err = annonceExpiry(body.ExpiresAt, now); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if !h.annonceCatAdoptable(w, annonce) {
+			return
+		}
+	}
+
+	if err := h.annonceQueries.ChangeAnnonceStatus(annonce, body.Status, body.Reason, expiresAt); err != nil {
+		http.Error(w, "error changing annonce status", http.StatusInternalServerError)
+		return
+	}
+	if annonce.Status == models.AnnoncePublished {
+		go MatchSavedSearches(h.annonceQueries, annonce.CatID)
+	}
+
+	withAnnonceDetails(h.annonceQueries, annonce)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(annonce)
+}
+
+// RenewAnnonceHandler godoc
+// @Summary Renew an annonce
+// @Description Push back the expiry date of a published or expired annonce, which is published again
+// @Tags annonces
+// @Accept json
+// @Produce json
+// @Param id path string true "Annonce ID"
+// @Success 200 {object} models.Annonce "Annonce renewed"
+// @Failure 400 {string} string "Invalid expiry date"
+// @Failure 403 {string} string "User is not authorized to modify this annonce"
+// @Failure 404 {string} string "Annonce not found"
+// @Failure 409 {string} string "the annonce cannot be renewed"
+// @Failure 500 {string} string "error renewing annonce"
+// @Router /annonces/{id}/renew [post]
+func (h *AnnonceHandler) RenewAnnonceHandler(w http.ResponseWriter, r *http.Request) {
+	annonce, ok := h.findManagedAnnonce(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		ExpiresAt string `json:"expiresAt"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+	if !annonce.Status.Renewable() {
+		http.Error(w, fmt.Sprintf("a %s annonce cannot be renewed", annonce.Status), http.StatusConflict)
+		return
+	}
+
+	expiresAt, err := annonceExpiry(body.ExpiresAt, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if annonce.Status == models.AnnonceExpired && !h.annonceCatAdoptable(w, annonce) {
+		return
+	}
+
+	wasExpired := annonce.Status == models.AnnonceExpired
+	if err := h.annonceQueries.ChangeAnnonceStatus(annonce, models.AnnoncePublished, "", expiresAt); err != nil {
+		http.Error(w, "error renewing annonce", http.StatusInternalServerError)
+		return
+	}
+	if wasExpired {
+		go MatchSavedSearches(h.annonceQueries, annonce.CatID)
+	}
+
+	withAnnonceDetails(h.annonceQueries, annonce)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(annonce)
+}
+
+// findManagedAnnonce loads the annonce of the route and writes the error
+// response when it does not exist or the current user may not edit it.
+func (h *AnnonceHandler) findManagedAnnonce(w http.ResponseWriter, r *http.Request) (*models.Annonce, bool) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	annonce, err := h.annonceQueries.FindAnnonceByID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Annonce not found", http.StatusNotFound)
+		return nil, false
+	}
+	if !canManageAnnonce(h.annonceQueries, annonce, subject) {
+		http.Error(w, "User is not authorized to modify this annonce", http.StatusForbidden)
+		return nil, false
+	}
+	return annonce, true
+}
+
+// annonceCatAdoptable checks that the cat of an annonce about to be published
+// is up for adoption, or one of the kittens for the annonce of a litter, and
+// writes the error response otherwise.
+func (h *AnnonceHandler) annonceCatAdoptable(w http.ResponseWriter, annonce *models.Annonce) bool {
+	var cats []models.Cats
+	if annonce.LitterID != nil {
+		kittens, err := h.catQueries.FindLitterKittens(*annonce.LitterID)
+		if err != nil {
+			http.Error(w, "error finding litter kittens", http.StatusInternalServerError)
+			return false
+		}
+		cats = kittens
+	} else {
+		cat, err := h.catQueries.FindCatByID(annonce.CatID)
+		if err != nil {
+			http.Error(w, "error finding cat", http.StatusInternalServerError)
+			return false
+		}
+		cats = append(cats, *cat)
+	}
+
+	for _, cat := range cats {
+		if cat.Status.Adoptable() {
+			return true
+		}
+	}
+	if annonce.LitterID != nil {
+		http.Error(w, errNoAdoptableKitten.Error(), http.StatusConflict)
+	} else {
+		http.Error(w, "the cat is not up for adoption", http.StatusConflict)
+	}
+	return false
+}
+
+// RunAnnonceLifecycle expires the annonces past their expiry date and reminds
+// the owners of the annonces about to expire to renew them. It is meant to be
+// run periodically by the scheduler.
+func RunAnnonceLifecycle(q *queries.DatabaseService, now time.Time) {
+	expired, err := q.ExpireAnnonces(now)
+	if err != nil {
+		utils.Logger("error", "Annonce Lifecycle:", "Failed to expire annonces", fmt.Sprintf("Error: %v", err))
+		return
+	}
+	for i := range expired {
+		notifyAnnonceOwner(q, &expired[i], notifications.EventAnnonceExpired)
+	}
+
+	annonces, err := q.FindAnnoncesToRemind(now.Add(config.AnnonceRenewalNotice()))
+	if err != nil {
+		utils.Logger("error", "Annonce Lifecycle:", "Failed to get annonces to remind", fmt.Sprintf("Error: %v", err))
+		return
+	}
+	for i := range annonces {
+		annonce := &annonces[i]
+		if err := q.MarkAnnonceRenewalReminded(annonce, now); err != nil {
+			utils.Logger("error", "Annonce Lifecycle:", "Failed to store reminder", fmt.Sprintf("Error: %v", err))
+			continue
+		}
+		notifyAnnonceOwner(q, annonce, notifications.EventAnnonceRenewal)
+	}
+}
+
+func notifyAnnonceOwner(q *queries.DatabaseService, annonce *models.Annonce, event notifications.Event) {
+	vars := map[string]string{"AnnonceTitle": strings.TrimSpace(annonce.Title)}
+	if annonce.ExpiresAt != nil {
+		vars["ExpiresAt"] = annonce.ExpiresAt.Format("02/01/2006")
+	}
+	if cat, err := q.FindCatByID(annonce.CatID); err == nil {
+		vars["CatName"] = cat.Name
+	}
+	data := map[string]string{"AnnonceID": fmt.Sprintf("%d", annonce.ID)}
+
+	if _, err := NotifyUserInApp(q, annonce.UserID, event, vars, data); err != nil {
+		utils.Logger("error", "Annonce Lifecycle:", "Failed to store in-app notification", fmt.Sprintf("Error: %v", err))
+	}
+	if _, err := NotifyUser(q, annonce.UserID, event, vars, data); err != nil {
+		utils.Logger("error", "Annonce Lifecycle:", "Failed to push notification", fmt.Sprintf("Error: %v", err))
+	}
+}
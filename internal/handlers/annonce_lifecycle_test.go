@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"go-challenge/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnonceExpiry(t *testing.T) {
+	now := time.Date(2024, 6, 15, 18, 30, 0, 0, time.UTC)
+	last := now.Add(config.AnnonceLifetime())
+
+	expiresAt, err := annonceExpiry(last.Format(annonceDateLayout), now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(last.Year(), last.Month(), last.Day()+1, 0, 0, 0, 0, time.UTC), *expiresAt)
+
+	expiresAt, err = annonceExpiry(now.Format(annonceDateLayout), now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 16, 0, 0, 0, 0, time.UTC), *expiresAt)
+
+	_, err = annonceExpiry(last.AddDate(0, 0, 1).Format(annonceDateLayout), now)
+	assert.Error(t, err)
+	_, err = annonceExpiry(now.AddDate(0, 0, -1).Format(annonceDateLayout), now)
+	assert.Error(t, err)
+	_, err = annonceExpiry("2024-06-20", now)
+	assert.Error(t, err)
+
+	expiresAt, err = annonceExpiry("", now)
+	require.NoError(t, err)
+	assert.Equal(t, last, *expiresAt)
+}
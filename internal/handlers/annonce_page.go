@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go-challenge/internal/config"
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/matching"
+	"go-challenge/internal/models"
+	"go-challenge/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+//go:embed templates/annonce_page.html
+var annoncePageFS embed.FS
+
+var annoncePageTemplates = template.Must(template.ParseFS(annoncePageFS, "templates/annonce_page.html"))
+
+// annoncePage is what the public page of an annonce shows.
+type annoncePage struct {
+	Title          string
+	Description    string
+	Text           string
+	PageURL        string
+	ImageURL       string
+	CatName        string
+	Summary        []string
+	Location       string
+	Publisher      string
+	AndroidPackage string
+	OpenAppURL     template.URL
+	StoreURL       string
+}
+
+// GetAnnoncePageHandler godoc
+// @Summary Public page of an annonce
+// @Description Render the web page an annonce is shared with, with its Open Graph and Twitter card tags. It opens in the app where it is installed.
+// @Tags annonces
+// @Produce html
+// @Param slug path string true "Slug of the annonce"
+// @Success 200 {string} string "Page of the annonce"
+// @Failure 404 {string} string "Page of an annonce that is not published"
+// @Failure 410 {string} string "Page of an annonce whose cat is no longer up for adoption"
+// @Router /a/{slug} [get]
+func (h *AnnonceHandler) GetAnnoncePageHandler(w http.ResponseWriter, r *http.Request) {
+	storeURL := "https://play.google.com/store/apps/details?id=" + url.QueryEscape(config.AndroidPackage())
+
+	annonce, err := h.annonceQueries.FindAnnonceBySlug(chi.URLParam(r, "slug"))
+	if err != nil || annonce.Status != models.AnnoncePublished {
+		renderAnnoncePage(w, "not_found", http.StatusNotFound, annoncePage{StoreURL: storeURL})
+		return
+	}
+	cat, err := h.annonceQueries.FindCatByID(annonce.CatID)
+	if err != nil {
+		renderAnnoncePage(w, "not_found", http.StatusNotFound, annoncePage{StoreURL: storeURL})
+		return
+	}
+	withAnnonceDetails(h.annonceQueries, annonce)
+	if !annonceAdoptable(annonce, cat) {
+		renderAnnoncePage(w, "unavailable", http.StatusGone, annoncePage{CatName: cat.Name, StoreURL: storeURL})
+		return
+	}
+
+	page := annoncePage{
+		Title:          annonce.Title,
+		PageURL:        config.PublicURL() + "/a/" + annonce.Slug,
+		ImageURL:       annonce.CoverMediumImage,
+		CatName:        cat.Name,
+		Summary:        catSummary(h.annonceQueries, cat, time.Now()),
+		AndroidPackage: config.AndroidPackage(),
+		StoreURL:       storeURL,
+	}
+	if page.ImageURL == "" {
+		page.ImageURL = annonce.CoverImage
+	}
+	if annonce.Description != nil {
+		page.Text = *annonce.Description
+	}
+	if association := publishingAssociation(h.annonceQueries, cat.PublishedAs); association != nil {
+		page.Publisher, page.Location = association.Name, formatLocation(association.Ville, association.Cp)
+	} else if owner, err := h.annonceQueries.FindUserByID(cat.UserID); err == nil {
+		page.Location = formatLocation(owner.Ville, owner.Cp)
+	}
+
+	page.Description = page.Text
+	if page.Description == "" {
+		page.Description = fmt.Sprintf("%s est à adopter", cat.Name)
+		if page.Location != "" {
+			page.Description += " à " + page.Location
+		}
+		page.Description += " sur Purrfect Match."
+	}
+
+	page.OpenAppURL = template.URL(page.PageURL)
+	if public, err := url.Parse(page.PageURL); err == nil && public.Host != "" {
+		// Android opens the app through an intent URL, and the store when it
+		// is not installed
+		page.OpenAppURL = template.URL(fmt.Sprintf("intent://%s%s#Intent;scheme=%s;package=%s;S.browser_fallback_url=%s;end",
+			public.Host, public.Path, public.Scheme, page.AndroidPackage, url.QueryEscape(storeURL)))
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	renderAnnoncePage(w, "annonce", http.StatusOK, page)
+}
+
+// annonceAdoptable reports whether the cat of an annonce, or one of the
+// kittens of its litter, is still up for adoption.
+func annonceAdoptable(annonce *models.Annonce, cat *models.Cats) bool {
+	if cat.Status.Adoptable() {
+		return true
+	}
+	for _, kitten := range annonce.LitterCats {
+		if kitten.Status.Adoptable() {
+			return true
+		}
+	}
+	return false
+}
+
+func renderAnnoncePage(w http.ResponseWriter, name string, status int, page annoncePage) {
+	var body bytes.Buffer
+	if err := annoncePageTemplates.ExecuteTemplate(&body, name, page); err != nil {
+		utils.Logger("error", "Annonce Page:", "Failed to render page", fmt.Sprintf("Error: %v", err))
+		http.Error(w, "error rendering page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body.Bytes())
+}
+
+// catSummary describes a cat in a few words for its public page: its breed,
+// sex, age and whether it is sterilized.
+func catSummary(q *queries.DatabaseService, cat *models.Cats, now time.Time) []string {
+	var summary []string
+	if race, err := q.FindRaceByID(cat.RaceID); err == nil && race.RaceName != "" {
+		summary = append(summary, race.RaceName)
+	}
+	switch cat.Sexe {
+	case "Male":
+		summary = append(summary, "Mâle")
+	case "Female":
+		summary = append(summary, "Femelle")
+	}
+	if cat.BirthDate != nil {
+		summary = append(summary, formatAge(matching.AgeInMonths(*cat.BirthDate, now)))
+	}
+	if cat.Sterilized {
+		summary = append(summary, "Stérilisé")
+	}
+	return summary
+}
+
+func formatAge(months int) string {
+	switch {
+	case months < 1:
+		return "moins d'un mois"
+	case months < 12:
+		return fmt.Sprintf("%d mois", months)
+	case months < 24:
+		return "1 an"
+	}
+	return fmt.Sprintf("%d ans", months/12)
+}
+
+func formatLocation(ville, cp string) string {
+	if ville == "" {
+		return cp
+	}
+	if cp == "" {
+		return ville
+	}
+	return fmt.Sprintf("%s (%s)", ville, cp)
+}
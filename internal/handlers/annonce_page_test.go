@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stubAnnoncePage(db *dbtest.DB, status models.CatStatus) {
+	db.On(`FROM "annonces"`, dbtest.Result{
+		Columns: []string{"id", "title", "slug", "cat_id", "status"},
+		Values:  [][]driver.Value{{int64(12), `Adoptez <b>Félix</b> & "ses" amis`, "felix-12", "7", string(models.AnnoncePublished)}},
+	})
+	db.On(`FROM "cats"`, dbtest.Result{
+		Columns: []string{"id", "name", "sexe", "status"},
+		Values:  [][]driver.Value{{int64(7), `<script>alert("miaou")</script>`, "Male", string(status)}},
+	})
+	db.On(`FROM "cat_photos"`, dbtest.Result{
+		Columns: []string{"id", "cat_id", "url", "medium_url", "cover"},
+		Values:  [][]driver.Value{{int64(3), int64(7), "https://cdn.example/felix.jpg", "https://cdn.example/felix-medium.jpg", true}},
+	})
+}
+
+func getAnnoncePage(q *AnnonceHandler) *httptest.ResponseRecorder {
+	r := withURLParams(httptest.NewRequest(http.MethodGet, "/a/felix-12", nil), map[string]string{"slug": "felix-12"})
+	w := httptest.NewRecorder()
+	q.GetAnnoncePageHandler(w, r)
+	return w
+}
+
+func TestGetAnnoncePageEscapesContent(t *testing.T) {
+	t.Setenv("SERVER_URL", "https://purrfect.example/")
+	q, db := newTestQueries()
+	stubAnnoncePage(db, models.CatAvailable)
+
+	w := getAnnoncePage(NewAnnonceHandler(q, q, q))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.NotContains(t, body, "<script>alert")
+	assert.NotContains(t, body, "<b>Félix</b>")
+	assert.Contains(t, body, `<strong>&lt;script&gt;alert(&#34;miaou&#34;)&lt;/script&gt;</strong>`)
+	assert.Contains(t, body, `<meta property="og:title" content="Adoptez &lt;b&gt;Félix&lt;/b&gt; &amp; &#34;ses&#34; amis">`)
+	assert.Contains(t, body, `<meta property="og:url" content="https://purrfect.example/a/felix-12">`)
+	assert.Contains(t, body, `<meta property="og:image" content="https://cdn.example/felix-medium.jpg">`)
+	assert.Contains(t, body, `<meta property="og:image:alt" content="&lt;script&gt;alert(&#34;miaou&#34;)&lt;/script&gt;">`)
+	assert.Contains(t, body, `<meta name="twitter:card" content="summary_large_image">`)
+}
+
+func TestGetAnnoncePageOfUnavailableCat(t *testing.T) {
+	for _, status := range []models.CatStatus{models.CatReserved, models.CatAdopted, models.CatDeceased} {
+		t.Run(string(status), func(t *testing.T) {
+			q, db := newTestQueries()
+			stubAnnoncePage(db, status)
+
+			w := getAnnoncePage(NewAnnonceHandler(q, q, q))
+
+			assert.Equal(t, http.StatusGone, w.Code)
+			body := w.Body.String()
+			assert.Contains(t, body, "n'est plus disponible</h1>")
+			assert.Contains(t, body, `<meta name="robots" content="noindex">`)
+			assert.NotContains(t, body, "og:title")
+			assert.NotContains(t, body, "<script>alert")
+		})
+	}
+}
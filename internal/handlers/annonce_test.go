@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWritePageLinks(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		next   string
+		want   string
+	}{
+		{
+			name:   "first page",
+			target: "/annonces/search?sexe=female&limit=10",
+			next:   "abc",
+			want:   `</annonces/search?limit=10&sexe=female>; rel="first", </annonces/search?cursor=abc&limit=10&sexe=female>; rel="next"`,
+		},
+		{
+			name:   "middle page",
+			target: "/annonces/search?cursor=abc&sexe=female",
+			next:   "def",
+			want:   `</annonces/search?sexe=female>; rel="first", </annonces/search?cursor=def&sexe=female>; rel="next"`,
+		},
+		{
+			name:   "last page",
+			target: "/annonces/search?cursor=def&sexe=female",
+			want:   `</annonces/search?sexe=female>; rel="first"`,
+		},
+		{
+			name:   "escaped cursor",
+			target: "/associations/3/profile/cats",
+			next:   "a+b/c=",
+			want:   `</associations/3/profile/cats>; rel="first", </associations/3/profile/cats?cursor=a%2Bb%2Fc%3D>; rel="next"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			writePageLinks(w, httptest.NewRequest(http.MethodGet, tt.target, nil), tt.next)
+
+			assert.Equal(t, tt.want, w.Header().Get("Link"))
+		})
+	}
+}
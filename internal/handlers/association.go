@@ -4,35 +4,31 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 
-	"go-challenge/internal/api"
-	"go-challenge/internal/config"
 	"go-challenge/internal/database/queries"
+	"go-challenge/internal/geo"
 	"go-challenge/internal/models"
+	"go-challenge/internal/storage"
+	"go-challenge/internal/utils"
 
 	"github.com/go-chi/chi/v5"
 	// "github.com/gorilla/schema"
-	"github.com/lib/pq" // Ajout de l'importation du package pq
-	"github.com/uploadcare/uploadcare-go/ucare"
 )
 
 type AssociationHandler struct {
 	associationQueries *queries.DatabaseService
-	uploadcareClient   ucare.Client
+	store              storage.BlobStore
 }
 
-func NewAssociationHandler(associationQueries *queries.DatabaseService, uploadcareClient ucare.Client) *AssociationHandler {
-	return &AssociationHandler{associationQueries: associationQueries, uploadcareClient: uploadcareClient}
+func NewAssociationHandler(associationQueries *queries.DatabaseService, store storage.BlobStore) *AssociationHandler {
+	return &AssociationHandler{associationQueries: associationQueries, store: store}
 }
 
 // @Summary Create a new association
-// @Description Create a new association with the input payload and a PDF file
+// @Description Create a new association with the input payload and a PDF file. Its owner is the current user and its first member, the others join it through invitations. It is submitted for verification, and publishes as an association once verified.
 // @Tags associations
 // @Accept multipart/form-data
 // @Accept json
@@ -43,33 +39,34 @@ func NewAssociationHandler(associationQueries *queries.DatabaseService, uploadca
 // @Param ville formData string true "Ville"
 // @Param phone formData string true "Phone"
 // @Param email formData string true "Email"
-// @Param ownerId formData string true "OwnerID"
-// @Param members formData string false "Comma-separated list of members IDs"
+// @Param siret formData string false "SIRET, 14 digits"
+// @Param rnaNumber formData string false "RNA number, a W followed by 9 digits"
 // @Param kbisFile formData file true "PDF file"
 // @Success 201 {object} models.Association "Successfully created association"
 // @Failure 400 {object} string "Bad Request"
 // @Failure 500 {object} string "Internal Server Error"
 // @Router /associations [post]
 func (h *AssociationHandler) CreateAssociationHandler(w http.ResponseWriter, r *http.Request) {
-	var association models.Association
-	var members []string
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
 
-	fmt.Println("Starting CreateAssociationHandler")
+	var association models.Association
 
 	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
-		fmt.Println("Processing JSON payload")
 		err := json.NewDecoder(r.Body).Decode(&association)
 		if err != nil {
-			fmt.Printf("Error decoding JSON: %v\n", err)
 			http.Error(w, "Error decoding JSON: "+err.Error(), http.StatusBadRequest)
 			return
 		}
-		members = association.Members
+		// The KBIS is only taken from an upload, a key sent as JSON could be
+		// the file of another association
+		association.KbisFile = ""
 	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
-		fmt.Println("Processing multipart/form-data payload")
 		err := r.ParseMultipartForm(10 << 20) // 10 MB
 		if err != nil {
-			fmt.Printf("Error parsing multipart form: %v\n", err)
 			http.Error(w, "Error parsing multipart form: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -81,78 +78,60 @@ func (h *AssociationHandler) CreateAssociationHandler(w http.ResponseWriter, r *
 		association.Ville = formData.Get("ville")
 		association.Phone = formData.Get("phone")
 		association.Email = formData.Get("email")
-		association.OwnerID = formData.Get("ownerId")
-
-		fmt.Printf("Received data: name=%s, addressRue=%s, cp=%s, ville=%s, phone=%s, email=%s, ownerId=%s\n",
-			association.Name, association.AddressRue, association.Cp, association.Ville, association.Phone, association.Email, association.OwnerID)
+		association.Siret = formData.Get("siret")
+		association.RnaNumber = formData.Get("rnaNumber")
 
 		file, handler, err := r.FormFile("kbisFile")
 		if err != nil {
-			fmt.Printf("Error retrieving file: %v\n", err)
 			http.Error(w, "Error retrieving file: "+err.Error(), http.StatusBadRequest)
 			return
 		}
 		defer file.Close()
 
 		if handler.Header.Get("Content-Type") != "application/pdf" {
-			fmt.Println("Invalid content type for kbisFile, expected application/pdf")
 			http.Error(w, "Invalid content type for kbisFile, expected application/pdf", http.StatusBadRequest)
 			return
 		}
 
-		ext := filepath.Ext(handler.Filename)
-		tempFile, err := os.CreateTemp(os.TempDir(), "upload-*"+ext)
-		if err != nil {
-			fmt.Printf("Error creating temp file: %v\n", err)
-			http.Error(w, "Error creating temp file: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-		defer os.Remove(tempFile.Name())
-
-		_, err = io.Copy(tempFile, file)
-		if err != nil {
-			fmt.Printf("Error copying file: %v\n", err)
-			http.Error(w, "Error copying file: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		FileURL, _, err := api.UploadFilePDF(h.uploadcareClient, tempFile.Name())
+		fileKey, err := uploadFile(r.Context(), h.store, file, handler.Filename, "application/pdf")
 		if err != nil {
-			fmt.Printf("Error uploading file: %v\n", err)
+			utils.Logger("error", "Create Association:", "Failed to upload KBIS", fmt.Sprintf("Error: %v", err))
 			http.Error(w, "Error uploading file: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-		association.KbisFile = FileURL
-
-		membersStr := formData.Get("members")
-		if membersStr != "" {
-			members = strings.Split(membersStr, ",")
-		}
-		association.Members = pq.StringArray(members)
+		association.KbisFile = fileKey
 	} else {
-		fmt.Println("Unsupported content type")
 		http.Error(w, "Unsupported content type", http.StatusBadRequest)
 		return
 	}
 
+	association.OwnerID = subject.UserID
+	association.Siret = strings.ReplaceAll(association.Siret, " ", "")
+	association.RnaNumber = strings.ToUpper(strings.TrimSpace(association.RnaNumber))
+	if err := validateAssociationIdentifiers(&association); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	verified := false
 	association.Verified = &verified
+	association.Latitude, association.Longitude = geo.Locate(association.Cp)
 
 	if err := h.associationQueries.CreateAssociation(&association); err != nil {
-		fmt.Printf("Error creating association: %v\n", err)
+		utils.Logger("error", "Create Association:", "Failed to create association", fmt.Sprintf("Error: %v", err))
 		http.Error(w, "Error creating association: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	h.hidePrivateAssociationFields(r, &association)
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(association)
-
-	fmt.Println("Association created successfully")
 }
 
 // @Summary Get all associations
-// @Description Retrieve all associations from the database
+// @Description Retrieve all associations from the database. Their KBIS and members are only given to site admins and to their owner and admins.
 // @Tags associations
 // @Produce json
 // @Success 200 {array} models.Association "Successfully retrieved all associations"
@@ -164,6 +143,9 @@ func (h *AssociationHandler) GetAllAssociationsHandler(w http.ResponseWriter, r
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	for i := range associations {
+		h.hidePrivateAssociationFields(r, &associations[i])
+	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
@@ -191,6 +173,9 @@ func (h *AssociationHandler) GetUserAssociationsHandler(w http.ResponseWriter, r
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	for i := range associations {
+		h.hidePrivateAssociationFields(r, &associations[i])
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -198,7 +183,7 @@ func (h *AssociationHandler) GetUserAssociationsHandler(w http.ResponseWriter, r
 }
 
 // @Summary Get association by ID
-// @Description Retrieve an association by its ID
+// @Description Retrieve an association by its ID. Its KBIS and members are only given to site admins and to its owner and admins, see GET /associations/{id}/profile for its public profile.
 // @Tags associations
 // @Produce json
 // @Param id path int true "Association ID"
@@ -225,6 +210,7 @@ func (h *AssociationHandler) GetAssociationByIdHandler(w http.ResponseWriter, r
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.hidePrivateAssociationFields(r, association)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -232,99 +218,77 @@ func (h *AssociationHandler) GetAssociationByIdHandler(w http.ResponseWriter, r
 }
 
 // @Summary Update an association's verify status
-// @Description Update the verify status of an association with the given ID
+// @Description Verify or reject an association with the given ID. It is a shortcut through the review of the association, see PUT /associations/{id}/verification.
 // @Tags associations
 // @Accept json
 // @Produce json
 // @Param id path int true "Association ID"
 // @Param verified body bool true "Verify status"
-// @Success 200 {object} models.Association "Successfully updated association"
+// @Success 200 {object} models.AssociationVerificationChange "Successfully reviewed association"
 // @Failure 400 {object} string "Bad Request: Missing association ID, Invalid association ID"
+// @Failure 404 {object} string "association not found"
+// @Failure 409 {object} string "Invalid transition"
 // @Failure 500 {object} string "Internal Server Error"
 // @Router /associations/{id}/verify [put]
 func (h *AssociationHandler) UpdateAssociationVerifyStatusHandler(w http.ResponseWriter, r *http.Request) {
-	associationIDStr := chi.URLParam(r, "id")
-	if associationIDStr == "" {
-		http.Error(w, "Missing association ID", http.StatusBadRequest)
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
 		return
 	}
-
-	associationID, err := strconv.Atoi(associationIDStr)
-	if err != nil {
-		http.Error(w, "Invalid association ID", http.StatusBadRequest)
+	association, ok := h.findAssociation(w, r)
+	if !ok {
 		return
 	}
 
 	var body struct {
-		Verified *bool `json:"verified"`
+		Verified *bool  `json:"verified"`
+		Note     string `json:"note"`
 	}
 	err = json.NewDecoder(r.Body).Decode(&body)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	association, err := h.associationQueries.FindAssociationById(associationID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if body.Verified == nil {
+		http.Error(w, "verified is required", http.StatusBadRequest)
 		return
 	}
 
-	association.Verified = body.Verified
-
-	if err := h.associationQueries.UpdateAssociation(association); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	status := models.AssociationRejected
+	if *body.Verified {
+		status = models.AssociationVerified
 	}
-
-	owner, err := h.associationQueries.FindUserByAssociationID(associationID)
-
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	notificationToken, err := h.associationQueries.GetNotificationTokenByUserID(owner.ID)
-
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	payload := make(map[string]string)
-	payload["AssociationID"] = strconv.Itoa(associationID)
-	payload["Verified"] = strconv.FormatBool(*association.Verified)
-	SendToToken(config.GetFirebaseApp(), notificationToken.Token, "Votre association a été vérifié", "Vérification Association", payload)
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(association)
+	h.reviewAssociation(w, association, status, subject.UserID, strings.TrimSpace(body.Note))
 }
 
 // @Summary Delete an association
-// @Description Delete an association by its ID
+// @Description Delete an association by its ID. Only its owner and site admins can delete it.
 // @Tags associations
 // @Produce json
 // @Param id path int true "Association ID"
 // @Success 204 "Successfully deleted association"
 // @Failure 400 {object} string "Bad Request: Invalid association ID"
+// @Failure 403 {object} string "Forbidden"
 // @Failure 404 {object} string "Not Found: Association not found"
 // @Failure 500 {object} string "Internal Server Error"
 // @Router /associations/{id} [delete]
 func (h *AssociationHandler) DeleteAssociationHandler(w http.ResponseWriter, r *http.Request) {
-	associationIDStr := chi.URLParam(r, "id")
-	if associationIDStr == "" {
-		http.Error(w, "Missing association ID", http.StatusBadRequest)
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
 		return
 	}
-
-	associationID, err := strconv.Atoi(associationIDStr)
-	if err != nil {
-		http.Error(w, "Invalid association ID", http.StatusBadRequest)
+	association, ok := h.findAssociation(w, r)
+	if !ok {
+		return
+	}
+	if !subject.IsAdmin() && association.OwnerID != subject.UserID {
+		http.Error(w, "only the owner of the association can delete it", http.StatusForbidden)
 		return
 	}
 
-	err = h.associationQueries.DeleteAssociation(associationID)
+	err = h.associationQueries.DeleteAssociation(int(association.ID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			http.Error(w, "Association not found", http.StatusNotFound)
@@ -338,7 +302,7 @@ func (h *AssociationHandler) DeleteAssociationHandler(w http.ResponseWriter, r *
 }
 
 // @Summary Update an association
-// @Description Update all fields of an association with the given ID
+// @Description Update all fields of an association with the given ID. Only its owner and admins can update it. Changing the name, SIRET or RNA number of a verified association submits it for verification again.
 // @Tags associations
 // @Accept multipart/form-data
 // @Accept json
@@ -350,34 +314,24 @@ func (h *AssociationHandler) DeleteAssociationHandler(w http.ResponseWriter, r *
 // @Param ville formData string false "Ville"
 // @Param phone formData string false "Phone"
 // @Param email formData string false "Email"
+// @Param siret formData string false "SIRET, 14 digits"
+// @Param rnaNumber formData string false "RNA number, a W followed by 9 digits"
 // @Param kbisFile formData file false "PDF file"
-// @Param members formData string false "Comma-separated list of members IDs"
 // @Param association body models.Association false "Association payload"
 // @Success 200 {object} models.Association "Successfully updated association"
 // @Failure 400 {object} string "Bad Request: Invalid association ID or Invalid content type for kbisFile, expected application/pdf"
+// @Failure 403 {object} string "Forbidden"
+// @Failure 404 {object} string "association not found"
 // @Failure 500 {object} string "Internal Server Error"
 // @Router /associations/{id} [put]
 func (h *AssociationHandler) UpdateAssociationHandler(w http.ResponseWriter, r *http.Request) {
-	associationIDStr := chi.URLParam(r, "id")
-	if associationIDStr == "" {
-		http.Error(w, "Missing association ID", http.StatusBadRequest)
-		return
-	}
-
-	associationID, err := strconv.Atoi(associationIDStr)
-	if err != nil {
-		http.Error(w, "Invalid association ID", http.StatusBadRequest)
-		return
-	}
-
 	var association models.Association
-	var members []string
 
-	existingAssociation, err := h.associationQueries.FindAssociationById(associationID)
-	if err != nil {
-		http.Error(w, "Error finding association: "+err.Error(), http.StatusInternalServerError)
+	existingAssociation, ok := h.findManagedAssociation(w, r)
+	if !ok {
 		return
 	}
+	before := *existingAssociation
 
 	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
 		err := json.NewDecoder(r.Body).Decode(&association)
@@ -385,7 +339,8 @@ func (h *AssociationHandler) UpdateAssociationHandler(w http.ResponseWriter, r *
 			http.Error(w, "Error decoding JSON: "+err.Error(), http.StatusBadRequest)
 			return
 		}
-		members = association.Members
+		// The KBIS is only taken from an upload
+		association.KbisFile = ""
 	} else if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
 		err := r.ParseMultipartForm(10 << 20) // 10 MB
 		if err != nil {
@@ -400,7 +355,8 @@ func (h *AssociationHandler) UpdateAssociationHandler(w http.ResponseWriter, r *
 		association.Ville = formData.Get("ville")
 		association.Phone = formData.Get("phone")
 		association.Email = formData.Get("email")
-		association.OwnerID = formData.Get("ownerId")
+		association.Siret = formData.Get("siret")
+		association.RnaNumber = formData.Get("rnaNumber")
 
 		file, handler, err := r.FormFile("kbisFile")
 		if err == nil {
@@ -411,31 +367,12 @@ func (h *AssociationHandler) UpdateAssociationHandler(w http.ResponseWriter, r *
 				return
 			}
 
-			ext := filepath.Ext(handler.Filename)
-			tempFile, err := os.CreateTemp(os.TempDir(), "upload-*"+ext)
-			if err != nil {
-				http.Error(w, "Error creating temp file: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
-			defer os.Remove(tempFile.Name())
-
-			_, err = io.Copy(tempFile, file)
-			if err != nil {
-				http.Error(w, "Error copying file: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			FileURL, _, err := api.UploadFilePDF(h.uploadcareClient, tempFile.Name())
+			fileKey, err := uploadFile(r.Context(), h.store, file, handler.Filename, "application/pdf")
 			if err != nil {
 				http.Error(w, "Error uploading file: "+err.Error(), http.StatusInternalServerError)
 				return
 			}
-			association.KbisFile = FileURL
-		}
-
-		membersStr := formData.Get("members")
-		if membersStr != "" {
-			members = strings.Split(membersStr, ",")
+			association.KbisFile = fileKey
 		}
 	} else {
 		http.Error(w, "Unsupported content type", http.StatusBadRequest)
@@ -463,17 +400,52 @@ func (h *AssociationHandler) UpdateAssociationHandler(w http.ResponseWriter, r *
 	if association.KbisFile != "" {
 		existingAssociation.KbisFile = association.KbisFile
 	}
-	if len(members) > 0 {
-		existingAssociation.Members = pq.StringArray(members)
-	} else {
-		existingAssociation.Members = nil
+	if association.Siret != "" {
+		existingAssociation.Siret = strings.ReplaceAll(association.Siret, " ", "")
+	}
+	if association.RnaNumber != "" {
+		existingAssociation.RnaNumber = strings.ToUpper(strings.TrimSpace(association.RnaNumber))
 	}
+	if err := validateAssociationIdentifiers(existingAssociation); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	existingAssociation.Latitude, existingAssociation.Longitude = geo.Locate(existingAssociation.Cp)
 
 	if err := h.associationQueries.UpdateAssociation(existingAssociation); err != nil {
 		http.Error(w, "Error updating association: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if err := h.associationQueries.UpdateAssociationCatsLocation(existingAssociation); err != nil {
+		http.Error(w, "Error updating association cats location: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if association.KbisFile != "" {
+		document := &models.AssociationDocument{
+			AssociationID: existingAssociation.ID,
+			Kind:          models.DocumentKbis,
+			Name:          "kbis.pdf",
+			FileKey:       association.KbisFile,
+		}
+		if subject, err := requestSubject(r); err == nil {
+			document.UploadedBy = subject.UserID
+		}
+		if err := h.associationQueries.CreateAssociationDocument(document); err != nil {
+			http.Error(w, "Error saving association KBIS: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := resubmitChangedAssociation(h.associationQueries, &before, existingAssociation); err != nil {
+		http.Error(w, "Error submitting association for verification: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.hidePrivateAssociationFields(r, existingAssociation)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(existingAssociation)
@@ -0,0 +1,586 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/mailer"
+	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
+	"go-challenge/internal/policy"
+	"go-challenge/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jinzhu/gorm"
+)
+
+type AssociationMemberHandler struct {
+	memberQueries *queries.DatabaseService
+	mailer        mailer.Mailer
+}
+
+func NewAssociationMemberHandler(memberQueries *queries.DatabaseService, m mailer.Mailer) *AssociationMemberHandler {
+	return &AssociationMemberHandler{memberQueries: memberQueries, mailer: m}
+}
+
+// GetAssociationMembersHandler godoc
+// @Summary Get the members of an association
+// @Description Retrieve the members of an association with their role, its owner first, for its members
+// @Tags associations
+// @Produce json
+// @Param id path string true "Association ID"
+// @Success 200 {array} queries.AssociationMemberDetails "Members"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "association not found"
+// @Failure 500 {string} string "error fetching members"
+// @Router /associations/{id}/members [get]
+func (h *AssociationMemberHandler) GetAssociationMembersHandler(w http.ResponseWriter, r *http.Request) {
+	association, subject, ok := h.findAssociation(w, r)
+	if !ok {
+		return
+	}
+	if !subject.IsAdmin() && !policy.IsAssociationMember(association, subject.UserID) {
+		http.Error(w, "only the members of the association can see its members", http.StatusForbidden)
+		return
+	}
+
+	members, err := h.memberQueries.FindAssociationMembers(association.ID)
+	if err != nil {
+		http.Error(w, "error fetching members", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(members)
+}
+
+// InviteAssociationMemberHandler godoc
+// @Summary Invite someone to join an association
+// @Description Invite an email address to join an association as an admin or a volunteer. The invitation is sent by email, and in the app when the address belongs to a user. The owner invites with any role, admins only volunteers.
+// @Tags associations
+// @Accept json
+// @Produce json
+// @Param id path string true "Association ID"
+// @Success 201 {object} models.AssociationInvitation "Invitation sent"
+// @Failure 400 {string} string "Invalid email or role"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "association not found"
+// @Failure 409 {string} string "Already a member or already invited"
+// @Failure 500 {string} string "error creating invitation"
+// @Router /associations/{id}/invitations [post]
+func (h *AssociationMemberHandler) InviteAssociationMemberHandler(w http.ResponseWriter, r *http.Request) {
+	association, subject, ok := h.findAssociation(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Email string                 `json:"email"`
+		Role  models.AssociationRole `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	address, err := mail.ParseAddress(strings.TrimSpace(body.Email))
+	if err != nil {
+		http.Error(w, "invalid email", http.StatusBadRequest)
+		return
+	}
+	email := strings.ToLower(address.Address)
+	if body.Role == "" {
+		body.Role = models.AssociationVolunteer
+	}
+	if !body.Role.Valid() || body.Role == models.AssociationOwner {
+		http.Error(w, "role must be admin or volunteer", http.StatusBadRequest)
+		return
+	}
+	if !policy.CanManageRole(subject, association, body.Role) {
+		http.Error(w, fmt.Sprintf("you cannot invite members as %s of the association", body.Role), http.StatusForbidden)
+		return
+	}
+
+	invitee, err := h.memberQueries.FindUserByEmail(email)
+	if err == nil && policy.IsAssociationMember(association, invitee.ID) {
+		http.Error(w, "this user is already a member of the association", http.StatusConflict)
+		return
+	}
+	now := time.Now()
+	pending, err := h.memberQueries.FindOpenAssociationInvitations(association.ID, email, now)
+	if err != nil {
+		http.Error(w, "error fetching invitations", http.StatusInternalServerError)
+		return
+	}
+	if len(pending) > 0 {
+		http.Error(w, "this email was already invited to the association", http.StatusConflict)
+		return
+	}
+
+	invitation := &models.AssociationInvitation{
+		AssociationID: association.ID,
+		Email:         email,
+		Role:          body.Role,
+		InvitedBy:     subject.UserID,
+		Status:        models.InvitationPending,
+		ExpiresAt:     now.Add(models.AssociationInvitationLifetime),
+	}
+	if err := h.memberQueries.CreateAssociationInvitation(invitation); err != nil {
+		http.Error(w, "error creating invitation", http.StatusInternalServerError)
+		return
+	}
+	go sendAssociationInvitation(h.memberQueries, h.mailer, association, invitation)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invitation)
+}
+
+// GetAssociationInvitationsHandler godoc
+// @Summary Get the invitations of an association
+// @Description Retrieve the invitations to an association that were not answered yet, for its owner and admins
+// @Tags associations
+// @Produce json
+// @Param id path string true "Association ID"
+// @Success 200 {array} models.AssociationInvitation "Invitations"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "association not found"
+// @Failure 500 {string} string "error fetching invitations"
+// @Router /associations/{id}/invitations [get]
+func (h *AssociationMemberHandler) GetAssociationInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	association, subject, ok := h.findAssociation(w, r)
+	if !ok {
+		return
+	}
+	if !policy.CanManageMembers(subject, association) {
+		http.Error(w, "only the owner and admins of the association can see its invitations", http.StatusForbidden)
+		return
+	}
+
+	invitations, err := h.memberQueries.FindOpenAssociationInvitations(association.ID, "", time.Now())
+	if err != nil {
+		http.Error(w, "error fetching invitations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+// RevokeAssociationInvitationHandler godoc
+// @Summary Revoke an invitation to an association
+// @Description Revoke an invitation that was not answered yet
+// @Tags associations
+// @Param id path string true "Association ID"
+// @Param invitationID path string true "Invitation ID"
+// @Success 204 "No Content"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "invitation not found"
+// @Failure 409 {string} string "the invitation is no longer open"
+// @Failure 500 {string} string "error revoking invitation"
+// @Router /associations/{id}/invitations/{invitationID} [delete]
+func (h *AssociationMemberHandler) RevokeAssociationInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	association, subject, ok := h.findAssociation(w, r)
+	if !ok {
+		return
+	}
+
+	invitation, err := h.memberQueries.FindAssociationInvitationByID(chi.URLParam(r, "invitationID"))
+	if err != nil || invitation.AssociationID != association.ID {
+		http.Error(w, "invitation not found", http.StatusNotFound)
+		return
+	}
+	if !policy.CanManageRole(subject, association, invitation.Role) {
+		http.Error(w, fmt.Sprintf("you cannot manage the %ss of the association", invitation.Role), http.StatusForbidden)
+		return
+	}
+	if !invitation.Open(time.Now()) {
+		http.Error(w, "the invitation is no longer open", http.StatusConflict)
+		return
+	}
+
+	if err := h.memberQueries.RespondAssociationInvitation(invitation, models.InvitationRevoked); err != nil {
+		http.Error(w, "error revoking invitation", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMyAssociationInvitationsHandler godoc
+// @Summary Get my invitations to associations
+// @Description Retrieve the invitations sent to the email of the current user that were not answered yet
+// @Tags associations
+// @Produce json
+// @Success 200 {array} queries.AssociationInvitationDetails "Invitations"
+// @Failure 500 {string} string "error fetching invitations"
+// @Router /me/invitations [get]
+func (h *AssociationMemberHandler) GetMyAssociationInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+	user, err := h.memberQueries.FindUserByID(subject.UserID)
+	if err != nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	invitations, err := h.memberQueries.FindUserAssociationInvitations(user.Email, time.Now())
+	if err != nil {
+		http.Error(w, "error fetching invitations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+// AcceptAssociationInvitationHandler godoc
+// @Summary Accept an invitation to an association
+// @Description Join the association an invitation sent to the email of the current user is to, with the role of the invitation
+// @Tags associations
+// @Produce json
+// @Param id path string true "Invitation ID"
+// @Success 200 {object} models.AssociationMember "Membership"
+// @Failure 404 {string} string "invitation not found"
+// @Failure 409 {string} string "The invitation is no longer open, or already a member"
+// @Failure 500 {string} string "error accepting invitation"
+// @Router /me/invitations/{id}/accept [post]
+func (h *AssociationMemberHandler) AcceptAssociationInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	invitation, user, ok := h.findMyInvitation(w, r)
+	if !ok {
+		return
+	}
+	association, err := h.memberQueries.FindAssociationById(int(invitation.AssociationID))
+	if err != nil {
+		http.Error(w, "association not found", http.StatusNotFound)
+		return
+	}
+	if policy.IsAssociationMember(association, user.ID) {
+		http.Error(w, "you are already a member of the association", http.StatusConflict)
+		return
+	}
+
+	member, err := h.memberQueries.AcceptAssociationInvitation(invitation, user.ID)
+	if err != nil {
+		http.Error(w, "error accepting invitation", http.StatusInternalServerError)
+		return
+	}
+	if invitation.InvitedBy != "" {
+		go notifyAssociationMember(h.memberQueries, invitation.InvitedBy, notifications.EventAssociationInvitationAccepted,
+			map[string]string{"AssociationName": association.Name, "MemberName": user.Name}, association.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(member)
+}
+
+// DeclineAssociationInvitationHandler godoc
+// @Summary Decline an invitation to an association
+// @Description Decline an invitation sent to the email of the current user
+// @Tags associations
+// @Param id path string true "Invitation ID"
+// @Success 204 "No Content"
+// @Failure 404 {string} string "invitation not found"
+// @Failure 409 {string} string "the invitation is no longer open"
+// @Failure 500 {string} string "error declining invitation"
+// @Router /me/invitations/{id}/decline [post]
+func (h *AssociationMemberHandler) DeclineAssociationInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	invitation, _, ok := h.findMyInvitation(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.memberQueries.RespondAssociationInvitation(invitation, models.InvitationDeclined); err != nil {
+		http.Error(w, "error declining invitation", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdateAssociationMemberHandler godoc
+// @Summary Change the role of a member of an association
+// @Description Make a member of an association an admin or a volunteer. The owner changes any role, admins none. The owner role only changes hands through a transfer.
+// @Tags associations
+// @Accept json
+// @Produce json
+// @Param id path string true "Association ID"
+// @Param userID path string true "User ID of the member"
+// @Success 200 {object} models.AssociationMember "Membership"
+// @Failure 400 {string} string "Invalid role"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "member not found"
+// @Failure 500 {string} string "error updating member"
+// @Router /associations/{id}/members/{userID} [put]
+func (h *AssociationMemberHandler) UpdateAssociationMemberHandler(w http.ResponseWriter, r *http.Request) {
+	association, subject, ok := h.findAssociation(w, r)
+	if !ok {
+		return
+	}
+	member, ok := h.findMember(w, r, association)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Role models.AssociationRole `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if !body.Role.Valid() || body.Role == models.AssociationOwner {
+		http.Error(w, "role must be admin or volunteer, the ownership is transferred instead", http.StatusBadRequest)
+		return
+	}
+	if !policy.CanManageRole(subject, association, member.Role) || !policy.CanManageRole(subject, association, body.Role) {
+		http.Error(w, "you cannot change the role of this member", http.StatusForbidden)
+		return
+	}
+
+	if err := h.memberQueries.UpdateAssociationMemberRole(member, body.Role); err != nil {
+		http.Error(w, "error updating member", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(member)
+}
+
+// RemoveAssociationMemberHandler godoc
+// @Summary Remove a member from an association
+// @Description Remove a member from an association, or leave it when the member is the current user. The owner removes any member and admins remove volunteers. The owner transfers the ownership before leaving.
+// @Tags associations
+// @Param id path string true "Association ID"
+// @Param userID path string true "User ID of the member"
+// @Success 204 "No Content"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "member not found"
+// @Failure 409 {string} string "the owner cannot leave the association"
+// @Failure 500 {string} string "error removing member"
+// @Router /associations/{id}/members/{userID} [delete]
+func (h *AssociationMemberHandler) RemoveAssociationMemberHandler(w http.ResponseWriter, r *http.Request) {
+	association, subject, ok := h.findAssociation(w, r)
+	if !ok {
+		return
+	}
+	member, ok := h.findMember(w, r, association)
+	if !ok {
+		return
+	}
+
+	leaving := member.UserID == subject.UserID
+	if member.Role == models.AssociationOwner || member.UserID == association.OwnerID {
+		http.Error(w, "the owner cannot leave the association, transfer its ownership first", http.StatusConflict)
+		return
+	}
+	if !leaving && !policy.CanManageRole(subject, association, member.Role) {
+		http.Error(w, fmt.Sprintf("you cannot remove the %ss of the association", member.Role), http.StatusForbidden)
+		return
+	}
+
+	if err := h.memberQueries.RemoveAssociationMember(member); err != nil {
+		http.Error(w, "error removing member", http.StatusInternalServerError)
+		return
+	}
+	if !leaving {
+		go notifyAssociationMember(h.memberQueries, member.UserID, notifications.EventAssociationMemberRemoved,
+			map[string]string{"AssociationName": association.Name}, association.ID)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TransferAssociationOwnershipHandler godoc
+// @Summary Transfer the ownership of an association
+// @Description Hand an association over to one of its members, for its owner. The previous owner stays on as an admin.
+// @Tags associations
+// @Accept json
+// @Produce json
+// @Param id path string true "Association ID"
+// @Success 200 {object} models.Association "Association"
+// @Failure 400 {string} string "userId is required"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "member not found"
+// @Failure 500 {string} string "error transferring ownership"
+// @Router /associations/{id}/transfer [post]
+func (h *AssociationMemberHandler) TransferAssociationOwnershipHandler(w http.ResponseWriter, r *http.Request) {
+	association, subject, ok := h.findAssociation(w, r)
+	if !ok {
+		return
+	}
+	if !subject.IsAdmin() && association.OwnerID != subject.UserID {
+		http.Error(w, "only the owner of the association can transfer it", http.StatusForbidden)
+		return
+	}
+
+	var body struct {
+		UserID string `json:"userId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if body.UserID == "" {
+		http.Error(w, "userId is required", http.StatusBadRequest)
+		return
+	}
+	if body.UserID == association.OwnerID {
+		http.Error(w, "this user already owns the association", http.StatusBadRequest)
+		return
+	}
+	member, err := h.memberQueries.FindAssociationMember(association.ID, body.UserID)
+	if err != nil {
+		http.Error(w, "the new owner must be a member of the association", http.StatusNotFound)
+		return
+	}
+
+	previousOwner, err := h.memberQueries.FindUserByID(association.OwnerID)
+	if err != nil {
+		http.Error(w, "owner not found", http.StatusInternalServerError)
+		return
+	}
+	if err := h.memberQueries.TransferAssociationOwnership(association, member); err != nil {
+		http.Error(w, "error transferring ownership", http.StatusInternalServerError)
+		return
+	}
+	go notifyAssociationMember(h.memberQueries, member.UserID, notifications.EventAssociationOwnership,
+		map[string]string{"AssociationName": association.Name, "PreviousOwnerName": previousOwner.Name}, association.ID)
+
+	association, err = h.memberQueries.FindAssociationById(int(association.ID))
+	if err != nil {
+		http.Error(w, "error fetching association", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(association)
+}
+
+// findAssociation loads the association of the route, or writes the error
+// response.
+func (h *AssociationMemberHandler) findAssociation(w http.ResponseWriter, r *http.Request) (*models.Association, policy.Subject, bool) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return nil, subject, false
+	}
+	associationID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid association ID", http.StatusBadRequest)
+		return nil, subject, false
+	}
+	association, err := h.memberQueries.FindAssociationById(associationID)
+	if err != nil {
+		http.Error(w, "association not found", http.StatusNotFound)
+		return nil, subject, false
+	}
+	return association, subject, true
+}
+
+// findMember loads the member of an association named in the URL, or writes
+// the error response.
+func (h *AssociationMemberHandler) findMember(w http.ResponseWriter, r *http.Request, association *models.Association) (*models.AssociationMember, bool) {
+	member, err := h.memberQueries.FindAssociationMember(association.ID, chi.URLParam(r, "userID"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "member not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "error fetching member", http.StatusInternalServerError)
+		return nil, false
+	}
+	return member, true
+}
+
+// findMyInvitation loads the invitation of the route when it was sent to the
+// email of the current user and may still be answered, or writes the error
+// response.
+func (h *AssociationMemberHandler) findMyInvitation(w http.ResponseWriter, r *http.Request) (*models.AssociationInvitation, *models.User, bool) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	user, err := h.memberQueries.FindUserByID(subject.UserID)
+	if err != nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	invitation, err := h.memberQueries.FindAssociationInvitationByID(chi.URLParam(r, "id"))
+	if err != nil || !strings.EqualFold(invitation.Email, user.Email) {
+		http.Error(w, "invitation not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	if !invitation.Open(time.Now()) {
+		http.Error(w, "the invitation is no longer open", http.StatusConflict)
+		return nil, nil, false
+	}
+	return invitation, user, true
+}
+
+// sendAssociationInvitation sends an invitation by email, and in the app when
+// its address belongs to a user. It is meant to run in its own goroutine.
+func sendAssociationInvitation(q *queries.DatabaseService, m mailer.Mailer, association *models.Association, invitation *models.AssociationInvitation) {
+	inviterName := association.Name
+	if inviter, err := q.FindUserByID(invitation.InvitedBy); err == nil {
+		inviterName = inviter.Name
+	}
+	vars := map[string]string{
+		"AssociationName": association.Name,
+		"InviterName":     inviterName,
+		"Role":            string(invitation.Role),
+		"Email":           invitation.Email,
+		"ExpiresAt":       invitation.ExpiresAt.Format("02/01/2006"),
+	}
+
+	locale := notifications.DefaultLocale
+	if invitee, err := q.FindUserByEmail(invitation.Email); err == nil {
+		locale = invitee.Locale
+		data := map[string]string{
+			"AssociationID": strconv.FormatUint(uint64(association.ID), 10),
+			"InvitationID":  strconv.FormatUint(uint64(invitation.ID), 10),
+		}
+		if _, err := NotifyUserInApp(q, invitee.ID, notifications.EventAssociationInvitation, vars, data); err != nil {
+			utils.Logger("error", "Association Invitation:", "Failed to store in-app notification", fmt.Sprintf("Error: %v", err))
+		}
+		if _, err := NotifyUser(q, invitee.ID, notifications.EventAssociationInvitation, vars, data); err != nil {
+			utils.Logger("error", "Association Invitation:", "Failed to push notification", fmt.Sprintf("Error: %v", err))
+		}
+	}
+
+	message, err := notifications.Render(notifications.EventAssociationInvitationEmail, locale, vars)
+	if err != nil {
+		utils.Logger("error", "Association Invitation:", "Failed to render email", fmt.Sprintf("Error: %v", err))
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := m.Send(ctx, mailer.Message{To: invitation.Email, Subject: message.Title, Body: message.Body}); err != nil {
+		utils.Logger("error", "Association Invitation:", "Failed to send email", fmt.Sprintf("Error: %v", err))
+	}
+}
+
+// notifyAssociationMember lets a user know about a change of their
+// membership, in the app and by push. It is meant to run in its own
+// goroutine.
+func notifyAssociationMember(q *queries.DatabaseService, userID string, event notifications.Event, vars map[string]string, associationID uint) {
+	data := map[string]string{"AssociationID": strconv.FormatUint(uint64(associationID), 10)}
+	if _, err := NotifyUserInApp(q, userID, event, vars, data); err != nil {
+		utils.Logger("error", "Association Members:", "Failed to store in-app notification", fmt.Sprintf("Error: %v", err))
+	}
+	if _, err := NotifyUser(q, userID, event, vars, data); err != nil {
+		utils.Logger("error", "Association Members:", "Failed to push notification", fmt.Sprintf("Error: %v", err))
+	}
+}
@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+	"go-challenge/internal/policy"
+	"go-challenge/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// associationProfilePreview is the number of cats and annonces shown on
+	// the profile of an association, the next ones are fetched page by page.
+	associationProfilePreview = 6
+
+	maxAssociationDescriptionLen = 5000
+	maxAssociationTermsLen       = 500
+	maxAssociationLinkLen        = 255
+	maxAssociationSocialLinks    = 5
+)
+
+// associationProfile is what anyone may see of an association: no documents,
+// identifiers or members.
+type associationProfile struct {
+	ID                 uint                           `json:"id"`
+	Name               string                         `json:"name"`
+	Description        string                         `json:"description"`
+	LogoURL            string                         `json:"logoUrl"`
+	LogoThumbnailURL   string                         `json:"logoThumbnailUrl"`
+	AddressRue         string                         `json:"addressRue"`
+	Cp                 string                         `json:"cp"`
+	Ville              string                         `json:"ville"`
+	Phone              string                         `json:"phone"`
+	Email              string                         `json:"email"`
+	Website            string                         `json:"website"`
+	SocialLinks        []string                       `json:"socialLinks"`
+	OpeningHours       string                         `json:"openingHours"`
+	AdoptionConditions string                         `json:"adoptionConditions"`
+	AdoptionFees       string                         `json:"adoptionFees"`
+	Verified           bool                           `json:"verified"`
+	VerifiedAt         *time.Time                     `json:"verifiedAt"`
+	Latitude           *float64                       `json:"latitude"`
+	Longitude          *float64                       `json:"longitude"`
+	Reputation         *queries.AssociationReputation `json:"reputation"`
+	Cats               *queries.CatSearchPage         `json:"cats"`
+	Annonces           *queries.AnnonceSearchPage     `json:"annonces"`
+}
+
+func newAssociationProfile(association *models.Association) *associationProfile {
+	profile := &associationProfile{
+		ID:                 association.ID,
+		Name:               association.Name,
+		Description:        association.Description,
+		LogoURL:            association.LogoURL,
+		LogoThumbnailURL:   association.LogoThumbnailURL,
+		AddressRue:         association.AddressRue,
+		Cp:                 association.Cp,
+		Ville:              association.Ville,
+		Phone:              association.Phone,
+		Email:              association.Email,
+		Website:            association.Website,
+		SocialLinks:        []string(association.SocialLinks),
+		OpeningHours:       association.OpeningHours,
+		AdoptionConditions: association.AdoptionConditions,
+		AdoptionFees:       association.AdoptionFees,
+		Verified:           association.IsVerified(),
+		Latitude:           association.Latitude,
+		Longitude:          association.Longitude,
+	}
+	if profile.SocialLinks == nil {
+		profile.SocialLinks = []string{}
+	}
+	if profile.Verified {
+		profile.VerifiedAt = association.VerifiedAt
+	}
+	return profile
+}
+
+// hidePrivateAssociationFields clears the documents and members of the
+// associations the current user may not manage. Only site admins and the
+// owner and admins of an association see who takes part in it, and get a
+// signed URL to its KBIS.
+func (h *AssociationHandler) hidePrivateAssociationFields(r *http.Request, associations ...*models.Association) {
+	subject, err := requestSubject(r)
+	for _, association := range associations {
+		if err == nil && policy.CanManageMembers(subject, association) {
+			if association.KbisFile == "" {
+				continue
+			}
+			kbisURL, err := signedFileURL(h.store, association.KbisFile)
+			if err != nil {
+				utils.Logger("error", "Association KBIS:", "Failed to sign KBIS URL", fmt.Sprintf("Error: %v", err))
+			}
+			association.KbisFile = kbisURL
+			continue
+		}
+		association.KbisFile = ""
+		association.Members = nil
+	}
+}
+
+// findPublicAssociation loads the association of the route for its public
+// pages, or writes the error response. A rejected association has none.
+func (h *AssociationHandler) findPublicAssociation(w http.ResponseWriter, r *http.Request) (*models.Association, bool) {
+	association, ok := h.findAssociation(w, r)
+	if !ok {
+		return nil, false
+	}
+	if association.VerificationStatus == models.AssociationRejected {
+		http.Error(w, "association not found", http.StatusNotFound)
+		return nil, false
+	}
+	return association, true
+}
+
+// GetAssociationProfileHandler godoc
+// @Summary Get the public profile of an association
+// @Description Retrieve what anyone may see of an association: its description, logo, contact details, links, opening hours, adoption terms and reputation, with the first cats up for adoption and annonces it publishes. Rejected associations have no public profile.
+// @Tags associations
+// @Produce json
+// @Param id path string true "Association ID"
+// @Success 200 {object} associationProfile "Profile"
+// @Failure 404 {string} string "association not found"
+// @Failure 500 {string} string "error fetching profile"
+// @Router /associations/{id}/profile [get]
+func (h *AssociationHandler) GetAssociationProfileHandler(w http.ResponseWriter, r *http.Request) {
+	association, ok := h.findPublicAssociation(w, r)
+	if !ok {
+		return
+	}
+
+	profile := newAssociationProfile(association)
+	reputation, err := h.associationQueries.FindAssociationReputation(association.ID)
+	if err != nil {
+		http.Error(w, "error fetching profile", http.StatusInternalServerError)
+		return
+	}
+	profile.Reputation = reputation
+
+	now := time.Now()
+	associationID := strconv.FormatUint(uint64(association.ID), 10)
+	catSearch := queries.CatSearch{AssociationID: associationID, Status: models.CatAvailable, Limit: associationProfilePreview}
+	if profile.Cats, err = h.associationQueries.SearchCats(catSearch, now); err != nil {
+		http.Error(w, "error fetching profile", http.StatusInternalServerError)
+		return
+	}
+	annonceSearch := queries.AnnonceSearch{Cat: queries.CatSearch{AssociationID: associationID}, Limit: associationProfilePreview}
+	if profile.Annonces, err = h.associationQueries.SearchAnnonces(annonceSearch, now); err != nil {
+		http.Error(w, "error fetching profile", http.StatusInternalServerError)
+		return
+	}
+	annonces := make([]*models.Annonce, len(profile.Annonces.Annonces))
+	for i := range profile.Annonces.Annonces {
+		annonces[i] = &profile.Annonces.Annonces[i].Annonce
+	}
+	withAnnonceDetails(h.associationQueries, annonces...)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// GetAssociationCatalogueCatsHandler godoc
+// @Summary Get the cats of an association up for adoption
+// @Description List the cats an association has up for adoption, one page at a time, with the filters and sorts of the cat search. The links to the first and next pages are given in the Link header.
+// @Tags associations
+// @Produce json
+// @Param id path string true "Association ID"
+// @Param sort query string false "newest, name, youngest or eldest"
+// @Param cursor query string false "Cursor of the next page"
+// @Param limit query int false "Page size (max 100)"
+// @Success 200 {object} queries.CatSearchPage "Page of cats"
+// @Failure 400 {string} string "Invalid filters"
+// @Failure 404 {string} string "association not found"
+// @Failure 500 {string} string "error fetching cats"
+// @Router /associations/{id}/profile/cats [get]
+func (h *AssociationHandler) GetAssociationCatalogueCatsHandler(w http.ResponseWriter, r *http.Request) {
+	association, ok := h.findPublicAssociation(w, r)
+	if !ok {
+		return
+	}
+
+	search, err := parseCatSearch(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	search.AssociationID = strconv.FormatUint(uint64(association.ID), 10)
+	search.Status = models.CatAvailable
+
+	page, err := h.associationQueries.SearchCats(search, time.Now())
+	if err != nil {
+		if errors.Is(err, queries.ErrInvalidCursor) || errors.Is(err, queries.ErrInvalidCatSearchSort) || errors.Is(err, queries.ErrMissingSearchOrigin) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "error fetching cats", http.StatusInternalServerError)
+		return
+	}
+
+	writePageLinks(w, r, page.NextCursor)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(page)
+}
+
+// GetAssociationCatalogueAnnoncesHandler godoc
+// @Summary Get the annonces of an association
+// @Description List the published annonces of the cats an association has up for adoption, one page at a time. The links to the first and next pages are given in the Link header, the number of annonces in X-Total-Count.
+// @Tags associations
+// @Produce json
+// @Param id path string true "Association ID"
+// @Param sort query string false "newest, nearest or favorites"
+// @Param cursor query string false "Cursor of the next page"
+// @Param limit query int false "Page size (max 100)"
+// @Success 200 {object} queries.AnnonceSearchPage "Page of annonces"
+// @Failure 400 {string} string "Invalid filters"
+// @Failure 404 {string} string "association not found"
+// @Failure 500 {string} string "error fetching annonces"
+// @Router /associations/{id}/profile/annonces [get]
+func (h *AssociationHandler) GetAssociationCatalogueAnnoncesHandler(w http.ResponseWriter, r *http.Request) {
+	association, ok := h.findPublicAssociation(w, r)
+	if !ok {
+		return
+	}
+
+	params := r.URL.Query()
+	catSearch, err := parseCatSearch(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	catSearch.AssociationID = strconv.FormatUint(uint64(association.ID), 10)
+	search := queries.AnnonceSearch{
+		Cat:    catSearch,
+		Sort:   queries.AnnonceSearchSort(params.Get("sort")),
+		Cursor: catSearch.Cursor,
+		Limit:  catSearch.Limit,
+	}
+
+	page, err := h.associationQueries.SearchAnnonces(search, time.Now())
+	if err != nil {
+		if errors.Is(err, queries.ErrInvalidCursor) || errors.Is(err, queries.ErrInvalidAnnonceSearchSort) || errors.Is(err, queries.ErrMissingSearchOrigin) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "error fetching annonces", http.StatusInternalServerError)
+		return
+	}
+	annonces := make([]*models.Annonce, len(page.Annonces))
+	for i := range page.Annonces {
+		annonces[i] = &page.Annonces[i].Annonce
+	}
+	withAnnonceDetails(h.associationQueries, annonces...)
+
+	writePageLinks(w, r, page.NextCursor)
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(page)
+}
+
+// UpdateAssociationProfileHandler godoc
+// @Summary Update the public profile of an association
+// @Description Replace the description, website, social links, opening hours and adoption terms of an association
+// @Tags associations
+// @Accept json
+// @Produce json
+// @Param id path string true "Association ID"
+// @Success 200 {object} associationProfile "Profile"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "association not found"
+// @Failure 500 {string} string "error updating profile"
+// @Router /associations/{id}/profile [put]
+func (h *AssociationHandler) UpdateAssociationProfileHandler(w http.ResponseWriter, r *http.Request) {
+	association, ok := h.findManagedAssociation(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Description        string   `json:"description"`
+		Website            string   `json:"website"`
+		SocialLinks        []string `json:"socialLinks"`
+		OpeningHours       string   `json:"openingHours"`
+		AdoptionConditions string   `json:"adoptionConditions"`
+		AdoptionFees       string   `json:"adoptionFees"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	body.Website = strings.TrimSpace(body.Website)
+	if len(body.Description) > maxAssociationDescriptionLen || len(body.AdoptionConditions) > maxAssociationDescriptionLen {
+		http.Error(w, fmt.Sprintf("description and adoptionConditions must be at most %d characters", maxAssociationDescriptionLen), http.StatusBadRequest)
+		return
+	}
+	if len(body.OpeningHours) > maxAssociationTermsLen || len(body.AdoptionFees) > maxAssociationTermsLen {
+		http.Error(w, fmt.Sprintf("openingHours and adoptionFees must be at most %d characters", maxAssociationTermsLen), http.StatusBadRequest)
+		return
+	}
+	if body.Website != "" && (len(body.Website) > maxAssociationLinkLen || !models.ValidLink(body.Website)) {
+		http.Error(w, "website must be an http or https link", http.StatusBadRequest)
+		return
+	}
+	if len(body.SocialLinks) > maxAssociationSocialLinks {
+		http.Error(w, fmt.Sprintf("socialLinks must hold at most %d links", maxAssociationSocialLinks), http.StatusBadRequest)
+		return
+	}
+	socialLinks := pq.StringArray{}
+	for _, link := range body.SocialLinks {
+		link = strings.TrimSpace(link)
+		if len(link) > maxAssociationLinkLen || !models.ValidLink(link) {
+			http.Error(w, "socialLinks must be http or https links", http.StatusBadRequest)
+			return
+		}
+		socialLinks = append(socialLinks, link)
+	}
+
+	association.Description = body.Description
+	association.Website = body.Website
+	association.SocialLinks = socialLinks
+	association.OpeningHours = body.OpeningHours
+	association.AdoptionConditions = body.AdoptionConditions
+	association.AdoptionFees = body.AdoptionFees
+	if err := h.associationQueries.UpdateAssociation(association); err != nil {
+		http.Error(w, "error updating profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(newAssociationProfile(association))
+}
+
+// UploadAssociationLogoHandler godoc
+// @Summary Upload the logo of an association
+// @Description Replace the logo shown on the profile of an association
+// @Tags associations
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Association ID"
+// @Param uploaded_file formData file true "Image"
+// @Success 200 {object} associationProfile "Profile with its new logo"
+// @Failure 400 {string} string "invalid image"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "association not found"
+// @Failure 500 {string} string "error updating logo"
+// @Router /associations/{id}/logo [put]
+func (h *AssociationHandler) UploadAssociationLogoHandler(w http.ResponseWriter, r *http.Request) {
+	association, ok := h.findManagedAssociation(w, r)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseMultipartForm(maxImageSize); err != nil {
+		http.Error(w, "invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	files := r.MultipartForm.File["uploaded_file"]
+	if len(files) == 0 {
+		http.Error(w, "uploaded_file is required", http.StatusBadRequest)
+		return
+	}
+
+	image, err := uploadImage(r.Context(), h.store, files[0])
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+
+	previous := []string{association.LogoURL, association.LogoThumbnailURL}
+	association.LogoURL = image.MediumURL
+	association.LogoThumbnailURL = image.ThumbnailURL
+	if err := h.associationQueries.UpdateAssociation(association); err != nil {
+		deleteImageFiles(r.Context(), h.store, image.URL, image.MediumURL, image.ThumbnailURL)
+		http.Error(w, "error updating logo", http.StatusInternalServerError)
+		return
+	}
+	// Only the medium and thumbnail sizes of a logo are shown
+	deleteImageFiles(r.Context(), h.store, append(previous, image.URL)...)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(newAssociationProfile(association))
+}
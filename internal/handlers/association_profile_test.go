@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAssociationByIdHidesPrivateFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  string
+		role    models.RoleName
+		private bool
+	}{
+		{name: "stranger", userID: "stranger", role: models.UserRole},
+		{name: "volunteer", userID: "volunteer", role: models.AssoRole},
+		{name: "admin member", userID: "manager", role: models.AssoRole, private: true},
+		{name: "owner", userID: "owner", role: models.AssoRole, private: true},
+		{name: "site admin", userID: "admin", role: models.AdminRole, private: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, db := newTestAssociationHandler(t)
+			db.On(`FROM "associations"`, dbtest.Result{
+				Columns: []string{"id", "name", "owner_id", "kbis_file", "verification_status"},
+				Values:  [][]driver.Value{{int64(3), "Chats Libres", "owner", "kbis.pdf", string(models.AssociationVerified)}},
+			})
+			db.On(`FROM "association_members"`, dbtest.Result{
+				Columns: []string{"id", "association_id", "user_id", "role"},
+				Values: [][]driver.Value{
+					{int64(1), int64(3), "manager", string(models.AssociationAdmin)},
+					{int64(2), int64(3), "volunteer", string(models.AssociationVolunteer)},
+				},
+			})
+			r := httptest.NewRequest(http.MethodGet, "/associations/3", nil)
+			r = withURLParams(withSubject(r, tt.userID, tt.role), map[string]string{"id": "3"})
+			w := httptest.NewRecorder()
+
+			h.GetAssociationByIdHandler(w, r)
+
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var association models.Association
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&association))
+			if tt.private {
+				assert.True(t, strings.HasPrefix(association.KbisFile, "http://localhost:8080/uploads/kbis.pdf?"), association.KbisFile)
+				assert.Contains(t, association.KbisFile, "signature=")
+				assert.Len(t, association.Members, 2)
+			} else {
+				assert.Empty(t, association.KbisFile)
+				assert.Empty(t, association.Members)
+			}
+		})
+	}
+}
+
+func TestGetAssociationProfileHidesRejected(t *testing.T) {
+	tests := []struct {
+		status models.AssociationVerificationStatus
+		code   int
+	}{
+		{status: models.AssociationVerified, code: http.StatusOK},
+		{status: models.AssociationSubmitted, code: http.StatusOK},
+		{status: models.AssociationRejected, code: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			h, db := newTestAssociationHandler(t)
+			stubAssociation(db, tt.status)
+			db.On("count(*)", dbtest.Result{Columns: []string{"count"}, Values: [][]driver.Value{{int64(0)}}})
+			r := withURLParams(httptest.NewRequest(http.MethodGet, "/associations/3/profile", nil), map[string]string{"id": "3"})
+			w := httptest.NewRecorder()
+
+			h.GetAssociationProfileHandler(w, r)
+
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+		})
+	}
+}
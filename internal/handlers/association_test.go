@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAssociationOwnedByCurrentUser(t *testing.T) {
+	h, db := newTestAssociationHandler(t)
+	r := httptest.NewRequest(http.MethodPost, "/associations", strings.NewReader(`{"Name":"Chats Libres","Cp":"75011","OwnerID":"victim"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	h.CreateAssociationHandler(w, withSubject(r, "user", models.UserRole))
+
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	for _, fragment := range []string{`INSERT INTO "associations"`, `INSERT INTO "association_members"`} {
+		inserts := db.Queries(fragment)
+		require.Len(t, inserts, 1, fragment)
+		assert.Contains(t, inserts[0].Args, driver.Value("user"), fragment)
+		assert.NotContains(t, inserts[0].Args, driver.Value("victim"), fragment)
+	}
+}
+
+func TestUpdateAssociationRequiresManager(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID string
+		role   models.RoleName
+		code   int
+	}{
+		{name: "owner", userID: "owner", role: models.UserRole, code: http.StatusOK},
+		{name: "association admin", userID: "manager", role: models.UserRole, code: http.StatusOK},
+		{name: "site admin", userID: "admin", role: models.AdminRole, code: http.StatusOK},
+		{name: "volunteer", userID: "volunteer", role: models.UserRole, code: http.StatusForbidden},
+		{name: "stranger", userID: "stranger", role: models.UserRole, code: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, db := newTestAssociationHandler(t)
+			stubAssociation(db, models.AssociationSubmitted)
+			stubAssociationMembers(db)
+			r := httptest.NewRequest(http.MethodPut, "/associations/3", strings.NewReader(`{"Name":"Chats Perdus"}`))
+			r.Header.Set("Content-Type", "application/json")
+			r = withURLParams(withSubject(r, tt.userID, tt.role), map[string]string{"id": "3"})
+			w := httptest.NewRecorder()
+
+			h.UpdateAssociationHandler(w, r)
+
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+			updates := db.Queries(`UPDATE "associations"`)
+			if tt.code == http.StatusOK {
+				assert.NotEmpty(t, updates)
+			} else {
+				assert.Empty(t, updates)
+			}
+		})
+	}
+}
+
+func TestDeleteAssociationRequiresOwner(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID string
+		role   models.RoleName
+		code   int
+	}{
+		{name: "owner", userID: "owner", role: models.UserRole, code: http.StatusNoContent},
+		{name: "site admin", userID: "admin", role: models.AdminRole, code: http.StatusNoContent},
+		{name: "association admin", userID: "manager", role: models.UserRole, code: http.StatusForbidden},
+		{name: "stranger", userID: "stranger", role: models.UserRole, code: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, db := newTestAssociationHandler(t)
+			stubAssociation(db, models.AssociationVerified)
+			stubAssociationMembers(db)
+			r := httptest.NewRequest(http.MethodDelete, "/associations/3", nil)
+			r = withURLParams(withSubject(r, tt.userID, tt.role), map[string]string{"id": "3"})
+			w := httptest.NewRecorder()
+
+			h.DeleteAssociationHandler(w, r)
+
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+			deletes := db.Queries(`UPDATE "associations" SET "deleted_at"`)
+			if tt.code == http.StatusNoContent {
+				assert.Len(t, deletes, 1)
+			} else {
+				assert.Empty(t, deletes)
+			}
+		})
+	}
+}
+
+func stubAssociationMembers(db *dbtest.DB) {
+	db.On(`FROM "association_members"`, dbtest.Result{Columns: []string{"id", "association_id", "user_id", "role"}, Values: [][]driver.Value{
+		{int64(1), int64(3), "owner", string(models.AssociationOwner)},
+		{int64(2), int64(3), "manager", string(models.AssociationAdmin)},
+		{int64(3), int64(3), "volunteer", string(models.AssociationVolunteer)},
+	}})
+}
+
+func TestAssociationHandlersIgnoreKbisSentAsJSON(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		h, db := newTestAssociationHandler(t)
+		r := httptest.NewRequest(http.MethodPost, "/associations", strings.NewReader(`{"Name":"Chats Libres","KbisFile":"other-kbis.pdf"}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		h.CreateAssociationHandler(w, withSubject(r, "user", models.UserRole))
+
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Empty(t, db.Queries(`INSERT INTO "association_documents"`))
+		for _, insert := range db.Queries(`INSERT INTO "associations"`) {
+			assert.NotContains(t, insert.Args, driver.Value("other-kbis.pdf"))
+		}
+	})
+	t.Run("update", func(t *testing.T) {
+		h, db := newTestAssociationHandler(t)
+		stubAssociation(db, models.AssociationSubmitted)
+		stubAssociationMembers(db)
+		r := httptest.NewRequest(http.MethodPut, "/associations/3", strings.NewReader(`{"KbisFile":"other-kbis.pdf"}`))
+		r.Header.Set("Content-Type", "application/json")
+		r = withURLParams(withSubject(r, "owner", models.UserRole), map[string]string{"id": "3"})
+		w := httptest.NewRecorder()
+
+		h.UpdateAssociationHandler(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Empty(t, db.Queries(`INSERT INTO "association_documents"`))
+		for _, update := range db.Queries(`UPDATE "associations"`) {
+			assert.NotContains(t, update.Args, driver.Value("other-kbis.pdf"))
+		}
+	})
+}
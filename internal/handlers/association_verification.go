@@ -0,0 +1,493 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
+	"go-challenge/internal/policy"
+	"go-challenge/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxAssociationDocumentSize = 10 << 20
+
+// associationDocumentTypes are the files accepted as documents, scans and
+// photos included. The type is sniffed from the content of the file, not
+// taken from the client.
+var associationDocumentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+}
+
+// associationVerification is where an association is in its review, with
+// what it provided and what reviewers said.
+type associationVerification struct {
+	Status    models.AssociationVerificationStatus   `json:"status"`
+	Verified  bool                                   `json:"verified"`
+	Siret     string                                 `json:"siret"`
+	RnaNumber string                                 `json:"rnaNumber"`
+	Missing   []string                               `json:"missing"`
+	Documents []associationDocument                  `json:"documents"`
+	History   []models.AssociationVerificationChange `json:"history"`
+}
+
+// associationDocument is a document of an association with a signed URL to
+// download its file.
+type associationDocument struct {
+	models.AssociationDocument
+	URL string `json:"url"`
+}
+
+// newAssociationDocument signs the URL of a document for the owner and
+// admins of its association or a reviewer.
+func (h *AssociationHandler) newAssociationDocument(document models.AssociationDocument) associationDocument {
+	fileURL, err := signedFileURL(h.store, document.FileKey)
+	if err != nil {
+		utils.Logger("error", "Association Documents:", "Failed to sign document URL", fmt.Sprintf("Error: %v", err))
+	}
+	return associationDocument{AssociationDocument: document, URL: fileURL}
+}
+
+// validateAssociationIdentifiers checks the format of the SIRET and RNA
+// number of an association, when it has them.
+func validateAssociationIdentifiers(association *models.Association) error {
+	if association.Siret != "" && !models.ValidSiret(association.Siret) {
+		return fmt.Errorf("invalid SIRET %s, it must be 14 digits", association.Siret)
+	}
+	if association.RnaNumber != "" && !models.ValidRNANumber(association.RnaNumber) {
+		return fmt.Errorf("invalid RNA number %s, it must be a W followed by 9 digits", association.RnaNumber)
+	}
+	return nil
+}
+
+// missingForVerification lists what an association must still provide to be
+// submitted for verification: a SIRET or an RNA number, and the matching
+// registration document.
+func missingForVerification(association *models.Association, documents map[models.AssociationDocumentKind]int) []string {
+	missing := []string{}
+	if association.Siret == "" && association.RnaNumber == "" {
+		missing = append(missing, "siret or rnaNumber")
+	}
+	if documents[models.DocumentKbis] == 0 && documents[models.DocumentRNA] == 0 {
+		missing = append(missing, "kbis or rna document")
+	}
+	return missing
+}
+
+// changeAssociationVerification moves an association to a verification
+// status and lets its owner know.
+func changeAssociationVerification(q *queries.DatabaseService, association *models.Association, to models.AssociationVerificationStatus, changedBy, note string) (*models.AssociationVerificationChange, error) {
+	change, err := q.ChangeAssociationVerification(association, to, changedBy, note)
+	if err != nil {
+		return nil, err
+	}
+	go notifyAssociationVerification(q, association, change)
+	return change, nil
+}
+
+// resubmitChangedAssociation submits an association for verification again
+// when its key information changed after it was verified or while it was
+// reviewed.
+func resubmitChangedAssociation(q *queries.DatabaseService, before, after *models.Association) error {
+	if before.Name == after.Name && before.Siret == after.Siret && before.RnaNumber == after.RnaNumber {
+		return nil
+	}
+	if after.VerificationStatus != models.AssociationVerified && after.VerificationStatus != models.AssociationInReview {
+		return nil
+	}
+	_, err := changeAssociationVerification(q, after, models.AssociationSubmitted, "", "key information changed")
+	return err
+}
+
+// notifyAssociationVerification lets the owner of an association know that
+// its verification moved a step. It is meant to run in its own goroutine.
+func notifyAssociationVerification(q *queries.DatabaseService, association *models.Association, change *models.AssociationVerificationChange) {
+	event := notifications.EventAssociationReview
+	switch {
+	case change.ToStatus == models.AssociationVerified:
+		event = notifications.EventAssociationVerified
+	case change.ToStatus == models.AssociationRejected && change.FromStatus == models.AssociationVerified:
+		event = notifications.EventAssociationRejected
+	}
+
+	vars := map[string]string{
+		"AssociationName": association.Name,
+		"Status":          string(change.ToStatus),
+		"Note":            change.Note,
+	}
+	if change.ToStatus == models.AssociationSubmitted && change.ChangedBy == "" {
+		vars["Resubmitted"] = "true"
+	}
+	data := map[string]string{
+		"AssociationID": strconv.FormatUint(uint64(association.ID), 10),
+		"Status":        string(change.ToStatus),
+		"Verified":      strconv.FormatBool(change.ToStatus == models.AssociationVerified),
+	}
+	if _, err := NotifyUserInApp(q, association.OwnerID, event, vars, data); err != nil {
+		utils.Logger("error", "Association Verification:", "Failed to store in-app notification", fmt.Sprintf("Error: %v", err))
+	}
+	if _, err := NotifyUser(q, association.OwnerID, event, vars, data); err != nil {
+		utils.Logger("error", "Association Verification:", "Failed to push notification", fmt.Sprintf("Error: %v", err))
+	}
+}
+
+// GetAssociationVerificationHandler godoc
+// @Summary Get the verification of an association
+// @Description Retrieve where an association is in its review, what it must still provide, its documents and the notes of its reviewers, for its owner and admins
+// @Tags associations
+// @Produce json
+// @Param id path string true "Association ID"
+// @Success 200 {object} associationVerification "Verification"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "association not found"
+// @Failure 500 {string} string "error fetching verification"
+// @Router /associations/{id}/verification [get]
+func (h *AssociationHandler) GetAssociationVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	association, ok := h.findManagedAssociation(w, r)
+	if !ok {
+		return
+	}
+
+	documents, err := h.associationQueries.FindAssociationDocuments(association.ID)
+	if err != nil {
+		http.Error(w, "error fetching verification", http.StatusInternalServerError)
+		return
+	}
+	history, err := h.associationQueries.FindAssociationVerificationChanges(association.ID)
+	if err != nil {
+		http.Error(w, "error fetching verification", http.StatusInternalServerError)
+		return
+	}
+	counts := map[models.AssociationDocumentKind]int{}
+	signed := make([]associationDocument, 0, len(documents))
+	for _, document := range documents {
+		counts[document.Kind]++
+		signed = append(signed, h.newAssociationDocument(document))
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(associationVerification{
+		Status:    association.VerificationStatus,
+		Verified:  association.IsVerified(),
+		Siret:     association.Siret,
+		RnaNumber: association.RnaNumber,
+		Missing:   missingForVerification(association, counts),
+		Documents: signed,
+		History:   history,
+	})
+}
+
+// SubmitAssociationVerificationHandler godoc
+// @Summary Submit an association for verification again
+// @Description Submit an association again once the changes its reviewers requested are made, or after it was rejected. It needs a SIRET or an RNA number and the matching document.
+// @Tags associations
+// @Produce json
+// @Param id path string true "Association ID"
+// @Success 200 {object} models.AssociationVerificationChange "Submitted"
+// @Failure 400 {string} string "Missing information"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "association not found"
+// @Failure 409 {string} string "The association cannot be submitted"
+// @Failure 500 {string} string "error submitting association"
+// @Router /associations/{id}/verification/submit [post]
+func (h *AssociationHandler) SubmitAssociationVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	association, ok := h.findManagedAssociation(w, r)
+	if !ok {
+		return
+	}
+	subject, _ := requestSubject(r)
+
+	if association.VerificationStatus != models.AssociationChangesRequested && association.VerificationStatus != models.AssociationRejected {
+		http.Error(w, fmt.Sprintf("an association with status %s cannot be submitted", association.VerificationStatus), http.StatusConflict)
+		return
+	}
+	counts, err := h.associationQueries.CountAssociationDocuments(association.ID)
+	if err != nil {
+		http.Error(w, "error fetching documents", http.StatusInternalServerError)
+		return
+	}
+	if missing := missingForVerification(association, counts); len(missing) > 0 {
+		http.Error(w, "missing "+strings.Join(missing, ", "), http.StatusBadRequest)
+		return
+	}
+
+	change, err := changeAssociationVerification(h.associationQueries, association, models.AssociationSubmitted, subject.UserID, "")
+	if err != nil {
+		http.Error(w, "error submitting association", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(change)
+}
+
+// ReviewAssociationHandler godoc
+// @Summary Review an association
+// @Description Move an association along its verification: in_review, changes_requested, verified or rejected. A note telling the owner what to change, or why it is rejected, is required. The owner is notified.
+// @Tags associations
+// @Accept json
+// @Produce json
+// @Param id path string true "Association ID"
+// @Success 200 {object} models.AssociationVerificationChange "Reviewed"
+// @Failure 400 {string} string "Invalid status or missing note"
+// @Failure 404 {string} string "association not found"
+// @Failure 409 {string} string "Invalid transition"
+// @Failure 500 {string} string "error reviewing association"
+// @Router /associations/{id}/verification [put]
+func (h *AssociationHandler) ReviewAssociationHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+	association, ok := h.findAssociation(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Status models.AssociationVerificationStatus `json:"status"`
+		Note   string                               `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	body.Note = strings.TrimSpace(body.Note)
+	if !body.Status.Reviewed() {
+		http.Error(w, "status must be in_review, changes_requested, verified or rejected", http.StatusBadRequest)
+		return
+	}
+	if body.Note == "" && (body.Status == models.AssociationChangesRequested || body.Status == models.AssociationRejected) {
+		http.Error(w, "a note is required to request changes or reject an association", http.StatusBadRequest)
+		return
+	}
+
+	h.reviewAssociation(w, association, body.Status, subject.UserID, body.Note)
+}
+
+// GetAssociationsToReviewHandler godoc
+// @Summary Get the associations to review
+// @Description Retrieve the associations with a verification status, submitted by default, the ones waiting the longest first
+// @Tags associations
+// @Produce json
+// @Param status query string false "Verification status, submitted by default"
+// @Success 200 {array} models.Association "Associations"
+// @Failure 400 {string} string "Invalid status"
+// @Failure 500 {string} string "error fetching associations"
+// @Router /associations/reviews [get]
+func (h *AssociationHandler) GetAssociationsToReviewHandler(w http.ResponseWriter, r *http.Request) {
+	status := models.AssociationSubmitted
+	if value := r.URL.Query().Get("status"); value != "" {
+		status = models.AssociationVerificationStatus(value)
+	}
+	if !status.Valid() {
+		http.Error(w, "invalid status", http.StatusBadRequest)
+		return
+	}
+
+	associations, err := h.associationQueries.FindAssociationsByVerificationStatus(status)
+	if err != nil {
+		http.Error(w, "error fetching associations", http.StatusInternalServerError)
+		return
+	}
+	for i := range associations {
+		h.hidePrivateAssociationFields(r, &associations[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(associations)
+}
+
+// UploadAssociationDocumentHandler godoc
+// @Summary Upload a document of an association
+// @Description Add a document for the verification of an association: its KBIS, its RNA receipt or its insurance certificate, as a PDF or a picture
+// @Tags associations
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Association ID"
+// @Param kind formData string true "kbis, rna or insurance"
+// @Param file formData file true "Document"
+// @Success 201 {object} associationDocument "Document"
+// @Failure 400 {string} string "Invalid kind or file"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "association not found"
+// @Failure 500 {string} string "error uploading document"
+// @Router /associations/{id}/documents [post]
+func (h *AssociationHandler) UploadAssociationDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	association, ok := h.findManagedAssociation(w, r)
+	if !ok {
+		return
+	}
+	subject, _ := requestSubject(r)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAssociationDocumentSize+1<<20)
+	if err := r.ParseMultipartForm(maxAssociationDocumentSize); err != nil {
+		http.Error(w, "the document must be at most 10 MB", http.StatusBadRequest)
+		return
+	}
+	kind := models.AssociationDocumentKind(r.FormValue("kind"))
+	if !kind.Valid() {
+		http.Error(w, "kind must be kbis, rna or insurance", http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "error reading document", http.StatusBadRequest)
+		return
+	}
+	contentType := http.DetectContentType(data)
+	if !associationDocumentTypes[contentType] {
+		http.Error(w, "the document must be a PDF, JPEG or PNG file", http.StatusBadRequest)
+		return
+	}
+
+	fileKey, err := uploadFile(r.Context(), h.store, bytes.NewReader(data), header.Filename, contentType)
+	if err != nil {
+		http.Error(w, "error uploading document", http.StatusInternalServerError)
+		return
+	}
+	document := &models.AssociationDocument{
+		AssociationID: association.ID,
+		Kind:          kind,
+		Name:          header.Filename,
+		FileKey:       fileKey,
+		UploadedBy:    subject.UserID,
+	}
+	if err := h.associationQueries.CreateAssociationDocument(document); err != nil {
+		deleteAssociationFile(r.Context(), h, fileKey)
+		http.Error(w, "error uploading document", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(h.newAssociationDocument(*document))
+}
+
+// DeleteAssociationDocumentHandler godoc
+// @Summary Delete a document of an association
+// @Description Remove a document of an association and its file. The only registration document (kbis or rna) of a verified association cannot be removed, upload its replacement first.
+// @Tags associations
+// @Param id path string true "Association ID"
+// @Param documentID path string true "Document ID"
+// @Success 204 "No Content"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "document not found"
+// @Failure 409 {string} string "the only registration document of a verified association cannot be deleted"
+// @Failure 500 {string} string "error deleting document"
+// @Router /associations/{id}/documents/{documentID} [delete]
+func (h *AssociationHandler) DeleteAssociationDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	association, ok := h.findManagedAssociation(w, r)
+	if !ok {
+		return
+	}
+
+	document, err := h.associationQueries.FindAssociationDocument(association.ID, chi.URLParam(r, "documentID"))
+	if err != nil {
+		http.Error(w, "document not found", http.StatusNotFound)
+		return
+	}
+	if association.IsVerified() && (document.Kind == models.DocumentKbis || document.Kind == models.DocumentRNA) {
+		counts, err := h.associationQueries.CountAssociationDocuments(association.ID)
+		if err != nil {
+			http.Error(w, "error fetching documents", http.StatusInternalServerError)
+			return
+		}
+		if counts[models.DocumentKbis]+counts[models.DocumentRNA] <= 1 {
+			http.Error(w, "the only registration document of a verified association cannot be deleted, upload its replacement first", http.StatusConflict)
+			return
+		}
+	}
+	if err := h.associationQueries.DeleteAssociationDocument(document); err != nil {
+		http.Error(w, "error deleting document", http.StatusInternalServerError)
+		return
+	}
+	if document.FileKey != association.KbisFile {
+		deleteAssociationFile(r.Context(), h, document.FileKey)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reviewAssociation moves an association to a status a reviewer chose, and
+// writes the response.
+func (h *AssociationHandler) reviewAssociation(w http.ResponseWriter, association *models.Association, to models.AssociationVerificationStatus, reviewerID, note string) {
+	if !association.VerificationStatus.CanTransitionTo(to) {
+		http.Error(w, fmt.Sprintf("cannot change the verification of an association from %s to %s", association.VerificationStatus, to), http.StatusConflict)
+		return
+	}
+
+	change, err := changeAssociationVerification(h.associationQueries, association, to, reviewerID, note)
+	if err != nil {
+		http.Error(w, "error reviewing association", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(change)
+}
+
+// findAssociation loads the association of the route, or writes the error
+// response.
+func (h *AssociationHandler) findAssociation(w http.ResponseWriter, r *http.Request) (*models.Association, bool) {
+	associationID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid association ID", http.StatusBadRequest)
+		return nil, false
+	}
+	association, err := h.associationQueries.FindAssociationById(associationID)
+	if err != nil {
+		http.Error(w, "association not found", http.StatusNotFound)
+		return nil, false
+	}
+	return association, true
+}
+
+// findManagedAssociation loads the association of the route when the current
+// user may manage it, or writes the error response.
+func (h *AssociationHandler) findManagedAssociation(w http.ResponseWriter, r *http.Request) (*models.Association, bool) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return nil, false
+	}
+	association, ok := h.findAssociation(w, r)
+	if !ok {
+		return nil, false
+	}
+	if !policy.CanManageMembers(subject, association) {
+		http.Error(w, "only the owner and admins of the association can manage it", http.StatusForbidden)
+		return nil, false
+	}
+	return association, true
+}
+
+// deleteAssociationFile removes a file of an association from the storage.
+// It is no longer referenced, so a failure is only logged.
+func deleteAssociationFile(ctx context.Context, h *AssociationHandler, file string) {
+	key, ok := storedFileKey(h.store, file)
+	if !ok {
+		return
+	}
+	if err := h.store.Delete(ctx, key); err != nil {
+		utils.Logger("error", "Association Documents:", "Failed to delete file from storage", fmt.Sprintf("Error: %v", err))
+	}
+}
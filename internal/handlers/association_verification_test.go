@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+	"go-challenge/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAssociationHandler(t *testing.T) (*AssociationHandler, *dbtest.DB) {
+	store, err := storage.NewLocal(t.TempDir(), "http://localhost:8080/uploads", []byte("secret"))
+	require.NoError(t, err)
+	q, db := newTestQueries()
+	return NewAssociationHandler(q, store), db
+}
+
+func stubAssociation(db *dbtest.DB, status models.AssociationVerificationStatus) {
+	db.On(`FROM "associations"`, dbtest.Result{Columns: []string{"id", "name", "owner_id", "verification_status"}, Values: [][]driver.Value{{int64(3), "Chats Libres", "owner", string(status)}}})
+}
+
+func TestReviewAssociationHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		from models.AssociationVerificationStatus
+		body string
+		code int
+	}{
+		{name: "verifies", from: models.AssociationInReview, body: `{"status":"verified"}`, code: http.StatusOK},
+		{name: "takes in review", from: models.AssociationSubmitted, body: `{"status":"in_review"}`, code: http.StatusOK},
+		{name: "rejects with a note", from: models.AssociationInReview, body: `{"status":"rejected","note":"not an association"}`, code: http.StatusOK},
+		{name: "rejects without a note", from: models.AssociationInReview, body: `{"status":"rejected","note":"  "}`, code: http.StatusBadRequest},
+		{name: "requests changes without a note", from: models.AssociationInReview, body: `{"status":"changes_requested"}`, code: http.StatusBadRequest},
+		{name: "unknown status", from: models.AssociationInReview, body: `{"status":"approved"}`, code: http.StatusBadRequest},
+		{name: "verifies a rejected association", from: models.AssociationRejected, body: `{"status":"verified"}`, code: http.StatusConflict},
+		{name: "verifies twice", from: models.AssociationVerified, body: `{"status":"verified"}`, code: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, db := newTestAssociationHandler(t)
+			stubAssociation(db, tt.from)
+			r := httptest.NewRequest(http.MethodPut, "/associations/3/verification", strings.NewReader(tt.body))
+			r = withURLParams(withSubject(r, "admin", models.AdminRole), map[string]string{"id": "3"})
+			w := httptest.NewRecorder()
+
+			h.ReviewAssociationHandler(w, r)
+
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+			updates := db.Queries(`UPDATE "associations"`)
+			if tt.code == http.StatusOK {
+				assert.NotEmpty(t, updates)
+				assert.Len(t, db.Queries(`INSERT INTO "association_verification_changes"`), 1)
+			} else {
+				assert.Empty(t, updates)
+			}
+		})
+	}
+}
+
+func documentRequest(t *testing.T, kind string, content []byte, contentType string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	require.NoError(t, writer.WriteField("kind", kind))
+	header := make(map[string][]string)
+	header["Content-Disposition"] = []string{`form-data; name="file"; filename="kbis.pdf"`}
+	header["Content-Type"] = []string{contentType}
+	part, err := writer.CreatePart(header)
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/associations/3/documents", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	return withURLParams(withSubject(r, "admin", models.AdminRole), map[string]string{"id": "3"})
+}
+
+func TestUploadAssociationDocumentSniffsContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		code    int
+	}{
+		{name: "pdf", content: []byte("%PDF-1.4\n%âãÏÓ\n1 0 obj"), code: http.StatusCreated},
+		{name: "png", content: pictureData(t), code: http.StatusCreated},
+		{name: "text sent as a pdf", content: []byte("<html><script>alert(1)</script></html>"), code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, db := newTestAssociationHandler(t)
+			stubAssociation(db, models.AssociationSubmitted)
+			w := httptest.NewRecorder()
+
+			h.UploadAssociationDocumentHandler(w, documentRequest(t, "kbis", tt.content, "application/pdf"))
+
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+			inserts := db.Queries(`INSERT INTO "association_documents"`)
+			if tt.code == http.StatusCreated {
+				assert.Len(t, inserts, 1)
+			} else {
+				assert.Empty(t, inserts)
+			}
+		})
+	}
+}
+
+func TestDeleteAssociationDocumentKeepsRegistration(t *testing.T) {
+	tests := []struct {
+		name   string
+		status models.AssociationVerificationStatus
+		kind   models.AssociationDocumentKind
+		counts [][]driver.Value
+		code   int
+	}{
+		{name: "only kbis of a verified association", status: models.AssociationVerified, kind: models.DocumentKbis, counts: [][]driver.Value{{"kbis", int64(1)}, {"insurance", int64(2)}}, code: http.StatusConflict},
+		{name: "kbis replaced", status: models.AssociationVerified, kind: models.DocumentKbis, counts: [][]driver.Value{{"kbis", int64(2)}}, code: http.StatusNoContent},
+		{name: "kbis with an rna", status: models.AssociationVerified, kind: models.DocumentKbis, counts: [][]driver.Value{{"kbis", int64(1)}, {"rna", int64(1)}}, code: http.StatusNoContent},
+		{name: "insurance of a verified association", status: models.AssociationVerified, kind: models.DocumentInsurance, counts: [][]driver.Value{{"kbis", int64(1)}}, code: http.StatusNoContent},
+		{name: "only kbis of a submitted association", status: models.AssociationSubmitted, kind: models.DocumentKbis, counts: [][]driver.Value{{"kbis", int64(1)}}, code: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, db := newTestAssociationHandler(t)
+			stubAssociation(db, tt.status)
+			db.On("COUNT(*) AS count", dbtest.Result{Columns: []string{"kind", "count"}, Values: tt.counts})
+			db.On(`FROM "association_documents"`, dbtest.Result{Columns: []string{"id", "association_id", "kind", "file_key"}, Values: [][]driver.Value{{int64(5), int64(3), string(tt.kind), "documents/kbis.pdf"}}})
+			r := httptest.NewRequest(http.MethodDelete, "/associations/3/documents/5", nil)
+			r = withURLParams(withSubject(r, "admin", models.AdminRole), map[string]string{"id": "3", "documentID": "5"})
+			w := httptest.NewRecorder()
+
+			h.DeleteAssociationDocumentHandler(w, r)
+
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+			deletes := db.Queries(`UPDATE "association_documents" SET "deleted_at"`)
+			if tt.code == http.StatusNoContent {
+				assert.Len(t, deletes, 1)
+			} else {
+				assert.Empty(t, deletes)
+			}
+		})
+	}
+}
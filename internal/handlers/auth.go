@@ -13,6 +13,7 @@ import (
 	"go-challenge/internal/auth"
 	"go-challenge/internal/database/queries"
 	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
 	"go-challenge/internal/utils"
 
 	"github.com/go-chi/chi/v5"
@@ -76,6 +77,7 @@ func (h *AuthHandler) GetAuthCallbackFunction(w http.ResponseWriter, r *http.Req
 				Name:          user.Email,
 				GoogleID:      user.UserID,
 				ProfilePicURL: "default",
+				Locale:        notifications.NormalizeLocale(r.Header.Get("Accept-Language")),
 			}
 
 			err = h.userQueries.CreateUser(newUser, userRole)
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-challenge/internal/care"
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
+	"go-challenge/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jinzhu/gorm"
+)
+
+type CareHandler struct {
+	careQueries *queries.DatabaseService
+}
+
+func NewCareHandler(careQueries *queries.DatabaseService) *CareHandler {
+	return &CareHandler{careQueries: careQueries}
+}
+
+type careProtocolRequest struct {
+	Type               models.MedicalEntryType `json:"type"`
+	Name               string                  `json:"name"`
+	Label              string                  `json:"label"`
+	IntervalDays       int                     `json:"intervalDays"`
+	ReminderDaysBefore *int                    `json:"reminderDaysBefore"`
+}
+
+func (req *careProtocolRequest) apply(protocol *models.CareProtocol) error {
+	switch req.Type {
+	case models.MedicalVaccination, models.MedicalDeworming, models.MedicalFleaTreatment, models.MedicalVetVisit:
+	default:
+		return errors.New("type must be vaccination, deworming, flea_treatment or vet_visit")
+	}
+	if strings.TrimSpace(req.Label) == "" {
+		return errors.New("label is required")
+	}
+	if req.IntervalDays <= 0 {
+		return errors.New("intervalDays must be positive")
+	}
+
+	reminderDaysBefore := 7
+	if req.ReminderDaysBefore != nil {
+		reminderDaysBefore = *req.ReminderDaysBefore
+	}
+	if reminderDaysBefore < 0 || reminderDaysBefore >= req.IntervalDays {
+		return errors.New("reminderDaysBefore must be between 0 and intervalDays")
+	}
+
+	protocol.Type = req.Type
+	protocol.Name = strings.TrimSpace(req.Name)
+	protocol.Label = strings.TrimSpace(req.Label)
+	protocol.IntervalDays = req.IntervalDays
+	protocol.ReminderDaysBefore = reminderDaysBefore
+	return nil
+}
+
+// GetCareScheduleHandler godoc
+// @Summary Get the care schedule of a cat
+// @Description Retrieve when the vaccinations and treatments of a cat are due, soonest first, for its owner or association
+// @Tags care
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Success 200 {array} care.Item "Care schedule"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "cat not found"
+// @Failure 500 {string} string "error computing care schedule"
+// @Router /cats/{id}/care-schedule [get]
+func (h *CareHandler) GetCareScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	cat, _, ok := findManagedCat(h.careQueries, w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.careQueries.FindMedicalEntriesByCatID(cat.ID)
+	if err != nil {
+		http.Error(w, "error fetching medical record", http.StatusInternalServerError)
+		return
+	}
+	protocols, err := h.careQueries.GetCareProtocols()
+	if err != nil {
+		http.Error(w, "error fetching care protocols", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(care.Schedule(entries, protocols, time.Now()))
+}
+
+// GetCareProtocolsHandler godoc
+// @Summary Get the care protocols
+// @Description Retrieve how often each vaccination and treatment must be renewed (admin only)
+// @Tags care
+// @Produce json
+// @Success 200 {array} models.CareProtocol "Care protocols"
+// @Failure 500 {string} string "error fetching care protocols"
+// @Router /care-protocols [get]
+func (h *CareHandler) GetCareProtocolsHandler(w http.ResponseWriter, r *http.Request) {
+	protocols, err := h.careQueries.GetCareProtocols()
+	if err != nil {
+		http.Error(w, "error fetching care protocols", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(protocols)
+}
+
+// CreateCareProtocolHandler godoc
+// @Summary Create a care protocol
+// @Description Create a renewal interval for a vaccine or treatment type (admin only)
+// @Tags care
+// @Accept json
+// @Produce json
+// @Success 201 {object} models.CareProtocol "Care protocol created"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 500 {string} string "error creating care protocol"
+// @Router /care-protocols [post]
+func (h *CareHandler) CreateCareProtocolHandler(w http.ResponseWriter, r *http.Request) {
+	var req careProtocolRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	var protocol models.CareProtocol
+	if err := req.apply(&protocol); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.careQueries.CreateCareProtocol(&protocol); err != nil {
+		http.Error(w, "error creating care protocol", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(protocol)
+}
+
+// UpdateCareProtocolHandler godoc
+// @Summary Update a care protocol
+// @Description Change the renewal interval or reminder notice of a care protocol (admin only)
+// @Tags care
+// @Accept json
+// @Produce json
+// @Param id path string true "Care protocol ID"
+// @Success 200 {object} models.CareProtocol "Care protocol updated"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 404 {string} string "care protocol not found"
+// @Failure 500 {string} string "error updating care protocol"
+// @Router /care-protocols/{id} [put]
+func (h *CareHandler) UpdateCareProtocolHandler(w http.ResponseWriter, r *http.Request) {
+	protocol, err := h.careQueries.FindCareProtocolByID(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "care protocol not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "error fetching care protocol", http.StatusInternalServerError)
+		return
+	}
+
+	var req careProtocolRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := req.apply(protocol); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.careQueries.UpdateCareProtocol(protocol); err != nil {
+		http.Error(w, "error updating care protocol", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(protocol)
+}
+
+// DeleteCareProtocolHandler godoc
+// @Summary Delete a care protocol
+// @Description Stop scheduling and reminding a care (admin only)
+// @Tags care
+// @Param id path string true "Care protocol ID"
+// @Success 204 "No Content"
+// @Failure 404 {string} string "care protocol not found"
+// @Failure 500 {string} string "error deleting care protocol"
+// @Router /care-protocols/{id} [delete]
+func (h *CareHandler) DeleteCareProtocolHandler(w http.ResponseWriter, r *http.Request) {
+	protocol, err := h.careQueries.FindCareProtocolByID(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "care protocol not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "error fetching care protocol", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.careQueries.DeleteCareProtocol(protocol); err != nil {
+		http.Error(w, "error deleting care protocol", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunCareReminders reminds the owners of the cats whose cares are due soon or
+// overdue, in their inbox and by push. Each care is reminded once per due date.
+func RunCareReminders(q *queries.DatabaseService, now time.Time) {
+	protocols, err := q.GetCareProtocols()
+	if err != nil {
+		utils.Logger("error", "Care Reminders:", "Failed to get care protocols", fmt.Sprintf("Error: %v", err))
+		return
+	}
+	catIDs, err := q.GetCatIDsWithMedicalEntries()
+	if err != nil {
+		utils.Logger("error", "Care Reminders:", "Failed to get cats", fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	for _, catID := range catIDs {
+		cat, err := q.FindCatByID(strconv.FormatUint(uint64(catID), 10))
+		if err != nil || cat.Status == models.CatDeceased {
+			continue
+		}
+		entries, err := q.FindMedicalEntriesByCatID(catID)
+		if err != nil {
+			utils.Logger("error", "Care Reminders:", "Failed to get medical record", fmt.Sprintf("Error: %v", err))
+			continue
+		}
+
+		for _, item := range care.Schedule(entries, protocols, now) {
+			if !item.NeedsReminder() || item.DueDate == nil {
+				continue
+			}
+
+			created, err := q.CreateCareReminder(&models.CareReminder{CatID: cat.ID, Key: item.Key, DueDate: *item.DueDate, UserID: cat.UserID})
+			if err != nil {
+				utils.Logger("error", "Care Reminders:", "Failed to store reminder", fmt.Sprintf("Error: %v", err))
+				continue
+			}
+			if !created {
+				continue
+			}
+
+			vars := map[string]string{
+				"CatName":  cat.Name,
+				"CareName": item.Name,
+				"DueDate":  item.DueDate.Format("02/01/2006"),
+			}
+			data := map[string]string{
+				"CatID":   strconv.FormatUint(uint64(cat.ID), 10),
+				"CareKey": item.Key,
+			}
+			if _, err := NotifyUserInApp(q, cat.UserID, notifications.EventCareReminder, vars, data); err != nil {
+				utils.Logger("error", "Care Reminders:", "Failed to store in-app reminder", fmt.Sprintf("Error: %v", err))
+			}
+			if _, err := NotifyUser(q, cat.UserID, notifications.EventCareReminder, vars, data); err != nil {
+				utils.Logger("error", "Care Reminders:", "Failed to push reminder", fmt.Sprintf("Error: %v", err))
+			}
+		}
+	}
+}
@@ -1,33 +1,35 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"math"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
-	"go-challenge/internal/api"
 	"go-challenge/internal/database/queries"
+	"go-challenge/internal/geo"
 	"go-challenge/internal/models"
+	"go-challenge/internal/policy"
+	"go-challenge/internal/storage"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jinzhu/gorm"
-	"github.com/uploadcare/uploadcare-go/ucare"
+	"github.com/lib/pq"
 )
 
 type CatHandler struct {
-	catQueries       *queries.DatabaseService
-	uploadcareClient ucare.Client
+	catQueries *queries.DatabaseService
+	store      storage.BlobStore
 }
 
-func NewCatHandler(catQueries *queries.DatabaseService, uploadcareClient ucare.Client) *CatHandler {
-	return &CatHandler{catQueries: catQueries, uploadcareClient: uploadcareClient}
+func NewCatHandler(catQueries *queries.DatabaseService, store storage.BlobStore) *CatHandler {
+	return &CatHandler{catQueries: catQueries, store: store}
 }
 
 // CatCreationHandler godoc
@@ -47,19 +49,27 @@ func NewCatHandler(catQueries *queries.DatabaseService, uploadcareClient ucare.C
 // @Param Sterilized formData string true "Sterilized"
 // @Param RaceID formData string true "RaceID"
 // @Param Description formData string false "Description"
-// @Param Reserved formData string true "Reserved"
-// @Param UserID formData string true "User ID"
 // @Param PublishedAs formData string false "Published As" // New parameter
 // @Param uploaded_file formData file true "Image"
 // @Success 201 {object} models.Cats "cat created successfully"
 // @Failure 400 {string} string "all fields are required"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "error creating cat"
 // @Router /cats [post]
 func (h *CatHandler) CatCreationHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
 	var (
-		name, birthDateStr, sexe, lastVaccineStr, lastVaccineName, color, behavior, sterilizedStr, race, description, reservedStr, userID, publishedAs string
-		pictures                                                                                                                                       []string
+		name, birthDateStr, sexe, lastVaccineStr, lastVaccineName, color, behavior, sterilizedStr, race, description, publishedAs string
+		pictures, uploaded                                                                                                        []models.CatPhoto
 	)
+	// The pictures uploaded with the request are removed unless the cat is
+	// saved with them
+	defer func() { deleteCatPictures(r.Context(), h.store, uploaded) }()
 
 	contentType := r.Header.Get("Content-Type")
 
@@ -81,11 +91,12 @@ func (h *CatHandler) CatCreationHandler(w http.ResponseWriter, r *http.Request)
 		sterilizedStr, _ = requestData["Sterilized"].(string)
 		race, _ = requestData["RaceID"].(string)
 		description, _ = requestData["Description"].(string)
-		reservedStr, _ = requestData["Reserved"].(string)
-		userID, _ = requestData["UserID"].(string)
 		publishedAs, _ = requestData["PublishedAs"].(string) // New field
 		if uploadedFiles, ok := requestData["uploaded_file"].([]interface{}); ok {
-			pictures = convertInterfaceSliceToStringSlice(uploadedFiles)
+			if pictures, err = photosFromURLs(r.Context(), h.store, convertInterfaceSliceToStringSlice(uploadedFiles)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 		}
 	} else {
 		err := r.ParseMultipartForm(10 << 20) // 10 MB is the max memory size
@@ -103,48 +114,30 @@ func (h *CatHandler) CatCreationHandler(w http.ResponseWriter, r *http.Request)
 		sterilizedStr = r.FormValue("Sterilized")
 		race = r.FormValue("RaceID")
 		description = r.FormValue("Description")
-		reservedStr = r.FormValue("Reserved")
-		userID = r.FormValue("UserID")
 		publishedAs = r.FormValue("PublishedAs") // New field
 		files := r.MultipartForm.File["uploaded_file"]
-		for _, header := range files {
-			file, err := header.Open()
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			defer file.Close()
-
-			ext := filepath.Ext(header.Filename)
-
-			tempFile, err := os.CreateTemp(os.TempDir(), "upload-*"+ext)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			defer os.Remove(tempFile.Name()) // clean up
-
-			_, err = io.Copy(tempFile, file)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			FileURL, _, err := api.UploadImage(h.uploadcareClient, tempFile.Name())
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			pictures = append(pictures, FileURL)
+		uploaded, err = uploadCatPictures(r.Context(), h.store, files)
+		if err != nil {
+			writeUploadError(w, err)
+			return
 		}
+		pictures = append(pictures, uploaded...)
 	}
 
 	// Validation des champs obligatoires
-	if name == "" || birthDateStr == "" || sexe == "" || color == "" || behavior == "" || sterilizedStr == "" || race == "" || reservedStr == "" || userID == "" {
+	if name == "" || birthDateStr == "" || sexe == "" || color == "" || behavior == "" || sterilizedStr == "" || race == "" {
 		http.Error(w, "all fields are required", http.StatusBadRequest)
 		return
 	}
+	if len(pictures) > maxCatPhotos {
+		http.Error(w, fmt.Sprintf("a cat can have at most %d photos", maxCatPhotos), http.StatusBadRequest)
+		return
+	}
+
+	if publishedAs != "" && !policy.CanPublishAs(subject, publishingAssociation(h.catQueries, publishedAs)) {
+		http.Error(w, "only the owner and admins of a verified association can publish cats as the association", http.StatusForbidden)
+		return
+	}
 
 	layout := "02-01-2006"
 	var birthDate, lastVaccine *time.Time
@@ -172,12 +165,6 @@ func (h *CatHandler) CatCreationHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	reserved, err := strconv.ParseBool(reservedStr)
-	if err != nil {
-		http.Error(w, "invalid Reserved format", http.StatusBadRequest)
-		return
-	}
-
 	cat := &models.Cats{
 		Name:            name,
 		BirthDate:       birthDate,
@@ -187,19 +174,27 @@ func (h *CatHandler) CatCreationHandler(w http.ResponseWriter, r *http.Request)
 		Color:           color,
 		Behavior:        behavior,
 		Sterilized:      sterilized,
-		PicturesURL:     pictures,
+		PicturesURL:     photoURLs(pictures),
 		RaceID:          race,
 		Description:     &description,
-		Reserved:        reserved,
-		UserID:          userID,
+		Status:          models.CatAvailable,
+		UserID:          subject.UserID,
 		PublishedAs:     publishedAs, // New field
 	}
+	h.locateCat(cat)
 
 	_, err = h.catQueries.CreateCat(cat)
 	if err != nil {
 		http.Error(w, "error creating cat", http.StatusInternalServerError)
 		return
 	}
+	uploaded = nil
+	if len(pictures) > 0 {
+		if _, err := h.catQueries.AddCatPhotos(cat.ID, pictures); err != nil {
+			http.Error(w, "error creating cat photos", http.StatusInternalServerError)
+			return
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
@@ -228,19 +223,22 @@ func (h *CatHandler) CatCreationHandler(w http.ResponseWriter, r *http.Request)
 // @Param Sterilized formData string false "Sterilized"
 // @Param RaceID formData string false "RaceID"
 // @Param Description formData string false "Description"
-// @Param Reserved formData string false "Reserved"
-// @Param UserID formData string false "User ID"
 // @Param PublishedAs formData string false "Published As" // New parameter
-// @Param uploaded_file formData file false "Image"
+// @Param uploaded_file formData file false "Images added to the gallery"
 // @Success 200 {object} models.Cats "Cat updated successfully"
 // @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "cat not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /cats/{id} [put]
 func (h *CatHandler) UpdateCatHandler(w http.ResponseWriter, r *http.Request) {
 	var (
-		name, birthDateStr, sexe, lastVaccineStr, lastVaccineName, color, behavior, sterilizedStr, race, description, reservedStr, userID, publishedAs string
-		pictures                                                                                                                                       []string
+		name, birthDateStr, sexe, lastVaccineStr, lastVaccineName, color, behavior, sterilizedStr, race, description, publishedAs string
+		pictures, uploaded                                                                                                        []models.CatPhoto
 	)
+	// The pictures uploaded with the request are removed unless the cat is
+	// saved with them
+	defer func() { deleteCatPictures(r.Context(), h.store, uploaded) }()
 
 	contentType := r.Header.Get("Content-Type")
 	catID := chi.URLParam(r, "id")
@@ -250,6 +248,22 @@ func (h *CatHandler) UpdateCatHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	cat, err := h.catQueries.FindCatByID(catID)
+	if err != nil {
+		http.Error(w, "cat not found", http.StatusNotFound)
+		return
+	}
+	if !canManageCat(h.catQueries, cat, subject) {
+		http.Error(w, "only the owner of the cat or its association can manage it", http.StatusForbidden)
+		return
+	}
+
 	if strings.Contains(contentType, "application/json") {
 		var requestData map[string]interface{}
 		err := json.NewDecoder(r.Body).Decode(&requestData)
@@ -268,11 +282,12 @@ func (h *CatHandler) UpdateCatHandler(w http.ResponseWriter, r *http.Request) {
 		sterilizedStr, _ = requestData["Sterilized"].(string)
 		race, _ = requestData["RaceID"].(string)
 		description, _ = requestData["Description"].(string)
-		reservedStr, _ = requestData["Reserved"].(string)
-		userID, _ = requestData["UserID"].(string)
 		publishedAs, _ = requestData["PublishedAs"].(string) // New field
 		if uploadedFiles, ok := requestData["uploaded_file"].([]interface{}); ok {
-			pictures = convertInterfaceSliceToStringSlice(uploadedFiles)
+			if pictures, err = photosFromURLs(r.Context(), h.store, convertInterfaceSliceToStringSlice(uploadedFiles)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 		}
 	} else {
 		err := r.ParseMultipartForm(10 << 20) // 10 MB is the max memory size
@@ -290,41 +305,14 @@ func (h *CatHandler) UpdateCatHandler(w http.ResponseWriter, r *http.Request) {
 		sterilizedStr = r.FormValue("Sterilized")
 		race = r.FormValue("RaceID")
 		description = r.FormValue("Description")
-		reservedStr = r.FormValue("Reserved")
-		userID = r.FormValue("UserID")
 		publishedAs = r.FormValue("PublishedAs") // New field
 		files := r.MultipartForm.File["uploaded_file"]
-		for _, header := range files {
-			file, err := header.Open()
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			defer file.Close()
-
-			ext := filepath.Ext(header.Filename)
-
-			tempFile, err := os.CreateTemp(os.TempDir(), "upload-*"+ext)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			defer os.Remove(tempFile.Name()) // clean up
-
-			_, err = io.Copy(tempFile, file)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			FileURL, _, err := api.UploadImage(h.uploadcareClient, tempFile.Name())
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			pictures = append(pictures, FileURL)
+		uploaded, err = uploadCatPictures(r.Context(), h.store, files)
+		if err != nil {
+			writeUploadError(w, err)
+			return
 		}
+		pictures = append(pictures, uploaded...)
 	}
 
 	layout := "02-01-2006"
@@ -353,15 +341,8 @@ func (h *CatHandler) UpdateCatHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reserved, err := strconv.ParseBool(reservedStr)
-	if err != nil {
-		http.Error(w, "invalid Reserved format", http.StatusBadRequest)
-		return
-	}
-
-	cat, err := h.catQueries.FindCatByID(catID)
-	if err != nil {
-		http.Error(w, "cat not found", http.StatusNotFound)
+	if publishedAs != "" && publishedAs != cat.PublishedAs && !policy.CanPublishAs(subject, publishingAssociation(h.catQueries, publishedAs)) {
+		http.Error(w, "only the owner and admins of a verified association can publish cats as the association", http.StatusForbidden)
 		return
 	}
 
@@ -395,17 +376,21 @@ func (h *CatHandler) UpdateCatHandler(w http.ResponseWriter, r *http.Request) {
 	if description != "" {
 		cat.Description = &description
 	}
-	if reservedStr != "" {
-		cat.Reserved = reserved
-	}
-	if userID != "" {
-		cat.UserID = userID
-	}
 	if publishedAs != "" {
 		cat.PublishedAs = publishedAs // New field
 	}
+	h.locateCat(cat)
+
 	if len(pictures) > 0 {
-		cat.PicturesURL = pictures
+		photos, err := h.catQueries.FindCatPhotos(cat.ID)
+		if err != nil {
+			http.Error(w, "error fetching cat photos", http.StatusInternalServerError)
+			return
+		}
+		if len(photos)+len(pictures) > maxCatPhotos {
+			http.Error(w, fmt.Sprintf("a cat can have at most %d photos", maxCatPhotos), http.StatusBadRequest)
+			return
+		}
 	}
 
 	err = h.catQueries.UpdateCat(cat)
@@ -414,6 +399,24 @@ func (h *CatHandler) UpdateCatHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Pictures sent with an update are added to the gallery, which is then
+	// copied back to the cat.
+	if len(pictures) > 0 {
+		photos, err := h.catQueries.AddCatPhotos(cat.ID, pictures)
+		if err != nil {
+			http.Error(w, "error adding cat photos", http.StatusInternalServerError)
+			return
+		}
+		uploaded = nil
+		go CheckAddedPhotos(h.catQueries, cat, photos)
+		if cat, err = h.catQueries.FindCatByID(catID); err != nil {
+			http.Error(w, "error fetching cat", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	go MatchSavedSearches(h.catQueries, fmt.Sprintf("%d", cat.ID))
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	if err := json.NewEncoder(w).Encode(cat); err != nil {
 		http.Error(w, "error encoding cat to JSON", http.StatusInternalServerError)
@@ -500,6 +503,7 @@ func (h *CatHandler) GetCatByIDHandler(w http.ResponseWriter, r *http.Request) {
 // @Param id path string true "Cat ID"
 // @Success 204 "No Content"
 // @Failure 400 {string} string "Cat ID is required"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 404 {string} string "Cat not found"
 // @Failure 500 {string} string "Error deleting cat"
 // @Router /cats/{id} [delete]
@@ -511,7 +515,23 @@ func (h *CatHandler) DeleteCatHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.catQueries.DeleteCatByID(id)
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	cat, err := h.catQueries.FindCatByID(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cat with ID %s not found", id), http.StatusNotFound)
+		return
+	}
+	if !canManageCat(h.catQueries, cat, subject) {
+		http.Error(w, "only the owner of the cat or its association can manage it", http.StatusForbidden)
+		return
+	}
+
+	photos, err := h.catQueries.DeleteCatByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			http.Error(w, fmt.Sprintf("cat with ID %s not found", id), http.StatusNotFound)
@@ -520,19 +540,26 @@ func (h *CatHandler) DeleteCatHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "error deleting cat", http.StatusInternalServerError)
 		return
 	}
+	deleteUnusedCatPictures(r.Context(), h.catQueries, h.store, photos)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // FindCatsByFilterHandler godoc
-// @Summary Get cats by filters
-// @Description Retrieve cats using their sex, age or race
+// @Summary Get annonces by cat filters
+// @Description Retrieve the annonces of the cats matching every given filter
 // @Tags cats
 // @Param raceId query string false "RaceID"
-// @Param age query int false "Age"
+// @Param age query int false "Age in years"
 // @Param sexe query string false "Sexe"
+// @Param assoID query string false "Association ID"
+// @Param cp query string false "Postal code to search around"
+// @Param lat query number false "Latitude to search around"
+// @Param lng query number false "Longitude to search around"
+// @Param radiusKm query number false "Maximum distance in kilometers. Most postal codes are located at the prefecture of their department, so a radius of a few kilometers is not reliable"
+// @Param sort query string false "newest, name, youngest, eldest or nearest"
 // @Produce  json
-// @Success 200 {object} []models.Cats "Found cats"
+// @Success 200 {object} []models.Annonce "Found annonces"
 // @Failure 400 {string} string "An error has occured"
 // @Failure 404 {string} string "No cats were found"
 // @Failure 500 {string} string "error fetching cats"
@@ -541,49 +568,245 @@ func (h *CatHandler) FindCatsByFilterHandler(w http.ResponseWriter, r *http.Requ
 	var data []*models.Annonce
 	params := r.URL.Query()
 
-	raceId := params.Get("raceId")
-	sexe := params.Get("sexe")
-	age, _ := strconv.Atoi(params.Get("age"))
-	assoId, _ := strconv.Atoi(params.Get("assoID"))
-
-	var assoName string
+	search := queries.CatSearch{
+		RaceID:        params.Get("raceId"),
+		Sexe:          params.Get("sexe"),
+		AssociationID: params.Get("assoID"),
+		Published:     true,
+		Sort:          queries.CatSearchSort(params.Get("sort")),
+		Limit:         queries.MaxCatSearchLimit,
+	}
 
-	if assoId != 0 {
-		asso, _ := h.catQueries.FindAssociationById(assoId)
-		assoName = asso.Name
+	near, radiusKm, err := parseSearchOrigin(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	search.Near, search.RadiusKm = near, radiusKm
+	if params.Get("age") != "" {
+		age, err := strconv.Atoi(params.Get("age"))
+		if err != nil || age < 0 {
+			http.Error(w, "age must be a positive number of years", http.StatusBadRequest)
+			return
+		}
+		minAge, maxAge := age*12, age*12+11
+		search.MinAgeMonths, search.MaxAgeMonths = &minAge, &maxAge
 	}
 
-	cats, err := h.catQueries.GetCatByFilters(raceId, age, sexe, assoName)
+	page, err := h.catQueries.SearchCats(search, time.Now())
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			http.Error(w, fmt.Sprintf("Error in parameters"), http.StatusNotFound)
+		if errors.Is(err, queries.ErrInvalidCatSearchSort) || errors.Is(err, queries.ErrMissingSearchOrigin) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 		http.Error(w, "error fetching cat", http.StatusInternalServerError)
 		return
 	}
 
-	for _, cat := range cats {
-		annonce, fail := h.catQueries.FindAnnonceByCatID(fmt.Sprintf("%d", cat.ID))
-		if fail != nil {
-			continue
+	catIDs := make([]uint, len(page.Cats))
+	for i, cat := range page.Cats {
+		catIDs[i] = cat.ID
+	}
+	annonces, err := h.catQueries.FindPublishedAnnoncesByCatIDs(catIDs)
+	if err != nil {
+		http.Error(w, "error fetching annonces", http.StatusInternalServerError)
+		return
+	}
+	for _, cat := range page.Cats {
+		if annonce, ok := annonces[cat.ID]; ok {
+			data = append(data, annonce)
 		}
+	}
+	withAnnonceDetails(h.catQueries, data...)
 
-		data = append(data, annonce)
+	if len(data) == 0 {
+		http.Error(w, "No cats were found using the filters.", http.StatusNotFound)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	err = json.NewEncoder(w).Encode(data)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		http.Error(w, "error encoding cat to JSON", http.StatusInternalServerError)
+		return
+	}
+}
 
-	if len(data) == 0 {
-		http.Error(w, "No cats were found using the filters.", http.StatusNotFound)
+// SearchCatsHandler godoc
+// @Summary Search cats
+// @Description Search cats matching every given filter, one page at a time
+// @Tags cats
+// @Param raceId query string false "Race ID"
+// @Param breed query string false "Part of the name or of an alias of the breed"
+// @Param coatLength query string false "Coat length of the breed (hairless, short, medium, long)"
+// @Param size query string false "Size of the breed (small, medium, large)"
+// @Param activityLevel query string false "Activity level of the breed (low, moderate, high)"
+// @Param hypoallergenic query bool false "Hypoallergenic breed"
+// @Param temperament query string false "Comma separated temperaments the breed must be known for"
+// @Param sexe query string false "Sexe"
+// @Param minAge query int false "Minimum age in months"
+// @Param maxAge query int false "Maximum age in months"
+// @Param color query string false "Color"
+// @Param behavior query string false "Behavior"
+// @Param sterilized query bool false "Sterilized"
+// @Param vaccinatedSince query string false "Vaccinated since (YYYY-MM-DD)"
+// @Param status query string false "Status (available, foster, reserved, adopted, deceased, returned)"
+// @Param associationId query string false "Association ID"
+// @Param cp query string false "Postal code to search around"
+// @Param lat query number false "Latitude to search around"
+// @Param lng query number false "Longitude to search around"
+// @Param radiusKm query number false "Maximum distance in kilometers. Most postal codes are located at the prefecture of their department, so a radius of a few kilometers is not reliable"
+// @Param sort query string false "newest, name, youngest, eldest or nearest"
+// @Param cursor query string false "Cursor of the next page"
+// @Param limit query int false "Page size (max 100)"
+// @Produce json
+// @Success 200 {object} queries.CatSearchPage "Page of cats"
+// @Failure 400 {string} string "Invalid filters"
+// @Failure 500 {string} string "error searching cats"
+// @Router /cats/search [get]
+func (h *CatHandler) SearchCatsHandler(w http.ResponseWriter, r *http.Request) {
+	search, err := parseCatSearch(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	page, err := h.catQueries.SearchCats(search, time.Now())
 	if err != nil {
-		http.Error(w, "error encoding cat to JSON", http.StatusInternalServerError)
+		if errors.Is(err, queries.ErrInvalidCursor) || errors.Is(err, queries.ErrInvalidCatSearchSort) || errors.Is(err, queries.ErrMissingSearchOrigin) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "error searching cats", http.StatusInternalServerError)
 		return
 	}
+	if page.Cats == nil {
+		page.Cats = []queries.CatSearchResult{}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(page)
+}
+
+// parseSearchOrigin reads the location a search measures distances from,
+// given either as a postal code or as coordinates, and its radius.
+func parseSearchOrigin(params url.Values) (*geo.Point, *float64, error) {
+	var near *geo.Point
+
+	if cp := params.Get("cp"); cp != "" {
+		point, err := geo.Lookup(cp)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unknown cp %s", cp)
+		}
+		near = &point
+	} else if params.Get("lat") != "" || params.Get("lng") != "" {
+		latitude, err := parseFiniteFloat(params.Get("lat"))
+		if err != nil || latitude < -90 || latitude > 90 {
+			return nil, nil, errors.New("lat must be a valid latitude")
+		}
+		longitude, err := parseFiniteFloat(params.Get("lng"))
+		if err != nil || longitude < -180 || longitude > 180 {
+			return nil, nil, errors.New("lng must be a valid longitude")
+		}
+		near = &geo.Point{Latitude: latitude, Longitude: longitude}
+	}
+
+	if params.Get("radiusKm") == "" {
+		return near, nil, nil
+	}
+	radiusKm, err := parseFiniteFloat(params.Get("radiusKm"))
+	if err != nil || radiusKm <= 0 {
+		return nil, nil, errors.New("radiusKm must be a positive number")
+	}
+	return near, &radiusKm, nil
+}
+
+// parseFiniteFloat parses a number, rejecting the NaN and infinities that
+// strconv.ParseFloat accepts.
+func parseFiniteFloat(value string) (float64, error) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("%s is not a finite number", value)
+	}
+	return number, nil
+}
+
+func parseCatSearch(params url.Values) (queries.CatSearch, error) {
+	search := queries.CatSearch{
+		RaceID:        params.Get("raceId"),
+		Sexe:          params.Get("sexe"),
+		Color:         params.Get("color"),
+		Behavior:      params.Get("behavior"),
+		AssociationID: params.Get("associationId"),
+		Sort:          queries.CatSearchSort(params.Get("sort")),
+		Cursor:        params.Get("cursor"),
+	}
+
+	near, radiusKm, err := parseSearchOrigin(params)
+	if err != nil {
+		return search, err
+	}
+	search.Near, search.RadiusKm = near, radiusKm
+
+	search.Breed, err = parseRaceFilter(params)
+	if err != nil {
+		return search, err
+	}
+	search.Breed.Query = strings.TrimSpace(params.Get("breed"))
+
+	intParams := map[string]**int{"minAge": &search.MinAgeMonths, "maxAge": &search.MaxAgeMonths}
+	for name, target := range intParams {
+		if params.Get(name) == "" {
+			continue
+		}
+		value, err := strconv.Atoi(params.Get(name))
+		if err != nil || value < 0 {
+			return search, fmt.Errorf("%s must be a positive number of months", name)
+		}
+		*target = &value
+	}
+	if search.MinAgeMonths != nil && search.MaxAgeMonths != nil && *search.MinAgeMonths > *search.MaxAgeMonths {
+		return search, errors.New("minAge must be lower than maxAge")
+	}
+
+	if params.Get("status") != "" {
+		search.Status = models.CatStatus(params.Get("status"))
+		if !search.Status.Valid() {
+			return search, errors.New("status must be available, foster, reserved, adopted, deceased or returned")
+		}
+	}
+
+	boolParams := map[string]**bool{"sterilized": &search.Sterilized}
+	for name, target := range boolParams {
+		if params.Get(name) == "" {
+			continue
+		}
+		value, err := strconv.ParseBool(params.Get(name))
+		if err != nil {
+			return search, fmt.Errorf("%s must be true or false", name)
+		}
+		*target = &value
+	}
+
+	if params.Get("vaccinatedSince") != "" {
+		since, err := time.Parse("2006-01-02", params.Get("vaccinatedSince"))
+		if err != nil {
+			return search, errors.New("vaccinatedSince must be formatted as YYYY-MM-DD")
+		}
+		search.VaccinatedSince = &since
+	}
+
+	if params.Get("limit") != "" {
+		limit, err := strconv.Atoi(params.Get("limit"))
+		if err != nil || limit <= 0 {
+			return search, errors.New("limit must be a positive number")
+		}
+		search.Limit = limit
+	}
+
+	return search, nil
 }
 
 // GetCatsByUserHandler godoc
@@ -617,6 +840,30 @@ func (h *CatHandler) GetCatsByUserHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// photosFromURLs makes gallery pictures out of URLs sent as JSON, which have
+// no variants. Only files of the store are accepted, so that a gallery never
+// points to a server we do not control.
+func photosFromURLs(ctx context.Context, store storage.BlobStore, urls []string) ([]models.CatPhoto, error) {
+	var photos []models.CatPhoto
+	for _, pictureURL := range urls {
+		key, ok := store.Key(pictureURL)
+		if !ok {
+			return nil, fmt.Errorf("uploaded_file must only reference uploaded files: %q is not one", pictureURL)
+		}
+		photoURL := store.URL(key)
+		photos = append(photos, models.CatPhoto{URL: photoURL, Hash: storedImageHash(ctx, store, photoURL)})
+	}
+	return photos, nil
+}
+
+func photoURLs(photos []models.CatPhoto) pq.StringArray {
+	var urls pq.StringArray
+	for _, photo := range photos {
+		urls = append(urls, photo.URL)
+	}
+	return urls
+}
+
 func convertInterfaceSliceToStringSlice(input []interface{}) []string {
 	var output []string
 	for _, v := range input {
@@ -644,7 +891,7 @@ func (h *CatHandler) GetAnnoncesByCatIDHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	annonces, err := h.catQueries.FindAnnoncesByCatID(catID)
+	catAnnonces, err := h.catQueries.FindAnnoncesByCatID(catID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			http.Error(w, fmt.Sprintf("No annonces found for cat ID %s", catID), http.StatusNotFound)
@@ -653,9 +900,36 @@ func (h *CatHandler) GetAnnoncesByCatIDHandler(w http.ResponseWriter, r *http.Re
 		http.Error(w, "Error fetching annonces", http.StatusInternalServerError)
 		return
 	}
+	annonces := []models.Annonce{}
+	for i := range catAnnonces {
+		if canSeeAnnonce(h.catQueries, &catAnnonces[i], r) {
+			annonces = append(annonces, catAnnonces[i])
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	if err := json.NewEncoder(w).Encode(annonces); err != nil {
 		http.Error(w, "Error encoding annonces to JSON", http.StatusInternalServerError)
 	}
 }
+
+// locateCat copies the location of the association the cat is published as,
+// or of its owner when it is published in their own name.
+func (h *CatHandler) locateCat(cat *models.Cats) {
+	cat.Latitude, cat.Longitude = nil, nil
+
+	if cat.PublishedAs != "" {
+		associationID, err := strconv.Atoi(cat.PublishedAs)
+		if err != nil {
+			return
+		}
+		if association, err := h.catQueries.FindAssociationById(associationID); err == nil {
+			cat.Latitude, cat.Longitude = association.Latitude, association.Longitude
+		}
+		return
+	}
+
+	if owner, err := h.catQueries.FindUserByID(cat.UserID); err == nil {
+		cat.Latitude, cat.Longitude = owner.Latitude, owner.Longitude
+	}
+}
@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+	"go-challenge/internal/storage"
+	"go-challenge/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	maxCatPhotos          = 10
+	maxCatPhotoCaptionLen = 250
+)
+
+type CatPhotoHandler struct {
+	catPhotoQueries *queries.DatabaseService
+	store           storage.BlobStore
+}
+
+func NewCatPhotoHandler(catPhotoQueries *queries.DatabaseService, store storage.BlobStore) *CatPhotoHandler {
+	return &CatPhotoHandler{catPhotoQueries: catPhotoQueries, store: store}
+}
+
+// GetCatPhotosHandler godoc
+// @Summary Get the photos of a cat
+// @Description Retrieve the gallery of a cat in display order
+// @Tags cats
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Success 200 {array} models.CatPhoto "Gallery"
+// @Failure 404 {string} string "cat not found"
+// @Failure 500 {string} string "error fetching photos"
+// @Router /cats/{id}/photos [get]
+func (h *CatPhotoHandler) GetCatPhotosHandler(w http.ResponseWriter, r *http.Request) {
+	cat, err := h.catPhotoQueries.FindCatByID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "cat not found", http.StatusNotFound)
+		return
+	}
+
+	photos, err := h.catPhotoQueries.FindCatPhotos(cat.ID)
+	if err != nil {
+		http.Error(w, "error fetching photos", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(photos)
+}
+
+// AddCatPhotosHandler godoc
+// @Summary Add photos to a cat
+// @Description Upload one or more photos at the end of the gallery of a cat
+// @Tags cats
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Param uploaded_file formData file true "Images"
+// @Success 201 {array} models.CatPhoto "Photos added"
+// @Failure 400 {string} string "Missing files, invalid image or too many photos"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "cat not found"
+// @Failure 500 {string} string "error adding photos"
+// @Router /cats/{id}/photos [post]
+func (h *CatPhotoHandler) AddCatPhotosHandler(w http.ResponseWriter, r *http.Request) {
+	cat, _, ok := findManagedCat(h.catPhotoQueries, w, r)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10 MB is the max memory size
+		http.Error(w, "invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	files := r.MultipartForm.File["uploaded_file"]
+	if len(files) == 0 {
+		http.Error(w, "at least one uploaded_file is required", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := h.catPhotoQueries.FindCatPhotos(cat.ID)
+	if err != nil {
+		http.Error(w, "error fetching photos", http.StatusInternalServerError)
+		return
+	}
+	if len(existing)+len(files) > maxCatPhotos {
+		http.Error(w, fmt.Sprintf("a cat can have at most %d photos", maxCatPhotos), http.StatusBadRequest)
+		return
+	}
+
+	pictures, err := uploadCatPictures(r.Context(), h.store, files)
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+
+	photos, err := h.catPhotoQueries.AddCatPhotos(cat.ID, pictures)
+	if err != nil {
+		deleteCatPictures(r.Context(), h.store, pictures)
+		http.Error(w, "error adding photos", http.StatusInternalServerError)
+		return
+	}
+	go CheckAddedPhotos(h.catPhotoQueries, cat, photos)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(photos)
+}
+
+// ReorderCatPhotosHandler godoc
+// @Summary Reorder the photos of a cat
+// @Description Set the display order of the gallery of a cat, listing every photo ID once
+// @Tags cats
+// @Accept json
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Success 200 {array} models.CatPhoto "Gallery"
+// @Failure 400 {string} string "the order must list every photo of the cat exactly once"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "cat not found"
+// @Failure 500 {string} string "error reordering photos"
+// @Router /cats/{id}/photos/order [put]
+func (h *CatPhotoHandler) ReorderCatPhotosHandler(w http.ResponseWriter, r *http.Request) {
+	cat, _, ok := findManagedCat(h.catPhotoQueries, w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		PhotoIDs []uint `json:"photoIds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.catPhotoQueries.ReorderCatPhotos(cat.ID, body.PhotoIDs); err != nil {
+		if errors.Is(err, queries.ErrInvalidPhotoOrder) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "error reordering photos", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeGallery(w, cat.ID)
+}
+
+// SetCatCoverPhotoHandler godoc
+// @Summary Set the cover photo of a cat
+// @Description Make a photo the cover of the gallery, shown on the annonces of the cat
+// @Tags cats
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Param photoID path string true "Photo ID"
+// @Success 200 {array} models.CatPhoto "Gallery"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "photo not found"
+// @Failure 500 {string} string "error setting cover photo"
+// @Router /cats/{id}/photos/{photoID}/cover [put]
+func (h *CatPhotoHandler) SetCatCoverPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	photo, ok := h.findManagedPhoto(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.catPhotoQueries.SetCatCoverPhoto(photo); err != nil {
+		http.Error(w, "error setting cover photo", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeGallery(w, photo.CatID)
+}
+
+// UpdateCatPhotoHandler godoc
+// @Summary Update the caption of a photo
+// @Description Set or clear the caption of a photo of a cat
+// @Tags cats
+// @Accept json
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Param photoID path string true "Photo ID"
+// @Success 200 {object} models.CatPhoto "Photo updated"
+// @Failure 400 {string} string "caption is too long"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "photo not found"
+// @Failure 500 {string} string "error updating photo"
+// @Router /cats/{id}/photos/{photoID} [put]
+func (h *CatPhotoHandler) UpdateCatPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	photo, ok := h.findManagedPhoto(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Caption string `json:"caption"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if len(body.Caption) > maxCatPhotoCaptionLen {
+		http.Error(w, fmt.Sprintf("caption must be at most %d characters", maxCatPhotoCaptionLen), http.StatusBadRequest)
+		return
+	}
+
+	photo.Caption = body.Caption
+	if err := h.catPhotoQueries.UpdateCatPhotoCaption(photo); err != nil {
+		http.Error(w, "error updating photo", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(photo)
+}
+
+// DeleteCatPhotoHandler godoc
+// @Summary Delete a photo of a cat
+// @Description Remove a photo from the gallery, and its files from the storage unless something else still uses them
+// @Tags cats
+// @Param id path string true "Cat ID"
+// @Param photoID path string true "Photo ID"
+// @Success 204 "No Content"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "photo not found"
+// @Failure 500 {string} string "error deleting photo"
+// @Router /cats/{id}/photos/{photoID} [delete]
+func (h *CatPhotoHandler) DeleteCatPhotoHandler(w http.ResponseWriter, r *http.Request) {
+	photo, ok := h.findManagedPhoto(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.catPhotoQueries.DeleteCatPhoto(photo); err != nil {
+		http.Error(w, "error deleting photo", http.StatusInternalServerError)
+		return
+	}
+
+	deleteUnusedCatPictures(r.Context(), h.catPhotoQueries, h.store, []models.CatPhoto{*photo})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findManagedPhoto loads the photo of the route and writes the error response
+// when it does not exist or the current user may not edit its cat.
+func (h *CatPhotoHandler) findManagedPhoto(w http.ResponseWriter, r *http.Request) (*models.CatPhoto, bool) {
+	cat, _, ok := findManagedCat(h.catPhotoQueries, w, r)
+	if !ok {
+		return nil, false
+	}
+
+	photo, err := h.catPhotoQueries.FindCatPhoto(cat.ID, chi.URLParam(r, "photoID"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "photo not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "error fetching photo", http.StatusInternalServerError)
+		return nil, false
+	}
+	return photo, true
+}
+
+func (h *CatPhotoHandler) writeGallery(w http.ResponseWriter, catID uint) {
+	photos, err := h.catPhotoQueries.FindCatPhotos(catID)
+	if err != nil {
+		http.Error(w, "error fetching photos", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(photos)
+}
+
+// uploadCatPictures uploads the pictures sent for a cat. When one of them
+// fails, the ones already uploaded are removed so no file is left behind.
+func uploadCatPictures(ctx context.Context, store storage.BlobStore, files []*multipart.FileHeader) ([]models.CatPhoto, error) {
+	var pictures []models.CatPhoto
+	for _, header := range files {
+		image, err := uploadImage(ctx, store, header)
+		if err != nil {
+			deleteCatPictures(ctx, store, pictures)
+			return nil, err
+		}
+		pictures = append(pictures, models.CatPhoto{URL: image.URL, MediumURL: image.MediumURL, ThumbnailURL: image.ThumbnailURL, Hash: image.Hash})
+	}
+	return pictures, nil
+}
+
+// deleteCatPictures removes the files of cat photos from the storage. It is
+// meant for pictures just uploaded, see deleteUnusedCatPictures for photos
+// removed from a gallery.
+func deleteCatPictures(ctx context.Context, store storage.BlobStore, pictures []models.CatPhoto) {
+	for _, picture := range pictures {
+		deleteImageFiles(ctx, store, picture.URL, picture.MediumURL, picture.ThumbnailURL)
+	}
+}
+
+// deleteUnusedCatPictures removes the files of photos removed from a gallery,
+// except those still referenced: a URL of the store can be attached to the
+// gallery of another cat, or be the picture of a breed, an association or a
+// user. The files are kept when the references cannot be checked.
+func deleteUnusedCatPictures(ctx context.Context, q *queries.DatabaseService, store storage.BlobStore, pictures []models.CatPhoto) {
+	var urls []string
+	for _, picture := range pictures {
+		urls = append(urls, picture.URL, picture.MediumURL, picture.ThumbnailURL)
+	}
+	referenced, err := q.FindReferencedImageURLs(urls)
+	if err != nil {
+		utils.Logger("error", "Delete Image:", "Failed to check the references of files", fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	var unused []string
+	for _, url := range urls {
+		if !referenced[url] {
+			unused = append(unused, url)
+		}
+	}
+	deleteImageFiles(ctx, store, unused...)
+}
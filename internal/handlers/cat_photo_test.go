@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+	"go-challenge/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// picturesRequest returns a request sending pictures for the cat 7.
+func picturesRequest(t *testing.T, method string, pictures int) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for i := 0; i < pictures; i++ {
+		part, err := form.CreateFormFile("uploaded_file", "felix.png")
+		require.NoError(t, err)
+		_, err = part.Write(pictureData(t))
+		require.NoError(t, err)
+	}
+	require.NoError(t, form.Close())
+
+	r := httptest.NewRequest(method, "/cats/7/photos", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	return withURLParams(withSubject(r, "owner", models.UserRole), map[string]string{"id": "7"})
+}
+
+func stubCat(db *dbtest.DB) {
+	db.On(`FROM "cats"`, dbtest.Result{Columns: []string{"id", "name", "user_id"}, Values: [][]driver.Value{{int64(7), "Félix", "owner"}}})
+}
+
+func TestUploadCatPicturesRemovesEarlierPictures(t *testing.T) {
+	dir := t.TempDir()
+	local, err := storage.NewLocal(dir, "http://localhost:8080/uploads", []byte("secret"))
+	require.NoError(t, err)
+
+	// The first picture and its variants are stored, the second one fails
+	_, err = uploadCatPictures(context.Background(), &failingStore{BlobStore: local, puts: 4}, []*multipart.FileHeader{pictureHeader(t), pictureHeader(t)})
+
+	require.Error(t, err)
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestAddCatPhotosHandlerRemovesUploadsOnError(t *testing.T) {
+	tests := []struct {
+		name  string
+		store func(storage.BlobStore) storage.BlobStore
+		stub  func(*dbtest.DB)
+		code  int
+	}{
+		{
+			name:  "failed upload",
+			store: func(local storage.BlobStore) storage.BlobStore { return &failingStore{BlobStore: local, puts: 3} },
+			stub:  func(*dbtest.DB) {},
+			code:  http.StatusInternalServerError,
+		},
+		{
+			name:  "failed insert",
+			store: func(local storage.BlobStore) storage.BlobStore { return local },
+			stub: func(db *dbtest.DB) {
+				db.On(`INSERT INTO "cat_photos"`, dbtest.Result{Err: errors.New("connection lost")})
+			},
+			code: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			local, err := storage.NewLocal(dir, "http://localhost:8080/uploads", []byte("secret"))
+			require.NoError(t, err)
+			q, db := newTestQueries()
+			tt.stub(db)
+			stubCat(db)
+			w := httptest.NewRecorder()
+
+			NewCatPhotoHandler(q, tt.store(local)).AddCatPhotosHandler(w, picturesRequest(t, http.MethodPost, 2))
+
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+			files, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Empty(t, files)
+		})
+	}
+}
+
+func TestDeleteCatHandlerRemovesPictures(t *testing.T) {
+	dir := t.TempDir()
+	store, err := storage.NewLocal(dir, "http://localhost:8080/uploads", []byte("secret"))
+	require.NoError(t, err)
+	image, err := uploadImage(context.Background(), store, pictureHeader(t))
+	require.NoError(t, err)
+	q, db := newTestQueries()
+	stubCat(db)
+	db.On(`FROM "cat_photos"`, dbtest.Result{Columns: []string{"id", "cat_id", "url", "medium_url", "thumbnail_url"}, Values: [][]driver.Value{
+		{int64(1), int64(7), image.URL, image.MediumURL, image.ThumbnailURL},
+	}})
+	r := httptest.NewRequest(http.MethodDelete, "/cats/7", nil)
+	r = withURLParams(withSubject(r, "owner", models.UserRole), map[string]string{"id": "7"})
+	w := httptest.NewRecorder()
+
+	NewCatHandler(q, store).DeleteCatHandler(w, r)
+
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	assert.Len(t, db.Queries(`UPDATE "cat_photos" SET "deleted_at"`), 1)
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestCatCreationHandlerRemovesUploadsOnError(t *testing.T) {
+	dir := t.TempDir()
+	store, err := storage.NewLocal(dir, "http://localhost:8080/uploads", []byte("secret"))
+	require.NoError(t, err)
+	q, db := newTestQueries()
+	r := picturesRequest(t, http.MethodPost, 2)
+	w := httptest.NewRecorder()
+
+	// The pictures are uploaded, but the cat is missing its required fields
+	NewCatHandler(q, store).CatCreationHandler(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+	assert.Empty(t, db.Queries(`INSERT INTO "cats"`))
+}
+
+func TestDeleteCatPhotoHandlerKeepsReferencedPictures(t *testing.T) {
+	dir := t.TempDir()
+	store, err := storage.NewLocal(dir, "http://localhost:8080/uploads", []byte("secret"))
+	require.NoError(t, err)
+	image, err := uploadImage(context.Background(), store, pictureHeader(t))
+	require.NoError(t, err)
+	q, db := newTestQueries()
+	stubCat(db)
+	db.On(`FROM "cat_photos"`, dbtest.Result{Columns: []string{"id", "cat_id", "url", "medium_url", "thumbnail_url"}, Values: [][]driver.Value{
+		{int64(1), int64(7), image.URL, image.MediumURL, image.ThumbnailURL},
+	}})
+	// The full size picture is also the photo of the cat of another owner
+	db.On(`UNION`, dbtest.Result{Columns: []string{"url"}, Values: [][]driver.Value{{image.URL}}})
+	r := httptest.NewRequest(http.MethodDelete, "/cats/7/photos/1", nil)
+	r = withURLParams(withSubject(r, "owner", models.UserRole), map[string]string{"id": "7", "photoID": "1"})
+	w := httptest.NewRecorder()
+
+	NewCatPhotoHandler(q, store).DeleteCatPhotoHandler(w, r)
+
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	key, ok := store.Key(image.URL)
+	require.True(t, ok)
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, key, files[0].Name())
+}
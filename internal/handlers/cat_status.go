@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+	"go-challenge/internal/policy"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxCatStatusNoteLength = 250
+
+type CatStatusHandler struct {
+	catStatusQueries *queries.DatabaseService
+}
+
+func NewCatStatusHandler(catStatusQueries *queries.DatabaseService) *CatStatusHandler {
+	return &CatStatusHandler{catStatusQueries: catStatusQueries}
+}
+
+// associationStatusHistory is the status history of the cats of an
+// association, with the number of changes to each status over the period.
+type associationStatusHistory struct {
+	Changes []models.CatStatusChange `json:"changes"`
+	Totals  map[models.CatStatus]int `json:"totals"`
+}
+
+// ChangeCatStatusHandler godoc
+// @Summary Change the status of a cat
+// @Description Move a cat to another status (available, foster, reserved, adopted, deceased or returned) and record the change. A cat leaving the foster status ends its foster placement.
+// @Tags cats
+// @Accept json
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Success 200 {object} models.CatStatusChange "Status changed"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "cat not found"
+// @Failure 409 {string} string "the transition is not allowed"
+// @Failure 500 {string} string "error changing cat status"
+// @Router /cats/{id}/status [put]
+func (h *CatStatusHandler) ChangeCatStatusHandler(w http.ResponseWriter, r *http.Request) {
+	cat, userID, ok := findManagedCat(h.catStatusQueries, w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Status models.CatStatus `json:"status"`
+		Note   string           `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if !body.Status.Valid() {
+		http.Error(w, "status must be available, foster, reserved, adopted, deceased or returned", http.StatusBadRequest)
+		return
+	}
+	if len(body.Note) > maxCatStatusNoteLength {
+		http.Error(w, fmt.Sprintf("note must be at most %d characters", maxCatStatusNoteLength), http.StatusBadRequest)
+		return
+	}
+	if !cat.Status.CanTransitionTo(body.Status) {
+		http.Error(w, fmt.Sprintf("cannot change the status of a cat from %s to %s", cat.Status, body.Status), http.StatusConflict)
+		return
+	}
+
+	change, err := h.catStatusQueries.ChangeCatStatus(cat, body.Status, userID, body.Note)
+	if err != nil {
+		if errors.Is(err, queries.ErrCatStatusChanged) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "error changing cat status", http.StatusInternalServerError)
+		return
+	}
+
+	if cat.Status.Adoptable() {
+		go MatchSavedSearches(h.catStatusQueries, fmt.Sprintf("%d", cat.ID))
+	}
+	go NotifyCatFavorites(h.catStatusQueries, cat)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(change)
+}
+
+// GetCatStatusHistoryHandler godoc
+// @Summary Get the status history of a cat
+// @Description Retrieve the status changes of a cat, most recent first, for its owner or association
+// @Tags cats
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Success 200 {array} models.CatStatusChange "Status history"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "cat not found"
+// @Failure 500 {string} string "error fetching status history"
+// @Router /cats/{id}/status-history [get]
+func (h *CatStatusHandler) GetCatStatusHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	cat, _, ok := findManagedCat(h.catStatusQueries, w, r)
+	if !ok {
+		return
+	}
+
+	changes, err := h.catStatusQueries.FindCatStatusChanges(cat.ID)
+	if err != nil {
+		http.Error(w, "error fetching status history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(changes)
+}
+
+// GetAssociationStatusHistoryHandler godoc
+// @Summary Get the status history of the cats of an association
+// @Description Retrieve the status changes of the cats published by an association, with totals per status, for its owner and members
+// @Tags associations
+// @Produce json
+// @Param id path string true "Association ID"
+// @Param status query string false "Only changes to this status"
+// @Param since query string false "Changes from this day (YYYY-MM-DD)"
+// @Param until query string false "Changes before this day (YYYY-MM-DD)"
+// @Success 200 {object} associationStatusHistory "Status history"
+// @Failure 400 {string} string "Invalid filters"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "association not found"
+// @Failure 500 {string} string "error fetching status history"
+// @Router /associations/{id}/status-history [get]
+func (h *CatStatusHandler) GetAssociationStatusHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	associationID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid association ID", http.StatusBadRequest)
+		return
+	}
+	association, err := h.catStatusQueries.FindAssociationById(associationID)
+	if err != nil {
+		http.Error(w, "association not found", http.StatusNotFound)
+		return
+	}
+	if !subject.IsAdmin() && !policy.IsAssociationMember(association, subject.UserID) {
+		http.Error(w, "only the owner and members of the association can access its reports", http.StatusForbidden)
+		return
+	}
+
+	params := r.URL.Query()
+	filter := queries.CatStatusHistoryFilter{
+		AssociationID: strconv.Itoa(associationID),
+		Status:        models.CatStatus(params.Get("status")),
+	}
+	if filter.Status != "" && !filter.Status.Valid() {
+		http.Error(w, "status must be available, foster, reserved, adopted, deceased or returned", http.StatusBadRequest)
+		return
+	}
+	for name, target := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if params.Get(name) == "" {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", params.Get(name))
+		if err != nil {
+			http.Error(w, name+" must be formatted as YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		*target = &day
+	}
+
+	changes, err := h.catStatusQueries.FindAssociationCatStatusChanges(filter)
+	if err != nil {
+		http.Error(w, "error fetching status history", http.StatusInternalServerError)
+		return
+	}
+
+	history := associationStatusHistory{Changes: changes, Totals: map[models.CatStatus]int{}}
+	for _, change := range changes {
+		history.Totals[change.ToStatus]++
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(history)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+	"go-challenge/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindCatsByFilterHandler(t *testing.T) {
+	store, err := storage.NewLocal(t.TempDir(), "http://localhost:8080/uploads", []byte("secret"))
+	require.NoError(t, err)
+	q, db := newTestQueries()
+	db.On(`FROM "annonces"`, dbtest.Result{Columns: []string{"id", "title", "cat_id", "status"}, Values: [][]driver.Value{
+		{int64(12), "Félix, câlin", "7", string(models.AnnoncePublished)},
+		{int64(11), "Félix", "7", string(models.AnnoncePublished)},
+		{int64(13), "Minette", "9", string(models.AnnoncePublished)},
+	}})
+	db.On(`FROM "cats"`, dbtest.Result{Columns: []string{"id", "name", "sexe", "race_id"}, Values: [][]driver.Value{
+		{int64(7), "Félix", "female", "2"},
+		{int64(8), "Tigrou", "female", "2"},
+		{int64(9), "Minette", "female", "2"},
+	}})
+	r := httptest.NewRequest(http.MethodGet, "/cats/?sexe=female&raceId=2&age=1", nil)
+	w := httptest.NewRecorder()
+
+	NewCatHandler(q, store).FindCatsByFilterHandler(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var annonces []models.Annonce
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&annonces))
+	require.Len(t, annonces, 2)
+	assert.Equal(t, uint(12), annonces[0].ID)
+	assert.Equal(t, uint(13), annonces[1].ID)
+
+	searches := db.Queries(`FROM "cats"`)
+	require.NotEmpty(t, searches)
+	assert.Contains(t, searches[0].SQL, "race_id = $1")
+	assert.Contains(t, searches[0].SQL, "LOWER(sexe) = LOWER($2)")
+	assert.Contains(t, searches[0].SQL, "birth_date")
+	assert.Contains(t, searches[0].SQL, "EXISTS (SELECT 1 FROM annonces")
+	lookups := db.Queries(`FROM "annonces"`)
+	require.Len(t, lookups, 1)
+	assert.Equal(t, []driver.Value{"7", "8", "9", string(models.AnnoncePublished)}, lookups[0].Args)
+}
+
+func TestFindCatsByFilterHandlerWithoutResults(t *testing.T) {
+	store, err := storage.NewLocal(t.TempDir(), "http://localhost:8080/uploads", []byte("secret"))
+	require.NoError(t, err)
+	q, db := newTestQueries()
+	r := httptest.NewRequest(http.MethodGet, "/cats/?sexe=male", nil)
+	w := httptest.NewRecorder()
+
+	NewCatHandler(q, store).FindCatsByFilterHandler(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, db.Queries(`FROM "annonces"`))
+}
+
+func TestParseSearchOriginRejectsNonFiniteNumbers(t *testing.T) {
+	tests := []struct {
+		query string
+		err   bool
+	}{
+		{query: "lat=48.85&lng=2.35&radiusKm=10"},
+		{query: "lat=NaN&lng=2.35", err: true},
+		{query: "lat=48.85&lng=NaN", err: true},
+		{query: "lat=48.85&lng=+Inf", err: true},
+		{query: "cp=75011&radiusKm=NaN", err: true},
+		{query: "cp=75011&radiusKm=Inf", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			params, err := url.ParseQuery(tt.query)
+			require.NoError(t, err)
+
+			_, _, err = parseSearchOrigin(params)
+
+			if tt.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,468 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+	"go-challenge/internal/policy"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	litterDateLayout        = "02-01-2006"
+	maxLitterKittens        = 12
+	maxLitterDescriptionLen = 250
+)
+
+type FamilyHandler struct {
+	familyQueries *queries.DatabaseService
+}
+
+func NewFamilyHandler(familyQueries *queries.DatabaseService) *FamilyHandler {
+	return &FamilyHandler{familyQueries: familyQueries}
+}
+
+type litterRequest struct {
+	Name        string `json:"name"`
+	BirthDate   string `json:"birthDate"`
+	MotherID    *uint  `json:"motherId"`
+	FatherID    *uint  `json:"fatherId"`
+	Description string `json:"description"`
+	PublishedAs string `json:"publishedAs"`
+	// CatIDs replaces the kittens of the litter when it is set.
+	CatIDs []uint `json:"catIds"`
+}
+
+type parentsRequest struct {
+	MotherID *uint `json:"motherId"`
+	FatherID *uint `json:"fatherId"`
+}
+
+// familyError is a request rejected for the family relations it would make.
+type familyError struct {
+	status  int
+	message string
+}
+
+func (e *familyError) Error() string {
+	return e.message
+}
+
+// GetCatFamilyHandler godoc
+// @Summary Get the family of a cat
+// @Description Retrieve the parents, litter, siblings and kittens of a cat
+// @Tags cats
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Success 200 {object} models.CatFamily "Family"
+// @Failure 404 {string} string "cat not found"
+// @Failure 500 {string} string "error fetching family"
+// @Router /cats/{id}/family [get]
+func (h *FamilyHandler) GetCatFamilyHandler(w http.ResponseWriter, r *http.Request) {
+	cat, err := h.familyQueries.FindCatByID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "cat not found", http.StatusNotFound)
+		return
+	}
+
+	family, err := h.familyQueries.FindCatFamily(cat)
+	if err != nil {
+		http.Error(w, "error fetching family", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(family)
+}
+
+// SetCatParentsHandler godoc
+// @Summary Set the parents of a cat
+// @Description Set or clear the mother and father of a cat
+// @Tags cats
+// @Accept json
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Success 200 {object} models.Cats "Cat updated"
+// @Failure 400 {string} string "Invalid parents"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "cat not found"
+// @Failure 500 {string} string "error setting parents"
+// @Router /cats/{id}/parents [put]
+func (h *FamilyHandler) SetCatParentsHandler(w http.ResponseWriter, r *http.Request) {
+	cat, _, ok := findManagedCat(h.familyQueries, w, r)
+	if !ok {
+		return
+	}
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	var body parentsRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := h.checkParents(subject, body.MotherID, body.FatherID, []uint{cat.ID}); err != nil {
+		writeFamilyError(w, err)
+		return
+	}
+
+	if err := h.familyQueries.SetCatParents(cat, body.MotherID, body.FatherID); err != nil {
+		http.Error(w, "error setting parents", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(cat)
+}
+
+// CreateLitterHandler godoc
+// @Summary Create a litter
+// @Description Group kittens born together, optionally with their parents
+// @Tags litters
+// @Accept json
+// @Produce json
+// @Success 201 {object} models.Litter "Litter created"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "error creating litter"
+// @Router /litters [post]
+func (h *FamilyHandler) CreateLitterHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	var body litterRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if body.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if body.PublishedAs != "" && !policy.CanPublishAs(subject, publishingAssociation(h.familyQueries, body.PublishedAs)) {
+		http.Error(w, "only the owner and admins of a verified association can publish litters as the association", http.StatusForbidden)
+		return
+	}
+
+	litter := &models.Litter{UserID: subject.UserID, PublishedAs: body.PublishedAs}
+	if err := h.applyLitterRequest(litter, &body); err != nil {
+		writeFamilyError(w, err)
+		return
+	}
+	if err := h.checkKittens(subject, body.CatIDs); err != nil {
+		writeFamilyError(w, err)
+		return
+	}
+	if err := h.checkParents(subject, litter.MotherID, litter.FatherID, body.CatIDs); err != nil {
+		writeFamilyError(w, err)
+		return
+	}
+
+	if err := h.familyQueries.CreateLitter(litter); err != nil {
+		http.Error(w, "error creating litter", http.StatusInternalServerError)
+		return
+	}
+	if err := h.familyQueries.SetLitterKittens(litter, body.CatIDs); err != nil {
+		http.Error(w, "error adding kittens", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeLitter(w, litter, http.StatusCreated)
+}
+
+// GetAvailableLittersHandler godoc
+// @Summary Get litters up for adoption
+// @Description Retrieve the litters with at least one kitten up for adoption, with their kittens
+// @Tags litters
+// @Produce json
+// @Success 200 {array} models.Litter "Litters"
+// @Failure 500 {string} string "error fetching litters"
+// @Router /litters [get]
+func (h *FamilyHandler) GetAvailableLittersHandler(w http.ResponseWriter, r *http.Request) {
+	litters, err := h.familyQueries.FindAvailableLitters()
+	if err != nil {
+		http.Error(w, "error fetching litters", http.StatusInternalServerError)
+		return
+	}
+	for i := range litters {
+		if litters[i].Kittens, err = h.familyQueries.FindLitterKittens(litters[i].ID); err != nil {
+			http.Error(w, "error fetching kittens", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(litters)
+}
+
+// GetLitterHandler godoc
+// @Summary Get a litter
+// @Description Retrieve a litter with its kittens
+// @Tags litters
+// @Produce json
+// @Param id path string true "Litter ID"
+// @Success 200 {object} models.Litter "Litter"
+// @Failure 404 {string} string "litter not found"
+// @Failure 500 {string} string "error fetching litter"
+// @Router /litters/{id} [get]
+func (h *FamilyHandler) GetLitterHandler(w http.ResponseWriter, r *http.Request) {
+	litter, err := h.familyQueries.FindLitterByID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeLitterLookupError(w, err)
+		return
+	}
+
+	h.writeLitter(w, litter, http.StatusOK)
+}
+
+// UpdateLitterHandler godoc
+// @Summary Update a litter
+// @Description Update the details, parents or kittens of a litter. The parents are copied to the kittens.
+// @Tags litters
+// @Accept json
+// @Produce json
+// @Param id path string true "Litter ID"
+// @Success 200 {object} models.Litter "Litter updated"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "litter not found"
+// @Failure 500 {string} string "error updating litter"
+// @Router /litters/{id} [put]
+func (h *FamilyHandler) UpdateLitterHandler(w http.ResponseWriter, r *http.Request) {
+	litter, subject, ok := h.findManagedLitter(w, r)
+	if !ok {
+		return
+	}
+
+	var body litterRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if body.PublishedAs != "" && body.PublishedAs != litter.PublishedAs && !policy.CanPublishAs(subject, publishingAssociation(h.familyQueries, body.PublishedAs)) {
+		http.Error(w, "only the owner and admins of a verified association can publish litters as the association", http.StatusForbidden)
+		return
+	}
+	if body.PublishedAs != "" {
+		litter.PublishedAs = body.PublishedAs
+	}
+	if err := h.applyLitterRequest(litter, &body); err != nil {
+		writeFamilyError(w, err)
+		return
+	}
+
+	catIDs := body.CatIDs
+	if catIDs == nil {
+		kittens, err := h.familyQueries.FindLitterKittens(litter.ID)
+		if err != nil {
+			http.Error(w, "error fetching kittens", http.StatusInternalServerError)
+			return
+		}
+		for _, kitten := range kittens {
+			catIDs = append(catIDs, kitten.ID)
+		}
+	} else if err := h.checkKittens(subject, catIDs); err != nil {
+		writeFamilyError(w, err)
+		return
+	}
+	if err := h.checkParents(subject, litter.MotherID, litter.FatherID, catIDs); err != nil {
+		writeFamilyError(w, err)
+		return
+	}
+
+	if err := h.familyQueries.UpdateLitter(litter); err != nil {
+		http.Error(w, "error updating litter", http.StatusInternalServerError)
+		return
+	}
+	if err := h.familyQueries.SetLitterKittens(litter, catIDs); err != nil {
+		http.Error(w, "error updating kittens", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeLitter(w, litter, http.StatusOK)
+}
+
+// DeleteLitterHandler godoc
+// @Summary Delete a litter
+// @Description Delete a litter. Its kittens are kept with their parents.
+// @Tags litters
+// @Param id path string true "Litter ID"
+// @Success 204 "No Content"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "litter not found"
+// @Failure 500 {string} string "error deleting litter"
+// @Router /litters/{id} [delete]
+func (h *FamilyHandler) DeleteLitterHandler(w http.ResponseWriter, r *http.Request) {
+	litter, _, ok := h.findManagedLitter(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.familyQueries.DeleteLitter(litter); err != nil {
+		http.Error(w, "error deleting litter", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyLitterRequest copies the details of a request to a litter, leaving the
+// fields it does not set untouched.
+func (h *FamilyHandler) applyLitterRequest(litter *models.Litter, body *litterRequest) error {
+	if body.Name != "" {
+		litter.Name = body.Name
+	}
+	if body.BirthDate != "" {
+		birthDate, err := time.Parse(litterDateLayout, body.BirthDate)
+		if err != nil {
+			return &familyError{http.StatusBadRequest, "invalid birthDate format, expected DD-MM-YYYY"}
+		}
+		litter.BirthDate = &birthDate
+	}
+	if len(body.Description) > maxLitterDescriptionLen {
+		return &familyError{http.StatusBadRequest, fmt.Sprintf("description must be at most %d characters", maxLitterDescriptionLen)}
+	}
+	if body.Description != "" {
+		litter.Description = body.Description
+	}
+	if body.MotherID != nil {
+		litter.MotherID = body.MotherID
+	}
+	if body.FatherID != nil {
+		litter.FatherID = body.FatherID
+	}
+	return nil
+}
+
+// checkKittens makes sure the subject may manage every cat added to a litter.
+func (h *FamilyHandler) checkKittens(subject policy.Subject, catIDs []uint) error {
+	if len(catIDs) > maxLitterKittens {
+		return &familyError{http.StatusBadRequest, fmt.Sprintf("a litter can have at most %d kittens", maxLitterKittens)}
+	}
+	seen := map[uint]bool{}
+	for _, catID := range catIDs {
+		if seen[catID] {
+			return &familyError{http.StatusBadRequest, "catIds must not repeat a cat"}
+		}
+		seen[catID] = true
+
+		cat, err := h.familyQueries.FindCatByID(strconv.FormatUint(uint64(catID), 10))
+		if err != nil {
+			return &familyError{http.StatusBadRequest, fmt.Sprintf("cat %d not found", catID)}
+		}
+		if !canManageCat(h.familyQueries, cat, subject) {
+			return &familyError{http.StatusForbidden, fmt.Sprintf("only the owner of cat %d or its association can add it to a litter", catID)}
+		}
+	}
+	return nil
+}
+
+// checkParents makes sure the given parents exist, may be managed by the
+// subject, are of a fitting sex and are not among the descendants of the cats
+// they are given to.
+func (h *FamilyHandler) checkParents(subject policy.Subject, motherID, fatherID *uint, catIDs []uint) error {
+	if motherID != nil && fatherID != nil && *motherID == *fatherID {
+		return &familyError{http.StatusBadRequest, "the mother and the father must be different cats"}
+	}
+	for _, parent := range []struct {
+		id      *uint
+		role    string
+		fitting func(*models.Cats) bool
+	}{
+		{motherID, "mother", (*models.Cats).CanBeMother},
+		{fatherID, "father", (*models.Cats).CanBeFather},
+	} {
+		if parent.id == nil {
+			continue
+		}
+		cat, err := h.familyQueries.FindCatByID(strconv.FormatUint(uint64(*parent.id), 10))
+		if err != nil {
+			return &familyError{http.StatusBadRequest, fmt.Sprintf("%s not found", parent.role)}
+		}
+		if !canManageCat(h.familyQueries, cat, subject) {
+			return &familyError{http.StatusForbidden, fmt.Sprintf("only the owner of the %s or its association can give it kittens", parent.role)}
+		}
+		if !parent.fitting(cat) {
+			return &familyError{http.StatusBadRequest, fmt.Sprintf("a %s cat cannot be a %s", cat.Sexe, parent.role)}
+		}
+		for _, catID := range catIDs {
+			if catID == cat.ID {
+				return &familyError{http.StatusBadRequest, fmt.Sprintf("a cat cannot be its own %s", parent.role)}
+			}
+			isDescendant, err := h.familyQueries.IsCatAncestor(catID, cat.ID)
+			if err != nil {
+				return err
+			}
+			if isDescendant {
+				return &familyError{http.StatusBadRequest, fmt.Sprintf("the %s cannot be a descendant of cat %d", parent.role, catID)}
+			}
+		}
+	}
+	return nil
+}
+
+// findManagedLitter loads the litter of the route and writes the error
+// response when it does not exist or the current user may not edit it.
+func (h *FamilyHandler) findManagedLitter(w http.ResponseWriter, r *http.Request) (*models.Litter, policy.Subject, bool) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return nil, subject, false
+	}
+
+	litter, err := h.familyQueries.FindLitterByID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeLitterLookupError(w, err)
+		return nil, subject, false
+	}
+
+	if !canManageLitter(h.familyQueries, litter, subject) {
+		http.Error(w, "only the owner of the litter or its association can manage it", http.StatusForbidden)
+		return nil, subject, false
+	}
+	return litter, subject, true
+}
+
+func (h *FamilyHandler) writeLitter(w http.ResponseWriter, litter *models.Litter, status int) {
+	kittens, err := h.familyQueries.FindLitterKittens(litter.ID)
+	if err != nil {
+		http.Error(w, "error fetching kittens", http.StatusInternalServerError)
+		return
+	}
+	litter.Kittens = kittens
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(litter)
+}
+
+func writeLitterLookupError(w http.ResponseWriter, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "litter not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, "error fetching litter", http.StatusInternalServerError)
+}
+
+func writeFamilyError(w http.ResponseWriter, err error) {
+	var familyErr *familyError
+	if errors.As(err, &familyErr) {
+		http.Error(w, familyErr.message, familyErr.status)
+		return
+	}
+	http.Error(w, "error checking family relations", http.StatusInternalServerError)
+}
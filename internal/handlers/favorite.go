@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"go-challenge/internal/database/queries"
@@ -31,17 +32,21 @@ func NewFavoriteHandler(favoriteQueries, userQueries *queries.DatabaseService) *
 // @Accept application/x-www-form-urlencoded
 // @Produce json
 // @Param annonceID formData string true "ID of the annonce"
+// @Param collectionID formData string false "ID of the collection to sort the favorite in"
 // @Success 201 {object} models.Favorite "favorite created successfully"
 // @Failure 400 {string} string "annonceID is required"
+// @Failure 404 {string} string "collection not found"
+// @Failure 409 {string} string "the annonce is already in your favorites"
 // @Failure 500 {string} string "error creating favorite"
 // @Router /favorites [post]
 func (h *FavoriteHandler) FavoriteCreationHandler(w http.ResponseWriter, r *http.Request) {
 	contentType := r.Header.Get("Content-Type")
-	var annonceID string
+	var annonceID, collectionID string
 
 	if strings.Contains(contentType, "application/json") {
 		var data struct {
-			AnnonceID string `json:"annonceID"`
+			AnnonceID    string `json:"annonceID"`
+			CollectionID *uint  `json:"collectionID"`
 		}
 		err := json.NewDecoder(r.Body).Decode(&data)
 		if err != nil {
@@ -49,9 +54,13 @@ func (h *FavoriteHandler) FavoriteCreationHandler(w http.ResponseWriter, r *http
 			return
 		}
 		annonceID = data.AnnonceID
+		if data.CollectionID != nil {
+			collectionID = strconv.FormatUint(uint64(*data.CollectionID), 10)
+		}
 	} else {
 		r.ParseForm()
 		annonceID = r.FormValue("annonceID")
+		collectionID = r.FormValue("collectionID")
 	}
 
 	print(annonceID)
@@ -74,23 +83,46 @@ func (h *FavoriteHandler) FavoriteCreationHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	_, err = h.favoriteQueries.FindAnnonceByID(annonceID)
+	annonce, err := h.favoriteQueries.FindAnnonceByID(annonceID)
 	if err != nil {
 		http.Error(w, "error finding annonce", http.StatusInternalServerError)
 		return
 	}
 
+	if _, err := h.favoriteQueries.FindFavoriteByUserAndAnnonceID(user.ID, annonceID); err == nil {
+		http.Error(w, "the annonce is already in your favorites", http.StatusConflict)
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "error finding favorite", http.StatusInternalServerError)
+		return
+	}
+
 	favorite := &models.Favorite{
 		UserID:    user.ID,
 		AnnonceID: annonceID,
 	}
+	if collectionID != "" {
+		collection, err := h.favoriteQueries.FindFavoriteCollection(user.ID, collectionID)
+		if err != nil {
+			http.Error(w, "collection not found", http.StatusNotFound)
+			return
+		}
+		favorite.CollectionID = &collection.ID
+	}
 
 	err = h.favoriteQueries.CreateFavorite(favorite)
+	if errors.Is(err, queries.ErrFavoriteExists) {
+		// Another request favorited the annonce in the meantime
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, "error creating favorite", http.StatusInternalServerError)
 		return
 	}
 
+	recordAnnonceEvent(h.favoriteQueries, annonce.ID, models.AnnonceFavorited, userID)
+
 	authorID, err := h.userQueries.GetUserIDByAnnonceID(annonceID)
 	if err != nil {
 		http.Error(w, "error getting author ID", http.StatusInternalServerError)
@@ -124,13 +156,15 @@ func (h *FavoriteHandler) FavoriteCreationHandler(w http.ResponseWriter, r *http
 
 // GetFavoritesByUserHandler godoc
 // @Summary Get user favorites
-// @Description Get all favorites of the user
+// @Description Get all favorites of the user, the most recent first, with their annonce and cat, for the user themselves or an admin. Favorites whose annonce is closed or whose cat is no longer up for adoption are not available.
 // @Tags favorites
 // @Produce json
 // @Param userID path string true "ID of the user"
-// @Success 200 {array} models.Favorite "List of user favorites"
+// @Param collectionID query string false "Only the favorites of this collection"
+// @Success 200 {array} queries.FavoriteDetails "List of user favorites"
 // @Failure 400 {string} string "user ID is required"
-// @Failure 404 {string} string "favorites not found"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "collection not found"
 // @Failure 500 {string} string "error retrieving favorites"
 // @Router /favorites/users/{userID} [get]
 func (h *FavoriteHandler) GetFavoritesByUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -140,16 +174,38 @@ func (h *FavoriteHandler) GetFavoritesByUserHandler(w http.ResponseWriter, r *ht
 		http.Error(w, "user ID is required", http.StatusBadRequest)
 		return
 	}
-
-	favorites, err := h.favoriteQueries.FindFavoritesByUserID(userID)
+	subject, err := requestSubject(r)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			http.Error(w, fmt.Sprintf("favorites for user with ID %s not found", userID), http.StatusNotFound)
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+	if subject.UserID != userID && !subject.IsAdmin() {
+		http.Error(w, "you can only see your own favorites", http.StatusForbidden)
+		return
+	}
+
+	var collectionID *uint
+	if id := r.URL.Query().Get("collectionID"); id != "" {
+		collection, err := h.favoriteQueries.FindFavoriteCollection(userID, id)
+		if err != nil {
+			http.Error(w, "collection not found", http.StatusNotFound)
 			return
 		}
+		collectionID = &collection.ID
+	}
+
+	favorites, err := h.favoriteQueries.FindFavoriteDetails(userID, collectionID)
+	if err != nil {
 		http.Error(w, "error retrieving favorites", http.StatusInternalServerError)
 		return
 	}
+	var annonces []*models.Annonce
+	for i := range favorites {
+		if favorites[i].Annonce != nil {
+			annonces = append(annonces, favorites[i].Annonce)
+		}
+	}
+	withAnnonceDetails(h.favoriteQueries, annonces...)
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	err = json.NewEncoder(w).Encode(favorites)
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
+	"go-challenge/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	maxFavoriteCollections       = 20
+	maxFavoriteCollectionNameLen = 50
+)
+
+// validateCollectionName checks the name of a collection of a user, which
+// must not be the name of another of their collections.
+func validateCollectionName(q *queries.DatabaseService, userID, name string, id uint) (int, error) {
+	if name == "" {
+		return http.StatusBadRequest, errors.New("name is required")
+	}
+	if len(name) > maxFavoriteCollectionNameLen {
+		return http.StatusBadRequest, fmt.Errorf("name must be at most %d characters", maxFavoriteCollectionNameLen)
+	}
+	collections, err := q.FindFavoriteCollections(userID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("error fetching collections")
+	}
+	for _, collection := range collections {
+		if collection.ID != id && strings.EqualFold(collection.Name, name) {
+			return http.StatusConflict, fmt.Errorf("you already have a collection named %s", collection.Name)
+		}
+	}
+	if id == 0 && len(collections) >= maxFavoriteCollections {
+		return http.StatusBadRequest, fmt.Errorf("you can have at most %d collections", maxFavoriteCollections)
+	}
+	return 0, nil
+}
+
+// CreateFavoriteCollectionHandler godoc
+// @Summary Create a collection of favorites
+// @Description Create a named collection to sort favorites in, such as "to visit"
+// @Tags favorites
+// @Accept json
+// @Produce json
+// @Success 201 {object} models.FavoriteCollection "Collection created"
+// @Failure 400 {string} string "Missing or invalid name"
+// @Failure 409 {string} string "you already have a collection with this name"
+// @Failure 500 {string} string "error creating collection"
+// @Router /favorites/collections [post]
+func (h *FavoriteHandler) CreateFavoriteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(body.Name)
+	if status, err := validateCollectionName(h.favoriteQueries, subject.UserID, name, 0); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	collection := &models.FavoriteCollection{UserID: subject.UserID, Name: name}
+	if err := h.favoriteQueries.CreateFavoriteCollection(collection); err != nil {
+		http.Error(w, "error creating collection", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(collection)
+}
+
+// GetFavoriteCollectionsHandler godoc
+// @Summary Get my collections of favorites
+// @Description Retrieve the collections of the current user by name, with the number of favorites in each
+// @Tags favorites
+// @Produce json
+// @Success 200 {array} queries.FavoriteCollectionSummary "Collections"
+// @Failure 500 {string} string "error fetching collections"
+// @Router /favorites/collections [get]
+func (h *FavoriteHandler) GetFavoriteCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	collections, err := h.favoriteQueries.FindFavoriteCollections(subject.UserID)
+	if err != nil {
+		http.Error(w, "error fetching collections", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(collections)
+}
+
+// UpdateFavoriteCollectionHandler godoc
+// @Summary Rename a collection of favorites
+// @Description Rename a collection of the current user
+// @Tags favorites
+// @Accept json
+// @Produce json
+// @Param id path string true "Collection ID"
+// @Success 200 {object} models.FavoriteCollection "Collection renamed"
+// @Failure 400 {string} string "Missing or invalid name"
+// @Failure 404 {string} string "collection not found"
+// @Failure 409 {string} string "you already have a collection with this name"
+// @Failure 500 {string} string "error updating collection"
+// @Router /favorites/collections/{id} [put]
+func (h *FavoriteHandler) UpdateFavoriteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	collection, ok := h.findCollection(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(body.Name)
+	if status, err := validateCollectionName(h.favoriteQueries, collection.UserID, name, collection.ID); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	collection.Name = name
+	if err := h.favoriteQueries.UpdateFavoriteCollection(collection); err != nil {
+		http.Error(w, "error updating collection", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(collection)
+}
+
+// DeleteFavoriteCollectionHandler godoc
+// @Summary Delete a collection of favorites
+// @Description Delete a collection of the current user. Its favorites are kept, out of any collection.
+// @Tags favorites
+// @Param id path string true "Collection ID"
+// @Success 204 "No Content"
+// @Failure 404 {string} string "collection not found"
+// @Failure 500 {string} string "error deleting collection"
+// @Router /favorites/collections/{id} [delete]
+func (h *FavoriteHandler) DeleteFavoriteCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	collection, ok := h.findCollection(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.favoriteQueries.DeleteFavoriteCollection(collection); err != nil {
+		http.Error(w, "error deleting collection", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MoveFavoriteHandler godoc
+// @Summary Sort a favorite in a collection
+// @Description Move a favorite of the current user to one of their collections, or out of any with a null collectionID
+// @Tags favorites
+// @Accept json
+// @Produce json
+// @Param favoriteID path string true "Favorite ID"
+// @Success 200 {object} models.Favorite "Favorite moved"
+// @Failure 400 {string} string "Invalid JSON"
+// @Failure 404 {string} string "favorite or collection not found"
+// @Failure 500 {string} string "error moving favorite"
+// @Router /favorites/{favoriteID}/collection [put]
+func (h *FavoriteHandler) MoveFavoriteHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	favorite, err := h.favoriteQueries.FindFavoriteByID(chi.URLParam(r, "favoriteID"))
+	if err != nil || favorite.UserID != subject.UserID {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "favorite not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "error finding favorite", http.StatusInternalServerError)
+		return
+	}
+
+	var body struct {
+		CollectionID *uint `json:"collectionID"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if body.CollectionID != nil {
+		if _, err := h.favoriteQueries.FindFavoriteCollection(subject.UserID, strconv.FormatUint(uint64(*body.CollectionID), 10)); err != nil {
+			http.Error(w, "collection not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	if err := h.favoriteQueries.MoveFavorite(favorite, body.CollectionID); err != nil {
+		http.Error(w, "error moving favorite", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(favorite)
+}
+
+// findCollection returns the collection of the current user named in the
+// URL, or writes the error response.
+func (h *FavoriteHandler) findCollection(w http.ResponseWriter, r *http.Request) (*models.FavoriteCollection, bool) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return nil, false
+	}
+	collection, err := h.favoriteQueries.FindFavoriteCollection(subject.UserID, chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "collection not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "error fetching collection", http.StatusInternalServerError)
+		return nil, false
+	}
+	return collection, true
+}
+
+// NotifyCatFavorites lets the users who favorited an annonce of a cat know
+// that its status changed. It is meant to run in its own goroutine after the
+// status of a cat is changed.
+func NotifyCatFavorites(q *queries.DatabaseService, cat *models.Cats) {
+	userIDs, err := q.FindCatFavoriteUserIDs(cat)
+	if err != nil {
+		utils.Logger("error", "Favorite Notifier:", "Failed to get favorites", fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	vars := map[string]string{"CatName": cat.Name, "Status": string(cat.Status)}
+	data := map[string]string{"CatID": strconv.FormatUint(uint64(cat.ID), 10), "Status": string(cat.Status)}
+	for _, userID := range userIDs {
+		if _, err := NotifyUserInApp(q, userID, notifications.EventFavoriteCatStatus, vars, data); err != nil {
+			utils.Logger("error", "Favorite Notifier:", "Failed to store in-app notification", fmt.Sprintf("Error: %v", err))
+		}
+		if _, err := NotifyUser(q, userID, notifications.EventFavoriteCatStatus, vars, data); err != nil {
+			utils.Logger("error", "Favorite Notifier:", "Failed to push notification", fmt.Sprintf("Error: %v", err))
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFavoriteCreationConflictsOnDuplicate(t *testing.T) {
+	q, db := newTestQueries()
+	db.On(`FROM "users"`, dbtest.Result{Columns: []string{"id"}, Values: [][]driver.Value{{"user"}}})
+	db.On(`FROM "annonces"`, dbtest.Result{Columns: []string{"id", "status"}, Values: [][]driver.Value{{int64(3), "published"}}})
+	// The check finds nothing, another request inserts the favorite first
+	db.On(`INSERT INTO "favorites"`, dbtest.Result{Err: &pq.Error{Code: "23505"}})
+	h := NewFavoriteHandler(q, q)
+
+	r := httptest.NewRequest(http.MethodPost, "/favorites", strings.NewReader(`{"annonceID":"3"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.FavoriteCreationHandler(w, withSubject(r, "user", models.UserRole))
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Empty(t, db.Queries(`INSERT INTO "rooms"`))
+}
+
+func TestGetFavoritesByUserIsOwnerOnly(t *testing.T) {
+	q, _ := newTestQueries()
+	h := NewFavoriteHandler(q, q)
+
+	for role, code := range map[models.RoleName]int{models.UserRole: http.StatusForbidden, models.AdminRole: http.StatusOK} {
+		r := withURLParams(httptest.NewRequest(http.MethodGet, "/favorites/users/owner", nil), map[string]string{"userID": "owner"})
+		w := httptest.NewRecorder()
+		h.GetFavoritesByUserHandler(w, withSubject(r, "someone", role))
+
+		assert.Equal(t, code, w.Code, role)
+	}
+}
+
+func TestGetFavoritesByUserScopesCollections(t *testing.T) {
+	q, db := newTestQueries()
+	h := NewFavoriteHandler(q, q)
+
+	// The collection belongs to another user, so it is not found
+	r := withURLParams(httptest.NewRequest(http.MethodGet, "/favorites/users/owner?collectionID=9", nil), map[string]string{"userID": "owner"})
+	w := httptest.NewRecorder()
+	h.GetFavoritesByUserHandler(w, withSubject(r, "owner", models.UserRole))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	lookups := db.Queries(`FROM "favorite_collections"`)
+	if assert.Len(t, lookups, 1) {
+		assert.Equal(t, []driver.Value{"9", "owner"}, lookups[0].Args)
+	}
+}
@@ -0,0 +1,754 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/geo"
+	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
+	"go-challenge/internal/policy"
+	"go-challenge/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+const (
+	maxFosterCapacity       = 20
+	maxFosterDescriptionLen = 2000
+	maxFosterMessageLen     = 2000
+)
+
+type FosterHandler struct {
+	fosterQueries *queries.DatabaseService
+}
+
+func NewFosterHandler(fosterQueries *queries.DatabaseService) *FosterHandler {
+	return &FosterHandler{fosterQueries: fosterQueries}
+}
+
+// parseFosterDay parses an optional day of a foster request, formatted as
+// YYYY-MM-DD.
+func parseFosterDay(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be formatted as YYYY-MM-DD", name)
+	}
+	return &day, nil
+}
+
+// GetMyFosterProfileHandler godoc
+// @Summary Get my foster profile
+// @Description Retrieve the foster profile of the current user
+// @Tags fosters
+// @Produce json
+// @Success 200 {object} models.FosterProfile "Foster profile"
+// @Failure 404 {string} string "foster profile not found"
+// @Router /me/foster-profile [get]
+func (h *FosterHandler) GetMyFosterProfileHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	profile, err := h.fosterQueries.FindFosterProfile(subject.UserID)
+	if err != nil {
+		http.Error(w, "foster profile not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// SaveMyFosterProfileHandler godoc
+// @Summary Create or update my foster profile
+// @Description Offer to foster cats for associations: how many cats at once, which profiles (kitten, adult, senior, special_needs) and when. A new profile is available unless available is false.
+// @Tags fosters
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.FosterProfile "Foster profile"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 500 {string} string "error saving foster profile"
+// @Router /me/foster-profile [put]
+func (h *FosterHandler) SaveMyFosterProfileHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	var body struct {
+		Capacity         int      `json:"capacity"`
+		AcceptedProfiles []string `json:"acceptedProfiles"`
+		Available        *bool    `json:"available"`
+		AvailableFrom    string   `json:"availableFrom"`
+		AvailableUntil   string   `json:"availableUntil"`
+		Cp               string   `json:"cp"`
+		Ville            string   `json:"ville"`
+		Description      string   `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if body.Capacity < 1 || body.Capacity > maxFosterCapacity {
+		http.Error(w, fmt.Sprintf("capacity must be between 1 and %d", maxFosterCapacity), http.StatusBadRequest)
+		return
+	}
+	if len(body.AcceptedProfiles) == 0 {
+		http.Error(w, "acceptedProfiles must list at least one profile", http.StatusBadRequest)
+		return
+	}
+	for _, profile := range body.AcceptedProfiles {
+		if !models.FosterCatProfile(profile).Valid() {
+			http.Error(w, "acceptedProfiles must be kitten, adult, senior or special_needs", http.StatusBadRequest)
+			return
+		}
+	}
+	if len(body.Description) > maxFosterDescriptionLen {
+		http.Error(w, fmt.Sprintf("description must be at most %d characters", maxFosterDescriptionLen), http.StatusBadRequest)
+		return
+	}
+	availableFrom, err := parseFosterDay("availableFrom", body.AvailableFrom)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	availableUntil, err := parseFosterDay("availableUntil", body.AvailableUntil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if availableFrom != nil && availableUntil != nil && !availableUntil.After(*availableFrom) {
+		http.Error(w, "availableUntil must be after availableFrom", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.fosterQueries.FindFosterProfile(subject.UserID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "error fetching foster profile", http.StatusInternalServerError)
+			return
+		}
+		profile = &models.FosterProfile{UserID: subject.UserID}
+	}
+	profile.Capacity = body.Capacity
+	profile.AcceptedProfiles = pq.StringArray(body.AcceptedProfiles)
+	// A new profile is available unless said otherwise, an existing one
+	// keeps its availability
+	if body.Available != nil {
+		profile.Available = *body.Available
+	} else if profile.ID == 0 {
+		profile.Available = true
+	}
+	profile.AvailableFrom = availableFrom
+	profile.AvailableUntil = availableUntil
+	profile.Cp = strings.TrimSpace(body.Cp)
+	profile.Ville = strings.TrimSpace(body.Ville)
+	profile.Description = body.Description
+	profile.Latitude, profile.Longitude = geo.Locate(profile.Cp)
+
+	if err := h.fosterQueries.SaveFosterProfile(profile); err != nil {
+		http.Error(w, "error saving foster profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// DeleteMyFosterProfileHandler godoc
+// @Summary Delete my foster profile
+// @Description Stop offering to foster cats. Cats being fostered, or that the foster agreed to take in, must be handed back first.
+// @Tags fosters
+// @Success 204 "No Content"
+// @Failure 404 {string} string "foster profile not found"
+// @Failure 409 {string} string "the foster still holds cats"
+// @Failure 500 {string} string "error deleting foster profile"
+// @Router /me/foster-profile [delete]
+func (h *FosterHandler) DeleteMyFosterProfileHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	profile, err := h.fosterQueries.FindFosterProfile(subject.UserID)
+	if err != nil {
+		http.Error(w, "foster profile not found", http.StatusNotFound)
+		return
+	}
+	held, err := h.fosterQueries.CountFosterHolds(subject.UserID, time.Now(), nil, 0)
+	if err != nil {
+		http.Error(w, "error deleting foster profile", http.StatusInternalServerError)
+		return
+	}
+	if held > 0 {
+		http.Error(w, "you still foster cats, or agreed to, for an association", http.StatusConflict)
+		return
+	}
+	if err := h.fosterQueries.DeleteFosterProfile(profile); err != nil {
+		http.Error(w, "error deleting foster profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetFosterProfilesHandler godoc
+// @Summary Find foster families
+// @Description Retrieve the foster profiles, the ones with the most room left first, for the members of verified associations. The email of a foster is only given once they accepted a placement of one of your associations.
+// @Tags fosters
+// @Produce json
+// @Param profile query string false "Only fosters accepting this profile: kitten, adult, senior or special_needs"
+// @Param availableOn query string false "Only fosters available on this day (YYYY-MM-DD)"
+// @Param cp query string false "Only fosters whose postal code starts with this"
+// @Success 200 {array} queries.FosterProfileDetails "Foster profiles"
+// @Failure 400 {string} string "Invalid filters"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "error fetching foster profiles"
+// @Router /fosters [get]
+func (h *FosterHandler) GetFosterProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+	// A nil list lets an admin see the email of every foster in touch with an
+	// association.
+	var associationIDs []uint
+	if !subject.IsAdmin() {
+		associations, err := h.fosterQueries.FindAssociationsByUserId(subject.UserID)
+		if err != nil {
+			http.Error(w, "error fetching associations", http.StatusInternalServerError)
+			return
+		}
+		associationIDs = []uint{}
+		for _, association := range associations {
+			if association.IsVerified() {
+				associationIDs = append(associationIDs, association.ID)
+			}
+		}
+		if len(associationIDs) == 0 {
+			http.Error(w, "only the members of a verified association can look for foster families", http.StatusForbidden)
+			return
+		}
+	}
+
+	params := r.URL.Query()
+	filter := queries.FosterProfileFilter{
+		Profile: models.FosterCatProfile(params.Get("profile")),
+		Cp:      params.Get("cp"),
+	}
+	if filter.Profile != "" && !filter.Profile.Valid() {
+		http.Error(w, "profile must be kitten, adult, senior or special_needs", http.StatusBadRequest)
+		return
+	}
+	if filter.AvailableOn, err = parseFosterDay("availableOn", params.Get("availableOn")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	profiles, err := h.fosterQueries.FindFosterProfiles(filter)
+	if err != nil {
+		http.Error(w, "error fetching foster profiles", http.StatusInternalServerError)
+		return
+	}
+	inTouch, err := h.fosterQueries.FindFosterIDsInTouch(associationIDs)
+	if err != nil {
+		http.Error(w, "error fetching foster profiles", http.StatusInternalServerError)
+		return
+	}
+	for i := range profiles {
+		if !inTouch[profiles[i].UserID] {
+			profiles[i].Email = ""
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(profiles)
+}
+
+// RequestFosterPlacementHandler godoc
+// @Summary Ask a foster family to take a cat in
+// @Description Ask a foster to take in a cat published as an association, from a start date, today by default, until an end date or further notice. The foster must accept the profile of the cat, be available and have room left over the period.
+// @Tags fosters
+// @Accept json
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Success 201 {object} models.FosterPlacement "Placement requested"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "cat or foster profile not found"
+// @Failure 409 {string} string "the cat cannot be placed with this foster"
+// @Failure 500 {string} string "error requesting placement"
+// @Router /cats/{id}/foster-placements [post]
+func (h *FosterHandler) RequestFosterPlacementHandler(w http.ResponseWriter, r *http.Request) {
+	cat, userID, ok := findManagedCat(h.fosterQueries, w, r)
+	if !ok {
+		return
+	}
+	association := publishingAssociation(h.fosterQueries, cat.PublishedAs)
+	if association == nil {
+		http.Error(w, "only cats published as an association can be placed with a foster family", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		FosterID     string `json:"fosterId"`
+		StartDate    string `json:"startDate"`
+		EndDate      string `json:"endDate"`
+		SpecialNeeds bool   `json:"specialNeeds"`
+		Message      string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if body.FosterID == "" {
+		http.Error(w, "fosterId is required", http.StatusBadRequest)
+		return
+	}
+	if len(body.Message) > maxFosterMessageLen {
+		http.Error(w, fmt.Sprintf("message must be at most %d characters", maxFosterMessageLen), http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	startDate, err := parseFosterDay("startDate", body.StartDate)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if startDate == nil {
+		startDate = &now
+	} else if startDate.AddDate(0, 0, 1).Before(now) {
+		http.Error(w, "startDate must not be in the past", http.StatusBadRequest)
+		return
+	}
+	endDate, err := parseFosterDay("endDate", body.EndDate)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if endDate != nil && !endDate.After(*startDate) {
+		http.Error(w, "endDate must be after startDate", http.StatusBadRequest)
+		return
+	}
+
+	if !cat.Status.CanTransitionTo(models.CatFoster) {
+		http.Error(w, fmt.Sprintf("a cat with status %s cannot be placed with a foster family", cat.Status), http.StatusConflict)
+		return
+	}
+	if _, err := h.fosterQueries.FindOpenCatFosterPlacement(cat.ID); err == nil {
+		http.Error(w, fmt.Sprintf("%s already has a foster placement under way", cat.Name), http.StatusConflict)
+		return
+	}
+	foster, err := h.fosterQueries.FindFosterProfile(body.FosterID)
+	if err != nil {
+		http.Error(w, "foster profile not found", http.StatusNotFound)
+		return
+	}
+	placement := &models.FosterPlacement{
+		CatID:         cat.ID,
+		AssociationID: association.ID,
+		FosterID:      foster.UserID,
+		Status:        models.FosterRequested,
+		StartDate:     *startDate,
+		EndDate:       endDate,
+		SpecialNeeds:  body.SpecialNeeds,
+		Message:       body.Message,
+		RequestedBy:   userID,
+	}
+	if reason := fosterCanHold(foster, placement, cat); reason != "" {
+		http.Error(w, reason, http.StatusConflict)
+		return
+	}
+
+	if err := h.fosterQueries.CreateFosterPlacement(placement); err != nil {
+		var full *queries.FosterFullError
+		if errors.As(err, &full) {
+			http.Error(w, full.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "error requesting placement", http.StatusInternalServerError)
+		return
+	}
+	go notifyFosterPlacement(h.fosterQueries, placement, cat, association)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(placement)
+}
+
+// GetCatFosterPlacementsHandler godoc
+// @Summary Get the foster placements of a cat
+// @Description Retrieve the foster placements of a cat, most recent first, for its owner or association
+// @Tags fosters
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Success 200 {array} queries.FosterPlacementDetails "Placements"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "cat not found"
+// @Failure 500 {string} string "error fetching placements"
+// @Router /cats/{id}/foster-placements [get]
+func (h *FosterHandler) GetCatFosterPlacementsHandler(w http.ResponseWriter, r *http.Request) {
+	cat, _, ok := findManagedCat(h.fosterQueries, w, r)
+	if !ok {
+		return
+	}
+
+	placements, err := h.fosterQueries.FindCatFosterPlacements(cat.ID)
+	if err != nil {
+		http.Error(w, "error fetching placements", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(placements)
+}
+
+// GetAssociationFosterPlacementsHandler godoc
+// @Summary Get the foster placements of an association
+// @Description Retrieve which foster holds which cat of an association: its active and upcoming placements by default, for its owner and members
+// @Tags fosters
+// @Produce json
+// @Param id path string true "Association ID"
+// @Param status query string false "Only placements with this status"
+// @Success 200 {array} queries.FosterPlacementDetails "Placements"
+// @Failure 400 {string} string "Invalid status"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "association not found"
+// @Failure 500 {string} string "error fetching placements"
+// @Router /associations/{id}/foster-placements [get]
+func (h *FosterHandler) GetAssociationFosterPlacementsHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	associationID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid association ID", http.StatusBadRequest)
+		return
+	}
+	association, err := h.fosterQueries.FindAssociationById(associationID)
+	if err != nil {
+		http.Error(w, "association not found", http.StatusNotFound)
+		return
+	}
+	if !subject.IsAdmin() && !policy.IsAssociationMember(association, subject.UserID) {
+		http.Error(w, "only the owner and members of the association can access its foster placements", http.StatusForbidden)
+		return
+	}
+
+	statuses := []models.FosterPlacementStatus{models.FosterActive, models.FosterAccepted}
+	if value := r.URL.Query().Get("status"); value != "" {
+		status := models.FosterPlacementStatus(value)
+		if !status.Valid() {
+			http.Error(w, "status must be requested, accepted, active, declined, cancelled or ended", http.StatusBadRequest)
+			return
+		}
+		statuses = []models.FosterPlacementStatus{status}
+	}
+
+	placements, err := h.fosterQueries.FindAssociationFosterPlacements(association.ID, statuses)
+	if err != nil {
+		http.Error(w, "error fetching placements", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(placements)
+}
+
+// GetMyFosterPlacementsHandler godoc
+// @Summary Get my foster placements
+// @Description Retrieve the placements offered to the current user as a foster, most recent first
+// @Tags fosters
+// @Produce json
+// @Success 200 {array} queries.FosterPlacementDetails "Placements"
+// @Failure 500 {string} string "error fetching placements"
+// @Router /me/foster-placements [get]
+func (h *FosterHandler) GetMyFosterPlacementsHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	placements, err := h.fosterQueries.FindFosterPlacementsByFoster(subject.UserID)
+	if err != nil {
+		http.Error(w, "error fetching placements", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(placements)
+}
+
+// ChangeFosterPlacementStatusHandler godoc
+// @Summary Change the status of a foster placement
+// @Description Accept or decline a placement, as its foster, or cancel or end it, as whoever manages its cat. An accepted placement starts on its start date and moves the cat to the foster status; ending it moves the cat back to available, or to catStatus.
+// @Tags fosters
+// @Accept json
+// @Produce json
+// @Param id path string true "Placement ID"
+// @Success 200 {object} models.FosterPlacement "Placement updated"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "placement not found"
+// @Failure 409 {string} string "the transition is not allowed"
+// @Failure 500 {string} string "error updating placement"
+// @Router /foster-placements/{id}/status [put]
+func (h *FosterHandler) ChangeFosterPlacementStatusHandler(w http.ResponseWriter, r *http.Request) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+
+	placement, err := h.fosterQueries.FindFosterPlacementByID(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "placement not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "error fetching placement", http.StatusInternalServerError)
+		return
+	}
+
+	var body struct {
+		Status    models.FosterPlacementStatus `json:"status"`
+		CatStatus models.CatStatus             `json:"catStatus"`
+		Note      string                       `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	switch body.Status {
+	case models.FosterAccepted, models.FosterDeclined, models.FosterCancelled, models.FosterEnded:
+	default:
+		http.Error(w, "status must be accepted, declined, cancelled or ended", http.StatusBadRequest)
+		return
+	}
+	if len(body.Note) > maxCatStatusNoteLength {
+		http.Error(w, fmt.Sprintf("note must be at most %d characters", maxCatStatusNoteLength), http.StatusBadRequest)
+		return
+	}
+
+	cat, err := h.fosterQueries.FindCatByID(strconv.FormatUint(uint64(placement.CatID), 10))
+	if err != nil {
+		http.Error(w, "cat not found", http.StatusNotFound)
+		return
+	}
+	if body.Status == models.FosterAccepted || body.Status == models.FosterDeclined {
+		if placement.FosterID != subject.UserID {
+			http.Error(w, "only the foster can accept or decline a placement", http.StatusForbidden)
+			return
+		}
+	} else if !canManageCat(h.fosterQueries, cat, subject) {
+		http.Error(w, "only the owner of the cat or its association can cancel or end a placement", http.StatusForbidden)
+		return
+	}
+
+	to := body.Status
+	now := time.Now()
+	if to == models.FosterAccepted && !placement.StartDate.After(now) {
+		to = models.FosterActive
+	}
+	if !placement.Status.CanTransitionTo(to) {
+		http.Error(w, fmt.Sprintf("cannot change a placement from %s to %s", placement.Status, body.Status), http.StatusConflict)
+		return
+	}
+
+	switch to {
+	case models.FosterAccepted, models.FosterActive:
+		foster, err := h.fosterQueries.FindFosterProfile(placement.FosterID)
+		if err != nil {
+			http.Error(w, "create your foster profile before accepting a placement", http.StatusConflict)
+			return
+		}
+		if reason := fosterCanHold(foster, placement, cat); reason != "" {
+			http.Error(w, reason, http.StatusConflict)
+			return
+		}
+		if to == models.FosterActive {
+			if !cat.Status.CanTransitionTo(models.CatFoster) {
+				http.Error(w, fmt.Sprintf("a cat with status %s cannot be placed with a foster family", cat.Status), http.StatusConflict)
+				return
+			}
+			err = h.fosterQueries.StartFosterPlacement(placement, cat, subject.UserID)
+		} else {
+			err = h.fosterQueries.RespondFosterPlacement(placement, to, subject.UserID)
+		}
+	case models.FosterEnded:
+		catStatus := body.CatStatus
+		if catStatus == "" {
+			catStatus = models.CatAvailable
+		}
+		if cat.Status == models.CatFoster && !cat.Status.CanTransitionTo(catStatus) {
+			http.Error(w, fmt.Sprintf("cannot change the status of a cat from %s to %s", cat.Status, catStatus), http.StatusConflict)
+			return
+		}
+		err = h.fosterQueries.EndFosterPlacement(placement, cat, catStatus, subject.UserID, body.Note)
+	default:
+		err = h.fosterQueries.RespondFosterPlacement(placement, to, subject.UserID)
+	}
+	if err != nil {
+		var full *queries.FosterFullError
+		if errors.As(err, &full) {
+			http.Error(w, full.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, queries.ErrCatStatusChanged) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "error updating placement", http.StatusInternalServerError)
+		return
+	}
+
+	association := publishingAssociation(h.fosterQueries, cat.PublishedAs)
+	go notifyFosterPlacement(h.fosterQueries, placement, cat, association)
+	if to == models.FosterActive || to == models.FosterEnded {
+		if cat.Status.Adoptable() {
+			go MatchSavedSearches(h.fosterQueries, fmt.Sprintf("%d", cat.ID))
+		}
+		go NotifyCatFavorites(h.fosterQueries, cat)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(placement)
+}
+
+// fosterCanHold checks that a foster takes in the profile of the cat of a
+// placement and is available on its start date. It returns why not, or an
+// empty string. Whether the foster has room left is checked by the queries
+// saving the placement, under a lock.
+func fosterCanHold(foster *models.FosterProfile, placement *models.FosterPlacement, cat *models.Cats) string {
+	if profile := placement.Profile(cat); !foster.Accepts(profile) {
+		return fmt.Sprintf("this foster does not take in %s cats", profile)
+	}
+	if !foster.AvailableOn(placement.StartDate) {
+		return "this foster is not available on the start date"
+	}
+	return ""
+}
+
+// notifyFosterPlacement lets the other side of a placement know that it moved
+// a step: the foster of a request, a cancellation or its end, and whoever
+// requested it of the answer. It is meant to run in its own goroutine.
+func notifyFosterPlacement(q *queries.DatabaseService, placement *models.FosterPlacement, cat *models.Cats, association *models.Association) {
+	vars := map[string]string{
+		"CatName":   cat.Name,
+		"Status":    string(placement.Status),
+		"StartDate": placement.StartDate.Format("02/01/2006"),
+	}
+	if association != nil {
+		vars["AssociationName"] = association.Name
+	}
+	data := map[string]string{
+		"PlacementID": strconv.FormatUint(uint64(placement.ID), 10),
+		"CatID":       strconv.FormatUint(uint64(cat.ID), 10),
+		"Status":      string(placement.Status),
+	}
+
+	userID, event := placement.FosterID, notifications.EventFosterRequest
+	switch placement.Status {
+	case models.FosterAccepted, models.FosterActive, models.FosterDeclined:
+		if placement.RequestedBy == "" {
+			return
+		}
+		foster, err := q.FindUserByID(placement.FosterID)
+		if err != nil {
+			utils.Logger("error", "Foster Placements:", "Failed to find foster", fmt.Sprintf("Error: %v", err))
+			return
+		}
+		vars["FosterName"] = foster.Name
+		userID, event = placement.RequestedBy, notifications.EventFosterResponse
+	case models.FosterCancelled, models.FosterEnded:
+		event = notifications.EventFosterClosed
+	}
+
+	if _, err := NotifyUserInApp(q, userID, event, vars, data); err != nil {
+		utils.Logger("error", "Foster Placements:", "Failed to store in-app notification", fmt.Sprintf("Error: %v", err))
+	}
+	if _, err := NotifyUser(q, userID, event, vars, data); err != nil {
+		utils.Logger("error", "Foster Placements:", "Failed to push notification", fmt.Sprintf("Error: %v", err))
+	}
+}
+
+// RunFosterPlacements starts the accepted placements whose start date has
+// come, moving their cats to the foster status, and ends the active ones
+// whose end date has passed, making their cats available again.
+func RunFosterPlacements(q *queries.DatabaseService, now time.Time) {
+	startFosterPlacements(q, now)
+	endFosterPlacements(q, now)
+}
+
+func startFosterPlacements(q *queries.DatabaseService, now time.Time) {
+	placements, err := q.FindDueFosterPlacements(now)
+	if err != nil {
+		utils.Logger("error", "Foster Placements:", "Failed to get due placements", fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	for i := range placements {
+		placement := &placements[i]
+		cat, err := q.FindCatByID(strconv.FormatUint(uint64(placement.CatID), 10))
+		if err != nil {
+			utils.Logger("error", "Foster Placements:", "Failed to get cat", fmt.Sprintf("Error: %v", err))
+			continue
+		}
+		if !cat.Status.CanTransitionTo(models.CatFoster) {
+			utils.Logger("error", "Foster Placements:", "Cannot start placement", fmt.Sprintf("Cat %d has status %s", cat.ID, cat.Status))
+			continue
+		}
+		if err := q.StartFosterPlacement(placement, cat, placement.FosterID); err != nil {
+			utils.Logger("error", "Foster Placements:", "Failed to start placement", fmt.Sprintf("Error: %v", err))
+			continue
+		}
+		go NotifyCatFavorites(q, cat)
+	}
+}
+
+func endFosterPlacements(q *queries.DatabaseService, now time.Time) {
+	placements, err := q.FindOverFosterPlacements(now)
+	if err != nil {
+		utils.Logger("error", "Foster Placements:", "Failed to get placements over", fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	for i := range placements {
+		placement := &placements[i]
+		cat, err := q.FindCatByID(strconv.FormatUint(uint64(placement.CatID), 10))
+		if err != nil {
+			utils.Logger("error", "Foster Placements:", "Failed to get cat", fmt.Sprintf("Error: %v", err))
+			continue
+		}
+		if err := q.EndFosterPlacement(placement, cat, models.CatAvailable, placement.FosterID, "the foster placement reached its end date"); err != nil {
+			utils.Logger("error", "Foster Placements:", "Failed to end placement", fmt.Sprintf("Error: %v", err))
+			continue
+		}
+		go notifyFosterPlacement(q, placement, cat, publishingAssociation(q, cat.PublishedAs))
+		if cat.Status.Adoptable() {
+			go MatchSavedSearches(q, fmt.Sprintf("%d", cat.ID))
+		}
+		go NotifyCatFavorites(q, cat)
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"go-challenge/internal/database"
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+)
+
+// newTestQueries returns queries running against a scripted database.
+func newTestQueries() (*queries.DatabaseService, *dbtest.DB) {
+	db, scripted := dbtest.New()
+	return queries.NewQueriesService(&database.Service{Db: db}), scripted
+}
+
+// withSubject authenticates a request as a user with a role.
+func withSubject(r *http.Request, userID string, role models.RoleName) *http.Request {
+	token, _, err := jwtauth.New("HS256", []byte("secret"), nil).Encode(map[string]interface{}{"id": userID, "role": string(role)})
+	if err != nil {
+		panic(err)
+	}
+	return r.WithContext(jwtauth.NewContext(r.Context(), token, nil))
+}
+
+// withURLParams sets the route parameters of a request, as chi would.
+func withURLParams(r *http.Request, params map[string]string) *http.Request {
+	routeContext := chi.NewRouteContext()
+	for key, value := range params {
+		routeContext.URLParams.Add(key, value)
+	}
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext))
+}
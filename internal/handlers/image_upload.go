@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-challenge/internal/imaging"
+	"go-challenge/internal/storage"
+	"go-challenge/internal/utils"
+)
+
+// maxImageSize is the largest picture accepted for upload, in bytes.
+const maxImageSize = 10 << 20
+
+// privateFileTTL is how long the signed URL of a private file, such as a
+// document of an association, gives access to it.
+const privateFileTTL = 15 * time.Minute
+
+// uploadedImage holds the URLs of the variants of an uploaded picture, and
+// its perceptual hash when it could be computed.
+type uploadedImage struct {
+	URL          string
+	MediumURL    string
+	ThumbnailURL string
+	Hash         *int64
+}
+
+// uploadImage processes a picture sent in a multipart form and stores each of
+// its variants. Errors wrapping imaging.ErrInvalidImage are caused by the
+// picture itself.
+func uploadImage(ctx context.Context, store storage.BlobStore, header *multipart.FileHeader) (*uploadedImage, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageSize {
+		return nil, fmt.Errorf("%w: the picture is larger than %d MB", imaging.ErrInvalidImage, maxImageSize>>20)
+	}
+
+	variants, err := imaging.Process(data)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename))
+	image := &uploadedImage{}
+	var stored []string
+	for _, variant := range variants {
+		key, err := store.Put(ctx, name+"-"+variant.Name+variant.Extension, variant.ContentType, variant.Data)
+		if err != nil {
+			// The variants already stored would never be referenced
+			deleteImageFiles(ctx, store, stored...)
+			return nil, err
+		}
+		url := store.URL(key)
+		stored = append(stored, url)
+		switch variant.Name {
+		case imaging.VariantFull:
+			image.URL = url
+		case imaging.VariantMedium:
+			image.MediumURL = url
+		case imaging.VariantThumbnail:
+			image.ThumbnailURL = url
+			// The thumbnail is the cheapest variant to decode again
+			image.Hash = imageHash(variant.Data)
+		}
+	}
+	return image, nil
+}
+
+// imageHash returns the perceptual hash of a picture as stored in the
+// database, or nil when the picture cannot be decoded.
+func imageHash(data []byte) *int64 {
+	hash, err := imaging.Hash(data)
+	if err != nil {
+		return nil
+	}
+	signed := int64(hash)
+	return &signed
+}
+
+// storedImageHash returns the perceptual hash of a picture of the store, or
+// nil when it cannot be read or decoded.
+func storedImageHash(ctx context.Context, store storage.BlobStore, url string) *int64 {
+	key, ok := store.Key(url)
+	if !ok {
+		return nil
+	}
+	data, err := store.Get(ctx, key)
+	if err != nil {
+		utils.Logger("error", "Image Hash:", "Failed to read file from storage", fmt.Sprintf("Error: %v", err))
+		return nil
+	}
+	return imageHash(data)
+}
+
+// writeUploadError writes the response of a failed upload: a bad request when
+// the picture is rejected, an internal error otherwise.
+func writeUploadError(w http.ResponseWriter, err error) {
+	if errors.Is(err, imaging.ErrInvalidImage) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	utils.Logger("error", "Upload Image:", "Failed to upload image", fmt.Sprintf("Error: %v", err))
+	http.Error(w, "error uploading image", http.StatusInternalServerError)
+}
+
+// uploadFile stores a private file sent in a multipart form as is and returns
+// its key. It is handed out with signedFileURL only.
+func uploadFile(ctx context.Context, store storage.BlobStore, file io.Reader, name, contentType string) (string, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	return store.Put(ctx, name, contentType, data)
+}
+
+// storedFileKey returns the key of a private file. Files stored before their
+// key was kept are referenced by their URL.
+func storedFileKey(store storage.BlobStore, file string) (string, bool) {
+	if key, ok := store.Key(file); ok {
+		return key, true
+	}
+	return file, file != "" && !strings.ContainsAny(file, ":/")
+}
+
+// signedFileURL returns a URL giving access to a private file for a while.
+func signedFileURL(store storage.BlobStore, file string) (string, error) {
+	key, ok := storedFileKey(store, file)
+	if !ok {
+		return "", fmt.Errorf("unknown file %q", file)
+	}
+	return store.SignedURL(key, privateFileTTL)
+}
+
+// deleteImageFiles removes the variants of a picture from the storage. They
+// are no longer referenced, so a failure is only logged. Pictures uploaded
+// before variants existed use the same file for all of them.
+func deleteImageFiles(ctx context.Context, store storage.BlobStore, urls ...string) {
+	deleted := map[string]bool{}
+	for _, url := range urls {
+		key, ok := store.Key(url)
+		if !ok || deleted[key] {
+			continue
+		}
+		deleted[key] = true
+		if err := store.Delete(ctx, key); err != nil {
+			utils.Logger("error", "Delete Image:", "Failed to delete file from storage", fmt.Sprintf("Error: %v", err))
+		}
+	}
+}
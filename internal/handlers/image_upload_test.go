@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"os"
+	"strings"
+	"testing"
+
+	"go-challenge/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedFileURL(t *testing.T) {
+	store, err := storage.NewLocal(t.TempDir(), "http://localhost:8080/uploads", []byte("secret"))
+	require.NoError(t, err)
+	key, err := store.Put(context.Background(), "kbis.pdf", "application/pdf", []byte("%PDF-1.4"))
+	require.NoError(t, err)
+
+	signed, err := signedFileURL(store, key)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(signed, store.URL(key)+"?"))
+	assert.Contains(t, signed, "signature=")
+
+	// Files stored before their key was kept are referenced by their URL
+	legacy, err := signedFileURL(store, store.URL(key))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(legacy, store.URL(key)+"?"))
+
+	_, err = signedFileURL(store, "https://example.com/kbis.pdf")
+	assert.Error(t, err)
+}
+
+// failingStore stores the first files it is given and fails after.
+type failingStore struct {
+	storage.BlobStore
+	puts int
+}
+
+func (s *failingStore) Put(ctx context.Context, name, contentType string, data []byte) (string, error) {
+	if s.puts == 0 {
+		return "", errors.New("storage unavailable")
+	}
+	s.puts--
+	return s.BlobStore.Put(ctx, name, contentType, data)
+}
+
+// pictureData returns a small PNG picture.
+func pictureData(t *testing.T) []byte {
+	t.Helper()
+	picture := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		picture.Set(x, x, color.RGBA{R: 255, A: 255})
+	}
+	var data bytes.Buffer
+	require.NoError(t, png.Encode(&data, picture))
+	return data.Bytes()
+}
+
+// pictureHeader returns the header of a picture sent in a multipart form.
+func pictureHeader(t *testing.T) *multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("uploaded_file", "felix.png")
+	require.NoError(t, err)
+	_, err = part.Write(pictureData(t))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	parsed, err := multipart.NewReader(&body, form.Boundary()).ReadForm(1 << 20)
+	require.NoError(t, err)
+	t.Cleanup(func() { parsed.RemoveAll() })
+	return parsed.File["uploaded_file"][0]
+}
+
+func TestUploadImageRemovesVariantsOnError(t *testing.T) {
+	dir := t.TempDir()
+	local, err := storage.NewLocal(dir, "http://localhost:8080/uploads", []byte("secret"))
+	require.NoError(t, err)
+
+	_, err = uploadImage(context.Background(), &failingStore{BlobStore: local, puts: 2}, pictureHeader(t))
+	require.Error(t, err)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	uploaded, err := uploadImage(context.Background(), local, pictureHeader(t))
+	require.NoError(t, err)
+	assert.NotNil(t, uploaded.Hash)
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 3)
+}
+
+func TestPhotosFromURLs(t *testing.T) {
+	store, err := storage.NewLocal(t.TempDir(), "http://localhost:8080/uploads", []byte("secret"))
+	require.NoError(t, err)
+
+	key, err := store.Put(context.Background(), "felix.png", "image/png", pictureData(t))
+	require.NoError(t, err)
+
+	photos, err := photosFromURLs(context.Background(), store, []string{store.URL(key), store.URL("missing.jpg")})
+	require.NoError(t, err)
+	require.Len(t, photos, 2)
+	assert.Equal(t, store.URL(key), photos[0].URL)
+	assert.NotNil(t, photos[0].Hash)
+	assert.Nil(t, photos[1].Hash)
+
+	_, err = photosFromURLs(context.Background(), store, []string{store.URL(key), "https://example.com/felix.jpg"})
+	assert.Error(t, err)
+	_, err = photosFromURLs(context.Background(), store, []string{"http://localhost:8080/uploads/../secrets"})
+	assert.Error(t, err)
+}
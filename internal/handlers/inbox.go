@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/jinzhu/gorm"
+)
+
+type InboxHandler struct {
+	inboxQueries *queries.DatabaseService
+}
+
+func NewInboxHandler(inboxQueries *queries.DatabaseService) *InboxHandler {
+	return &InboxHandler{inboxQueries: inboxQueries}
+}
+
+// NotifyUserInApp renders the notification of an event in the preferred
+// locale of the user and stores it in their in-app inbox.
+func NotifyUserInApp(q *queries.DatabaseService, userID string, event notifications.Event, vars map[string]string, data map[string]string) (*models.InAppNotification, error) {
+	user, err := q.FindUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding user: %w", err)
+	}
+
+	message, err := notifications.Render(event, user.Locale, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	encodedData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	notification := &models.InAppNotification{
+		UserID: userID,
+		Event:  string(event),
+		Title:  message.Title,
+		Body:   message.Body,
+		Data:   string(encodedData),
+	}
+	if err := q.CreateInAppNotification(notification); err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// GetInboxHandler godoc
+// @Summary Get the inbox of the current user
+// @Description Retrieve the in-app notifications of the authenticated user, most recent first
+// @Tags notifications
+// @Produce json
+// @Param unread query bool false "Only return unread notifications"
+// @Success 200 {array} models.InAppNotification "List of notifications"
+// @Failure 500 {string} string "error fetching notifications"
+// @Router /me/notifications [get]
+func (h *InboxHandler) GetInboxHandler(w http.ResponseWriter, r *http.Request) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+	userID := claims["id"].(string)
+
+	unreadOnly, _ := strconv.ParseBool(r.URL.Query().Get("unread"))
+
+	inbox, err := h.inboxQueries.FindInAppNotificationsByUserID(userID, unreadOnly)
+	if err != nil {
+		http.Error(w, "error fetching notifications", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(inbox)
+}
+
+// MarkInboxNotificationReadHandler godoc
+// @Summary Mark an inbox notification as read
+// @Description Mark one in-app notification of the authenticated user as read
+// @Tags notifications
+// @Param id path string true "Notification ID"
+// @Success 204 "No Content"
+// @Failure 404 {string} string "notification not found"
+// @Failure 500 {string} string "error updating notification"
+// @Router /me/notifications/{id}/read [put]
+func (h *InboxHandler) MarkInboxNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+	userID := claims["id"].(string)
+
+	if err := h.inboxQueries.MarkInAppNotificationAsRead(chi.URLParam(r, "id"), userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "notification not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "error updating notification", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkInboxNotificationReadHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		updated int64
+		found   bool
+		code    int
+	}{
+		{name: "unread", updated: 1, code: http.StatusNoContent},
+		{name: "already read", updated: 0, found: true, code: http.StatusNoContent},
+		{name: "someone else's or missing", updated: 0, code: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, db := newTestQueries()
+			db.On(`UPDATE "in_app_notifications"`, dbtest.Result{RowsAffected: tt.updated})
+			if tt.found {
+				db.On(`FROM "in_app_notifications"`, dbtest.Result{Columns: []string{"id", "user_id"}, Values: [][]driver.Value{{int64(9), "user"}}})
+			}
+			r := httptest.NewRequest(http.MethodPut, "/me/notifications/9/read", nil)
+			r = withURLParams(withSubject(r, "user", models.UserRole), map[string]string{"id": "9"})
+			w := httptest.NewRecorder()
+
+			NewInboxHandler(q).MarkInboxNotificationReadHandler(w, r)
+
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+		})
+	}
+}
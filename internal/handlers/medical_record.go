@@ -0,0 +1,486 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const medicalDateLayout = "02-01-2006"
+
+var identificationNumberPattern = regexp.MustCompile(`^[A-Z0-9]{3,20}$`)
+
+type MedicalRecordHandler struct {
+	medicalQueries *queries.DatabaseService
+}
+
+func NewMedicalRecordHandler(medicalQueries *queries.DatabaseService) *MedicalRecordHandler {
+	return &MedicalRecordHandler{medicalQueries: medicalQueries}
+}
+
+type medicalRecord struct {
+	CatID                uint                  `json:"catId"`
+	IdentificationNumber string                `json:"identificationNumber"`
+	Entries              []models.MedicalEntry `json:"entries"`
+}
+
+type vaccinationSummary struct {
+	Name        string     `json:"name"`
+	Date        time.Time  `json:"date"`
+	NextDueDate *time.Time `json:"nextDueDate,omitempty"`
+	UpToDate    bool       `json:"upToDate"`
+}
+
+// medicalSummary is the read-only part of the medical record shown to
+// adopters: no batch numbers, notes or identification number.
+type medicalSummary struct {
+	Identified        bool                 `json:"identified"`
+	Vaccinations      []vaccinationSummary `json:"vaccinations"`
+	LastDeworming     *time.Time           `json:"lastDeworming,omitempty"`
+	LastFleaTreatment *time.Time           `json:"lastFleaTreatment,omitempty"`
+	LastVetVisit      *time.Time           `json:"lastVetVisit,omitempty"`
+	WeightGrams       *int                 `json:"weightGrams,omitempty"`
+	Conditions        []string             `json:"conditions"`
+}
+
+type medicalEntryRequest struct {
+	Type         models.MedicalEntryType `json:"type"`
+	Date         string                  `json:"date"`
+	Name         string                  `json:"name"`
+	BatchNumber  string                  `json:"batchNumber"`
+	Veterinarian string                  `json:"veterinarian"`
+	WeightGrams  *int                    `json:"weightGrams"`
+	NextDueDate  string                  `json:"nextDueDate"`
+	Ongoing      bool                    `json:"ongoing"`
+	Notes        string                  `json:"notes"`
+}
+
+func (req *medicalEntryRequest) apply(entry *models.MedicalEntry, now time.Time) error {
+	switch req.Type {
+	case models.MedicalVaccination, models.MedicalCondition:
+		if strings.TrimSpace(req.Name) == "" {
+			return errors.New("name is required for a " + string(req.Type))
+		}
+	case models.MedicalWeight:
+		if req.WeightGrams == nil || *req.WeightGrams <= 0 {
+			return errors.New("weightGrams must be positive for a weight measurement")
+		}
+	case models.MedicalDeworming, models.MedicalFleaTreatment, models.MedicalVetVisit:
+	default:
+		return errors.New("type must be vaccination, deworming, flea_treatment, vet_visit, weight or condition")
+	}
+
+	date, err := time.Parse(medicalDateLayout, req.Date)
+	if err != nil {
+		return errors.New("invalid date format, expected DD-MM-YYYY")
+	}
+	if date.After(now) {
+		return errors.New("date cannot be in the future")
+	}
+
+	var nextDueDate *time.Time
+	if req.NextDueDate != "" {
+		parsed, err := time.Parse(medicalDateLayout, req.NextDueDate)
+		if err != nil {
+			return errors.New("invalid nextDueDate format, expected DD-MM-YYYY")
+		}
+		if !parsed.After(date) {
+			return errors.New("nextDueDate must be after date")
+		}
+		nextDueDate = &parsed
+	}
+
+	entry.Type = req.Type
+	entry.Date = date
+	entry.Name = strings.TrimSpace(req.Name)
+	entry.BatchNumber = strings.TrimSpace(req.BatchNumber)
+	entry.Veterinarian = strings.TrimSpace(req.Veterinarian)
+	entry.WeightGrams = nil
+	if req.Type == models.MedicalWeight {
+		entry.WeightGrams = req.WeightGrams
+	}
+	entry.NextDueDate = nextDueDate
+	entry.Ongoing = req.Type == models.MedicalCondition && req.Ongoing
+	entry.Notes = req.Notes
+	return nil
+}
+
+// GetMedicalRecordHandler godoc
+// @Summary Get the medical record of a cat
+// @Description Retrieve the full medical record of a cat, for its owner or association
+// @Tags medical
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Success 200 {object} medicalRecord "Medical record"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "cat not found"
+// @Failure 500 {string} string "error fetching medical record"
+// @Router /cats/{id}/medical [get]
+func (h *MedicalRecordHandler) GetMedicalRecordHandler(w http.ResponseWriter, r *http.Request) {
+	cat, _, ok := findManagedCat(h.medicalQueries, w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.medicalQueries.FindMedicalEntriesByCatID(cat.ID)
+	if err != nil {
+		http.Error(w, "error fetching medical record", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(medicalRecord{
+		CatID:                cat.ID,
+		IdentificationNumber: cat.IdentificationNumber,
+		Entries:              entries,
+	})
+}
+
+// CreateMedicalEntryHandler godoc
+// @Summary Add a medical entry
+// @Description Add a vaccination, deworming, flea treatment, vet visit, weight measurement or condition to the medical record of a cat
+// @Tags medical
+// @Accept json
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Success 201 {object} models.MedicalEntry "Medical entry created"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "cat not found"
+// @Failure 500 {string} string "error creating medical entry"
+// @Router /cats/{id}/medical/entries [post]
+func (h *MedicalRecordHandler) CreateMedicalEntryHandler(w http.ResponseWriter, r *http.Request) {
+	cat, userID, ok := findManagedCat(h.medicalQueries, w, r)
+	if !ok {
+		return
+	}
+
+	var body medicalEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	entry := &models.MedicalEntry{CatID: cat.ID, RecordedBy: userID}
+	if err := body.apply(entry, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.medicalQueries.CreateMedicalEntry(entry); err != nil {
+		http.Error(w, "error creating medical entry", http.StatusInternalServerError)
+		return
+	}
+	if entry.Type == models.MedicalVaccination {
+		if err := h.medicalQueries.SyncCatLastVaccine(cat.ID); err != nil {
+			http.Error(w, "error updating last vaccine", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// UpdateMedicalEntryHandler godoc
+// @Summary Update a medical entry
+// @Description Replace a medical entry of a cat
+// @Tags medical
+// @Accept json
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Param entryID path string true "Medical entry ID"
+// @Success 200 {object} models.MedicalEntry "Medical entry updated"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "medical entry not found"
+// @Failure 500 {string} string "error updating medical entry"
+// @Router /cats/{id}/medical/entries/{entryID} [put]
+func (h *MedicalRecordHandler) UpdateMedicalEntryHandler(w http.ResponseWriter, r *http.Request) {
+	cat, userID, ok := findManagedCat(h.medicalQueries, w, r)
+	if !ok {
+		return
+	}
+
+	entry, err := h.medicalQueries.FindMedicalEntry(cat.ID, chi.URLParam(r, "entryID"))
+	if err != nil {
+		http.Error(w, "medical entry not found", http.StatusNotFound)
+		return
+	}
+
+	var body medicalEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	wasVaccination := entry.Type == models.MedicalVaccination
+	if err := body.apply(entry, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entry.RecordedBy = userID
+
+	if err := h.medicalQueries.UpdateMedicalEntry(entry); err != nil {
+		http.Error(w, "error updating medical entry", http.StatusInternalServerError)
+		return
+	}
+	if wasVaccination || entry.Type == models.MedicalVaccination {
+		if err := h.medicalQueries.SyncCatLastVaccine(cat.ID); err != nil {
+			http.Error(w, "error updating last vaccine", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// DeleteMedicalEntryHandler godoc
+// @Summary Delete a medical entry
+// @Description Delete a medical entry of a cat
+// @Tags medical
+// @Param id path string true "Cat ID"
+// @Param entryID path string true "Medical entry ID"
+// @Success 204 "No Content"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "medical entry not found"
+// @Failure 500 {string} string "error deleting medical entry"
+// @Router /cats/{id}/medical/entries/{entryID} [delete]
+func (h *MedicalRecordHandler) DeleteMedicalEntryHandler(w http.ResponseWriter, r *http.Request) {
+	cat, _, ok := findManagedCat(h.medicalQueries, w, r)
+	if !ok {
+		return
+	}
+
+	entry, err := h.medicalQueries.FindMedicalEntry(cat.ID, chi.URLParam(r, "entryID"))
+	if err != nil {
+		http.Error(w, "medical entry not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.medicalQueries.DeleteMedicalEntry(entry); err != nil {
+		http.Error(w, "error deleting medical entry", http.StatusInternalServerError)
+		return
+	}
+	if entry.Type == models.MedicalVaccination {
+		if err := h.medicalQueries.SyncCatLastVaccine(cat.ID); err != nil {
+			http.Error(w, "error updating last vaccine", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdateCatIdentificationHandler godoc
+// @Summary Update the identification number of a cat
+// @Description Set the microchip or tattoo number of a cat
+// @Tags medical
+// @Accept json
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Success 200 {object} medicalRecord "Medical record"
+// @Failure 400 {string} string "invalid identification number"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "cat not found"
+// @Failure 500 {string} string "error updating cat"
+// @Router /cats/{id}/medical/identification [put]
+func (h *MedicalRecordHandler) UpdateCatIdentificationHandler(w http.ResponseWriter, r *http.Request) {
+	cat, _, ok := findManagedCat(h.medicalQueries, w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		IdentificationNumber string `json:"identificationNumber"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	number := strings.ToUpper(strings.ReplaceAll(body.IdentificationNumber, " ", ""))
+	if number != "" && !identificationNumberPattern.MatchString(number) {
+		http.Error(w, "invalid identification number", http.StatusBadRequest)
+		return
+	}
+
+	cat.IdentificationNumber = number
+	if err := h.medicalQueries.UpdateCatIdentificationNumber(cat); err != nil {
+		http.Error(w, "error updating cat", http.StatusInternalServerError)
+		return
+	}
+
+	entries, err := h.medicalQueries.FindMedicalEntriesByCatID(cat.ID)
+	if err != nil {
+		http.Error(w, "error fetching medical record", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(medicalRecord{
+		CatID:                cat.ID,
+		IdentificationNumber: cat.IdentificationNumber,
+		Entries:              entries,
+	})
+}
+
+// GetAnnonceMedicalSummaryHandler godoc
+// @Summary Get the medical summary of an annonce
+// @Description Retrieve a read-only summary of the medical record of the cat of an annonce. The annonce must be published unless the current user manages it.
+// @Tags medical
+// @Produce json
+// @Param id path string true "Annonce ID"
+// @Success 200 {object} medicalSummary "Medical summary"
+// @Failure 404 {string} string "annonce not found"
+// @Failure 500 {string} string "error fetching medical record"
+// @Router /annonces/{id}/medical [get]
+func (h *MedicalRecordHandler) GetAnnonceMedicalSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	annonce, err := h.medicalQueries.FindAnnonceByID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "annonce not found", http.StatusNotFound)
+		return
+	}
+	if !canSeeAnnonce(h.medicalQueries, annonce, r) {
+		http.Error(w, "annonce not found", http.StatusNotFound)
+		return
+	}
+
+	cat, err := h.medicalQueries.FindCatByID(annonce.CatID)
+	if err != nil {
+		http.Error(w, "cat not found", http.StatusNotFound)
+		return
+	}
+
+	entries, err := h.medicalQueries.FindMedicalEntriesByCatID(cat.ID)
+	if err != nil {
+		http.Error(w, "error fetching medical record", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(summarizeMedicalRecord(cat, entries, time.Now()))
+}
+
+// TransferCatHandler godoc
+// @Summary Transfer a cat to its adopter
+// @Description Give the ownership of a cat, with its medical record, to its adopter and close its annonces
+// @Tags medical
+// @Accept json
+// @Produce json
+// @Param id path string true "Cat ID"
+// @Success 200 {object} models.CatOwnershipTransfer "Ownership transferred"
+// @Failure 400 {string} string "adopterId is required"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "cat not found"
+// @Failure 409 {string} string "the cat cannot be adopted"
+// @Failure 500 {string} string "error transferring cat"
+// @Router /cats/{id}/transfer [post]
+func (h *MedicalRecordHandler) TransferCatHandler(w http.ResponseWriter, r *http.Request) {
+	cat, userID, ok := findManagedCat(h.medicalQueries, w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		AdopterID string `json:"adopterId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if body.AdopterID == "" {
+		http.Error(w, "adopterId is required", http.StatusBadRequest)
+		return
+	}
+	if cat.Status != models.CatAdopted && !cat.Status.CanTransitionTo(models.CatAdopted) {
+		http.Error(w, fmt.Sprintf("a cat with status %s cannot be adopted", cat.Status), http.StatusConflict)
+		return
+	}
+	if body.AdopterID == cat.UserID && cat.PublishedAs == "" {
+		http.Error(w, "the adopter already owns this cat", http.StatusBadRequest)
+		return
+	}
+
+	adopter, err := h.medicalQueries.FindUserByID(body.AdopterID)
+	if err != nil {
+		http.Error(w, "adopter not found", http.StatusNotFound)
+		return
+	}
+
+	transfer, err := h.medicalQueries.TransferCat(cat, adopter, userID)
+	if err != nil {
+		if errors.Is(err, queries.ErrCatStatusChanged) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "error transferring cat", http.StatusInternalServerError)
+		return
+	}
+	go NotifyCatFavorites(h.medicalQueries, cat)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// summarizeMedicalRecord keeps the latest vaccination of each vaccine, the
+// latest care and weight, and the ongoing conditions. Entries are expected
+// most recent first.
+func summarizeMedicalRecord(cat *models.Cats, entries []models.MedicalEntry, now time.Time) medicalSummary {
+	summary := medicalSummary{
+		Identified:   cat.IdentificationNumber != "",
+		Vaccinations: []vaccinationSummary{},
+		Conditions:   []string{},
+	}
+
+	seenVaccines := map[string]bool{}
+	for i := range entries {
+		entry := &entries[i]
+		switch entry.Type {
+		case models.MedicalVaccination:
+			key := strings.ToLower(entry.Name)
+			if seenVaccines[key] {
+				continue
+			}
+			seenVaccines[key] = true
+			summary.Vaccinations = append(summary.Vaccinations, vaccinationSummary{
+				Name:        entry.Name,
+				Date:        entry.Date,
+				NextDueDate: entry.NextDueDate,
+				UpToDate:    entry.NextDueDate == nil || entry.NextDueDate.After(now),
+			})
+		case models.MedicalDeworming:
+			if summary.LastDeworming == nil {
+				summary.LastDeworming = &entry.Date
+			}
+		case models.MedicalFleaTreatment:
+			if summary.LastFleaTreatment == nil {
+				summary.LastFleaTreatment = &entry.Date
+			}
+		case models.MedicalVetVisit:
+			if summary.LastVetVisit == nil {
+				summary.LastVetVisit = &entry.Date
+			}
+		case models.MedicalWeight:
+			if summary.WeightGrams == nil {
+				summary.WeightGrams = entry.WeightGrams
+			}
+		case models.MedicalCondition:
+			if entry.Ongoing {
+				summary.Conditions = append(summary.Conditions, entry.Name)
+			}
+		}
+	}
+	return summary
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMedicalEntryRequestApply(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	weight, noWeight := 4200, 0
+	tests := []struct {
+		name string
+		req  medicalEntryRequest
+		err  string
+	}{
+		{name: "vaccination", req: medicalEntryRequest{Type: models.MedicalVaccination, Date: "01-06-2024", Name: " Typhus ", NextDueDate: "01-06-2025"}},
+		{name: "vaccination without a name", req: medicalEntryRequest{Type: models.MedicalVaccination, Date: "01-06-2024", Name: "  "}, err: "name is required for a vaccination"},
+		{name: "condition without a name", req: medicalEntryRequest{Type: models.MedicalCondition, Date: "01-06-2024"}, err: "name is required for a condition"},
+		{name: "weight", req: medicalEntryRequest{Type: models.MedicalWeight, Date: "01-06-2024", WeightGrams: &weight}},
+		{name: "weight without grams", req: medicalEntryRequest{Type: models.MedicalWeight, Date: "01-06-2024"}, err: "weightGrams must be positive for a weight measurement"},
+		{name: "weight of zero", req: medicalEntryRequest{Type: models.MedicalWeight, Date: "01-06-2024", WeightGrams: &noWeight}, err: "weightGrams must be positive for a weight measurement"},
+		{name: "deworming without a name", req: medicalEntryRequest{Type: models.MedicalDeworming, Date: "01-06-2024"}},
+		{name: "unknown type", req: medicalEntryRequest{Type: "surgery", Date: "01-06-2024", Name: "Castration"}, err: "type must be vaccination, deworming, flea_treatment, vet_visit, weight or condition"},
+		{name: "invalid date", req: medicalEntryRequest{Type: models.MedicalVetVisit, Date: "2024-06-01"}, err: "invalid date format, expected DD-MM-YYYY"},
+		{name: "date in the future", req: medicalEntryRequest{Type: models.MedicalVetVisit, Date: "16-06-2024"}, err: "date cannot be in the future"},
+		{name: "invalid next due date", req: medicalEntryRequest{Type: models.MedicalVaccination, Date: "01-06-2024", Name: "Typhus", NextDueDate: "2025-06-01"}, err: "invalid nextDueDate format, expected DD-MM-YYYY"},
+		{name: "next due date before the date", req: medicalEntryRequest{Type: models.MedicalVaccination, Date: "01-06-2024", Name: "Typhus", NextDueDate: "01-06-2024"}, err: "nextDueDate must be after date"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entry models.MedicalEntry
+
+			err := tt.req.apply(&entry, now)
+
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				assert.Empty(t, entry.Type)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.req.Type, entry.Type)
+		})
+	}
+}
+
+func TestMedicalEntryRequestApplyNormalizes(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	weight := 4200
+	entry := models.MedicalEntry{WeightGrams: &weight, Ongoing: true}
+
+	req := medicalEntryRequest{Type: models.MedicalVaccination, Date: "01-06-2024", Name: " Typhus ", BatchNumber: " A12 ", WeightGrams: &weight, NextDueDate: "01-06-2025", Ongoing: true}
+	require.NoError(t, req.apply(&entry, now))
+
+	assert.Equal(t, "Typhus", entry.Name)
+	assert.Equal(t, "A12", entry.BatchNumber)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), entry.Date)
+	require.NotNil(t, entry.NextDueDate)
+	assert.Equal(t, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), *entry.NextDueDate)
+	// Weights and ongoing only belong to their own type of entry
+	assert.Nil(t, entry.WeightGrams)
+	assert.False(t, entry.Ongoing)
+}
+
+func TestSummarizeMedicalRecord(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	day := func(month time.Month, d int) time.Time { return time.Date(2024, month, d, 0, 0, 0, 0, time.UTC) }
+	due := func(month time.Month, d int) *time.Time {
+		date := day(month, d)
+		return &date
+	}
+	light, heavy := 3800, 4200
+
+	// Entries come most recent first
+	entries := []models.MedicalEntry{
+		{Type: models.MedicalWeight, Date: day(6, 10), WeightGrams: &heavy},
+		{Type: models.MedicalVaccination, Date: day(6, 1), Name: "Typhus", NextDueDate: due(12, 1)},
+		{Type: models.MedicalCondition, Date: day(5, 20), Name: "Coryza", Ongoing: true},
+		{Type: models.MedicalDeworming, Date: day(5, 1)},
+		{Type: models.MedicalVaccination, Date: day(4, 1), Name: "Leucose", NextDueDate: due(6, 1)},
+		{Type: models.MedicalCondition, Date: day(3, 1), Name: "Otite"},
+		{Type: models.MedicalWeight, Date: day(3, 1), WeightGrams: &light},
+		{Type: models.MedicalVaccination, Date: day(2, 1), Name: "Rage"},
+		{Type: models.MedicalVaccination, Date: day(1, 1), Name: "typhus", NextDueDate: due(6, 1)},
+		{Type: models.MedicalDeworming, Date: day(1, 1)},
+	}
+
+	summary := summarizeMedicalRecord(&models.Cats{IdentificationNumber: "250268500123456"}, entries, now)
+
+	assert.True(t, summary.Identified)
+	assert.Equal(t, []vaccinationSummary{
+		{Name: "Typhus", Date: day(6, 1), NextDueDate: due(12, 1), UpToDate: true},
+		{Name: "Leucose", Date: day(4, 1), NextDueDate: due(6, 1), UpToDate: false},
+		{Name: "Rage", Date: day(2, 1), UpToDate: true},
+	}, summary.Vaccinations)
+	require.NotNil(t, summary.LastDeworming)
+	assert.Equal(t, day(5, 1), *summary.LastDeworming)
+	assert.Nil(t, summary.LastFleaTreatment)
+	assert.Nil(t, summary.LastVetVisit)
+	require.NotNil(t, summary.WeightGrams)
+	assert.Equal(t, heavy, *summary.WeightGrams)
+	assert.Equal(t, []string{"Coryza"}, summary.Conditions)
+}
+
+func TestSummarizeMedicalRecordWithoutEntries(t *testing.T) {
+	summary := summarizeMedicalRecord(&models.Cats{}, nil, time.Now())
+
+	assert.False(t, summary.Identified)
+	assert.NotNil(t, summary.Vaccinations)
+	assert.Empty(t, summary.Vaccinations)
+	assert.NotNil(t, summary.Conditions)
+	assert.Nil(t, summary.WeightGrams)
+}
+
+func TestGetAnnonceMedicalSummaryHandlerHidesUnpublished(t *testing.T) {
+	tests := []struct {
+		name   string
+		status models.AnnonceStatus
+		userID string
+		code   int
+	}{
+		{name: "published annonce seen by a stranger", status: models.AnnoncePublished, userID: "stranger", code: http.StatusOK},
+		{name: "draft annonce seen by its owner", status: models.AnnonceDraft, userID: "owner", code: http.StatusOK},
+		{name: "draft annonce seen by a stranger", status: models.AnnonceDraft, userID: "stranger", code: http.StatusNotFound},
+		{name: "paused annonce seen by a stranger", status: models.AnnoncePaused, userID: "stranger", code: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, db := newTestQueries()
+			db.On(`FROM "annonces"`, dbtest.Result{Columns: []string{"id", "cat_id", "user_id", "status"}, Values: [][]driver.Value{{int64(12), "7", "owner", string(tt.status)}}})
+			stubCat(db)
+			r := httptest.NewRequest(http.MethodGet, "/annonces/12/medical", nil)
+			r = withURLParams(withSubject(r, tt.userID, models.UserRole), map[string]string{"id": "12"})
+			w := httptest.NewRecorder()
+
+			NewMedicalRecordHandler(q).GetAnnonceMedicalSummaryHandler(w, r)
+
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+			entries := db.Queries(`FROM "medical_entries"`)
+			if tt.code == http.StatusOK {
+				assert.NotEmpty(t, entries)
+			} else {
+				assert.Empty(t, entries)
+			}
+		})
+	}
+}
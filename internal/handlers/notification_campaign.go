@@ -0,0 +1,496 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-challenge/internal/config"
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
+	"go-challenge/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+const campaignEvent notifications.Event = "campaign"
+
+// campaignBatch bounds the notifications a campaign sends per run of the
+// campaign job, so a large broadcast is spread over several runs.
+const campaignBatch = 500
+
+// campaignPlaceholder is the only template action campaign content may use.
+const campaignPlaceholder = "{{.Name}}"
+
+// The titles and bodies of a campaign are bounded by the size of their
+// columns, in characters.
+const (
+	maxCampaignTitleLen = 100
+	maxCampaignBodyLen  = 500
+)
+
+type NotificationCampaignHandler struct {
+	campaignQueries *queries.DatabaseService
+}
+
+func NewNotificationCampaignHandler(campaignQueries *queries.DatabaseService) *NotificationCampaignHandler {
+	return &NotificationCampaignHandler{campaignQueries: campaignQueries}
+}
+
+type notificationTargetRequest struct {
+	UserIDs        []string `json:"userIds"`
+	Roles          []string `json:"roles"`
+	AssociationIDs []string `json:"associationIds"`
+}
+
+func (t notificationTargetRequest) toModel() models.NotificationTarget {
+	return models.NotificationTarget{
+		UserIDs:        pq.StringArray(t.UserIDs),
+		Roles:          pq.StringArray(t.Roles),
+		AssociationIDs: pq.StringArray(t.AssociationIDs),
+	}
+}
+
+func (t notificationTargetRequest) isEmpty() bool {
+	return len(t.UserIDs) == 0 && len(t.Roles) == 0 && len(t.AssociationIDs) == 0
+}
+
+type campaignRecipient struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Locale string `json:"locale"`
+}
+
+type campaignReport struct {
+	Campaign   *models.NotificationCampaign  `json:"campaign"`
+	Deliveries []models.NotificationDelivery `json:"deliveries"`
+}
+
+// CreateNotificationAudienceHandler godoc
+// @Summary Create a notification audience
+// @Description Save a named set of users, roles and associations to target with broadcasts
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Success 201 {object} models.NotificationAudience "Audience created"
+// @Failure 400 {string} string "name and at least one target are required"
+// @Failure 500 {string} string "error creating audience"
+// @Router /notifications/audiences [post]
+func (h *NotificationCampaignHandler) CreateNotificationAudienceHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name string `json:"name"`
+		notificationTargetRequest
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if body.Name == "" || body.isEmpty() {
+		http.Error(w, "name and at least one target are required", http.StatusBadRequest)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+	adminID, _ := claims["id"].(string)
+
+	audience := &models.NotificationAudience{
+		Name:               body.Name,
+		CreatedBy:          adminID,
+		NotificationTarget: body.toModel(),
+	}
+	if err := h.campaignQueries.CreateNotificationAudience(audience); err != nil {
+		http.Error(w, "error creating audience", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(audience)
+}
+
+// GetNotificationAudiencesHandler godoc
+// @Summary List notification audiences
+// @Description Retrieve all saved notification audiences
+// @Tags notifications
+// @Produce json
+// @Success 200 {array} models.NotificationAudience "List of audiences"
+// @Failure 500 {string} string "error fetching audiences"
+// @Router /notifications/audiences [get]
+func (h *NotificationCampaignHandler) GetNotificationAudiencesHandler(w http.ResponseWriter, r *http.Request) {
+	audiences, err := h.campaignQueries.GetNotificationAudiences()
+	if err != nil {
+		http.Error(w, "error fetching audiences", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(audiences)
+}
+
+// DeleteNotificationAudienceHandler godoc
+// @Summary Delete a notification audience
+// @Description Delete a saved notification audience by its ID
+// @Tags notifications
+// @Param id path int true "Audience ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "invalid audience ID"
+// @Failure 500 {string} string "error deleting audience"
+// @Router /notifications/audiences/{id} [delete]
+func (h *NotificationCampaignHandler) DeleteNotificationAudienceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid audience ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.campaignQueries.DeleteNotificationAudience(uint(id)); err != nil {
+		http.Error(w, "error deleting audience", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateNotificationCampaignHandler godoc
+// @Summary Broadcast a notification
+// @Description Send a localized push notification to users, roles, associations or a saved audience. {{.Name}} in a title or body is replaced by the name of the recipient, any other brace is sent as written. The campaign is delivered in the background by batches, its report shows the progress. With dryRun set, only the resolved recipients are returned and nothing is sent.
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param dryRun query bool false "Only resolve the recipients"
+// @Success 200 {array} campaignRecipient "Resolved recipients (dry run)"
+// @Success 202 {object} models.NotificationCampaign "Campaign accepted"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 404 {string} string "audience not found"
+// @Failure 500 {string} string "error creating campaign"
+// @Router /notifications/campaigns [post]
+func (h *NotificationCampaignHandler) CreateNotificationCampaignHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		TitleFR    string `json:"titleFr"`
+		BodyFR     string `json:"bodyFr"`
+		TitleEN    string `json:"titleEn"`
+		BodyEN     string `json:"bodyEn"`
+		AudienceID *uint  `json:"audienceId"`
+		DryRun     bool   `json:"dryRun"`
+		notificationTargetRequest
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if dryRun, err := strconv.ParseBool(r.URL.Query().Get("dryRun")); err == nil {
+		body.DryRun = dryRun
+	}
+
+	target := body.toModel()
+	if body.AudienceID != nil {
+		audience, err := h.campaignQueries.FindNotificationAudienceByID(*body.AudienceID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				http.Error(w, "audience not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "error fetching audience", http.StatusInternalServerError)
+			return
+		}
+		target.UserIDs = append(target.UserIDs, audience.UserIDs...)
+		target.Roles = append(target.Roles, audience.Roles...)
+		target.AssociationIDs = append(target.AssociationIDs, audience.AssociationIDs...)
+	} else if body.isEmpty() {
+		http.Error(w, "at least one target or an audience is required", http.StatusBadRequest)
+		return
+	}
+
+	campaign := &models.NotificationCampaign{
+		TitleFR:            body.TitleFR,
+		BodyFR:             body.BodyFR,
+		TitleEN:            body.TitleEN,
+		BodyEN:             body.BodyEN,
+		AudienceID:         body.AudienceID,
+		NotificationTarget: target,
+	}
+
+	for _, field := range []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"titleFr", campaign.TitleFR, maxCampaignTitleLen},
+		{"bodyFr", campaign.BodyFR, maxCampaignBodyLen},
+		{"titleEn", campaign.TitleEN, maxCampaignTitleLen},
+		{"bodyEn", campaign.BodyEN, maxCampaignBodyLen},
+	} {
+		if utf8.RuneCountInString(field.value) > field.max {
+			http.Error(w, fmt.Sprintf("%s must be at most %d characters", field.name, field.max), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, err := campaignRegistry(campaign); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	users, err := h.campaignQueries.ResolveNotificationRecipients(target)
+	if err != nil {
+		http.Error(w, "error resolving recipients", http.StatusInternalServerError)
+		return
+	}
+
+	if body.DryRun {
+		recipients := make([]campaignRecipient, 0, len(users))
+		for _, user := range users {
+			recipients = append(recipients, campaignRecipient{
+				ID:     user.ID,
+				Name:   user.Name,
+				Locale: notifications.NormalizeLocale(user.Locale),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(recipients)
+		return
+	}
+
+	_, claims, _ := jwtauth.FromContext(r.Context())
+	campaign.CreatedBy, _ = claims["id"].(string)
+	campaign.Status = models.CampaignSending
+	campaign.Recipients = len(users)
+
+	if err := h.campaignQueries.CreateNotificationCampaign(campaign); err != nil {
+		http.Error(w, "error creating campaign", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(campaign)
+}
+
+// GetNotificationCampaignsHandler godoc
+// @Summary List notification campaigns
+// @Description Retrieve all broadcast campaigns, most recent first
+// @Tags notifications
+// @Produce json
+// @Success 200 {array} models.NotificationCampaign "List of campaigns"
+// @Failure 500 {string} string "error fetching campaigns"
+// @Router /notifications/campaigns [get]
+func (h *NotificationCampaignHandler) GetNotificationCampaignsHandler(w http.ResponseWriter, r *http.Request) {
+	campaigns, err := h.campaignQueries.GetNotificationCampaigns()
+	if err != nil {
+		http.Error(w, "error fetching campaigns", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(campaigns)
+}
+
+// GetNotificationCampaignReportHandler godoc
+// @Summary Get a campaign delivery report
+// @Description Retrieve a campaign with its counters and the delivery status of every recipient
+// @Tags notifications
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Success 200 {object} campaignReport "Delivery report"
+// @Failure 400 {string} string "invalid campaign ID"
+// @Failure 404 {string} string "campaign not found"
+// @Failure 500 {string} string "error fetching deliveries"
+// @Router /notifications/campaigns/{id}/report [get]
+func (h *NotificationCampaignHandler) GetNotificationCampaignReportHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid campaign ID", http.StatusBadRequest)
+		return
+	}
+
+	campaign, err := h.campaignQueries.FindNotificationCampaignByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "campaign not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "error fetching campaign", http.StatusInternalServerError)
+		return
+	}
+
+	deliveries, err := h.campaignQueries.GetNotificationDeliveriesByCampaignID(campaign.ID)
+	if err != nil {
+		http.Error(w, "error fetching deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(campaignReport{Campaign: campaign, Deliveries: deliveries})
+}
+
+// campaignRegistry builds a template registry holding the content of the
+// campaign, so broadcasts are rendered like every other notification.
+// Recipients whose locale has no content get the French version, or the
+// English one when the campaign is written in English only.
+// Admins write plain text, so the content is escaped but for the name
+// placeholder.
+func campaignRegistry(campaign *models.NotificationCampaign) (*notifications.Registry, error) {
+	contents := map[string]notifications.Template{
+		notifications.LocaleFR: {Title: campaign.TitleFR, Body: campaign.BodyFR},
+		notifications.LocaleEN: {Title: campaign.TitleEN, Body: campaign.BodyEN},
+	}
+
+	fallback := notifications.LocaleFR
+	if campaign.TitleFR == "" && campaign.BodyFR == "" {
+		fallback = notifications.LocaleEN
+	}
+	registry := notifications.NewRegistry(fallback)
+
+	hasContent := false
+	for locale, content := range contents {
+		if content.Title == "" && content.Body == "" {
+			continue
+		}
+		if content.Title == "" || content.Body == "" {
+			return nil, fmt.Errorf("title and body are both required for locale %s", locale)
+		}
+		content = notifications.Template{Title: campaignTemplate(content.Title), Body: campaignTemplate(content.Body)}
+		if err := registry.Register(campaignEvent, locale, content); err != nil {
+			return nil, err
+		}
+		hasContent = true
+	}
+
+	if !hasContent {
+		return nil, errors.New("a title and a body are required in at least one locale")
+	}
+	return registry, nil
+}
+
+// campaignEscaper turns the braces of campaign content into template
+// actions printing them.
+var campaignEscaper = strings.NewReplacer("{{", `{{"{{"}}`, "}}", `{{"}}"}}`)
+
+// campaignTemplate turns campaign content into a template printing it as
+// written, with the name placeholder as its only action.
+func campaignTemplate(text string) string {
+	parts := strings.Split(text, campaignPlaceholder)
+	for i, part := range parts {
+		parts[i] = campaignEscaper.Replace(part)
+	}
+	return strings.Join(parts, campaignPlaceholder)
+}
+
+// RunNotificationCampaigns sends the next batch of every campaign being
+// delivered. The deliveries saved so far record the progress, so a campaign
+// interrupted by a restart goes on from where it stopped.
+func RunNotificationCampaigns(q *queries.DatabaseService, now time.Time) {
+	campaigns, err := q.FindSendingNotificationCampaigns()
+	if err != nil {
+		utils.Logger("error", "Notification Campaign:", "Failed to fetch campaigns", fmt.Sprintf("Error: %v", err))
+		return
+	}
+	for i := range campaigns {
+		deliverCampaign(q, &campaigns[i], now)
+	}
+}
+
+// deliverCampaign sends a campaign to at most campaignBatch of the recipients
+// it did not go to yet, and closes it once they all had theirs.
+func deliverCampaign(q *queries.DatabaseService, campaign *models.NotificationCampaign, now time.Time) {
+	registry, err := campaignRegistry(campaign)
+	if err != nil {
+		utils.Logger("error", "Notification Campaign:", "Invalid campaign content", fmt.Sprintf("Error: %v", err))
+		campaign.Status = models.CampaignFailed
+		campaign.FinishedAt = &now
+		if err := q.UpdateNotificationCampaign(campaign); err != nil {
+			utils.Logger("error", "Notification Campaign:", "Failed to update campaign", fmt.Sprintf("Error: %v", err))
+		}
+		return
+	}
+	users, err := q.ResolveNotificationRecipients(campaign.NotificationTarget)
+	if err != nil {
+		utils.Logger("error", "Notification Campaign:", "Failed to resolve recipients", fmt.Sprintf("Error: %v", err))
+		return
+	}
+	delivered, err := q.FindDeliveredUserIDs(campaign.ID)
+	if err != nil {
+		utils.Logger("error", "Notification Campaign:", "Failed to fetch deliveries", fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	var pending []models.User
+	for _, user := range users {
+		if !delivered[user.ID] {
+			pending = append(pending, user)
+		}
+	}
+	finished := len(pending) <= campaignBatch
+	if !finished {
+		pending = pending[:campaignBatch]
+	}
+	for _, user := range pending {
+		deliverCampaignTo(q, campaign, registry, user)
+	}
+
+	counts, err := q.CountNotificationDeliveries(campaign.ID)
+	if err != nil {
+		utils.Logger("error", "Notification Campaign:", "Failed to count deliveries", fmt.Sprintf("Error: %v", err))
+		return
+	}
+	campaign.Sent = counts[models.DeliverySent]
+	campaign.NoToken = counts[models.DeliveryNoToken]
+	campaign.Failed = counts[models.DeliveryFailed]
+	if finished {
+		campaign.FinishedAt = &now
+		campaign.Status = models.CampaignSent
+		if campaign.Recipients > 0 && campaign.Failed == campaign.Recipients {
+			campaign.Status = models.CampaignFailed
+		}
+	}
+	if err := q.UpdateNotificationCampaign(campaign); err != nil {
+		utils.Logger("error", "Notification Campaign:", "Failed to update campaign", fmt.Sprintf("Error: %v", err))
+	}
+}
+
+// deliverCampaignTo sends a campaign to one user and records the outcome.
+func deliverCampaignTo(q *queries.DatabaseService, campaign *models.NotificationCampaign, registry *notifications.Registry, user models.User) {
+	delivery := &models.NotificationDelivery{
+		CampaignID: campaign.ID,
+		UserID:     user.ID,
+	}
+
+	message, err := registry.Render(campaignEvent, user.Locale, map[string]string{"Name": user.Name})
+	if err != nil {
+		delivery.Status = models.DeliveryFailed
+		delivery.Error = err.Error()
+	} else {
+		delivery.Locale = message.Locale
+		notificationToken, err := q.GetNotificationTokenByUserID(user.ID)
+		if err != nil || notificationToken.Token == "" {
+			delivery.Status = models.DeliveryNoToken
+		} else {
+			payload := map[string]string{
+				"Event":      string(campaignEvent),
+				"CampaignID": strconv.FormatUint(uint64(campaign.ID), 10),
+			}
+			messageID, err := SendToToken(config.GetFirebaseApp(), notificationToken.Token, message.Body, message.Title, payload)
+			if err != nil {
+				delivery.Status = models.DeliveryFailed
+				delivery.Error = err.Error()
+			} else {
+				delivery.Status = models.DeliverySent
+				delivery.MessageID = messageID
+			}
+		}
+	}
+
+	if err := q.CreateNotificationDelivery(delivery); err != nil {
+		utils.Logger("error", "Notification Campaign:", "Failed to save delivery", fmt.Sprintf("Error: %v", err))
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCampaignRegistryPrintsBraces(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{body: "Bonjour {{.Name}} !", want: "Bonjour Léa !"},
+		{body: "Code {{PROMO}} valable", want: "Code {{PROMO}} valable"},
+		{body: "{{.Name}}, tapez {{ .Name }} ou }} {{", want: "Léa, tapez {{ .Name }} ou }} {{"},
+		{body: `{{"{{"}}`, want: `{{"{{"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			registry, err := campaignRegistry(&models.NotificationCampaign{TitleFR: "Nouvelles", BodyFR: tt.body})
+			require.NoError(t, err)
+
+			message, err := registry.Render(campaignEvent, "fr", map[string]string{"Name": "Léa"})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, message.Body)
+		})
+	}
+}
+
+func TestRunNotificationCampaignsResumes(t *testing.T) {
+	q, db := newTestQueries()
+	db.On(`FROM "notification_campaigns"`, dbtest.Result{
+		Columns: []string{"id", "title_fr", "body_fr", "user_ids", "status", "recipients"},
+		Values:  [][]driver.Value{{int64(4), "Nouvelles", "Bonjour {{.Name}}", "{a,b,c}", string(models.CampaignSending), int64(3)}},
+	})
+	db.On(`FROM "users"`, dbtest.Result{Columns: []string{"id", "name"}, Values: [][]driver.Value{{"a", "Ana"}, {"b", "Bob"}, {"c", "Cléo"}}})
+	db.On("COUNT(*) AS count", dbtest.Result{Columns: []string{"status", "count"}, Values: [][]driver.Value{{string(models.DeliveryNoToken), int64(3)}}})
+	db.On(`FROM "notification_deliveries"`, dbtest.Result{Columns: []string{"user_id"}, Values: [][]driver.Value{{"a"}}})
+
+	RunNotificationCampaigns(q, time.Now())
+
+	inserts := db.Queries(`INSERT INTO "notification_deliveries"`)
+	require.Len(t, inserts, 2)
+	assert.Contains(t, inserts[0].Args, "b")
+	assert.Contains(t, inserts[1].Args, "c")
+	updates := db.Queries(`UPDATE "notification_campaigns"`)
+	require.Len(t, updates, 1)
+	assert.Contains(t, updates[0].Args, string(models.CampaignSent))
+}
+
+func TestCreateNotificationCampaignHandlerChecksLengths(t *testing.T) {
+	tests := []struct {
+		name    string
+		titleFr string
+		bodyEn  string
+		code    int
+	}{
+		{name: "longest title", titleFr: strings.Repeat("é", maxCampaignTitleLen), bodyEn: "Hello", code: http.StatusAccepted},
+		{name: "title too long", titleFr: strings.Repeat("a", maxCampaignTitleLen+1), bodyEn: "Hello", code: http.StatusBadRequest},
+		{name: "body too long", titleFr: "Nouvelles", bodyEn: strings.Repeat("a", maxCampaignBodyLen+1), code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, db := newTestQueries()
+			payload, err := json.Marshal(map[string]interface{}{
+				"titleFr": tt.titleFr, "bodyFr": "Bonjour", "titleEn": "News", "bodyEn": tt.bodyEn, "userIds": []string{"user"},
+			})
+			require.NoError(t, err)
+			r := httptest.NewRequest(http.MethodPost, "/notifications/campaigns", bytes.NewReader(payload))
+			w := httptest.NewRecorder()
+
+			NewNotificationCampaignHandler(q).CreateNotificationCampaignHandler(w, withSubject(r, "admin", models.AdminRole))
+
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+			inserts := db.Queries(`INSERT INTO "notification_campaigns"`)
+			if tt.code == http.StatusAccepted {
+				assert.Len(t, inserts, 1)
+			} else {
+				assert.Empty(t, inserts)
+			}
+		})
+	}
+}
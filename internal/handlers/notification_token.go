@@ -2,16 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"go-challenge/internal/config"
 	"go-challenge/internal/database/queries"
 	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jinzhu/gorm"
 
 	"context"
 	"fmt"
-	"log"
 
 	firebase "firebase.google.com/go"
 	"firebase.google.com/go/messaging"
@@ -60,22 +62,39 @@ func (h *NotificationTokenHandler) DeleteNotificationTokenHandler(w http.Respons
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *NotificationTokenHandler) SendNotificationHandler(w http.ResponseWriter, r *http.Request) {
-	var notification models.Notification
-	err := json.NewDecoder(r.Body).Decode(&notification)
+// NotifyUser renders the notification of an event in the preferred locale of
+// the user and pushes it to the device registered for that user. A user with
+// no registered device gets nothing pushed, which is not an error.
+func NotifyUser(q *queries.DatabaseService, userID string, event notifications.Event, vars map[string]string, payload map[string]string) (*notifications.Message, error) {
+	user, err := q.FindUserByID(userID)
 	if err != nil {
-		http.Error(w, "error decoding notification", http.StatusBadRequest)
-		return
+		return nil, fmt.Errorf("error finding user: %w", err)
 	}
 
-	payload := make(map[string]string)
-	payload["RoomID"] = notification.RoomID
-	SendToToken(config.GetFirebaseApp(), notification.Token, notification.Text, notification.Title, payload)
+	message, err := notifications.Render(event, user.Locale, vars)
+	if err != nil {
+		return nil, err
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(notification)
+	notificationToken, err := q.GetNotificationTokenByUserID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return message, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting notification token: %w", err)
+	}
+	if notificationToken.Token == "" {
+		return message, nil
+	}
 
+	if payload == nil {
+		payload = make(map[string]string)
+	}
+	payload["Event"] = string(event)
+	if _, err := SendToToken(config.GetFirebaseApp(), notificationToken.Token, message.Body, message.Title, payload); err != nil {
+		return nil, err
+	}
+	return message, nil
 }
 
 func FindUserToken(notificationTokenQueries *queries.DatabaseService, userID string) (string, error) {
@@ -87,11 +106,12 @@ func FindUserToken(notificationTokenQueries *queries.DatabaseService, userID str
 
 }
 
-func SendToToken(app *firebase.App, fcmToken string, text string, title string, payload map[string]string) {
+// SendToToken pushes a notification to a device and returns the FCM message ID.
+func SendToToken(app *firebase.App, fcmToken string, text string, title string, payload map[string]string) (string, error) {
 	ctx := context.Background()
 	client, err := app.Messaging(ctx)
 	if err != nil {
-		log.Fatalf("error getting Messaging client: %v\n", err)
+		return "", fmt.Errorf("error getting Messaging client: %w", err)
 	}
 
 	message := &messaging.Message{
@@ -105,32 +125,8 @@ func SendToToken(app *firebase.App, fcmToken string, text string, title string,
 
 	response, err := client.Send(ctx, message)
 	if err != nil {
-		log.Fatalln(err)
-	}
-	fmt.Println("Successfully sent message:", response)
-}
-
-func (h *NotificationTokenHandler) TestSendNotificationHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-	client, err := config.GetFirebaseApp().Messaging(ctx)
-	if err != nil {
-		log.Fatalf("error getting Messaging client: %v\n", err)
-	}
-
-	// query token from database
-	fcmToken := "dqLX69OBQnexTV5QJEdRTj:APA91bHCmnH8MKNpYZuTaxONWwyAOs2mK1hUxOhKqS2MK5Nk-1WUCZdpC1BVQcFnbDGW6wcrBeS6c67nmOUuYdieXOCRp2_7zPYt8XTcYjTb8rZYmdn4EjSmh45VkYo9x8WgYw7BThxt"
-
-	message := &messaging.Message{
-		Notification: &messaging.Notification{
-			Title: "Title",
-			Body:  "Message",
-		},
-		Token: fcmToken,
-	}
-
-	response, err := client.Send(ctx, message)
-	if err != nil {
-		log.Fatalln(err)
+		return "", fmt.Errorf("error sending message: %w", err)
 	}
 	fmt.Println("Successfully sent message:", response)
+	return response, nil
 }
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/models"
+	"go-challenge/internal/policy"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+)
+
+// requestSubject returns the user a request is authenticated as, from the
+// claims of its JWT.
+func requestSubject(r *http.Request) (policy.Subject, error) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		return policy.Subject{}, err
+	}
+	userID, ok := claims["id"].(string)
+	if !ok || userID == "" {
+		return policy.Subject{}, errors.New("missing user ID in claims")
+	}
+	role, _ := claims["role"].(string)
+	return policy.Subject{UserID: userID, Role: models.RoleName(role)}, nil
+}
+
+// publishingAssociation returns the association named by the PublishedAs of
+// a cat, or nil when there is none or it no longer exists.
+func publishingAssociation(q *queries.DatabaseService, publishedAs string) *models.Association {
+	if publishedAs == "" {
+		return nil
+	}
+	associationID, err := strconv.Atoi(publishedAs)
+	if err != nil {
+		return nil
+	}
+	association, err := q.FindAssociationById(associationID)
+	if err != nil {
+		return nil
+	}
+	return association
+}
+
+// canManageCat reports whether a subject may edit a cat: admins, its owner,
+// and the owner and members of the association it is published as.
+func canManageCat(q *queries.DatabaseService, cat *models.Cats, subject policy.Subject) bool {
+	return policy.CanWriteCat(subject, cat, publishingAssociation(q, cat.PublishedAs))
+}
+
+// canManageLitter reports whether a subject may edit a litter, with the same
+// rules as for cats.
+func canManageLitter(q *queries.DatabaseService, litter *models.Litter, subject policy.Subject) bool {
+	return policy.CanWriteLitter(subject, litter, publishingAssociation(q, litter.PublishedAs))
+}
+
+// canManageAnnonce reports whether a subject may edit an annonce: its author,
+// and whoever may edit the cat it is about.
+func canManageAnnonce(q *queries.DatabaseService, annonce *models.Annonce, subject policy.Subject) bool {
+	cat, err := q.FindCatByID(annonce.CatID)
+	if err != nil {
+		return policy.CanWriteAnnonce(subject, annonce, nil, nil)
+	}
+	return policy.CanWriteAnnonce(subject, annonce, cat, publishingAssociation(q, cat.PublishedAs))
+}
+
+// canSeeAnnonce reports whether the current user may see an annonce: anyone
+// once it is published, only whoever manages it otherwise.
+func canSeeAnnonce(q *queries.DatabaseService, annonce *models.Annonce, r *http.Request) bool {
+	if annonce.Status == models.AnnoncePublished {
+		return true
+	}
+	subject, err := requestSubject(r)
+	if err != nil {
+		return false
+	}
+	return canManageAnnonce(q, annonce, subject)
+}
+
+// findManagedCat loads the cat of the route and writes the error response
+// when it does not exist or the current user may not edit it.
+func findManagedCat(q *queries.DatabaseService, w http.ResponseWriter, r *http.Request) (*models.Cats, string, bool) {
+	subject, err := requestSubject(r)
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return nil, "", false
+	}
+
+	cat, err := q.FindCatByID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "cat not found", http.StatusNotFound)
+		return nil, "", false
+	}
+
+	if !canManageCat(q, cat, subject) {
+		http.Error(w, "only the owner of the cat or its association can manage it", http.StatusForbidden)
+		return nil, "", false
+	}
+	return cat, subject.UserID, true
+}
@@ -4,33 +4,61 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"go-challenge/internal/database/queries"
 	"go-challenge/internal/models"
+	"go-challenge/internal/storage"
+	"go-challenge/internal/utils"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/uploadcare/uploadcare-go/ucare"
+	"github.com/jinzhu/gorm"
 )
 
+const (
+	raceFormatJSON = "json"
+	raceFormatCSV  = "csv"
+)
+
+// maxRaceCatalogueSize is the largest catalogue accepted for import, in bytes.
+const maxRaceCatalogueSize = 5 << 20
+
 type RaceHandler struct {
-	raceQueries      *queries.DatabaseService
-	uploadcareClient ucare.Client
+	raceQueries *queries.DatabaseService
+	store       storage.BlobStore
 }
 
-func NewRaceHandler(raceQueries *queries.DatabaseService, uploadcareClient ucare.Client) *RaceHandler {
-	return &RaceHandler{raceQueries: raceQueries, uploadcareClient: uploadcareClient}
+func NewRaceHandler(raceQueries *queries.DatabaseService, store storage.BlobStore) *RaceHandler {
+	return &RaceHandler{raceQueries: raceQueries, store: store}
 }
 
 // GetAllRaceHandler godoc
 // @Summary Get all races
-// @Description Retrieve a list of all race
+// @Description Retrieve the breeds of the catalogue, by name, optionally filtered by their traits
 // @Tags race
 // @Produce  json
+// @Param q query string false "Part of the name or of an alias of the breed"
+// @Param coatLength query string false "hairless, short, medium or long"
+// @Param size query string false "small, medium or large"
+// @Param activityLevel query string false "low, moderate or high"
+// @Param hypoallergenic query bool false "Only hypoallergenic breeds, or only the others"
+// @Param temperament query string false "Comma separated temperaments the breed must be known for"
 // @Success 200 {array} models.Races "List of race"
+// @Failure 400 {string} string "Invalid filter"
 // @Failure 500 {string} string "error fetching races"
 // @Router /races [get]
 func (h *RaceHandler) GetAllRaceHandler(w http.ResponseWriter, r *http.Request) {
-	races, err := h.raceQueries.GetAllRace()
+	filter, err := parseRaceFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Query = strings.TrimSpace(r.URL.Query().Get("q"))
+
+	races, err := h.raceQueries.SearchRaces(filter)
 	if err != nil {
 		http.Error(w, "error fetching races", http.StatusInternalServerError)
 		return
@@ -88,7 +116,7 @@ func (h *RaceHandler) GetRaceByIDHandler(w http.ResponseWriter, r *http.Request)
 // @Success 200 {object} models.Races "Successfully updated race"
 // @Failure 400 {object} string "Invalid ID supplied"
 // @Failure 404 {object} string "Race not found"
-// @Failure 400 {object} string "Invalid JSON body"
+// @Failure 400 {object} string "Invalid JSON body or traits"
 // @Failure 500 {object} string "Error updating race"
 // @Router /races/{id} [put]
 func (h *RaceHandler) UpdateRaceHandler(w http.ResponseWriter, r *http.Request) {
@@ -109,6 +137,11 @@ func (h *RaceHandler) UpdateRaceHandler(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
 		return
 	}
+	if err := race.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	race.FillPhotoVariants()
 
 	err = h.raceQueries.UpdateRace(race)
 	if err != nil {
@@ -129,7 +162,7 @@ func (h *RaceHandler) UpdateRaceHandler(w http.ResponseWriter, r *http.Request)
 // @Produce  json
 // @Param body body models.Races true "Race object"
 // @Success 200 {object} models.Races "Successfully created race"
-// @Failure 400 {object} string "Invalid JSON body"
+// @Failure 400 {object} string "Invalid JSON body or traits"
 // @Failure 500 {object} string "Error creating race"
 // @Router /races [post]
 func (h *RaceHandler) CreateRaceHandler(w http.ResponseWriter, r *http.Request) {
@@ -140,6 +173,11 @@ func (h *RaceHandler) CreateRaceHandler(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
 		return
 	}
+	if err := race.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	race.FillPhotoVariants()
 
 	err = h.raceQueries.CreateRace(&race)
 	if err != nil {
@@ -178,3 +216,191 @@ func (h *RaceHandler) DeleteRaceHandler(w http.ResponseWriter, r *http.Request)
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// UploadRacePhotoHandler godoc
+// @Summary Upload the photo of a race
+// @Description Replace the photo of a breed of the catalogue
+// @Tags race
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Race ID"
+// @Param uploaded_file formData file true "Image"
+// @Success 200 {object} models.Races "Race with its new photo"
+// @Failure 400 {string} string "invalid image"
+// @Failure 404 {string} string "Race not found"
+// @Failure 500 {string} string "Error updating race"
+// @Router /races/{id}/photo [put]
+func (h *RaceHandler) UploadRacePhotoHandler(w http.ResponseWriter, r *http.Request) {
+	race, err := h.raceQueries.FindRaceByID(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Race not found", http.StatusNotFound)
+		return
+	}
+
+	if err := r.ParseMultipartForm(maxImageSize); err != nil {
+		http.Error(w, "invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	files := r.MultipartForm.File["uploaded_file"]
+	if len(files) == 0 {
+		http.Error(w, "uploaded_file is required", http.StatusBadRequest)
+		return
+	}
+
+	image, err := uploadImage(r.Context(), h.store, files[0])
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+
+	previous := []string{race.PhotoURL, race.PhotoMediumURL, race.PhotoThumbnailURL}
+	race.PhotoURL = image.URL
+	race.PhotoMediumURL = image.MediumURL
+	race.PhotoThumbnailURL = image.ThumbnailURL
+	if err := h.raceQueries.UpdateRace(race); err != nil {
+		deleteImageFiles(r.Context(), h.store, image.URL, image.MediumURL, image.ThumbnailURL)
+		http.Error(w, "Error updating race", http.StatusInternalServerError)
+		return
+	}
+	deleteImageFiles(r.Context(), h.store, previous...)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(race)
+}
+
+// ExportRacesHandler godoc
+// @Summary Export the race catalogue
+// @Description Download every breed of the catalogue, in JSON or in CSV with lists separated by |
+// @Tags race
+// @Produce json
+// @Produce text/csv
+// @Param format query string false "json (default) or csv"
+// @Success 200 {array} models.Races "Catalogue"
+// @Failure 400 {string} string "format must be json or csv"
+// @Failure 500 {string} string "error fetching races"
+// @Router /races/export [get]
+func (h *RaceHandler) ExportRacesHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != raceFormatJSON && format != raceFormatCSV {
+		http.Error(w, "format must be json or csv", http.StatusBadRequest)
+		return
+	}
+
+	races, err := h.raceQueries.SearchRaces(queries.RaceFilter{})
+	if err != nil {
+		http.Error(w, "error fetching races", http.StatusInternalServerError)
+		return
+	}
+
+	if format == raceFormatCSV {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="races.csv"`)
+		if err := models.WriteRacesCSV(w, races); err != nil {
+			utils.Logger("error", "Export Races:", "Failed to write races CSV", fmt.Sprintf("Error: %v", err))
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="races.json"`)
+	json.NewEncoder(w).Encode(races)
+}
+
+// ImportRacesHandler godoc
+// @Summary Import a race catalogue
+// @Description Create the breeds of a JSON or CSV catalogue, or update the ones already known under the same name. The format is taken from the format parameter, or else from the Content-Type.
+// @Tags race
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param format query string false "json or csv"
+// @Success 200 {object} queries.RaceImport "Number of races created and updated"
+// @Failure 400 {string} string "Invalid catalogue"
+// @Failure 500 {string} string "error importing races"
+// @Router /races/import [post]
+func (h *RaceHandler) ImportRacesHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = raceFormatJSON
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+			format = raceFormatCSV
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxRaceCatalogueSize)
+	var races []models.Races
+	var err error
+	switch format {
+	case raceFormatJSON:
+		err = json.NewDecoder(body).Decode(&races)
+	case raceFormatCSV:
+		races, err = models.ReadRacesCSV(body)
+	default:
+		http.Error(w, "format must be json or csv", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid catalogue: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(races) == 0 {
+		http.Error(w, "the catalogue has no race", http.StatusBadRequest)
+		return
+	}
+
+	for i := range races {
+		races[i].Model = gorm.Model{}
+		races[i].Cats = nil
+		if err := races[i].Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("race %d: %v", i+1, err), http.StatusBadRequest)
+			return
+		}
+		races[i].FillPhotoVariants()
+	}
+
+	result, err := h.raceQueries.ImportRaces(races)
+	if err != nil {
+		http.Error(w, "error importing races", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(result)
+}
+
+// parseRaceFilter reads the trait filters of a breed listing or of a cat
+// search from query parameters.
+func parseRaceFilter(params url.Values) (queries.RaceFilter, error) {
+	filter := queries.RaceFilter{
+		CoatLength:    models.CoatLength(params.Get("coatLength")),
+		Size:          models.BreedSize(params.Get("size")),
+		ActivityLevel: models.ActivityLevel(params.Get("activityLevel")),
+	}
+	if filter.CoatLength != "" && !filter.CoatLength.Valid() {
+		return filter, errors.New("coatLength must be hairless, short, medium or long")
+	}
+	if filter.Size != "" && !filter.Size.Valid() {
+		return filter, errors.New("size must be small, medium or large")
+	}
+	if filter.ActivityLevel != "" && !filter.ActivityLevel.Valid() {
+		return filter, errors.New("activityLevel must be low, moderate or high")
+	}
+
+	if params.Get("hypoallergenic") != "" {
+		hypoallergenic, err := strconv.ParseBool(params.Get("hypoallergenic"))
+		if err != nil {
+			return filter, errors.New("hypoallergenic must be true or false")
+		}
+		filter.Hypoallergenic = &hypoallergenic
+	}
+
+	if params.Get("temperament") != "" {
+		for _, temperament := range strings.Split(params.Get("temperament"), ",") {
+			temperament = strings.TrimSpace(temperament)
+			if !models.ValidTemperament(temperament) {
+				return filter, fmt.Errorf("unknown temperament %q", temperament)
+			}
+			filter.Temperament = append(filter.Temperament, temperament)
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/matching"
+	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	defaultRecommendationLimit = 20
+	maxRecommendationLimit     = 100
+)
+
+type RecommendationHandler struct {
+	recommendationQueries *queries.DatabaseService
+}
+
+func NewRecommendationHandler(recommendationQueries *queries.DatabaseService) *RecommendationHandler {
+	return &RecommendationHandler{recommendationQueries: recommendationQueries}
+}
+
+type recommendation struct {
+	Cat     models.Cats       `json:"cat"`
+	Annonce *models.Annonce   `json:"annonce"`
+	Score   int               `json:"score"`
+	Reasons []matching.Reason `json:"reasons"`
+}
+
+// GetLifestyleProfileHandler godoc
+// @Summary Get the lifestyle questionnaire of the current user
+// @Description Retrieve the answers of the authenticated user to the adopter questionnaire
+// @Tags recommendations
+// @Produce json
+// @Success 200 {object} models.LifestyleProfile "Lifestyle profile"
+// @Failure 404 {string} string "lifestyle profile not found"
+// @Failure 500 {string} string "error fetching lifestyle profile"
+// @Router /me/lifestyle [get]
+func (h *RecommendationHandler) GetLifestyleProfileHandler(w http.ResponseWriter, r *http.Request) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+	userID := claims["id"].(string)
+
+	profile, err := h.recommendationQueries.FindLifestyleProfileByUserID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "lifestyle profile not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "error fetching lifestyle profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// UpdateLifestyleProfileHandler godoc
+// @Summary Fill in the lifestyle questionnaire
+// @Description Save the answers of the authenticated user to the adopter questionnaire
+// @Tags recommendations
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.LifestyleProfile "Lifestyle profile saved"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 500 {string} string "error saving lifestyle profile"
+// @Router /me/lifestyle [put]
+func (h *RecommendationHandler) UpdateLifestyleProfileHandler(w http.ResponseWriter, r *http.Request) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+	userID := claims["id"].(string)
+
+	var body struct {
+		HomeType         models.HomeType   `json:"homeType"`
+		HasYoungChildren bool              `json:"hasYoungChildren"`
+		HasOlderChildren bool              `json:"hasOlderChildren"`
+		HasCats          bool              `json:"hasCats"`
+		HasDogs          bool              `json:"hasDogs"`
+		HoursAlonePerDay int               `json:"hoursAlonePerDay"`
+		Experience       models.Experience `json:"experience"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	switch body.HomeType {
+	case models.HomeApartment, models.HomeHouse, models.HomeGarden:
+	default:
+		http.Error(w, "homeType must be apartment, house or house_with_garden", http.StatusBadRequest)
+		return
+	}
+	switch body.Experience {
+	case models.ExperienceNone, models.ExperienceSome, models.ExperienceExperienced:
+	default:
+		http.Error(w, "experience must be none, some or experienced", http.StatusBadRequest)
+		return
+	}
+	if body.HoursAlonePerDay < 0 || body.HoursAlonePerDay > 24 {
+		http.Error(w, "hoursAlonePerDay must be between 0 and 24", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.recommendationQueries.FindLifestyleProfileByUserID(userID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "error fetching lifestyle profile", http.StatusInternalServerError)
+			return
+		}
+		profile = &models.LifestyleProfile{UserID: userID}
+	}
+
+	profile.HomeType = body.HomeType
+	profile.HasYoungChildren = body.HasYoungChildren
+	profile.HasOlderChildren = body.HasOlderChildren
+	profile.HasCats = body.HasCats
+	profile.HasDogs = body.HasDogs
+	profile.HoursAlonePerDay = body.HoursAlonePerDay
+	profile.Experience = body.Experience
+
+	if err := h.recommendationQueries.SaveLifestyleProfile(profile); err != nil {
+		http.Error(w, "error saving lifestyle profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// GetRecommendationsHandler godoc
+// @Summary Get recommended cats
+// @Description Score the adoptable cats against the lifestyle questionnaire, favorites and dismissals of the authenticated user, best matches first. Reasons are explained in the locale of the user.
+// @Tags recommendations
+// @Produce json
+// @Param limit query int false "Number of recommendations (max 100)"
+// @Success 200 {array} recommendation "Scored cats with an explanation"
+// @Failure 500 {string} string "error computing recommendations"
+// @Router /me/recommendations [get]
+func (h *RecommendationHandler) GetRecommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+	userID := claims["id"].(string)
+
+	limit := defaultRecommendationLimit
+	if value, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && value > 0 {
+		limit = value
+	}
+	if limit > maxRecommendationLimit {
+		limit = maxRecommendationLimit
+	}
+
+	profile, err := h.recommendationQueries.FindLifestyleProfileByUserID(userID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "error fetching lifestyle profile", http.StatusInternalServerError)
+			return
+		}
+		profile = nil
+	}
+
+	cats, err := h.recommendationQueries.GetAdoptableCats(userID)
+	if err != nil {
+		http.Error(w, "error fetching cats", http.StatusInternalServerError)
+		return
+	}
+
+	racesByID := map[string]*models.Races{}
+	races, err := h.recommendationQueries.GetAllRace()
+	if err != nil {
+		http.Error(w, "error fetching races", http.StatusInternalServerError)
+		return
+	}
+	for i := range races {
+		racesByID[strconv.FormatUint(uint64(races[i].ID), 10)] = &races[i]
+	}
+
+	signals, dismissed, err := h.userSignals(userID)
+	if err != nil {
+		http.Error(w, "error computing recommendations", http.StatusInternalServerError)
+		return
+	}
+
+	locale := notifications.NormalizeLocale(r.Header.Get("Accept-Language"))
+	if user, err := h.recommendationQueries.FindUserByID(userID); err == nil && user.Locale != "" {
+		locale = user.Locale
+	}
+
+	now := time.Now()
+	recommendations := []recommendation{}
+	for i := range cats {
+		cat := &cats[i]
+		if dismissed[cat.ID] {
+			continue
+		}
+		result := matching.Score(profile, cat, racesByID[cat.RaceID], signals, locale, now)
+		recommendations = append(recommendations, recommendation{Cat: *cat, Score: result.Score, Reasons: result.Reasons})
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return recommendations[i].Cat.CreatedAt.After(recommendations[j].Cat.CreatedAt)
+	})
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+
+	for i := range recommendations {
+		annonce, err := h.recommendationQueries.FindPublishedAnnonceByCatID(fmt.Sprintf("%d", recommendations[i].Cat.ID))
+		if err == nil {
+			withAnnonceDetails(h.recommendationQueries, annonce)
+			recommendations[i].Annonce = annonce
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(recommendations)
+}
+
+// DismissCatHandler godoc
+// @Summary Dismiss a recommended cat
+// @Description Stop recommending a cat to the authenticated user
+// @Tags recommendations
+// @Param catID path string true "Cat ID"
+// @Success 204 "No Content"
+// @Failure 404 {string} string "cat not found"
+// @Failure 500 {string} string "error dismissing cat"
+// @Router /me/recommendations/{catID}/dismiss [post]
+func (h *RecommendationHandler) DismissCatHandler(w http.ResponseWriter, r *http.Request) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+	userID := claims["id"].(string)
+
+	cat, err := h.recommendationQueries.FindCatByID(chi.URLParam(r, "catID"))
+	if err != nil {
+		http.Error(w, "cat not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.recommendationQueries.CreateCatDismissal(&models.CatDismissal{UserID: userID, CatID: cat.ID}); err != nil {
+		http.Error(w, "error dismissing cat", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UndoCatDismissalHandler godoc
+// @Summary Undo the dismissal of a cat
+// @Description Recommend a dismissed cat again to the authenticated user
+// @Tags recommendations
+// @Param catID path string true "Cat ID"
+// @Success 204 "No Content"
+// @Failure 500 {string} string "error undoing dismissal"
+// @Router /me/recommendations/{catID}/dismiss [delete]
+func (h *RecommendationHandler) UndoCatDismissalHandler(w http.ResponseWriter, r *http.Request) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+	userID := claims["id"].(string)
+
+	if err := h.recommendationQueries.DeleteCatDismissal(userID, chi.URLParam(r, "catID")); err != nil {
+		http.Error(w, "error undoing dismissal", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// userSignals counts the races and colors of the cats a user favorited or
+// dismissed, and returns the set of dismissed cats.
+func (h *RecommendationHandler) userSignals(userID string) (matching.Signals, map[uint]bool, error) {
+	signals := matching.Signals{
+		FavoriteRaces:  map[string]int{},
+		FavoriteColors: map[string]int{},
+		DismissedRaces: map[string]int{},
+	}
+	dismissed := map[uint]bool{}
+
+	favorites, err := h.recommendationQueries.FindFavoriteCats(userID)
+	if err != nil {
+		return signals, nil, err
+	}
+	for _, cat := range favorites {
+		signals.FavoriteRaces[cat.RaceID]++
+		signals.FavoriteColors[strings.ToLower(cat.Color)]++
+	}
+
+	dismissals, err := h.recommendationQueries.FindDismissedCats(userID)
+	if err != nil {
+		return signals, nil, err
+	}
+	for _, cat := range dismissals {
+		dismissed[cat.ID] = true
+		signals.DismissedRaces[cat.RaceID]++
+	}
+
+	return signals, dismissed, nil
+}
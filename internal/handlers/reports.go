@@ -363,32 +363,12 @@ func (h *ReportsHandler) GetReportedAnnonces(w http.ResponseWriter, r *http.Requ
 
 	modifiedReport := make(ModifiedReport, 0)
 	for _, reportedAnnonce := range reportedAnnonces {
-		annonce, err := h.reportsQueries.GetAnnonceByID(reportedAnnonce.AnnonceID)
+		entry, err := annonceReportEntry(h.reportsQueries, reportedAnnonce)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-
-		reason, err := h.reportsQueries.GetReportReasonById(reportedAnnonce.ReasonID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		modifiedReport = append(modifiedReport, map[string]interface{}{
-			"id": reportedAnnonce.ID,
-			"annonce": map[string]interface{}{
-				"ID":          annonce.ID,
-				"Title":       annonce.Title,
-				"Description": annonce.Description,
-			},
-			"reporterUserId": reportedAnnonce.ReporterUserID,
-			"reportedUserId": reportedAnnonce.ReportedUserID,
-			"createdAt":      reportedAnnonce.CreatedAt,
-			"reason":         reason.Reason,
-			"isHandled":      reportedAnnonce.IsHandled,
-			"type":           "annonce",
-		})
+		modifiedReport = append(modifiedReport, entry)
 	}
 
 	modifiedReportToJSON, err := json.Marshal(modifiedReport)
@@ -450,32 +430,12 @@ func (h *ReportsHandler) GetAllReports(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for _, reportedAnnonce := range reportedAnnonces {
-		annonce, err := h.reportsQueries.GetAnnonceByID(reportedAnnonce.AnnonceID)
+		entry, err := annonceReportEntry(h.reportsQueries, reportedAnnonce)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-
-		reason, err := h.reportsQueries.GetReportReasonById(reportedAnnonce.ReasonID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		modifiedReport = append(modifiedReport, map[string]interface{}{
-			"id": reportedAnnonce.ID,
-			"annonce": map[string]interface{}{
-				"ID":          annonce.ID,
-				"Title":       annonce.Title,
-				"Description": annonce.Description,
-			},
-			"reporterUserId": reportedAnnonce.ReporterUserID,
-			"reportedUserId": reportedAnnonce.ReportedUserID,
-			"createdAt":      reportedAnnonce.CreatedAt,
-			"reason":         reason.Reason,
-			"isHandled":      reportedAnnonce.IsHandled,
-			"type":           "annonce",
-		})
+		modifiedReport = append(modifiedReport, entry)
 	}
 
 	modifiedReportToJSON, err := json.Marshal(modifiedReport)
@@ -489,6 +449,56 @@ func (h *ReportsHandler) GetAllReports(w http.ResponseWriter, r *http.Request) {
 	w.Write(modifiedReportToJSON)
 }
 
+// annonceReportEntry describes the report of an annonce for the moderation
+// view. Automatic reports come with the photos that caused them.
+func annonceReportEntry(q *queries.DatabaseService, reportedAnnonce *models.ReportedAnnonce) (map[string]interface{}, error) {
+	annonce, err := q.GetAnnonceByID(reportedAnnonce.AnnonceID)
+	if err != nil {
+		return nil, err
+	}
+
+	reason, err := q.GetReportReasonById(reportedAnnonce.ReasonID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := map[string]interface{}{
+		"id": reportedAnnonce.ID,
+		"annonce": map[string]interface{}{
+			"ID":          annonce.ID,
+			"Title":       annonce.Title,
+			"Description": annonce.Description,
+		},
+		"reporterUserId": reportedAnnonce.ReporterUserID,
+		"reportedUserId": reportedAnnonce.ReportedUserID,
+		"createdAt":      reportedAnnonce.CreatedAt,
+		"reason":         reason.Reason,
+		"isHandled":      reportedAnnonce.IsHandled,
+		"automatic":      reportedAnnonce.Automatic,
+		"type":           "annonce",
+	}
+	if reportedAnnonce.Automatic {
+		matches, err := q.FindAnnoncePhotoMatches(reportedAnnonce.ID)
+		if err != nil {
+			return nil, err
+		}
+		evidence := make([]map[string]interface{}, 0, len(matches))
+		for _, match := range matches {
+			evidence = append(evidence, map[string]interface{}{
+				"photoUrl":         match.PhotoURL,
+				"matchedPhotoUrl":  match.MatchedPhotoURL,
+				"matchedCatId":     match.MatchedCatID,
+				"matchedUserId":    match.MatchedUserID,
+				"matchedAnnonceId": match.MatchedAnnonceID,
+				"distance":         match.Distance,
+				"similarity":       1 - float64(match.Distance)/64,
+			})
+		}
+		entry["evidence"] = evidence
+	}
+	return entry, nil
+}
+
 // GetReportReasons retrieves all report reasons from the database.
 // @Summary Get all report reasons
 // @Description Retrieve all report reasons from the database
@@ -9,9 +9,9 @@ import (
 	"sync"
 	"time"
 
-	"go-challenge/internal/config"
 	"go-challenge/internal/database/queries"
 	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
 	"go-challenge/internal/utils"
 
 	"github.com/go-chi/chi/v5"
@@ -225,17 +225,20 @@ func (c *Client) readPump(room *Room, h *RoomHandler) {
 			log.Printf("Error saving message: %v", error)
 			break
 		}
+		if err := h.roomQueries.RecordChatOpened(createdMessage, time.Now()); err != nil {
+			utils.Logger("error", "Annonce Analytics:", "Failed to record event", fmt.Sprintf("Error: %v", err))
+		}
 
 		for k, _ := range room.clients {
 			if k != c.userID {
-				notificationToken, error := h.roomQueries.GetNotificationTokenByUserID(k)
-				if error != nil {
-					fmt.Println("Error getting notification token: ", error)
-				} else if notificationToken.Token != "" {
-					fmt.Println("Sending notification....", notificationToken.Token)
-					payload := make(map[string]string)
-					payload["RoomID"] = strconv.FormatUint(uint64(room.roomID), 10)
-					SendToToken(config.GetFirebaseApp(), notificationToken.Token, createdMessage.Content, userName, payload)
+				payload := make(map[string]string)
+				payload["RoomID"] = strconv.FormatUint(uint64(room.roomID), 10)
+				vars := map[string]string{
+					"SenderName": userName,
+					"Message":    messagePreview(createdMessage.Content),
+				}
+				if _, err := NotifyUser(h.roomQueries, k, notifications.EventNewMessage, vars, payload); err != nil {
+					utils.Logger("error", "Read Pump:", "Failed to send notification", fmt.Sprintf("Error: %v", err))
 				}
 			}
 		}
@@ -252,6 +255,16 @@ func (c *Client) readPump(room *Room, h *RoomHandler) {
 	}
 }
 
+// messagePreview shortens a chat message so it fits in a push notification.
+func messagePreview(content string) string {
+	const maxLength = 100
+	runes := []rune(content)
+	if len(runes) <= maxLength {
+		return content
+	}
+	return string(runes[:maxLength]) + "…"
+}
+
 func (c *Client) writePump() {
 	defer func() {
 		c.conn.Close()
@@ -0,0 +1,473 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/geo"
+	"go-challenge/internal/matching"
+	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
+	"go-challenge/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/jinzhu/gorm"
+)
+
+type SavedSearchHandler struct {
+	savedSearchQueries *queries.DatabaseService
+}
+
+func NewSavedSearchHandler(savedSearchQueries *queries.DatabaseService) *SavedSearchHandler {
+	return &SavedSearchHandler{savedSearchQueries: savedSearchQueries}
+}
+
+type savedSearchRequest struct {
+	Name          string `json:"name"`
+	RaceID        string `json:"raceId"`
+	Sexe          string `json:"sexe"`
+	MinAgeMonths  *int   `json:"minAgeMonths"`
+	MaxAgeMonths  *int   `json:"maxAgeMonths"`
+	Color         string `json:"color"`
+	Behavior      string `json:"behavior"`
+	Sterilized    *bool  `json:"sterilized"`
+	AssociationID string `json:"associationId"`
+	Cp            string `json:"cp"`
+	RadiusKm      *int   `json:"radiusKm"`
+	Frequency     string `json:"frequency"`
+	Push          *bool  `json:"push"`
+}
+
+func (req *savedSearchRequest) apply(search *models.SavedSearch) error {
+	if req.MinAgeMonths != nil && req.MaxAgeMonths != nil && *req.MinAgeMonths > *req.MaxAgeMonths {
+		return errors.New("minAgeMonths must be lower than maxAgeMonths")
+	}
+	if req.RadiusKm != nil && (*req.RadiusKm <= 0 || req.Cp == "") {
+		return errors.New("radiusKm must be positive and requires a cp")
+	}
+	if req.Cp != "" {
+		if _, err := geo.Lookup(req.Cp); err != nil {
+			return errors.New("unknown cp")
+		}
+	}
+
+	frequency := models.SearchFrequency(req.Frequency)
+	switch frequency {
+	case "":
+		frequency = models.SearchInstant
+	case models.SearchInstant, models.SearchDaily, models.SearchWeekly:
+	default:
+		return errors.New("frequency must be instant, daily or weekly")
+	}
+
+	search.Name = req.Name
+	search.RaceID = req.RaceID
+	search.Sexe = req.Sexe
+	search.MinAgeMonths = req.MinAgeMonths
+	search.MaxAgeMonths = req.MaxAgeMonths
+	search.Color = req.Color
+	search.Behavior = req.Behavior
+	search.Sterilized = req.Sterilized
+	search.AssociationID = req.AssociationID
+	search.Cp = req.Cp
+	search.RadiusKm = req.RadiusKm
+	search.Frequency = frequency
+	search.Push = req.Push == nil || *req.Push
+	search.Active = true
+	return nil
+}
+
+// CreateSavedSearchHandler godoc
+// @Summary Save a search
+// @Description Save cat filters to be alerted when a new cat matches them. Most postal codes are located at the prefecture of their department, so a radiusKm of a few kilometers is not reliable
+// @Tags searches
+// @Accept json
+// @Produce json
+// @Success 201 {object} models.SavedSearch "Saved search created"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 500 {string} string "error creating saved search"
+// @Router /me/searches [post]
+func (h *SavedSearchHandler) CreateSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+	userID := claims["id"].(string)
+
+	var body savedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	search := &models.SavedSearch{UserID: userID}
+	if err := body.apply(search); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if search.Name == "" {
+		search.Name = "Ma recherche"
+	}
+
+	if err := h.savedSearchQueries.CreateSavedSearch(search); err != nil {
+		http.Error(w, "error creating saved search", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(search)
+}
+
+// GetSavedSearchesHandler godoc
+// @Summary Get the saved searches of the current user
+// @Description Retrieve the saved searches of the authenticated user
+// @Tags searches
+// @Produce json
+// @Success 200 {array} models.SavedSearch "List of saved searches"
+// @Failure 500 {string} string "error fetching saved searches"
+// @Router /me/searches [get]
+func (h *SavedSearchHandler) GetSavedSearchesHandler(w http.ResponseWriter, r *http.Request) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return
+	}
+	userID := claims["id"].(string)
+
+	searches, err := h.savedSearchQueries.FindSavedSearchesByUserID(userID)
+	if err != nil {
+		http.Error(w, "error fetching saved searches", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(searches)
+}
+
+// UpdateSavedSearchHandler godoc
+// @Summary Update a saved search
+// @Description Replace the filters and alert settings of a saved search
+// @Tags searches
+// @Accept json
+// @Produce json
+// @Param id path string true "Saved search ID"
+// @Success 200 {object} models.SavedSearch "Saved search updated"
+// @Failure 400 {string} string "Missing or invalid fields in the request"
+// @Failure 404 {string} string "saved search not found"
+// @Failure 500 {string} string "error updating saved search"
+// @Router /me/searches/{id} [put]
+func (h *SavedSearchHandler) UpdateSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	search, ok := h.findOwnSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	var body savedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	name := search.Name
+	if err := body.apply(search); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if search.Name == "" {
+		search.Name = name
+	}
+
+	if err := h.savedSearchQueries.UpdateSavedSearch(search); err != nil {
+		http.Error(w, "error updating saved search", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(search)
+}
+
+// UnsubscribeSavedSearchHandler godoc
+// @Summary Unsubscribe from a saved search
+// @Description Stop the alerts of a saved search while keeping its filters
+// @Tags searches
+// @Param id path string true "Saved search ID"
+// @Success 204 "No Content"
+// @Failure 404 {string} string "saved search not found"
+// @Failure 500 {string} string "error updating saved search"
+// @Router /me/searches/{id}/unsubscribe [post]
+func (h *SavedSearchHandler) UnsubscribeSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	search, ok := h.findOwnSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	search.Active = false
+	if err := h.savedSearchQueries.UpdateSavedSearch(search); err != nil {
+		http.Error(w, "error updating saved search", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteSavedSearchHandler godoc
+// @Summary Delete a saved search
+// @Description Delete a saved search of the authenticated user
+// @Tags searches
+// @Param id path string true "Saved search ID"
+// @Success 204 "No Content"
+// @Failure 404 {string} string "saved search not found"
+// @Failure 500 {string} string "error deleting saved search"
+// @Router /me/searches/{id} [delete]
+func (h *SavedSearchHandler) DeleteSavedSearchHandler(w http.ResponseWriter, r *http.Request) {
+	search, ok := h.findOwnSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.savedSearchQueries.DeleteSavedSearch(search); err != nil {
+		http.Error(w, "error deleting saved search", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSavedSearchMatchesHandler godoc
+// @Summary Get the matches of a saved search
+// @Description Retrieve the cats that matched a saved search, most recent first
+// @Tags searches
+// @Produce json
+// @Param id path string true "Saved search ID"
+// @Success 200 {array} models.SavedSearchMatch "List of matches"
+// @Failure 404 {string} string "saved search not found"
+// @Failure 500 {string} string "error fetching matches"
+// @Router /me/searches/{id}/matches [get]
+func (h *SavedSearchHandler) GetSavedSearchMatchesHandler(w http.ResponseWriter, r *http.Request) {
+	search, ok := h.findOwnSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	matches, err := h.savedSearchQueries.FindSavedSearchMatches(search.ID)
+	if err != nil {
+		http.Error(w, "error fetching matches", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(matches)
+}
+
+// findOwnSavedSearch loads the saved search of the route and writes the error
+// response when it does not exist or belongs to another user.
+func (h *SavedSearchHandler) findOwnSavedSearch(w http.ResponseWriter, r *http.Request) (*models.SavedSearch, bool) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		http.Error(w, "error getting claims", http.StatusInternalServerError)
+		return nil, false
+	}
+	userID := claims["id"].(string)
+
+	search, err := h.savedSearchQueries.FindSavedSearchByID(chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "saved search not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "error fetching saved search", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if search.UserID != userID {
+		http.Error(w, "saved search not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	return search, true
+}
+
+// MatchSavedSearches looks for the saved searches matched by a cat with an
+// annonce and alerts their owners. Searches alerted instantly are notified
+// right away, the others are left for the next digest. It is meant to run in
+// its own goroutine after a cat or an annonce is saved.
+func MatchSavedSearches(q *queries.DatabaseService, catID string) {
+	cat, err := q.FindCatByID(catID)
+	if err != nil {
+		utils.Logger("error", "Saved Search Matcher:", "Failed to find cat", fmt.Sprintf("Error: %v", err))
+		return
+	}
+	if !cat.Status.Adoptable() {
+		return
+	}
+
+	annonce, err := q.FindPublishedAnnonceByCatID(catID)
+	if err != nil {
+		// Cats without a published annonce are not up for adoption yet.
+		return
+	}
+
+	searches, err := q.GetActiveSavedSearches(cat.UserID)
+	if err != nil {
+		utils.Logger("error", "Saved Search Matcher:", "Failed to get saved searches", fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	now := time.Now()
+	for i := range searches {
+		search := &searches[i]
+		if !savedSearchMatches(search, cat, now) {
+			continue
+		}
+
+		match := &models.SavedSearchMatch{SavedSearchID: search.ID, CatID: cat.ID, AnnonceID: annonce.ID}
+		isNew, err := q.CreateSavedSearchMatch(match)
+		if err != nil {
+			utils.Logger("error", "Saved Search Matcher:", "Failed to save match", fmt.Sprintf("Error: %v", err))
+			continue
+		}
+		if !isNew || search.Frequency != models.SearchInstant {
+			continue
+		}
+
+		vars := map[string]string{
+			"SearchName":   search.Name,
+			"CatName":      cat.Name,
+			"AnnonceTitle": annonce.Title,
+		}
+		data := map[string]string{
+			"SavedSearchID": strconv.FormatUint(uint64(search.ID), 10),
+			"AnnonceID":     strconv.FormatUint(uint64(annonce.ID), 10),
+			"CatID":         strconv.FormatUint(uint64(cat.ID), 10),
+		}
+		alertSavedSearch(q, search, notifications.EventSavedSearchMatch, vars, data, now)
+	}
+}
+
+// RunSavedSearchDigests sends one alert per daily or weekly saved search
+// holding matches that were not notified yet.
+func RunSavedSearchDigests(q *queries.DatabaseService, now time.Time) {
+	periods := map[models.SearchFrequency]time.Duration{
+		models.SearchDaily:  24 * time.Hour,
+		models.SearchWeekly: 7 * 24 * time.Hour,
+	}
+
+	for frequency, period := range periods {
+		searches, err := q.GetDigestSavedSearches(frequency, now.Add(-period))
+		if err != nil {
+			utils.Logger("error", "Saved Search Digest:", "Failed to get saved searches", fmt.Sprintf("Error: %v", err))
+			continue
+		}
+
+		for i := range searches {
+			search := &searches[i]
+			matches, err := q.FindPendingSavedSearchMatches(search.ID)
+			if err != nil {
+				utils.Logger("error", "Saved Search Digest:", "Failed to get matches", fmt.Sprintf("Error: %v", err))
+				continue
+			}
+			if len(matches) == 0 {
+				continue
+			}
+
+			var catNames []string
+			for _, match := range matches {
+				if len(catNames) == 3 {
+					break
+				}
+				cat, err := q.FindCatByID(strconv.FormatUint(uint64(match.CatID), 10))
+				if err == nil {
+					catNames = append(catNames, cat.Name)
+				}
+			}
+
+			vars := map[string]string{
+				"SearchName": search.Name,
+				"Count":      strconv.Itoa(len(matches)),
+				"CatNames":   strings.Join(catNames, ", "),
+			}
+			data := map[string]string{
+				"SavedSearchID": strconv.FormatUint(uint64(search.ID), 10),
+			}
+			alertSavedSearch(q, search, notifications.EventSavedSearchDigest, vars, data, now)
+		}
+	}
+}
+
+func alertSavedSearch(q *queries.DatabaseService, search *models.SavedSearch, event notifications.Event, vars map[string]string, data map[string]string, now time.Time) {
+	if _, err := NotifyUserInApp(q, search.UserID, event, vars, data); err != nil {
+		utils.Logger("error", "Saved Search Alert:", "Failed to store in-app alert", fmt.Sprintf("Error: %v", err))
+		return
+	}
+	if search.Push {
+		if _, err := NotifyUser(q, search.UserID, event, vars, data); err != nil {
+			utils.Logger("error", "Saved Search Alert:", "Failed to push alert", fmt.Sprintf("Error: %v", err))
+		}
+	}
+
+	if err := q.MarkSavedSearchMatchesNotified(search.ID, now); err != nil {
+		utils.Logger("error", "Saved Search Alert:", "Failed to mark matches as notified", fmt.Sprintf("Error: %v", err))
+	}
+	search.LastNotified = &now
+	if err := q.UpdateSavedSearch(search); err != nil {
+		utils.Logger("error", "Saved Search Alert:", "Failed to update saved search", fmt.Sprintf("Error: %v", err))
+	}
+}
+
+// savedSearchMatches reports whether a cat satisfies every filter set on a
+// saved search. A cp without a radiusKm does not filter on location.
+func savedSearchMatches(search *models.SavedSearch, cat *models.Cats, now time.Time) bool {
+	if search.RaceID != "" && search.RaceID != cat.RaceID {
+		return false
+	}
+	if search.Sexe != "" && !strings.EqualFold(search.Sexe, cat.Sexe) {
+		return false
+	}
+	if search.Color != "" && !strings.EqualFold(search.Color, cat.Color) {
+		return false
+	}
+	if search.Behavior != "" && !strings.EqualFold(search.Behavior, cat.Behavior) {
+		return false
+	}
+	if search.Sterilized != nil && *search.Sterilized != cat.Sterilized {
+		return false
+	}
+	if search.AssociationID != "" && search.AssociationID != cat.PublishedAs {
+		return false
+	}
+
+	if search.MinAgeMonths != nil || search.MaxAgeMonths != nil {
+		if cat.BirthDate == nil {
+			return false
+		}
+		age := matching.AgeInMonths(*cat.BirthDate, now)
+		if search.MinAgeMonths != nil && age < *search.MinAgeMonths {
+			return false
+		}
+		if search.MaxAgeMonths != nil && age > *search.MaxAgeMonths {
+			return false
+		}
+	}
+
+	if search.Cp != "" && search.RadiusKm != nil {
+		origin, err := geo.Lookup(search.Cp)
+		if err != nil || cat.Latitude == nil || cat.Longitude == nil {
+			return false
+		}
+		location := geo.Point{Latitude: *cat.Latitude, Longitude: *cat.Longitude}
+		if geo.Distance(origin, location) > float64(*search.RadiusKm) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-challenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSavedSearchHandlerStoresPush(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		push bool
+	}{
+		{name: "push by default", body: `{"name":"Chatons"}`, push: true},
+		{name: "without push", body: `{"name":"Chatons","push":false}`, push: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, db := newTestQueries()
+			r := httptest.NewRequest(http.MethodPost, "/me/searches", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			NewSavedSearchHandler(q).CreateSavedSearchHandler(w, withSubject(r, "user", models.UserRole))
+
+			require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+			inserts := db.Queries(`INSERT INTO "saved_searches"`)
+			require.Len(t, inserts, 1)
+			for _, column := range []string{"push", "active"} {
+				value, ok := inserts[0].Inserted(column)
+				require.True(t, ok, column)
+				expected := driver.Value(true)
+				if column == "push" {
+					expected = tt.push
+				}
+				assert.Equal(t, expected, value, column)
+			}
+		})
+	}
+}
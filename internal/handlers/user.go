@@ -3,31 +3,29 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
-	"go-challenge/internal/api"
 	"go-challenge/internal/auth"
 	"go-challenge/internal/database/queries"
+	"go-challenge/internal/geo"
 	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
+	"go-challenge/internal/storage"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/jwtauth/v5"
 	"github.com/google/uuid"
-	"github.com/uploadcare/uploadcare-go/ucare"
 )
 
 type UserHandler struct {
-	userQueries      *queries.DatabaseService
-	uploadcareClient ucare.Client
+	userQueries *queries.DatabaseService
+	store       storage.BlobStore
 }
 
-func NewUserHandler(userQueries *queries.DatabaseService, uploadcareClient ucare.Client) *UserHandler {
-	return &UserHandler{userQueries: userQueries, uploadcareClient: uploadcareClient}
+func NewUserHandler(userQueries *queries.DatabaseService, store storage.BlobStore) *UserHandler {
+	return &UserHandler{userQueries: userQueries, store: store}
 }
 
 // RegisterHandler godoc
@@ -42,13 +40,14 @@ func NewUserHandler(userQueries *queries.DatabaseService, uploadcareClient ucare
 // @Param addressRue formData string false "Address"
 // @Param cp formData string false "CP"
 // @Param ville formData string false "Ville"
+// @Param locale formData string false "Preferred locale (fr or en)"
 // @Success 200 {string} string "success"
 // @Failure 400 {string} string "email and password are required"
 // @Failure 500 {string} string "error creating user"
 // @Router /register [post]
 func (h *UserHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	contentType := r.Header.Get("Content-Type")
-	var email, password, name, addressRue, cp, ville string
+	var email, password, name, addressRue, cp, ville, locale string
 
 	if strings.Contains(contentType, "application/json") {
 		var reqBody struct {
@@ -58,6 +57,7 @@ func (h *UserHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 			AddressRue string `json:"addressRue"`
 			Cp         string `json:"cp"`
 			Ville      string `json:"ville"`
+			Locale     string `json:"locale"`
 		}
 
 		err := json.NewDecoder(r.Body).Decode(&reqBody)
@@ -72,6 +72,7 @@ func (h *UserHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		addressRue = reqBody.AddressRue
 		cp = reqBody.Cp
 		ville = reqBody.Ville
+		locale = reqBody.Locale
 	} else {
 		err := r.ParseForm()
 		if err != nil {
@@ -85,6 +86,11 @@ func (h *UserHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		addressRue = r.FormValue("addressRue")
 		cp = r.FormValue("cp")
 		ville = r.FormValue("ville")
+		locale = r.FormValue("locale")
+	}
+
+	if locale == "" {
+		locale = r.Header.Get("Accept-Language")
 	}
 
 	fmt.Println("email: " + email)
@@ -120,7 +126,9 @@ func (h *UserHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		Cp:            cp,
 		Ville:         ville,
 		ProfilePicURL: "default",
+		Locale:        notifications.NormalizeLocale(locale),
 	}
+	user.Latitude, user.Longitude = geo.Locate(cp)
 
 	err = h.userQueries.CreateUser(user, userRole)
 	if err != nil {
@@ -151,6 +159,7 @@ func (h *UserHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 // @Accept multipart/form-data
 // @Param uploaded_file formData file true "Image"
 // @Success 200 {string} string "Profile picture updated successfully"
+// @Failure 400 {string} string "invalid image"
 // @Failure 500 {string} string "error updating user"
 // @Router /profile/picture [post]
 func (h *UserHandler) ModifyProfilePictureHandler(w http.ResponseWriter, r *http.Request) {
@@ -161,36 +170,15 @@ func (h *UserHandler) ModifyProfilePictureHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	file, header, err := r.FormFile("uploaded_file")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		fmt.Println("failed to get file")
+	files := r.MultipartForm.File["uploaded_file"]
+	if len(files) == 0 {
+		http.Error(w, "uploaded_file is required", http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
-
-	ext := filepath.Ext(header.Filename)
 
-	tempFile, err := os.CreateTemp(os.TempDir(), "upload-*"+ext)
+	image, err := uploadImage(r.Context(), h.store, files[0])
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		fmt.Println("failed to create temp file")
-		return
-	}
-	defer os.Remove(tempFile.Name()) // clean up
-
-	_, err = io.Copy(tempFile, file)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		fmt.Println("failed to copy file")
-		return
-	}
-
-	FileURL, _, err := api.UploadImage(h.uploadcareClient, tempFile.Name())
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		errorMsg := fmt.Errorf("failed to upload file to uploadcare: %v", err)
-		fmt.Println(errorMsg)
+		writeUploadError(w, err)
 		return
 	}
 
@@ -207,7 +195,9 @@ func (h *UserHandler) ModifyProfilePictureHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	user.ProfilePicURL = FileURL
+	user.ProfilePicURL = image.URL
+	user.ProfilePicMediumURL = image.MediumURL
+	user.ProfilePicThumbnailURL = image.ThumbnailURL
 
 	err = h.userQueries.UpdateUser(user)
 	if err != nil {
@@ -215,8 +205,13 @@ func (h *UserHandler) ModifyProfilePictureHandler(w http.ResponseWriter, r *http
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(fmt.Sprintf(`{"profilePicURL": "%s"}`, FileURL)))
+	json.NewEncoder(w).Encode(map[string]string{
+		"profilePicURL":          image.URL,
+		"profilePicMediumURL":    image.MediumURL,
+		"profilePicThumbnailURL": image.ThumbnailURL,
+	})
 }
 
 // GetAllUsersHandler godoc
@@ -275,6 +270,7 @@ func (h *UserHandler) CreateUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	user.Password = hashedPassword
+	user.Locale = notifications.NormalizeLocale(user.Locale)
 
 	userRole, err := h.userQueries.GetRoleByName(models.RoleName(user.Roles[0].Name))
 	if err != nil {
@@ -282,6 +278,7 @@ func (h *UserHandler) CreateUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	user.Roles = []models.Roles{*userRole}
+	user.Latitude, user.Longitude = geo.Locate(user.Cp)
 
 	err = h.userQueries.CreateUser(&user, userRole)
 	if err != nil {
@@ -329,6 +326,7 @@ func (h *UserHandler) UpdateUserHandler(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
 		return
 	}
+	user.Locale = notifications.NormalizeLocale(user.Locale)
 
 	userRole, err := h.userQueries.GetRoleByName(models.RoleName(user.Roles[0].Name))
 	if err != nil {
@@ -336,6 +334,7 @@ func (h *UserHandler) UpdateUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	user.Roles = []models.Roles{*userRole}
+	user.Latitude, user.Longitude = geo.Locate(user.Cp)
 
 	err = h.userQueries.UpdateUser(user)
 	if err != nil {
@@ -343,6 +342,11 @@ func (h *UserHandler) UpdateUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if err := h.userQueries.UpdateUserCatsLocation(user); err != nil {
+		http.Error(w, "Error updating user cats location", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(user)
@@ -0,0 +1,45 @@
+package imaging
+
+import (
+	"image"
+	"math/bits"
+)
+
+// Hash computes the perceptual hash of a picture, see DHash.
+func Hash(data []byte) (uint64, error) {
+	img, _, err := decode(data)
+	if err != nil {
+		return 0, err
+	}
+	return DHash(img), nil
+}
+
+// DHash computes the difference hash of a picture: it is shrunk to 9x8
+// pixels in gray levels, and each bit tells whether a pixel is brighter than
+// its right neighbour. Resized, recompressed or slightly retouched copies of
+// a picture get the same hash, or one a few bits away.
+func DHash(img image.Image) uint64 {
+	small := resize(img, 9, 8)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		row := small.Pix[y*small.Stride:]
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if luminance(row[x*4:]) > luminance(row[(x+1)*4:]) {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// Distance is the number of bits two hashes differ by: 0 for the same
+// picture, around 32 for unrelated ones.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+func luminance(pixel []uint8) uint32 {
+	return (299*uint32(pixel[0]) + 587*uint32(pixel[1]) + 114*uint32(pixel[2])) / 1000
+}
@@ -0,0 +1,155 @@
+// Package imaging prepares uploaded pictures before they are stored: it checks
+// their real type, drops their metadata, applies their EXIF orientation and
+// makes resized variants of them.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+const (
+	VariantFull      = "full"
+	VariantMedium    = "medium"
+	VariantThumbnail = "thumbnail"
+
+	// maxPixels guards against images that are small on disk but huge once
+	// decoded.
+	maxPixels = 40_000_000
+	// jpegQuality is the quality variants of JPEG pictures are encoded with.
+	jpegQuality = 85
+)
+
+var (
+	// ErrInvalidImage is wrapped by the errors caused by the picture itself
+	// rather than by its processing.
+	ErrInvalidImage    = errors.New("invalid image")
+	ErrUnsupportedType = fmt.Errorf("%w: only JPEG, PNG and GIF pictures are accepted", ErrInvalidImage)
+	ErrTooLarge        = fmt.Errorf("%w: the picture has too many pixels", ErrInvalidImage)
+)
+
+// Spec is a variant to make: the picture is scaled down so that its longest
+// side is at most MaxSize pixels. Smaller pictures are not enlarged.
+type Spec struct {
+	Name    string
+	MaxSize int
+}
+
+// Specs are the variants made of every uploaded picture.
+var Specs = []Spec{
+	{Name: VariantFull, MaxSize: 2048},
+	{Name: VariantMedium, MaxSize: 800},
+	{Name: VariantThumbnail, MaxSize: 240},
+}
+
+// Variant is a processed picture, ready to be stored.
+type Variant struct {
+	Name        string
+	Width       int
+	Height      int
+	ContentType string
+	Extension   string
+	Data        []byte
+}
+
+type format string
+
+const (
+	formatJPEG format = "jpeg"
+	formatPNG  format = "png"
+	formatGIF  format = "gif"
+)
+
+// detectFormat recognizes a picture by its first bytes, whatever its file
+// name or declared content type.
+func detectFormat(data []byte) (format, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return formatJPEG, nil
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return formatPNG, nil
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return formatGIF, nil
+	}
+	return "", ErrUnsupportedType
+}
+
+// Process makes the variants of a picture listed in Specs. JPEG pictures stay
+// JPEG, PNG and GIF pictures become PNG; only the first frame of an animated
+// GIF is kept. Variants are re-encoded from the decoded pixels, so no
+// metadata of the original makes it through.
+func Process(data []byte) ([]Variant, error) {
+	return ProcessSpecs(data, Specs)
+}
+
+// ProcessSpecs is Process with custom variants.
+func ProcessSpecs(data []byte, specs []Spec) ([]Variant, error) {
+	img, f, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	variants := make([]Variant, 0, len(specs))
+	for _, spec := range specs {
+		resized := fit(img, spec.MaxSize)
+		variant := Variant{
+			Name:   spec.Name,
+			Width:  resized.Bounds().Dx(),
+			Height: resized.Bounds().Dy(),
+		}
+
+		var buffer bytes.Buffer
+		if f == formatJPEG {
+			variant.ContentType, variant.Extension = "image/jpeg", ".jpg"
+			err = jpeg.Encode(&buffer, resized, &jpeg.Options{Quality: jpegQuality})
+		} else {
+			variant.ContentType, variant.Extension = "image/png", ".png"
+			err = png.Encode(&buffer, resized)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not encode %s variant: %v", spec.Name, err)
+		}
+		variant.Data = buffer.Bytes()
+		variants = append(variants, variant)
+	}
+	return variants, nil
+}
+
+// decode checks and decodes a picture, applying its EXIF orientation.
+func decode(data []byte) (image.Image, format, error) {
+	f, err := detectFormat(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, "", ErrTooLarge
+	}
+
+	var img image.Image
+	switch f {
+	case formatJPEG:
+		img, err = jpeg.Decode(bytes.NewReader(data))
+	case formatPNG:
+		img, err = png.Decode(bytes.NewReader(data))
+	case formatGIF:
+		img, err = gif.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	if f == formatJPEG {
+		img = orient(img, jpegOrientation(data))
+	}
+	return img, f, nil
+}
@@ -0,0 +1,148 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	var buffer bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buffer, img, nil))
+	return buffer.Bytes()
+}
+
+// withExif inserts an APP1 segment holding an orientation and a GPS marker
+// right after the SOI marker of a JPEG picture.
+func withExif(data []byte, orientation uint16) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientationTag)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	tiff = append(tiff, []byte("GPS 48.8566N 2.3522E")...)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	return append(out, data[2:]...)
+}
+
+func TestDetectFormat(t *testing.T) {
+	f, err := detectFormat(encodeJPEG(t, 4, 4))
+	assert.NoError(t, err)
+	assert.Equal(t, formatJPEG, f)
+
+	_, err = detectFormat([]byte("<?php echo 'not a cat'; ?>"))
+	assert.ErrorIs(t, err, ErrInvalidImage)
+
+	_, err = detectFormat([]byte("RIFF\x00\x00\x00\x00WEBPVP8 "))
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	data := encodeJPEG(t, 4, 4)
+	assert.Equal(t, 1, jpegOrientation(data))
+	assert.Equal(t, 6, jpegOrientation(withExif(data, 6)))
+	assert.Equal(t, 1, jpegOrientation(withExif(data, 42)))
+}
+
+func TestOrient(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	marker := color.RGBA{R: 255, A: 255}
+	img.Set(0, 0, marker)
+
+	rotated := orient(img, 6)
+	assert.Equal(t, image.Rect(0, 0, 2, 3), rotated.Bounds())
+	assert.Equal(t, marker, rotated.At(1, 0))
+
+	upsideDown := orient(img, 3)
+	assert.Equal(t, marker, upsideDown.At(2, 1))
+
+	rotatedBack := orient(img, 8)
+	assert.Equal(t, marker, rotatedBack.At(0, 2))
+}
+
+func TestProcessStripsMetadataAndRotates(t *testing.T) {
+	data := withExif(encodeJPEG(t, 300, 100), 6)
+
+	variants, err := ProcessSpecs(data, []Spec{{Name: VariantFull, MaxSize: 1000}, {Name: VariantThumbnail, MaxSize: 60}})
+	require.NoError(t, err)
+	require.Len(t, variants, 2)
+
+	full := variants[0]
+	assert.Equal(t, "image/jpeg", full.ContentType)
+	assert.Equal(t, 100, full.Width)
+	assert.Equal(t, 300, full.Height)
+	assert.False(t, bytes.Contains(full.Data, []byte("Exif")))
+	assert.False(t, bytes.Contains(full.Data, []byte("GPS")))
+
+	thumbnail := variants[1]
+	assert.Equal(t, 20, thumbnail.Width)
+	assert.Equal(t, 60, thumbnail.Height)
+	decoded, err := jpeg.Decode(bytes.NewReader(thumbnail.Data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 20, 60), decoded.Bounds())
+}
+
+func TestProcessPNG(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, png.Encode(&buffer, image.NewNRGBA(image.Rect(0, 0, 50, 10))))
+
+	variants, err := ProcessSpecs(buffer.Bytes(), []Spec{{Name: VariantMedium, MaxSize: 25}})
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", variants[0].ContentType)
+	assert.Equal(t, 25, variants[0].Width)
+	assert.Equal(t, 5, variants[0].Height)
+}
+
+func TestProcessRejectsFakeImage(t *testing.T) {
+	_, err := Process(append([]byte{0xFF, 0xD8, 0xFF}, []byte("truncated")...))
+	assert.ErrorIs(t, err, ErrInvalidImage)
+}
+
+func TestHashMatchesCopies(t *testing.T) {
+	original := encodeJPEG(t, 600, 400)
+	hash, err := Hash(original)
+	require.NoError(t, err)
+
+	variants, err := Process(original)
+	require.NoError(t, err)
+	for _, variant := range variants {
+		copyHash, err := Hash(variant.Data)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, Distance(hash, copyHash), 4, variant.Name)
+	}
+
+	rotated, err := Hash(withExif(original, 6))
+	require.NoError(t, err)
+	assert.Greater(t, Distance(hash, rotated), 10)
+
+	_, err = Hash([]byte("not a picture"))
+	assert.ErrorIs(t, err, ErrInvalidImage)
+}
+
+func TestDistance(t *testing.T) {
+	assert.Equal(t, 0, Distance(0xF0F0, 0xF0F0))
+	assert.Equal(t, 64, Distance(0, ^uint64(0)))
+	assert.Equal(t, 2, Distance(0b1010, 0b0110))
+}
@@ -0,0 +1,74 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+)
+
+// fit scales a picture down so that its longest side is at most maxSize
+// pixels, keeping its aspect ratio.
+func fit(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxSize && h <= maxSize {
+		return img
+	}
+
+	dw, dh := maxSize, h*maxSize/w
+	if h > w {
+		dw, dh = w*maxSize/h, maxSize
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+	return resize(img, dw, dh)
+}
+
+// resize scales a picture down with a box filter: each pixel is the average
+// of the pixels of the source it covers.
+func resize(img image.Image, dw, dh int) *image.RGBA {
+	bounds := img.Bounds()
+	src, ok := img.(*image.RGBA)
+	if !ok || bounds.Min != (image.Point{}) {
+		src = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		sy0, sy1 := dy*h/dh, (dy+1)*h/dh
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		for dx := 0; dx < dw; dx++ {
+			sx0, sx1 := dx*w/dw, (dx+1)*w/dw
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+
+			var r, g, b, a, count uint32
+			for sy := sy0; sy < sy1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := sx0; sx < sx1; sx++ {
+					pixel := row[sx*4 : sx*4+4]
+					r += uint32(pixel[0])
+					g += uint32(pixel[1])
+					b += uint32(pixel[2])
+					a += uint32(pixel[3])
+					count++
+				}
+			}
+
+			offset := dy*dst.Stride + dx*4
+			dst.Pix[offset] = uint8(r / count)
+			dst.Pix[offset+1] = uint8(g / count)
+			dst.Pix[offset+2] = uint8(b / count)
+			dst.Pix[offset+3] = uint8(a / count)
+		}
+	}
+	return dst
+}
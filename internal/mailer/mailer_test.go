@@ -0,0 +1,54 @@
+package mailer
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildMessage(t *testing.T) {
+	from := &mail.Address{Name: "Purrfect Match", Address: "no-reply@purrfectmatch.fr"}
+	to := &mail.Address{Address: "jeanne@example.com"}
+	date := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	data, err := buildMessage(from, to, Message{
+		Subject: "Invitation à rejoindre Les Chats Libres",
+		Body:    "Bonjour,\nVous êtes invitée à rejoindre l'association.",
+	}, date)
+	require.NoError(t, err)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	assert.Equal(t, `"Purrfect Match" <no-reply@purrfectmatch.fr>`, parsed.Header.Get("From"))
+	assert.Equal(t, "<jeanne@example.com>", parsed.Header.Get("To"))
+	assert.Equal(t, "Wed, 01 May 2024 10:00:00 +0000", parsed.Header.Get("Date"))
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Invitation à rejoindre Les Chats Libres", subject)
+
+	body, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	require.NoError(t, err)
+	assert.Equal(t, "Bonjour,\r\nVous êtes invitée à rejoindre l'association.", string(body))
+}
+
+func TestBuildMessageRequiresSubject(t *testing.T) {
+	_, err := buildMessage(&mail.Address{Address: "a@example.com"}, &mail.Address{Address: "b@example.com"}, Message{Body: "Hello"}, time.Now())
+	assert.Error(t, err)
+}
+
+func TestSendRejectsInvalidRecipients(t *testing.T) {
+	m := &SMTP{Addr: "127.0.0.1:0", From: defaultFrom}
+	for _, to := range []string{"", "not an address", "a@example.com\r\nBcc: b@example.com"} {
+		assert.Error(t, m.Send(context.Background(), Message{To: to, Subject: "Hello"}), to)
+		assert.Error(t, Log{}.Send(context.Background(), Message{To: to, Subject: "Hello"}), to)
+	}
+}
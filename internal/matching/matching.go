@@ -0,0 +1,211 @@
+// Package matching scores how well a cat fits the lifestyle of an adopter.
+//
+// A score starts at 50 and every factor that applies moves it up or down,
+// within 0 and 100. Each factor is reported as a Reason so the score can be
+// explained to the adopter.
+package matching
+
+import (
+	"strings"
+	"time"
+
+	"go-challenge/internal/models"
+)
+
+const (
+	baseScore = 50
+	minScore  = 0
+	maxScore  = 100
+)
+
+const (
+	kittenMaxMonths = 12
+	seniorMinMonths = 120
+	longHoursAlone  = 8
+	shortHoursAlone = 4
+)
+
+// Reason explains how one factor moved the score of a cat. Key names the
+// explanation, which is rendered in the locale of the adopter.
+type Reason struct {
+	Factor      string `json:"factor"`
+	Key         string `json:"key"`
+	Points      int    `json:"points"`
+	Explanation string `json:"explanation"`
+}
+
+type Result struct {
+	Score   int      `json:"score"`
+	Reasons []Reason `json:"reasons"`
+}
+
+// Signals are the past choices of an adopter, counted by race ID and color.
+type Signals struct {
+	FavoriteRaces  map[string]int
+	FavoriteColors map[string]int
+	DismissedRaces map[string]int
+}
+
+// Score rates a cat for an adopter, explaining the score in their locale. The
+// profile may be nil when the adopter did not fill in the questionnaire: only
+// their signals are used then. The race may be nil when the breed of the cat
+// is unknown.
+func Score(profile *models.LifestyleProfile, cat *models.Cats, race *models.Races, signals Signals, locale string, now time.Time) Result {
+	s := &scorer{locale: locale, result: Result{Score: baseScore, Reasons: []Reason{}}}
+	traits := catTraits(cat, race)
+
+	if profile != nil {
+		ageMonths := -1
+		if cat.BirthDate != nil {
+			ageMonths = AgeInMonths(*cat.BirthDate, now)
+		}
+		scoreHome(s, profile, traits)
+		scoreChildren(s, profile, traits)
+		scorePets(s, profile, traits)
+		scoreTimeAlone(s, profile, traits, ageMonths)
+		scoreExperience(s, profile, traits, ageMonths)
+	}
+	scoreSignals(s, cat, signals)
+
+	if s.result.Score < minScore {
+		s.result.Score = minScore
+	}
+	if s.result.Score > maxScore {
+		s.result.Score = maxScore
+	}
+	return s.result
+}
+
+// AgeInMonths returns the number of full months between a birth date and now.
+func AgeInMonths(birthDate time.Time, now time.Time) int {
+	months := (now.Year()-birthDate.Year())*12 + int(now.Month()) - int(birthDate.Month())
+	if now.Day() < birthDate.Day() {
+		months--
+	}
+	if months < 0 {
+		return 0
+	}
+	return months
+}
+
+type scorer struct {
+	locale string
+	result Result
+}
+
+func (s *scorer) add(factor string, points int, key string) {
+	s.result.Score += points
+	s.result.Reasons = append(s.result.Reasons, Reason{Factor: factor, Key: key, Points: points, Explanation: explain(key, s.locale)})
+}
+
+func scoreHome(s *scorer, profile *models.LifestyleProfile, traits trait) {
+	switch profile.HomeType {
+	case models.HomeApartment:
+		if traits.has(energetic) {
+			s.add("home", -10, "home_energetic_apartment")
+		} else if traits.has(calm) {
+			s.add("home", 10, "home_calm_apartment")
+		}
+	case models.HomeGarden:
+		if traits.has(energetic) {
+			s.add("home", 10, "home_energetic_garden")
+		}
+	}
+}
+
+func scoreChildren(s *scorer, profile *models.LifestyleProfile, traits trait) {
+	switch {
+	case profile.HasYoungChildren:
+		switch {
+		case traits.has(noChildren):
+			s.add("children", -25, "children_not_placed")
+		case traits.has(aggressive):
+			s.add("children", -30, "children_aggressive_young")
+		case traits.has(goodWithChildren):
+			s.add("children", 15, "children_good")
+		case traits.has(shy):
+			s.add("children", -10, "children_shy")
+		case traits.has(sociable):
+			s.add("children", 5, "children_friendly")
+		}
+	case profile.HasOlderChildren:
+		switch {
+		case traits.has(noChildren):
+			s.add("children", -15, "children_not_placed")
+		case traits.has(aggressive):
+			s.add("children", -15, "children_aggressive")
+		case traits.has(goodWithChildren), traits.has(sociable):
+			s.add("children", 5, "children_older")
+		}
+	}
+}
+
+func scorePets(s *scorer, profile *models.LifestyleProfile, traits trait) {
+	if !profile.HasCats && !profile.HasDogs {
+		if traits.has(onlyPet) {
+			s.add("pets", 10, "pets_only_pet_allowed")
+		}
+		return
+	}
+
+	switch {
+	case traits.has(onlyPet):
+		s.add("pets", -25, "pets_only_pet")
+	case traits.has(aggressive):
+		s.add("pets", -15, "pets_aggressive")
+	case traits.has(goodWithPets):
+		s.add("pets", 10, "pets_good")
+	case traits.has(sociable):
+		s.add("pets", 5, "pets_friendly")
+	}
+}
+
+func scoreTimeAlone(s *scorer, profile *models.LifestyleProfile, traits trait, ageMonths int) {
+	kitten := ageMonths >= 0 && ageMonths < kittenMaxMonths
+
+	switch {
+	case profile.HoursAlonePerDay >= longHoursAlone:
+		if kitten {
+			s.add("time_alone", -15, "time_alone_kitten")
+		} else if traits.has(needsCompany) {
+			s.add("time_alone", -10, "time_alone_needs_company")
+		} else if ageMonths >= seniorMinMonths || traits.has(calm) {
+			s.add("time_alone", 5, "time_alone_calm")
+		}
+	case profile.HoursAlonePerDay <= shortHoursAlone:
+		if kitten {
+			s.add("time_alone", 10, "time_alone_kitten_home")
+		} else if traits.has(needsCompany) {
+			s.add("time_alone", 10, "time_alone_company_home")
+		}
+	}
+}
+
+func scoreExperience(s *scorer, profile *models.LifestyleProfile, traits trait, ageMonths int) {
+	switch profile.Experience {
+	case models.ExperienceNone:
+		if traits.has(aggressive) {
+			s.add("experience", -15, "experience_aggressive")
+		} else if traits.has(shy) {
+			s.add("experience", -5, "experience_shy")
+		} else if traits.has(sociable) || ageMonths >= seniorMinMonths {
+			s.add("experience", 5, "experience_easy")
+		}
+	case models.ExperienceExperienced:
+		if traits.has(shy) || traits.has(aggressive) {
+			s.add("experience", 5, "experience_helps")
+		}
+	}
+}
+
+func scoreSignals(s *scorer, cat *models.Cats, signals Signals) {
+	if cat.RaceID != "" && signals.FavoriteRaces[cat.RaceID] > 0 {
+		s.add("favorites", 10, "favorite_breed")
+	}
+	if cat.Color != "" && signals.FavoriteColors[strings.ToLower(cat.Color)] > 0 {
+		s.add("favorites", 5, "favorite_color")
+	}
+	if cat.RaceID != "" && signals.DismissedRaces[cat.RaceID] >= 2 {
+		s.add("dismissals", -10, "dismissed_breed")
+	}
+}
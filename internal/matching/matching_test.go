@@ -0,0 +1,135 @@
+package matching
+
+import (
+	"testing"
+	"time"
+
+	"go-challenge/internal/models"
+	"go-challenge/internal/notifications"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+
+func newCat(behavior string, description string, ageMonths int) *models.Cats {
+	birthDate := now.AddDate(0, -ageMonths, 0)
+	return &models.Cats{Behavior: behavior, Description: &description, BirthDate: &birthDate, RaceID: "1", Color: "Black"}
+}
+
+func TestScoreFamilyWithYoungChildren(t *testing.T) {
+	profile := &models.LifestyleProfile{HomeType: models.HomeHouse, HasYoungChildren: true, HoursAlonePerDay: 6}
+
+	friendly := Score(profile, newCat("Friendly", "Adore les enfants.", 36), nil, Signals{}, notifications.LocaleEN, now)
+	aggressive := Score(profile, newCat("Aggressive", "", 36), nil, Signals{}, notifications.LocaleEN, now)
+
+	assert.Greater(t, friendly.Score, baseScore)
+	assert.Less(t, aggressive.Score, baseScore)
+	assert.Equal(t, "children", aggressive.Reasons[0].Factor)
+}
+
+var mainecoon = &models.Races{
+	RaceName:    "Maine Coon",
+	Temperament: []string{models.TemperamentSociable, models.TemperamentGoodWithChildren, models.TemperamentGoodWithPets},
+}
+
+func TestScoreNotesWinOverRaceTraits(t *testing.T) {
+	profile := &models.LifestyleProfile{HasYoungChildren: true}
+
+	result := Score(profile, newCat("", "Placement sans enfant.", 36), mainecoon, Signals{}, notifications.LocaleEN, now)
+
+	assert.Equal(t, baseScore-25, result.Score)
+}
+
+func TestScoreUsesRaceTraits(t *testing.T) {
+	profile := &models.LifestyleProfile{HomeType: models.HomeApartment}
+	persian := &models.Races{RaceName: "Persan", ActivityLevel: models.ActivityLow}
+
+	unknown := Score(profile, newCat("", "", 36), nil, Signals{}, notifications.LocaleEN, now)
+	calm := Score(profile, newCat("", "", 36), persian, Signals{}, notifications.LocaleEN, now)
+
+	assert.Equal(t, baseScore, unknown.Score)
+	assert.Equal(t, baseScore+10, calm.Score)
+}
+
+func TestScoreKittenLeftAlone(t *testing.T) {
+	profile := &models.LifestyleProfile{HoursAlonePerDay: 10}
+
+	result := Score(profile, newCat("", "", 4), nil, Signals{}, notifications.LocaleEN, now)
+
+	assert.Equal(t, baseScore-15, result.Score)
+	assert.Equal(t, "time_alone", result.Reasons[0].Factor)
+}
+
+func TestScoreSignalsWithoutProfile(t *testing.T) {
+	signals := Signals{
+		FavoriteRaces:  map[string]int{"1": 2},
+		FavoriteColors: map[string]int{"black": 1},
+	}
+
+	result := Score(nil, newCat("Aggressive", "", 36), nil, signals, notifications.LocaleEN, now)
+
+	assert.Equal(t, baseScore+15, result.Score)
+	assert.Len(t, result.Reasons, 2)
+}
+
+func TestScoreIsClamped(t *testing.T) {
+	profile := &models.LifestyleProfile{
+		HomeType:         models.HomeApartment,
+		HasYoungChildren: true,
+		HasDogs:          true,
+		HoursAlonePerDay: 10,
+		Experience:       models.ExperienceNone,
+	}
+
+	result := Score(profile, newCat("Aggressive playful", "", 36), nil, Signals{DismissedRaces: map[string]int{"1": 3}}, notifications.LocaleEN, now)
+
+	assert.Equal(t, minScore, result.Score)
+}
+
+func TestScoreReadsDeniedNotes(t *testing.T) {
+	tests := []struct {
+		description string
+		has         trait
+		hasNot      trait
+	}{
+		{description: "N'aime pas les enfants.", has: noChildren, hasNot: goodWithChildren},
+		{description: "Ne supporte pas les chiens.", has: onlyPet, hasNot: goodWithPets},
+		{description: "Doesn't get along with dogs.", has: onlyPet, hasNot: goodWithPets},
+		{description: "N'aime pas les chiens, mais adore les enfants.", has: onlyPet | goodWithChildren, hasNot: goodWithPets | noChildren},
+		{description: "N'a aucun problème avec les chiens.", has: goodWithPets, hasNot: onlyPet},
+		{description: "Pas timide du tout.", hasNot: shy},
+		{description: "Adore les enfants.", has: goodWithChildren, hasNot: noChildren},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			traits := catTraits(newCat("", tt.description, 36), nil)
+
+			assert.Equal(t, tt.has, traits&tt.has)
+			assert.Zero(t, traits&tt.hasNot)
+		})
+	}
+
+	assert.False(t, catTraits(newCat("Ne mord pas, jamais agressif", "", 36), nil).has(aggressive))
+}
+
+func TestScoreExplainsInLocale(t *testing.T) {
+	profile := &models.LifestyleProfile{HasYoungChildren: true}
+	cat := newCat("", "N'aime pas les enfants.", 36)
+
+	fr := Score(profile, cat, nil, Signals{}, notifications.LocaleFR, now)
+	en := Score(profile, cat, nil, Signals{}, "en-GB", now)
+
+	assert.Equal(t, baseScore-25, fr.Score)
+	assert.Equal(t, "children_not_placed", fr.Reasons[0].Key)
+	assert.Equal(t, "L'association ne place pas ce chat dans une famille avec enfants", fr.Reasons[0].Explanation)
+	assert.Equal(t, "The association does not place this cat with children", en.Reasons[0].Explanation)
+}
+
+func TestReasonTemplatesAreTranslated(t *testing.T) {
+	for key, byLocale := range reasonTemplates {
+		for _, locale := range notifications.SupportedLocales {
+			assert.NotEmpty(t, byLocale[locale], "%s has no %s explanation", key, locale)
+		}
+	}
+}
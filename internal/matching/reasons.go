@@ -0,0 +1,142 @@
+package matching
+
+import (
+	"go-challenge/internal/notifications"
+)
+
+// reasons holds the explanations of the factors of a score, keyed by reason
+// and locale like the notification templates. Only their body is used.
+var reasons = newReasonRegistry()
+
+func newReasonRegistry() *notifications.Registry {
+	registry := notifications.NewRegistry(notifications.DefaultLocale)
+	for key, byLocale := range reasonTemplates {
+		for locale, explanation := range byLocale {
+			if err := registry.Register(notifications.Event(key), locale, notifications.Template{Body: explanation}); err != nil {
+				panic(err)
+			}
+		}
+	}
+	return registry
+}
+
+// explain renders the explanation of a reason in a locale, or returns its key
+// when it has none.
+func explain(key, locale string) string {
+	message, err := reasons.Render(notifications.Event(key), locale, nil)
+	if err != nil {
+		return key
+	}
+	return message.Body
+}
+
+var reasonTemplates = map[string]map[string]string{
+	"home_energetic_apartment": {
+		notifications.LocaleFR: "Un chat énergique risque de se sentir à l'étroit en appartement",
+		notifications.LocaleEN: "An energetic cat may feel cramped in an apartment",
+	},
+	"home_calm_apartment": {
+		notifications.LocaleFR: "Un chat calme s'adapte bien à la vie en appartement",
+		notifications.LocaleEN: "A calm cat suits apartment life",
+	},
+	"home_energetic_garden": {
+		notifications.LocaleFR: "Un jardin laisse de la place pour jouer à un chat énergique",
+		notifications.LocaleEN: "A garden gives an energetic cat room to play",
+	},
+	"children_not_placed": {
+		notifications.LocaleFR: "L'association ne place pas ce chat dans une famille avec enfants",
+		notifications.LocaleEN: "The association does not place this cat with children",
+	},
+	"children_aggressive_young": {
+		notifications.LocaleFR: "Ce chat peut être agressif, ce qui est risqué avec de jeunes enfants",
+		notifications.LocaleEN: "This cat can be aggressive, which is risky with young children",
+	},
+	"children_good": {
+		notifications.LocaleFR: "Ce chat s'entend bien avec les enfants",
+		notifications.LocaleEN: "This cat is known to get along with children",
+	},
+	"children_shy": {
+		notifications.LocaleFR: "Un chat timide peut être stressé par de jeunes enfants",
+		notifications.LocaleEN: "A shy cat may be stressed by young children",
+	},
+	"children_friendly": {
+		notifications.LocaleFR: "Un chat sociable s'adapte en général aux enfants",
+		notifications.LocaleEN: "A friendly cat usually adapts to children",
+	},
+	"children_aggressive": {
+		notifications.LocaleFR: "Ce chat peut être agressif avec les enfants",
+		notifications.LocaleEN: "This cat can be aggressive with children",
+	},
+	"children_older": {
+		notifications.LocaleFR: "Ce chat devrait apprécier des enfants plus grands",
+		notifications.LocaleEN: "This cat should enjoy older children",
+	},
+	"pets_only_pet_allowed": {
+		notifications.LocaleFR: "Ce chat veut être le seul animal, ce que votre foyer permet",
+		notifications.LocaleEN: "This cat wants to be the only pet, which your home allows",
+	},
+	"pets_only_pet": {
+		notifications.LocaleFR: "Ce chat doit être le seul animal du foyer",
+		notifications.LocaleEN: "This cat needs to be the only pet of the home",
+	},
+	"pets_aggressive": {
+		notifications.LocaleFR: "Ce chat peut être agressif avec les autres animaux",
+		notifications.LocaleEN: "This cat can be aggressive with other animals",
+	},
+	"pets_good": {
+		notifications.LocaleFR: "Ce chat s'entend bien avec les autres animaux",
+		notifications.LocaleEN: "This cat is known to get along with other animals",
+	},
+	"pets_friendly": {
+		notifications.LocaleFR: "Un chat sociable accepte en général les autres animaux",
+		notifications.LocaleEN: "A friendly cat usually accepts other animals",
+	},
+	"time_alone_kitten": {
+		notifications.LocaleFR: "Un chaton ne doit pas rester seul de longues journées",
+		notifications.LocaleEN: "A kitten should not stay alone for long days",
+	},
+	"time_alone_needs_company": {
+		notifications.LocaleFR: "Ce chat a besoin de compagnie et serait souvent seul",
+		notifications.LocaleEN: "This cat needs company and would often be alone",
+	},
+	"time_alone_calm": {
+		notifications.LocaleFR: "Ce chat est assez calme pour vous attendre",
+		notifications.LocaleEN: "This cat is calm enough to wait for you",
+	},
+	"time_alone_kitten_home": {
+		notifications.LocaleFR: "Vous êtes assez présent pour élever un chaton",
+		notifications.LocaleEN: "You are home enough to raise a kitten",
+	},
+	"time_alone_company_home": {
+		notifications.LocaleFR: "Vous êtes assez présent pour un chat qui a besoin de compagnie",
+		notifications.LocaleEN: "You are home enough for a cat that needs company",
+	},
+	"experience_aggressive": {
+		notifications.LocaleFR: "Ce chat convient mieux à un adoptant expérimenté",
+		notifications.LocaleEN: "This cat is better suited to an experienced adopter",
+	},
+	"experience_shy": {
+		notifications.LocaleFR: "Un chat timide demande une patience qui peut manquer à un premier adoptant",
+		notifications.LocaleEN: "A shy cat asks for patience a first adopter may lack",
+	},
+	"experience_easy": {
+		notifications.LocaleFR: "Un chat facile à vivre est un bon premier chat",
+		notifications.LocaleEN: "An easy-going cat is a good first cat",
+	},
+	"experience_helps": {
+		notifications.LocaleFR: "Votre expérience aidera ce chat à prendre ses marques",
+		notifications.LocaleEN: "Your experience will help this cat settle in",
+	},
+	"favorite_breed": {
+		notifications.LocaleFR: "Même race que des chats de vos favoris",
+		notifications.LocaleEN: "Same breed as cats you added to your favorites",
+	},
+	"favorite_color": {
+		notifications.LocaleFR: "Même couleur que des chats de vos favoris",
+		notifications.LocaleEN: "Same color as cats you added to your favorites",
+	},
+	"dismissed_breed": {
+		notifications.LocaleFR: "Vous avez écarté plusieurs chats de cette race",
+		notifications.LocaleEN: "You dismissed several cats of this breed",
+	},
+}
@@ -0,0 +1,188 @@
+package matching
+
+import (
+	"strings"
+	"unicode"
+
+	"go-challenge/internal/models"
+)
+
+type trait uint
+
+const (
+	energetic trait = 1 << iota
+	calm
+	sociable
+	shy
+	aggressive
+	goodWithChildren
+	noChildren
+	goodWithPets
+	onlyPet
+	needsCompany
+)
+
+func (t trait) has(other trait) bool {
+	return t&other != 0
+}
+
+// behaviorKeywords detect traits in the free text behavior of a cat, in
+// French and English.
+var behaviorKeywords = map[trait][]string{
+	energetic:  {"playful", "active", "energetic", "joueur", "joueuse", "actif", "énergique"},
+	calm:       {"lazy", "calm", "quiet", "calme", "paresseux", "paresseuse", "tranquille", "placide"},
+	sociable:   {"friendly", "affectionate", "cuddly", "gentle", "affectueux", "affectueuse", "câlin", "sociable", "amical", "doux", "douce"},
+	shy:        {"shy", "fearful", "timide", "craintif", "craintive", "peureux", "peureuse"},
+	aggressive: {"aggressive", "agressif", "agressive", "griffe", "mord"},
+}
+
+// noteKeywords detect the placement constraints associations write in the
+// description of a cat. Negative constraints are listed first as they win
+// over positive ones. A keyword denied in its sentence, as in "n'aime pas les
+// enfants", stands for the negated trait instead, or for nothing.
+var noteKeywords = []struct {
+	trait    trait
+	negated  trait
+	keywords []string
+}{
+	{trait: noChildren, keywords: []string{"pas d'enfant", "sans enfant", "no children", "no kids"}},
+	{trait: onlyPet, keywords: []string{"seul animal", "chat unique", "pas d'autres animaux", "sans autre animal", "only pet", "only cat"}},
+	{trait: goodWithChildren, negated: noChildren, keywords: []string{"enfant", "children", "kids"}},
+	{trait: goodWithPets, negated: onlyPet, keywords: []string{"chien", "autres chats", "dogs", "other cats", "congénères"}},
+	{trait: needsCompany, keywords: []string{"compagnie", "pas seul", "company"}},
+	{trait: energetic, keywords: []string{"joueur", "joueuse", "playful", "chasser", "grimper"}},
+	{trait: calm, keywords: []string{"prélasser", "calme", "calm"}},
+	{trait: sociable, keywords: []string{"affectueux", "affectueuse", "amical", "friendly"}},
+	{trait: shy, keywords: []string{"timide", "shy"}},
+}
+
+// negationWords deny what follows them in a sentence. "n't" splits into "t".
+var negationWords = map[string]bool{
+	"ne": true, "n": true, "pas": true, "jamais": true, "ni": true,
+	"not": true, "no": true, "never": true, "t": true,
+}
+
+// negationIdioms read as negations but are not, as in "n'a pas de problème
+// avec les chiens".
+var negationIdioms = []string{
+	"pas de problème", "pas de souci", "aucun problème", "aucun souci",
+	"no problem", "no issue",
+}
+
+// temperamentTraits map the temperaments of the breed catalogue to traits.
+var temperamentTraits = map[string]trait{
+	models.TemperamentCalm:             calm,
+	models.TemperamentEnergetic:        energetic,
+	models.TemperamentSociable:         sociable,
+	models.TemperamentShy:              shy,
+	models.TemperamentGoodWithChildren: goodWithChildren,
+	models.TemperamentGoodWithPets:     goodWithPets,
+	models.TemperamentNeedsCompany:     needsCompany,
+}
+
+// raceTraits are the usual traits of a breed, from its temperament and
+// activity level in the catalogue.
+func raceTraits(race *models.Races) trait {
+	var traits trait
+	if race == nil {
+		return traits
+	}
+	for _, temperament := range race.Temperament {
+		traits |= temperamentTraits[temperament]
+	}
+	switch race.ActivityLevel {
+	case models.ActivityHigh:
+		traits |= energetic
+	case models.ActivityLow:
+		traits |= calm
+	}
+	return traits
+}
+
+func catTraits(cat *models.Cats, race *models.Races) trait {
+	var traits trait
+
+	behavior := strings.ToLower(cat.Behavior)
+	for t, keywords := range behaviorKeywords {
+		if found, _ := findKeywords(behavior, keywords); found {
+			traits |= t
+		}
+	}
+
+	if cat.Description != nil {
+		notes := strings.ToLower(*cat.Description)
+		for _, note := range noteKeywords {
+			if traits.has(noChildren) && note.trait == goodWithChildren {
+				continue
+			}
+			if traits.has(onlyPet) && note.trait == goodWithPets {
+				continue
+			}
+			// Negative constraints hold their own negation
+			if note.trait == noChildren || note.trait == onlyPet {
+				if containsAny(notes, note.keywords) {
+					traits |= note.trait
+				}
+				continue
+			}
+			found, denied := findKeywords(notes, note.keywords)
+			if found {
+				traits |= note.trait
+			} else if denied {
+				traits |= note.negated
+			}
+		}
+	}
+
+	return traits | raceTraits(race)
+}
+
+func containsAny(text string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// findKeywords reports whether a text states one of the keywords, and whether
+// it denies one in the sentence where it appears.
+func findKeywords(text string, keywords []string) (found, denied bool) {
+	for _, keyword := range keywords {
+		for start := 0; ; {
+			i := strings.Index(text[start:], keyword)
+			if i < 0 {
+				break
+			}
+			i += start
+			if deniedBefore(text[:i]) {
+				denied = true
+			} else {
+				found = true
+			}
+			start = i + len(keyword)
+		}
+	}
+	return found, denied
+}
+
+// deniedBefore reports whether the end of a text, from the start of its last
+// sentence or clause, holds a negation.
+func deniedBefore(text string) bool {
+	if i := strings.LastIndexAny(text, ".!?;,\n"); i >= 0 {
+		text = text[i+1:]
+	}
+	if containsAny(text, negationIdioms) {
+		return false
+	}
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, word := range words {
+		if negationWords[word] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+type AdoptionApplicationStatus string
+
+const (
+	ApplicationPending   AdoptionApplicationStatus = "pending"
+	ApplicationAccepted  AdoptionApplicationStatus = "accepted"
+	ApplicationRejected  AdoptionApplicationStatus = "rejected"
+	ApplicationWithdrawn AdoptionApplicationStatus = "withdrawn"
+)
+
+// AdoptionApplication is the request of a user to adopt one cat, or several
+// siblings together. Accepting it reserves its cats for the applicant.
+type AdoptionApplication struct {
+	gorm.Model
+	ApplicantID string                    `gorm:"type:varchar(100);not null;index"`
+	CatIDs      pq.StringArray            `gorm:"type:varchar(100)[]"`
+	Message     string                    `gorm:"type:text"`
+	Status      AdoptionApplicationStatus `gorm:"type:varchar(20);not null;default:'pending'"`
+	DecidedBy   string                    `gorm:"type:varchar(100)"`
+	DecidedAt   *time.Time
+}
+
+// Decided reports whether an application was already accepted, rejected or
+// withdrawn.
+func (a *AdoptionApplication) Decided() bool {
+	return a.Status != ApplicationPending
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/jinzhu/gorm"
 )
 
@@ -10,4 +12,32 @@ type Annonce struct {
 	Description *string `gorm:"type:varchar(250)"`
 	UserID      string
 	CatID       string
+	// LitterID is set on the annonces of a whole litter, which cover all of
+	// its kittens. CatID is then one of them.
+	LitterID *uint `gorm:"index"`
+	// Slug names the public page of the annonce. It is set once on creation
+	// and survives title edits.
+	Slug string `gorm:"type:varchar(80);unique_index"`
+	// Status is changed through the status endpoint. Only published annonces
+	// are listed, until they expire at ExpiresAt unless renewed.
+	Status            AnnonceStatus      `gorm:"type:varchar(20);not null;default:'published'"`
+	CloseReason       AnnonceCloseReason `gorm:"type:varchar(20)"`
+	PublishedAt       *time.Time
+	ExpiresAt         *time.Time `gorm:"index"`
+	RenewalRemindedAt *time.Time
+	// CoverImage is the cover photo of the cat, filled in for responses along
+	// with its smaller variants.
+	CoverImage          string `gorm:"-"`
+	CoverMediumImage    string `gorm:"-"`
+	CoverThumbnailImage string `gorm:"-"`
+	// LitterCats are the kittens of the litter, filled in for responses.
+	LitterCats []Cats `gorm:"-"`
+}
+
+// BeforeCreate gives a new annonce the slug of its public page.
+func (a *Annonce) BeforeCreate() error {
+	if a.Slug == "" {
+		a.Slug = NewAnnonceSlug(a.Title)
+	}
+	return nil
 }
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+type AnnonceEventKind string
+
+const (
+	AnnonceViewed     AnnonceEventKind = "view"
+	AnnonceFavorited  AnnonceEventKind = "favorite"
+	AnnonceChatOpened AnnonceEventKind = "chat"
+	AnnonceApplied    AnnonceEventKind = "application"
+	AnnonceCatAdopted AnnonceEventKind = "adoption"
+)
+
+// AnnonceFunnel lists the kinds of events in the order users go through them,
+// from seeing an annonce to adopting its cat.
+var AnnonceFunnel = []AnnonceEventKind{
+	AnnonceViewed,
+	AnnonceFavorited,
+	AnnonceChatOpened,
+	AnnonceApplied,
+	AnnonceCatAdopted,
+}
+
+// AnnonceEvent records something a user did on an annonce. Events are only
+// ever added: a user counts once per annonce, kind and day. The association
+// the cat was published as is kept for reporting, as it is cleared when the
+// cat is adopted.
+type AnnonceEvent struct {
+	ID            uint `gorm:"primary_key"`
+	CreatedAt     time.Time
+	AnnonceID     uint             `gorm:"not null;unique_index:idx_annonce_event"`
+	Kind          AnnonceEventKind `gorm:"type:varchar(20);not null;unique_index:idx_annonce_event"`
+	UserID        string           `gorm:"type:varchar(100);not null;unique_index:idx_annonce_event"`
+	Day           time.Time        `gorm:"type:date;not null;unique_index:idx_annonce_event"`
+	AssociationID string           `gorm:"type:varchar(100);index"`
+}
+
+// EventDay returns the day an event happening at t is counted on, in UTC.
+func EventDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,24 @@
+package models
+
+import "github.com/jinzhu/gorm"
+
+// ReasonDuplicatePhotos is the reason of the reports made when the photos of
+// an annonce were already published by another owner.
+const ReasonDuplicatePhotos = "duplicatePhotos"
+
+// AnnoncePhotoMatch is the evidence of an automatic report: a photo of the
+// cat of the reported annonce looks like a photo of the cat of another owner.
+// Distance is the number of bits their perceptual hashes differ by, 0 for
+// the same picture.
+type AnnoncePhotoMatch struct {
+	gorm.Model
+	ReportID         uint `gorm:"not null;index"`
+	PhotoID          uint
+	PhotoURL         string `gorm:"type:varchar(500)"`
+	MatchedPhotoID   uint
+	MatchedPhotoURL  string `gorm:"type:varchar(500)"`
+	MatchedCatID     uint
+	MatchedUserID    string `gorm:"type:varchar(100)"`
+	MatchedAnnonceID *uint
+	Distance         int
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+const (
+	maxSlugTitleLen = 60
+	slugSuffixLen   = 6
+	slugAlphabet    = "abcdefghijkmnpqrstuvwxyz23456789"
+)
+
+var slugAccents = strings.NewReplacer(
+	"à", "a", "â", "a", "ä", "a", "á", "a", "ã", "a", "å", "a",
+	"ç", "c",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "í", "i", "ì", "i",
+	"ô", "o", "ö", "o", "ó", "o", "ò", "o", "õ", "o",
+	"ù", "u", "û", "u", "ü", "u", "ú", "u",
+	"ÿ", "y", "ñ", "n", "æ", "ae", "œ", "oe", "ß", "ss",
+)
+
+// NewAnnonceSlug makes the slug of the public page of an annonce out of its
+// title and a random suffix, so that two annonces with the same title get
+// different pages. The slug is kept when the title changes, for links
+// already shared to keep working.
+func NewAnnonceSlug(title string) string {
+	suffix := make([]byte, slugSuffixLen)
+	rand.Read(suffix)
+	for i, b := range suffix {
+		suffix[i] = slugAlphabet[int(b)%len(slugAlphabet)]
+	}
+
+	slug := slugify(title)
+	if slug == "" {
+		slug = "annonce"
+	}
+	return slug + "-" + string(suffix)
+}
+
+// slugify lowercases a text, drops its accents and joins its words with
+// dashes, cutting it at a word boundary if it is too long.
+func slugify(text string) string {
+	text = slugAccents.Replace(strings.ToLower(text))
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+
+	slug := ""
+	for _, word := range words {
+		if len(slug)+len(word)+1 > maxSlugTitleLen {
+			if slug == "" {
+				slug = word[:maxSlugTitleLen]
+			}
+			break
+		}
+		if slug != "" {
+			slug += "-"
+		}
+		slug += word
+	}
+	return slug
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "chaton-tigre-a-adopter", slugify("Chaton tigré à adopter !"))
+	assert.Equal(t, "l-oeil-de-felix-2-ans", slugify("  L'œil de Félix, 2 ans  "))
+	assert.Equal(t, "", slugify("🐱 !!"))
+
+	long := slugify(strings.Repeat("minou ", 20))
+	assert.LessOrEqual(t, len(long), maxSlugTitleLen)
+	assert.False(t, strings.HasSuffix(long, "-"))
+	assert.Len(t, slugify(strings.Repeat("a", 100)), maxSlugTitleLen)
+}
+
+func TestNewAnnonceSlug(t *testing.T) {
+	slug := NewAnnonceSlug("Doux Maine Coon")
+	assert.Regexp(t, regexp.MustCompile(`^doux-maine-coon-[a-z2-9]{6}$`), slug)
+	assert.NotEqual(t, slug, NewAnnonceSlug("Doux Maine Coon"))
+	assert.Regexp(t, regexp.MustCompile(`^annonce-[a-z2-9]{6}$`), NewAnnonceSlug(""))
+}
@@ -0,0 +1,77 @@
+package models
+
+type AnnonceStatus string
+
+const (
+	AnnonceDraft     AnnonceStatus = "draft"
+	AnnoncePublished AnnonceStatus = "published"
+	AnnoncePaused    AnnonceStatus = "paused"
+	AnnonceExpired   AnnonceStatus = "expired"
+	AnnonceClosed    AnnonceStatus = "closed"
+)
+
+// annonceStatusTransitions lists the statuses an annonce may move to from
+// each status. Annonces only expire on schedule, and publishing an expired
+// annonce renews it.
+var annonceStatusTransitions = map[AnnonceStatus][]AnnonceStatus{
+	AnnonceDraft:     {AnnoncePublished, AnnonceClosed},
+	AnnoncePublished: {AnnoncePaused, AnnonceClosed},
+	AnnoncePaused:    {AnnoncePublished, AnnonceClosed},
+	AnnonceExpired:   {AnnoncePublished, AnnonceClosed},
+	AnnonceClosed:    {},
+}
+
+// Valid reports whether s is a known status.
+func (s AnnonceStatus) Valid() bool {
+	_, ok := annonceStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether an annonce may move from s to the given
+// status.
+func (s AnnonceStatus) CanTransitionTo(to AnnonceStatus) bool {
+	for _, allowed := range annonceStatusTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Renewable reports whether an annonce with this status may have its expiry
+// pushed back.
+func (s AnnonceStatus) Renewable() bool {
+	return s == AnnoncePublished || s == AnnonceExpired
+}
+
+type AnnonceCloseReason string
+
+const (
+	CloseAdopted   AnnonceCloseReason = "adopted"
+	CloseDeceased  AnnonceCloseReason = "deceased"
+	CloseWithdrawn AnnonceCloseReason = "withdrawn"
+	CloseOther     AnnonceCloseReason = "other"
+)
+
+// Valid reports whether r is a known close reason.
+func (r AnnonceCloseReason) Valid() bool {
+	switch r {
+	case CloseAdopted, CloseDeceased, CloseWithdrawn, CloseOther:
+		return true
+	}
+	return false
+}
+
+// annonceCloseReasons are the reasons annonces are closed for when their cat
+// leaves the association for good.
+var annonceCloseReasons = map[CatStatus]AnnonceCloseReason{
+	CatAdopted:  CloseAdopted,
+	CatDeceased: CloseDeceased,
+}
+
+// AnnonceCloseReasonFor returns the reason the annonces of a cat are closed
+// for when it moves to a status, if they should be.
+func AnnonceCloseReasonFor(status CatStatus) (AnnonceCloseReason, bool) {
+	reason, ok := annonceCloseReasons[status]
+	return reason, ok
+}
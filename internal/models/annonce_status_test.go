@@ -0,0 +1,38 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnonceStatusTransitions(t *testing.T) {
+	assert.True(t, AnnonceDraft.CanTransitionTo(AnnoncePublished))
+	assert.True(t, AnnoncePublished.CanTransitionTo(AnnoncePaused))
+	assert.True(t, AnnoncePaused.CanTransitionTo(AnnoncePublished))
+	assert.True(t, AnnonceExpired.CanTransitionTo(AnnoncePublished))
+	assert.True(t, AnnoncePublished.CanTransitionTo(AnnonceClosed))
+
+	assert.False(t, AnnoncePublished.CanTransitionTo(AnnonceExpired))
+	assert.False(t, AnnoncePublished.CanTransitionTo(AnnonceDraft))
+	assert.False(t, AnnonceDraft.CanTransitionTo(AnnoncePaused))
+	assert.False(t, AnnonceClosed.CanTransitionTo(AnnoncePublished))
+}
+
+func TestAnnonceStatusRenewable(t *testing.T) {
+	assert.True(t, AnnoncePublished.Renewable())
+	assert.True(t, AnnonceExpired.Renewable())
+	for _, status := range []AnnonceStatus{AnnonceDraft, AnnoncePaused, AnnonceClosed} {
+		assert.False(t, status.Renewable(), status)
+	}
+}
+
+func TestAnnonceCloseReasonFor(t *testing.T) {
+	reason, ok := AnnonceCloseReasonFor(CatAdopted)
+	assert.True(t, ok)
+	assert.Equal(t, CloseAdopted, reason)
+
+	_, ok = AnnonceCloseReasonFor(CatReserved)
+	assert.False(t, ok)
+	assert.False(t, AnnonceCloseReason("sold").Valid())
+}
@@ -1,20 +1,62 @@
 package models
 
 import (
+	"net/url"
+	"time"
+
 	"github.com/jinzhu/gorm"
 	"github.com/lib/pq"
 )
 
 type Association struct {
 	gorm.Model
-	Name       string         `gorm:"type:varchar(100)"`
-	AddressRue string         `gorm:"type:varchar(250)"`
-	Cp         string         `gorm:"type:char(5)"`
-	Ville      string         `gorm:"type:varchar(100)"`
-	Phone      string         `gorm:"type:varchar(13)"`
-	Email      string         `gorm:"type:varchar(100)"`
-	KbisFile   string         `gorm:"type:varchar(500)"`
-	Members    pq.StringArray `gorm:"type:text[]"`
-	OwnerID    string         `gorm:"type:uuid;not null"`
-	Verified   *bool          `gorm:"type:boolean;default:false"`
+	Name       string `gorm:"type:varchar(100)"`
+	AddressRue string `gorm:"type:varchar(250)"`
+	Cp         string `gorm:"type:char(5)"`
+	Ville      string `gorm:"type:varchar(100)"`
+	Phone      string `gorm:"type:varchar(13)"`
+	Email      string `gorm:"type:varchar(100)"`
+	// KbisFile is the storage key of the registration extract of the
+	// association, which is only handed out as a signed URL.
+	KbisFile  string `gorm:"type:varchar(500)"`
+	Siret     string `gorm:"type:char(14)"`
+	RnaNumber string `gorm:"type:varchar(10)"`
+	// Members are the users taking part in the association, its owner
+	// included.
+	Members  []AssociationMember
+	OwnerID  string `gorm:"type:uuid;not null"`
+	Verified *bool  `gorm:"type:boolean;default:false"`
+	// VerificationStatus is where the association is in its review.
+	// Verified follows it, only verified associations get a badge and
+	// publish cats.
+	VerificationStatus AssociationVerificationStatus `gorm:"type:varchar(20);not null;default:'submitted';index"`
+	VerifiedAt         *time.Time
+	// Latitude and Longitude locate the postal code of the association.
+	Latitude  *float64
+	Longitude *float64
+	// Description, its logo, links, opening hours and adoption terms make up
+	// the public profile of the association.
+	Description        string         `gorm:"type:text"`
+	LogoURL            string         `gorm:"type:varchar(255)"`
+	LogoThumbnailURL   string         `gorm:"type:varchar(255)"`
+	Website            string         `gorm:"type:varchar(255)"`
+	SocialLinks        pq.StringArray `gorm:"type:varchar(255)[]"`
+	OpeningHours       string         `gorm:"type:varchar(500)"`
+	AdoptionConditions string         `gorm:"type:text"`
+	AdoptionFees       string         `gorm:"type:varchar(500)"`
+}
+
+// IsVerified reports whether the association went through its review.
+func (a *Association) IsVerified() bool {
+	return a.VerificationStatus == AssociationVerified
+}
+
+// ValidLink reports whether a link of a public profile is an absolute http or
+// https URL.
+func ValidLink(link string) bool {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
 }
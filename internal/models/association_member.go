@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type AssociationRole string
+
+const (
+	AssociationOwner     AssociationRole = "owner"
+	AssociationAdmin     AssociationRole = "admin"
+	AssociationVolunteer AssociationRole = "volunteer"
+)
+
+// AssociationInvitationLifetime is how long an invitation to join an
+// association may be accepted for.
+const AssociationInvitationLifetime = 14 * 24 * time.Hour
+
+// Valid reports whether r is a known role.
+func (r AssociationRole) Valid() bool {
+	switch r {
+	case AssociationOwner, AssociationAdmin, AssociationVolunteer:
+		return true
+	}
+	return false
+}
+
+// CanPublish reports whether members with this role may publish cats as
+// their association. Volunteers help with the cats already published.
+func (r AssociationRole) CanPublish() bool {
+	return r == AssociationOwner || r == AssociationAdmin
+}
+
+// AssociationMember is a user taking part in an association. The owner of an
+// association is also its member, with the owner role.
+type AssociationMember struct {
+	ID            uint `gorm:"primary_key"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	AssociationID uint            `gorm:"not null;unique_index:idx_association_member"`
+	UserID        string          `gorm:"type:uuid;not null;unique_index:idx_association_member;index"`
+	Role          AssociationRole `gorm:"type:varchar(20);not null"`
+}
+
+type InvitationStatus string
+
+const (
+	InvitationPending  InvitationStatus = "pending"
+	InvitationAccepted InvitationStatus = "accepted"
+	InvitationDeclined InvitationStatus = "declined"
+	InvitationRevoked  InvitationStatus = "revoked"
+)
+
+// AssociationInvitation invites whoever signs in with an email address to
+// join an association with a role.
+type AssociationInvitation struct {
+	gorm.Model
+	AssociationID uint             `gorm:"not null;index"`
+	Email         string           `gorm:"type:varchar(100);not null;index"`
+	Role          AssociationRole  `gorm:"type:varchar(20);not null"`
+	InvitedBy     string           `gorm:"type:varchar(100)"`
+	Status        InvitationStatus `gorm:"type:varchar(20);not null;default:'pending';index"`
+	ExpiresAt     time.Time        `gorm:"not null"`
+	RespondedAt   *time.Time
+}
+
+// Open reports whether the invitation may still be accepted or declined.
+func (i *AssociationInvitation) Open(now time.Time) bool {
+	return i.Status == InvitationPending && now.Before(i.ExpiresAt)
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssociationRole(t *testing.T) {
+	assert.True(t, AssociationVolunteer.Valid())
+	assert.False(t, AssociationRole("member").Valid())
+
+	assert.True(t, AssociationOwner.CanPublish())
+	assert.True(t, AssociationAdmin.CanPublish())
+	assert.False(t, AssociationVolunteer.CanPublish())
+}
+
+func TestAssociationInvitationOpen(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	invitation := &AssociationInvitation{Status: InvitationPending, ExpiresAt: now.Add(time.Hour)}
+
+	assert.True(t, invitation.Open(now))
+	assert.False(t, invitation.Open(now.Add(2*time.Hour)))
+
+	invitation.Status = InvitationRevoked
+	assert.False(t, invitation.Open(now))
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidLink(t *testing.T) {
+	assert.True(t, ValidLink("https://leschatslibres.fr"))
+	assert.True(t, ValidLink("http://www.facebook.com/leschatslibres"))
+
+	for _, link := range []string{"", "leschatslibres.fr", "javascript:alert(1)", "ftp://leschatslibres.fr", "https://"} {
+		assert.False(t, ValidLink(link), link)
+	}
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"regexp"
+
+	"github.com/jinzhu/gorm"
+)
+
+type AssociationVerificationStatus string
+
+const (
+	AssociationSubmitted        AssociationVerificationStatus = "submitted"
+	AssociationInReview         AssociationVerificationStatus = "in_review"
+	AssociationChangesRequested AssociationVerificationStatus = "changes_requested"
+	AssociationVerified         AssociationVerificationStatus = "verified"
+	AssociationRejected         AssociationVerificationStatus = "rejected"
+)
+
+// associationVerificationTransitions lists the statuses an association may
+// move to from each status. Reviewers move submitted associations along,
+// owners submit them again once changed, and a verified association is
+// submitted again when its key information changes.
+var associationVerificationTransitions = map[AssociationVerificationStatus][]AssociationVerificationStatus{
+	AssociationSubmitted:        {AssociationInReview, AssociationChangesRequested, AssociationVerified, AssociationRejected},
+	AssociationInReview:         {AssociationSubmitted, AssociationChangesRequested, AssociationVerified, AssociationRejected},
+	AssociationChangesRequested: {AssociationSubmitted},
+	AssociationVerified:         {AssociationSubmitted, AssociationRejected},
+	AssociationRejected:         {AssociationSubmitted},
+}
+
+// Valid reports whether s is a known status.
+func (s AssociationVerificationStatus) Valid() bool {
+	_, ok := associationVerificationTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether an association may move from s to the
+// given status.
+func (s AssociationVerificationStatus) CanTransitionTo(to AssociationVerificationStatus) bool {
+	for _, allowed := range associationVerificationTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Reviewed reports whether reviewers move associations to this status, as
+// opposed to their owners.
+func (s AssociationVerificationStatus) Reviewed() bool {
+	return s != AssociationSubmitted && s.Valid()
+}
+
+// AssociationVerificationChange records an association moving from one
+// verification status to another, with the notes of its reviewer. ChangedBy
+// is empty when the association was submitted again because its key
+// information changed.
+type AssociationVerificationChange struct {
+	gorm.Model
+	AssociationID uint                          `gorm:"not null;index"`
+	FromStatus    AssociationVerificationStatus `gorm:"type:varchar(20);not null"`
+	ToStatus      AssociationVerificationStatus `gorm:"type:varchar(20);not null"`
+	ChangedBy     string                        `gorm:"type:varchar(100)"`
+	Note          string                        `gorm:"type:text"`
+}
+
+type AssociationDocumentKind string
+
+const (
+	DocumentKbis      AssociationDocumentKind = "kbis"
+	DocumentRNA       AssociationDocumentKind = "rna"
+	DocumentInsurance AssociationDocumentKind = "insurance"
+)
+
+// Valid reports whether k is a known kind of document.
+func (k AssociationDocumentKind) Valid() bool {
+	switch k {
+	case DocumentKbis, DocumentRNA, DocumentInsurance:
+		return true
+	}
+	return false
+}
+
+// AssociationDocument is a document an association provides for its
+// verification: an extract of its registration, its RNA receipt or its
+// insurance certificate. Its file is private: FileKey is its key in the
+// storage, handed out only as a signed URL.
+type AssociationDocument struct {
+	gorm.Model
+	AssociationID uint                    `gorm:"not null;index"`
+	Kind          AssociationDocumentKind `gorm:"type:varchar(20);not null"`
+	Name          string                  `gorm:"type:varchar(255)"`
+	FileKey       string                  `gorm:"type:varchar(500);not null" json:"-"`
+	UploadedBy    string                  `gorm:"type:varchar(100)"`
+}
+
+var rnaNumberPattern = regexp.MustCompile(`^W\d{9}$`)
+
+// laPosteSiren is the SIREN of La Poste, whose establishments are too many
+// for their SIRET to all pass the Luhn check.
+const laPosteSiren = "356000000"
+
+// ValidSiret reports whether a SIRET has 14 digits and passes the Luhn check.
+func ValidSiret(siret string) bool {
+	if len(siret) != 14 {
+		return false
+	}
+	sum, digits := 0, 0
+	for i := len(siret) - 1; i >= 0; i-- {
+		c := siret[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		digit := int(c - '0')
+		digits += digit
+		if (len(siret)-1-i)%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	// Apart from their headquarters, the establishments of La Poste have
+	// digits adding up to a multiple of 5 instead
+	return sum%10 == 0 || siret[:9] == laPosteSiren && digits%5 == 0
+}
+
+// ValidRNANumber reports whether a number of the Répertoire National des
+// Associations is a W followed by 9 digits.
+func ValidRNANumber(number string) bool {
+	return rnaNumberPattern.MatchString(number)
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssociationVerificationTransitions(t *testing.T) {
+	assert.True(t, AssociationSubmitted.CanTransitionTo(AssociationInReview))
+	assert.True(t, AssociationInReview.CanTransitionTo(AssociationVerified))
+	assert.True(t, AssociationChangesRequested.CanTransitionTo(AssociationSubmitted))
+	assert.True(t, AssociationVerified.CanTransitionTo(AssociationSubmitted))
+
+	assert.False(t, AssociationChangesRequested.CanTransitionTo(AssociationVerified))
+	assert.False(t, AssociationRejected.CanTransitionTo(AssociationVerified))
+	assert.False(t, AssociationVerified.CanTransitionTo(AssociationVerified))
+
+	assert.True(t, AssociationRejected.Reviewed())
+	assert.False(t, AssociationSubmitted.Reviewed())
+	assert.False(t, AssociationVerificationStatus("pending").Reviewed())
+}
+
+func TestValidSiret(t *testing.T) {
+	assert.True(t, ValidSiret("73282932000074"))
+	assert.True(t, ValidSiret("35600000000048"))
+	assert.True(t, ValidSiret("35600000049837"))
+
+	assert.False(t, ValidSiret("73282932000075"))
+	assert.False(t, ValidSiret("7328293200007"))
+	assert.False(t, ValidSiret("732829320000740"))
+	assert.False(t, ValidSiret("7328293200007A"))
+	assert.False(t, ValidSiret("35600000049838"))
+}
+
+func TestValidRNANumber(t *testing.T) {
+	assert.True(t, ValidRNANumber("W751234567"))
+
+	assert.False(t, ValidRNANumber("751234567"))
+	assert.False(t, ValidRNANumber("W75123456"))
+	assert.False(t, ValidRNANumber("w751234567"))
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// CareProtocol tells how often a care must be renewed. Name matches the
+// medical entries of the type whose name contains it, ignoring case; an empty
+// name matches every entry of the type.
+type CareProtocol struct {
+	gorm.Model
+	Type               MedicalEntryType `gorm:"type:varchar(20);not null"`
+	Name               string           `gorm:"type:varchar(100)"`
+	Label              string           `gorm:"type:varchar(100);not null"`
+	IntervalDays       int              `gorm:"not null"`
+	ReminderDaysBefore int              `gorm:"not null"`
+}
+
+// CareReminder records that the owner of a cat was reminded of a care due on
+// a date, so they are reminded only once per due date.
+type CareReminder struct {
+	gorm.Model
+	CatID   uint      `gorm:"not null;unique_index:idx_care_reminder"`
+	Key     string    `gorm:"type:varchar(150);not null;unique_index:idx_care_reminder"`
+	DueDate time.Time `gorm:"not null;unique_index:idx_care_reminder"`
+	UserID  string    `gorm:"type:varchar(100)"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// CatPhoto is one picture of the gallery of a cat. Photos are shown by
+// Position; exactly one photo of a gallery is its cover. URL is the full size
+// picture, MediumURL and ThumbnailURL are its smaller variants.
+type CatPhoto struct {
+	gorm.Model
+	CatID        uint   `gorm:"not null;index"`
+	URL          string `gorm:"type:varchar(500);not null"`
+	MediumURL    string `gorm:"type:varchar(500)"`
+	ThumbnailURL string `gorm:"type:varchar(500)"`
+	Position     int    `gorm:"not null"`
+	Cover        bool
+	Caption      string `gorm:"type:varchar(250)"`
+	// Hash is the perceptual hash of the picture, used to spot the same photos
+	// published by other owners. It is nil when the picture cannot be decoded.
+	Hash *int64 `gorm:"index" json:"-"`
+}
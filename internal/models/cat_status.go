@@ -0,0 +1,62 @@
+package models
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+type CatStatus string
+
+const (
+	CatAvailable CatStatus = "available"
+	CatFoster    CatStatus = "foster"
+	CatReserved  CatStatus = "reserved"
+	CatAdopted   CatStatus = "adopted"
+	CatDeceased  CatStatus = "deceased"
+	CatReturned  CatStatus = "returned"
+)
+
+// catStatusTransitions lists the statuses a cat may move to from each status.
+// A returned cat goes back to the association before being available again.
+var catStatusTransitions = map[CatStatus][]CatStatus{
+	CatAvailable: {CatFoster, CatReserved, CatAdopted, CatDeceased},
+	CatFoster:    {CatAvailable, CatReserved, CatAdopted, CatDeceased},
+	CatReserved:  {CatAvailable, CatFoster, CatAdopted, CatDeceased},
+	CatAdopted:   {CatReturned, CatDeceased},
+	CatReturned:  {CatAvailable, CatFoster, CatDeceased},
+	CatDeceased:  {},
+}
+
+// Valid reports whether s is a known status.
+func (s CatStatus) Valid() bool {
+	_, ok := catStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether a cat may move from s to the given status.
+func (s CatStatus) CanTransitionTo(to CatStatus) bool {
+	for _, allowed := range catStatusTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Adoptable reports whether a cat with this status may be listed for
+// adoption.
+func (s CatStatus) Adoptable() bool {
+	return s == CatAvailable
+}
+
+// CatStatusChange records a cat moving from one status to another. The
+// association the cat was published as is kept for reporting, as it is
+// cleared when the cat is adopted.
+type CatStatusChange struct {
+	gorm.Model
+	CatID         uint      `gorm:"not null;index"`
+	AssociationID string    `gorm:"type:varchar(100);index"`
+	FromStatus    CatStatus `gorm:"type:varchar(20);not null"`
+	ToStatus      CatStatus `gorm:"type:varchar(20);not null"`
+	ChangedBy     string    `gorm:"type:varchar(100)"`
+	Note          string    `gorm:"type:varchar(250)"`
+}
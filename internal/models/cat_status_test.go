@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatStatusTransitions(t *testing.T) {
+	assert.True(t, CatAvailable.CanTransitionTo(CatReserved))
+	assert.True(t, CatReserved.CanTransitionTo(CatAvailable))
+	assert.True(t, CatAdopted.CanTransitionTo(CatReturned))
+	assert.True(t, CatReturned.CanTransitionTo(CatAvailable))
+
+	assert.False(t, CatAvailable.CanTransitionTo(CatAvailable))
+	assert.False(t, CatAvailable.CanTransitionTo(CatReturned))
+	assert.False(t, CatAdopted.CanTransitionTo(CatAvailable))
+	assert.False(t, CatReturned.CanTransitionTo(CatAdopted))
+	assert.False(t, CatDeceased.CanTransitionTo(CatAvailable))
+}
+
+func TestCatStatusValid(t *testing.T) {
+	assert.True(t, CatFoster.Valid())
+	assert.False(t, CatStatus("lost").Valid())
+	assert.False(t, CatStatus("").Valid())
+}
+
+func TestCatStatusAdoptable(t *testing.T) {
+	assert.True(t, CatAvailable.Adoptable())
+	for _, status := range []CatStatus{CatFoster, CatReserved, CatAdopted, CatDeceased, CatReturned} {
+		assert.False(t, status.Adoptable(), status)
+	}
+}
@@ -18,9 +18,22 @@ type Cats struct {
 	Behavior        string `gorm:"type:varchar(100)"`
 	Sterilized      bool
 	RaceID          string
-	Description     *string `gorm:"type:varchar(250)"`
-	Reserved        bool
+	Description     *string        `gorm:"type:varchar(250)"`
 	PicturesURL     pq.StringArray `gorm:"type:varchar(500)[]"`
 	UserID          string         `gorm:"type:varchar(100)"`
 	PublishedAs     string         `gorm:"type:varchar(100)"`
+	// Latitude and Longitude are copied from the association the cat is
+	// published as, or from its owner.
+	Latitude  *float64
+	Longitude *float64
+	// IdentificationNumber is the microchip or tattoo number of the cat.
+	IdentificationNumber string `gorm:"type:varchar(20)"`
+	// Status is changed through the status endpoint, which keeps the history
+	// of the changes.
+	Status          CatStatus `gorm:"type:varchar(20);not null;default:'available'"`
+	StatusChangedAt *time.Time
+	// MotherID, FatherID and LitterID relate the cat to its family.
+	MotherID *uint `gorm:"index"`
+	FatherID *uint `gorm:"index"`
+	LitterID *uint `gorm:"index"`
 }
@@ -2,8 +2,19 @@ package models
 
 import "github.com/jinzhu/gorm"
 
+// Favorite is an annonce a user keeps an eye on. A user favorites an annonce
+// only once, and may sort it in one of their collections.
 type Favorite struct {
 	gorm.Model
-	UserID    string
-	AnnonceID string
+	UserID       string
+	AnnonceID    string
+	CollectionID *uint `gorm:"index"`
+}
+
+// FavoriteCollection is a named list a user sorts their favorites in, such as
+// "to visit".
+type FavoriteCollection struct {
+	gorm.Model
+	UserID string `gorm:"type:varchar(100);not null;index"`
+	Name   string `gorm:"type:varchar(100);not null"`
 }
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+type FosterCatProfile string
+
+const (
+	FosterKitten       FosterCatProfile = "kitten"
+	FosterAdult        FosterCatProfile = "adult"
+	FosterSenior       FosterCatProfile = "senior"
+	FosterSpecialNeeds FosterCatProfile = "special_needs"
+)
+
+// Valid reports whether p is a known profile.
+func (p FosterCatProfile) Valid() bool {
+	switch p {
+	case FosterKitten, FosterAdult, FosterSenior, FosterSpecialNeeds:
+		return true
+	}
+	return false
+}
+
+// CatFosterProfile returns the profile of a cat from its age: kittens are
+// under a year old, seniors 10 years old or more. Cats of unknown age are
+// taken for adults.
+func CatFosterProfile(cat *Cats, now time.Time) FosterCatProfile {
+	if cat.BirthDate == nil {
+		return FosterAdult
+	}
+	switch {
+	case now.Before(cat.BirthDate.AddDate(1, 0, 0)):
+		return FosterKitten
+	case !now.Before(cat.BirthDate.AddDate(10, 0, 0)):
+		return FosterSenior
+	}
+	return FosterAdult
+}
+
+// FosterProfile is a user offering to foster cats for associations until
+// they are adopted. Capacity is the number of cats they can hold at once.
+type FosterProfile struct {
+	gorm.Model
+	UserID           string         `gorm:"type:varchar(100);not null;unique_index"`
+	Capacity         int            `gorm:"not null"`
+	AcceptedProfiles pq.StringArray `gorm:"type:varchar(20)[]"`
+	Available        bool           `gorm:"not null"`
+	AvailableFrom    *time.Time
+	AvailableUntil   *time.Time
+	Cp               string `gorm:"type:varchar(5)"`
+	Ville            string `gorm:"type:varchar(100)"`
+	Description      string `gorm:"type:text"`
+	Latitude         *float64
+	Longitude        *float64
+}
+
+// Accepts reports whether the foster takes in cats of a profile.
+func (p *FosterProfile) Accepts(profile FosterCatProfile) bool {
+	for _, accepted := range p.AcceptedProfiles {
+		if FosterCatProfile(accepted) == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// AvailableOn reports whether the foster can take cats in on a day.
+func (p *FosterProfile) AvailableOn(day time.Time) bool {
+	if !p.Available {
+		return false
+	}
+	if p.AvailableFrom != nil && day.Before(*p.AvailableFrom) {
+		return false
+	}
+	return p.AvailableUntil == nil || day.Before(*p.AvailableUntil)
+}
+
+type FosterPlacementStatus string
+
+const (
+	FosterRequested FosterPlacementStatus = "requested"
+	FosterDeclined  FosterPlacementStatus = "declined"
+	FosterCancelled FosterPlacementStatus = "cancelled"
+	FosterAccepted  FosterPlacementStatus = "accepted"
+	FosterActive    FosterPlacementStatus = "active"
+	FosterEnded     FosterPlacementStatus = "ended"
+)
+
+// fosterPlacementTransitions lists the statuses a placement may move to from
+// each status. An accepted placement becomes active on its start date.
+var fosterPlacementTransitions = map[FosterPlacementStatus][]FosterPlacementStatus{
+	FosterRequested: {FosterAccepted, FosterActive, FosterDeclined, FosterCancelled},
+	FosterAccepted:  {FosterActive, FosterCancelled},
+	FosterActive:    {FosterEnded},
+	FosterDeclined:  {},
+	FosterCancelled: {},
+	FosterEnded:     {},
+}
+
+// Valid reports whether s is a known status.
+func (s FosterPlacementStatus) Valid() bool {
+	_, ok := fosterPlacementTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether a placement may move from s to the given
+// status.
+func (s FosterPlacementStatus) CanTransitionTo(to FosterPlacementStatus) bool {
+	for _, allowed := range fosterPlacementTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Open reports whether a placement is still to be answered, to start or
+// under way.
+func (s FosterPlacementStatus) Open() bool {
+	return s == FosterRequested || s == FosterAccepted || s == FosterActive
+}
+
+// FosterPlacement is a cat of an association placed with a foster from
+// StartDate until EndDate, or until further notice. The association requests
+// it and the foster accepts or declines it. While it is active, the cat has
+// the foster status.
+type FosterPlacement struct {
+	gorm.Model
+	CatID         uint                  `gorm:"not null;index"`
+	AssociationID uint                  `gorm:"not null;index"`
+	FosterID      string                `gorm:"type:varchar(100);not null;index"`
+	Status        FosterPlacementStatus `gorm:"type:varchar(20);not null;default:'requested'"`
+	StartDate     time.Time             `gorm:"not null"`
+	EndDate       *time.Time
+	SpecialNeeds  bool
+	Message       string `gorm:"type:text"`
+	RequestedBy   string `gorm:"type:varchar(100)"`
+	RespondedAt   *time.Time
+	EndedBy       string `gorm:"type:varchar(100)"`
+	EndedAt       *time.Time
+}
+
+// Profile returns the profile a foster must accept to take the cat of the
+// placement in.
+func (p *FosterPlacement) Profile(cat *Cats) FosterCatProfile {
+	if p.SpecialNeeds {
+		return FosterSpecialNeeds
+	}
+	return CatFosterProfile(cat, p.StartDate)
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatFosterProfile(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	born := func(years, months int) *Cats {
+		birthDate := now.AddDate(-years, -months, 0)
+		return &Cats{BirthDate: &birthDate}
+	}
+
+	assert.Equal(t, FosterKitten, CatFosterProfile(born(0, 3), now))
+	assert.Equal(t, FosterAdult, CatFosterProfile(born(1, 0), now))
+	assert.Equal(t, FosterAdult, CatFosterProfile(born(9, 11), now))
+	assert.Equal(t, FosterSenior, CatFosterProfile(born(10, 0), now))
+	assert.Equal(t, FosterAdult, CatFosterProfile(&Cats{}, now))
+}
+
+func TestFosterPlacementProfile(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	birthDate := start.AddDate(0, -2, 0)
+	kitten := &Cats{BirthDate: &birthDate}
+
+	assert.Equal(t, FosterKitten, (&FosterPlacement{StartDate: start}).Profile(kitten))
+	assert.Equal(t, FosterSpecialNeeds, (&FosterPlacement{StartDate: start, SpecialNeeds: true}).Profile(kitten))
+}
+
+func TestFosterProfileAccepts(t *testing.T) {
+	profile := &FosterProfile{AcceptedProfiles: pq.StringArray{"kitten", "special_needs"}}
+
+	assert.True(t, profile.Accepts(FosterKitten))
+	assert.True(t, profile.Accepts(FosterSpecialNeeds))
+	assert.False(t, profile.Accepts(FosterSenior))
+}
+
+func TestFosterProfileAvailableOn(t *testing.T) {
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+	profile := &FosterProfile{Available: true, AvailableFrom: &from, AvailableUntil: &until}
+
+	assert.True(t, profile.AvailableOn(from))
+	assert.True(t, profile.AvailableOn(time.Date(2024, 7, 14, 0, 0, 0, 0, time.UTC)))
+	assert.False(t, profile.AvailableOn(from.AddDate(0, 0, -1)))
+	assert.False(t, profile.AvailableOn(until))
+
+	profile.Available = false
+	assert.False(t, profile.AvailableOn(time.Date(2024, 7, 14, 0, 0, 0, 0, time.UTC)))
+
+	assert.True(t, (&FosterProfile{Available: true}).AvailableOn(from))
+}
+
+func TestFosterPlacementTransitions(t *testing.T) {
+	assert.True(t, FosterRequested.CanTransitionTo(FosterAccepted))
+	assert.True(t, FosterRequested.CanTransitionTo(FosterActive))
+	assert.True(t, FosterAccepted.CanTransitionTo(FosterActive))
+	assert.True(t, FosterActive.CanTransitionTo(FosterEnded))
+
+	assert.False(t, FosterRequested.CanTransitionTo(FosterEnded))
+	assert.False(t, FosterActive.CanTransitionTo(FosterCancelled))
+	assert.False(t, FosterDeclined.CanTransitionTo(FosterAccepted))
+	assert.False(t, FosterEnded.CanTransitionTo(FosterActive))
+}
+
+func TestFosterPlacementOpen(t *testing.T) {
+	for _, status := range []FosterPlacementStatus{FosterRequested, FosterAccepted, FosterActive} {
+		assert.True(t, status.Open(), status)
+	}
+	for _, status := range []FosterPlacementStatus{FosterDeclined, FosterCancelled, FosterEnded} {
+		assert.False(t, status.Open(), status)
+	}
+	assert.False(t, FosterPlacementStatus("lost").Valid())
+}
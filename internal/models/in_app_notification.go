@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// InAppNotification is an entry of the user inbox shown in the app.
+type InAppNotification struct {
+	gorm.Model
+	UserID string `gorm:"type:varchar(100);not null;index"`
+	Event  string `gorm:"type:varchar(50)"`
+	Title  string `gorm:"type:varchar(250)"`
+	Body   string `gorm:"type:varchar(1000)"`
+	Data   string `gorm:"type:text"`
+	ReadAt *time.Time
+}
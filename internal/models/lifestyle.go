@@ -0,0 +1,41 @@
+package models
+
+import "github.com/jinzhu/gorm"
+
+type HomeType string
+
+const (
+	HomeApartment HomeType = "apartment"
+	HomeHouse     HomeType = "house"
+	HomeGarden    HomeType = "house_with_garden"
+)
+
+type Experience string
+
+const (
+	ExperienceNone        Experience = "none"
+	ExperienceSome        Experience = "some"
+	ExperienceExperienced Experience = "experienced"
+)
+
+// LifestyleProfile holds the answers of a user to the adopter questionnaire,
+// used to score how well each cat would fit in their home.
+type LifestyleProfile struct {
+	gorm.Model
+	UserID           string   `gorm:"type:uuid;unique_index;not null"`
+	HomeType         HomeType `gorm:"type:varchar(20)"`
+	HasYoungChildren bool
+	HasOlderChildren bool
+	HasCats          bool
+	HasDogs          bool
+	HoursAlonePerDay int
+	Experience       Experience `gorm:"type:varchar(20)"`
+}
+
+// CatDismissal records that a user is not interested in a cat, so it is no
+// longer recommended to them.
+type CatDismissal struct {
+	gorm.Model
+	UserID string `gorm:"type:uuid;not null;unique_index:idx_dismissal_user_cat"`
+	CatID  uint   `gorm:"not null;unique_index:idx_dismissal_user_cat"`
+}
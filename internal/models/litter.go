@@ -0,0 +1,70 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Litter groups kittens born together. Its parents are copied to the kittens
+// added to it.
+type Litter struct {
+	gorm.Model
+	Name        string `gorm:"type:varchar(100);not null"`
+	BirthDate   *time.Time
+	MotherID    *uint
+	FatherID    *uint
+	Description string `gorm:"type:varchar(250)"`
+	UserID      string `gorm:"type:varchar(100)"`
+	PublishedAs string `gorm:"type:varchar(100)"`
+	// Kittens is filled in for responses.
+	Kittens []Cats `gorm:"-"`
+}
+
+// CatFamily holds the cats related to a cat. Siblings share its litter or one
+// of its parents; Kittens are the cats it is a parent of.
+type CatFamily struct {
+	Mother   *Cats
+	Father   *Cats
+	Litter   *Litter
+	Siblings []Cats
+	Kittens  []Cats
+}
+
+// Siblings reports whether two cats are siblings or half-siblings.
+func Siblings(a, b *Cats) bool {
+	if a.ID == b.ID {
+		return false
+	}
+	return sameParent(a.LitterID, b.LitterID) || sameParent(a.MotherID, b.MotherID) || sameParent(a.FatherID, b.FatherID)
+}
+
+// AllSiblings reports whether every cat of a group is a sibling of all the
+// others. A single cat makes a group of its own.
+func AllSiblings(cats []Cats) bool {
+	for i := range cats {
+		for j := i + 1; j < len(cats); j++ {
+			if !Siblings(&cats[i], &cats[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// CanBeMother reports whether a cat may be set as a mother, which rules out
+// males. Cats of unknown sex are accepted.
+func (c *Cats) CanBeMother() bool {
+	return !strings.EqualFold(c.Sexe, "male")
+}
+
+// CanBeFather reports whether a cat may be set as a father, which rules out
+// females. Cats of unknown sex are accepted.
+func (c *Cats) CanBeFather() bool {
+	return !strings.EqualFold(c.Sexe, "female")
+}
+
+func sameParent(a, b *uint) bool {
+	return a != nil && b != nil && *a == *b
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+func cat(id uint, litterID, motherID, fatherID *uint) Cats {
+	return Cats{Model: gorm.Model{ID: id}, LitterID: litterID, MotherID: motherID, FatherID: fatherID}
+}
+
+func TestSiblings(t *testing.T) {
+	a := cat(1, uintPtr(10), nil, nil)
+	b := cat(2, uintPtr(10), nil, nil)
+	halfSister := cat(3, nil, nil, uintPtr(7))
+	halfBrother := cat(4, uintPtr(11), nil, uintPtr(7))
+	stranger := cat(5, nil, nil, nil)
+	otherStranger := cat(6, nil, nil, nil)
+
+	assert.True(t, Siblings(&a, &b))
+	assert.True(t, Siblings(&halfSister, &halfBrother))
+	assert.False(t, Siblings(&a, &a))
+	assert.False(t, Siblings(&a, &halfBrother))
+	assert.False(t, Siblings(&stranger, &otherStranger))
+}
+
+func TestAllSiblings(t *testing.T) {
+	a := cat(1, nil, uintPtr(9), nil)
+	b := cat(2, nil, uintPtr(9), uintPtr(7))
+	c := cat(3, nil, nil, uintPtr(7))
+
+	assert.True(t, AllSiblings([]Cats{a}))
+	assert.True(t, AllSiblings([]Cats{a, b}))
+	assert.True(t, AllSiblings([]Cats{b, c}))
+	// a and c only share a half-sibling.
+	assert.False(t, AllSiblings([]Cats{a, b, c}))
+}
+
+func TestParentSex(t *testing.T) {
+	assert.True(t, (&Cats{Sexe: "Female"}).CanBeMother())
+	assert.False(t, (&Cats{Sexe: "Male"}).CanBeMother())
+	assert.True(t, (&Cats{Sexe: "male"}).CanBeFather())
+	assert.False(t, (&Cats{Sexe: "FEMALE"}).CanBeFather())
+	assert.True(t, (&Cats{}).CanBeMother())
+}
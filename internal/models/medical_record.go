@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type MedicalEntryType string
+
+const (
+	MedicalVaccination   MedicalEntryType = "vaccination"
+	MedicalDeworming     MedicalEntryType = "deworming"
+	MedicalFleaTreatment MedicalEntryType = "flea_treatment"
+	MedicalVetVisit      MedicalEntryType = "vet_visit"
+	MedicalWeight        MedicalEntryType = "weight"
+	MedicalCondition     MedicalEntryType = "condition"
+)
+
+// MedicalEntry is one event of the medical record of a cat. Name holds the
+// vaccine, the product, the reason of the visit or the condition depending on
+// the type. The record follows the cat when it changes owner.
+type MedicalEntry struct {
+	gorm.Model
+	CatID        uint             `gorm:"not null;index"`
+	Type         MedicalEntryType `gorm:"type:varchar(20);not null"`
+	Date         time.Time        `gorm:"not null"`
+	Name         string           `gorm:"type:varchar(100)"`
+	BatchNumber  string           `gorm:"type:varchar(50)"`
+	Veterinarian string           `gorm:"type:varchar(100)"`
+	WeightGrams  *int
+	NextDueDate  *time.Time
+	// Ongoing tells whether a condition still affects the cat.
+	Ongoing    bool
+	Notes      string `gorm:"type:text"`
+	RecordedBy string `gorm:"type:varchar(100)"`
+}
+
+// CatOwnershipTransfer records a cat changing hands, from its owner or the
+// association it was published as to its adopter.
+type CatOwnershipTransfer struct {
+	gorm.Model
+	CatID             uint   `gorm:"not null;index"`
+	FromUserID        string `gorm:"type:varchar(100)"`
+	FromAssociationID string `gorm:"type:varchar(100)"`
+	ToUserID          string `gorm:"type:varchar(100);not null"`
+	TransferredBy     string `gorm:"type:varchar(100)"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+type CampaignStatus string
+
+const (
+	CampaignSending CampaignStatus = "sending"
+	CampaignSent    CampaignStatus = "sent"
+	CampaignFailed  CampaignStatus = "failed"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliverySent    DeliveryStatus = "sent"
+	DeliveryFailed  DeliveryStatus = "failed"
+	DeliveryNoToken DeliveryStatus = "no_token"
+)
+
+// NotificationTarget describes who a broadcast is sent to. A user matching
+// any of the criteria is part of the recipients.
+type NotificationTarget struct {
+	UserIDs        pq.StringArray `gorm:"type:text[]"`
+	Roles          pq.StringArray `gorm:"type:text[]"`
+	AssociationIDs pq.StringArray `gorm:"type:text[]"`
+}
+
+// NotificationAudience is a named target saved by an admin for reuse.
+type NotificationAudience struct {
+	gorm.Model
+	Name      string `gorm:"type:varchar(100);not null"`
+	CreatedBy string `gorm:"type:varchar(100)"`
+	NotificationTarget
+}
+
+// NotificationCampaign is an admin broadcast. Its content is written per
+// locale and rendered for each recipient in their preferred locale.
+type NotificationCampaign struct {
+	gorm.Model
+	CreatedBy  string `gorm:"type:varchar(100)"`
+	TitleFR    string `gorm:"type:varchar(100)"`
+	BodyFR     string `gorm:"type:varchar(500)"`
+	TitleEN    string `gorm:"type:varchar(100)"`
+	BodyEN     string `gorm:"type:varchar(500)"`
+	AudienceID *uint
+	NotificationTarget
+	Status     CampaignStatus `gorm:"type:varchar(20)"`
+	Recipients int
+	Sent       int
+	Failed     int
+	NoToken    int
+	FinishedAt *time.Time
+}
+
+type NotificationDelivery struct {
+	gorm.Model
+	CampaignID uint           `gorm:"not null;index"`
+	UserID     string         `gorm:"type:varchar(100);not null"`
+	Status     DeliveryStatus `gorm:"type:varchar(20)"`
+	Locale     string         `gorm:"type:varchar(5)"`
+	MessageID  string         `gorm:"type:varchar(250)"`
+	Error      string         `gorm:"type:varchar(500)"`
+}
@@ -1,11 +1,155 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+type CoatLength string
+
+const (
+	CoatHairless CoatLength = "hairless"
+	CoatShort    CoatLength = "short"
+	CoatMedium   CoatLength = "medium"
+	CoatLong     CoatLength = "long"
+)
+
+type BreedSize string
+
+const (
+	BreedSmall  BreedSize = "small"
+	BreedMedium BreedSize = "medium"
+	BreedLarge  BreedSize = "large"
 )
 
+type ActivityLevel string
+
+const (
+	ActivityLow      ActivityLevel = "low"
+	ActivityModerate ActivityLevel = "moderate"
+	ActivityHigh     ActivityLevel = "high"
+)
+
+// Temperaments are the typical traits a breed can be known for. Matching
+// relies on them to score cats whose own description says little.
+const (
+	TemperamentCalm             = "calm"
+	TemperamentEnergetic        = "energetic"
+	TemperamentSociable         = "sociable"
+	TemperamentShy              = "shy"
+	TemperamentGoodWithChildren = "good_with_children"
+	TemperamentGoodWithPets     = "good_with_pets"
+	TemperamentNeedsCompany     = "needs_company"
+)
+
+var temperaments = []string{
+	TemperamentCalm,
+	TemperamentEnergetic,
+	TemperamentSociable,
+	TemperamentShy,
+	TemperamentGoodWithChildren,
+	TemperamentGoodWithPets,
+	TemperamentNeedsCompany,
+}
+
+// Races is a breed of the reference catalogue. Only the name is required,
+// traits left empty are unknown. Lifespans are in years. A photo set by URL,
+// as in imports, uses the same picture for all of its variants.
 type Races struct {
 	gorm.Model
-	RaceName string `gorm:"type:varchar(100);not null"`
-	Cats     []Cats
+	RaceName          string         `gorm:"type:varchar(100);not null"`
+	Description       string         `gorm:"type:text"`
+	Temperament       pq.StringArray `gorm:"type:varchar(30)[]"`
+	CoatLength        CoatLength     `gorm:"type:varchar(10)"`
+	Size              BreedSize      `gorm:"type:varchar(10)"`
+	ActivityLevel     ActivityLevel  `gorm:"type:varchar(10)"`
+	Hypoallergenic    bool           `gorm:"default:false"`
+	LifespanMin       *int
+	LifespanMax       *int
+	PhotoURL          string         `gorm:"type:varchar(255)"`
+	PhotoMediumURL    string         `gorm:"type:varchar(255)"`
+	PhotoThumbnailURL string         `gorm:"type:varchar(255)"`
+	AliasesFR         pq.StringArray `gorm:"type:varchar(100)[]"`
+	AliasesEN         pq.StringArray `gorm:"type:varchar(100)[]"`
+	Cats              []Cats
+}
+
+// Valid reports whether c is a known coat length.
+func (c CoatLength) Valid() bool {
+	switch c {
+	case CoatHairless, CoatShort, CoatMedium, CoatLong:
+		return true
+	}
+	return false
+}
+
+// Valid reports whether s is a known breed size.
+func (s BreedSize) Valid() bool {
+	switch s {
+	case BreedSmall, BreedMedium, BreedLarge:
+		return true
+	}
+	return false
+}
+
+// Valid reports whether a is a known activity level.
+func (a ActivityLevel) Valid() bool {
+	switch a {
+	case ActivityLow, ActivityModerate, ActivityHigh:
+		return true
+	}
+	return false
+}
+
+// ValidTemperament reports whether t is a known temperament.
+func ValidTemperament(t string) bool {
+	for _, known := range temperaments {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the name and traits of a breed, leaving unknown traits
+// empty.
+func (r *Races) Validate() error {
+	if strings.TrimSpace(r.RaceName) == "" {
+		return errors.New("raceName is required")
+	}
+	if r.CoatLength != "" && !r.CoatLength.Valid() {
+		return errors.New("coatLength must be hairless, short, medium or long")
+	}
+	if r.Size != "" && !r.Size.Valid() {
+		return errors.New("size must be small, medium or large")
+	}
+	if r.ActivityLevel != "" && !r.ActivityLevel.Valid() {
+		return errors.New("activityLevel must be low, moderate or high")
+	}
+	for _, t := range r.Temperament {
+		if !ValidTemperament(t) {
+			return fmt.Errorf("unknown temperament %q, must be one of %s", t, strings.Join(temperaments, ", "))
+		}
+	}
+	if (r.LifespanMin != nil && *r.LifespanMin <= 0) || (r.LifespanMax != nil && *r.LifespanMax <= 0) {
+		return errors.New("lifespan must be a positive number of years")
+	}
+	if r.LifespanMin != nil && r.LifespanMax != nil && *r.LifespanMin > *r.LifespanMax {
+		return errors.New("lifespanMin must be lower than lifespanMax")
+	}
+	return nil
+}
+
+// FillPhotoVariants uses the photo for the variants that are missing.
+func (r *Races) FillPhotoVariants() {
+	if r.PhotoMediumURL == "" {
+		r.PhotoMediumURL = r.PhotoURL
+	}
+	if r.PhotoThumbnailURL == "" {
+		r.PhotoThumbnailURL = r.PhotoURL
+	}
 }
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// raceCSVColumns are the columns of the breed catalogue in CSV. Lists are
+// separated by raceCSVListSeparator within their cell.
+var raceCSVColumns = []string{
+	"name",
+	"description",
+	"temperament",
+	"coat_length",
+	"size",
+	"activity_level",
+	"hypoallergenic",
+	"lifespan_min",
+	"lifespan_max",
+	"photo_url",
+	"aliases_fr",
+	"aliases_en",
+}
+
+const raceCSVListSeparator = "|"
+
+// WriteRacesCSV writes breeds as CSV, with a header line.
+func WriteRacesCSV(w io.Writer, races []Races) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(raceCSVColumns); err != nil {
+		return err
+	}
+	for _, race := range races {
+		record := []string{
+			race.RaceName,
+			race.Description,
+			strings.Join(race.Temperament, raceCSVListSeparator),
+			string(race.CoatLength),
+			string(race.Size),
+			string(race.ActivityLevel),
+			strconv.FormatBool(race.Hypoallergenic),
+			formatOptionalInt(race.LifespanMin),
+			formatOptionalInt(race.LifespanMax),
+			race.PhotoURL,
+			strings.Join(race.AliasesFR, raceCSVListSeparator),
+			strings.Join(race.AliasesEN, raceCSVListSeparator),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReadRacesCSV reads breeds written as CSV. The header line names the
+// columns, in any order; only the name column is required.
+func ReadRacesCSV(r io.Reader) ([]Races, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("the CSV file is empty")
+		}
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !knownRaceCSVColumn(name) {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("the name column is required")
+	}
+
+	races := []Races{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		cell := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		race := Races{
+			RaceName:      cell("name"),
+			Description:   cell("description"),
+			Temperament:   splitCSVList(cell("temperament")),
+			CoatLength:    CoatLength(cell("coat_length")),
+			Size:          BreedSize(cell("size")),
+			ActivityLevel: ActivityLevel(cell("activity_level")),
+			PhotoURL:      cell("photo_url"),
+			AliasesFR:     splitCSVList(cell("aliases_fr")),
+			AliasesEN:     splitCSVList(cell("aliases_en")),
+		}
+		if value := cell("hypoallergenic"); value != "" {
+			race.Hypoallergenic, err = strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: hypoallergenic must be true or false", line)
+			}
+		}
+		if race.LifespanMin, err = parseOptionalInt(cell("lifespan_min")); err != nil {
+			return nil, fmt.Errorf("line %d: lifespan_min must be a number of years", line)
+		}
+		if race.LifespanMax, err = parseOptionalInt(cell("lifespan_max")); err != nil {
+			return nil, fmt.Errorf("line %d: lifespan_max must be a number of years", line)
+		}
+		races = append(races, race)
+	}
+	return races, nil
+}
+
+func knownRaceCSVColumn(name string) bool {
+	for _, column := range raceCSVColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+func splitCSVList(cell string) []string {
+	values := []string{}
+	for _, value := range strings.Split(cell, raceCSVListSeparator) {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func formatOptionalInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func parseOptionalInt(cell string) (*int, error) {
+	if cell == "" {
+		return nil, nil
+	}
+	value, err := strconv.Atoi(cell)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
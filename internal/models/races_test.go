@@ -0,0 +1,81 @@
+package models
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestRaceValidate(t *testing.T) {
+	race := Races{
+		RaceName:      "Maine Coon",
+		Temperament:   []string{TemperamentSociable, TemperamentGoodWithChildren},
+		CoatLength:    CoatLong,
+		Size:          BreedLarge,
+		ActivityLevel: ActivityModerate,
+		LifespanMin:   intPtr(12),
+		LifespanMax:   intPtr(15),
+	}
+	assert.NoError(t, race.Validate())
+
+	unknown := race
+	unknown.Temperament = []string{"grumpy"}
+	assert.Error(t, unknown.Validate())
+
+	reversed := race
+	reversed.LifespanMin, reversed.LifespanMax = intPtr(15), intPtr(12)
+	assert.Error(t, reversed.Validate())
+
+	assert.Error(t, (&Races{RaceName: " "}).Validate())
+	assert.NoError(t, (&Races{RaceName: "Gouttière"}).Validate())
+}
+
+func TestRacesCSVRoundTrip(t *testing.T) {
+	races := []Races{
+		{
+			RaceName:       "Sphynx",
+			Description:    "Sans poils, mais \"très\" câlin",
+			Temperament:    []string{TemperamentSociable, TemperamentNeedsCompany},
+			CoatLength:     CoatHairless,
+			Size:           BreedMedium,
+			ActivityLevel:  ActivityHigh,
+			Hypoallergenic: true,
+			LifespanMin:    intPtr(8),
+			LifespanMax:    intPtr(14),
+			AliasesFR:      []string{"Sphinx"},
+			AliasesEN:      []string{"Canadian Hairless"},
+		},
+		{RaceName: "Gouttière", Temperament: []string{}, AliasesFR: []string{}, AliasesEN: []string{}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteRacesCSV(&buf, races))
+	read, err := ReadRacesCSV(&buf)
+
+	require.NoError(t, err)
+	assert.Equal(t, races, read)
+}
+
+func TestReadRacesCSVColumns(t *testing.T) {
+	races, err := ReadRacesCSV(strings.NewReader("size,name\nlarge,Ragdoll\n"))
+	require.NoError(t, err)
+	require.Len(t, races, 1)
+	assert.Equal(t, "Ragdoll", races[0].RaceName)
+	assert.Equal(t, BreedLarge, races[0].Size)
+
+	_, err = ReadRacesCSV(strings.NewReader("size\nlarge\n"))
+	assert.Error(t, err)
+
+	_, err = ReadRacesCSV(strings.NewReader("name,colour\nRagdoll,white\n"))
+	assert.Error(t, err)
+
+	_, err = ReadRacesCSV(strings.NewReader("name,lifespan_min\nRagdoll,old\n"))
+	assert.Error(t, err)
+}
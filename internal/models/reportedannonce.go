@@ -9,4 +9,7 @@ type ReportedAnnonce struct {
 	ReportedUserID string `gorm:"not null" json:"reportedUserId"`
 	ReasonID       uint   `gorm:"not null" json:"reasonId"`
 	IsHandled      bool   `gorm:"default:false"`
+	// Automatic reports are made by the application itself, they have no
+	// reporter.
+	Automatic bool `gorm:"default:false" json:"automatic"`
 }
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type SearchFrequency string
+
+const (
+	SearchInstant SearchFrequency = "instant"
+	SearchDaily   SearchFrequency = "daily"
+	SearchWeekly  SearchFrequency = "weekly"
+)
+
+// SavedSearch stores the cat filters of a user so new matching cats can be
+// alerted on. Empty filters match every cat.
+type SavedSearch struct {
+	gorm.Model
+	UserID        string `gorm:"type:varchar(100);not null;index"`
+	Name          string `gorm:"type:varchar(100)"`
+	RaceID        string `gorm:"type:varchar(100)"`
+	Sexe          string `gorm:"type:varchar(7)"`
+	MinAgeMonths  *int
+	MaxAgeMonths  *int
+	Color         string `gorm:"type:varchar(100)"`
+	Behavior      string `gorm:"type:varchar(100)"`
+	Sterilized    *bool
+	AssociationID string `gorm:"type:varchar(100)"`
+	Cp            string `gorm:"type:char(5)"`
+	RadiusKm      *int
+	Frequency     SearchFrequency `gorm:"type:varchar(10);default:'instant'"`
+	Push          bool
+	Active        bool
+	LastNotified  *time.Time
+}
+
+// SavedSearchMatch records that a cat matched a saved search, so a user is
+// alerted about a cat only once per search.
+type SavedSearchMatch struct {
+	gorm.Model
+	SavedSearchID uint `gorm:"not null;unique_index:idx_saved_search_cat"`
+	CatID         uint `gorm:"not null;unique_index:idx_saved_search_cat"`
+	AnnonceID     uint
+	NotifiedAt    *time.Time
+}
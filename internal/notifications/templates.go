@@ -0,0 +1,335 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Event identifies the kind of notification being sent. Every push sent by
+// the application goes through a template registered for its event.
+type Event string
+
+const (
+	EventNewMessage          Event = "new_message"
+	EventAssociationVerified Event = "association_verified"
+	EventAssociationRejected Event = "association_unverified"
+	EventSavedSearchMatch    Event = "saved_search_match"
+	EventSavedSearchDigest   Event = "saved_search_digest"
+	EventCareReminder        Event = "care_reminder"
+	EventAnnonceRenewal      Event = "annonce_renewal"
+	EventAnnonceExpired      Event = "annonce_expired"
+	EventFavoriteCatStatus   Event = "favorite_cat_status"
+
+	EventAssociationInvitation         Event = "association_invitation"
+	EventAssociationInvitationEmail    Event = "association_invitation_email"
+	EventAssociationInvitationAccepted Event = "association_invitation_accepted"
+	EventAssociationMemberRemoved      Event = "association_member_removed"
+	EventAssociationOwnership          Event = "association_ownership"
+	EventAssociationReview             Event = "association_review"
+
+	EventAdoptionApplication Event = "adoption_application"
+
+	EventFosterRequest  Event = "foster_request"
+	EventFosterResponse Event = "foster_response"
+	EventFosterClosed   Event = "foster_closed"
+)
+
+const (
+	LocaleFR = "fr"
+	LocaleEN = "en"
+
+	// DefaultLocale is used when a user has no preferred locale or when a
+	// template has no translation for the requested one.
+	DefaultLocale = LocaleFR
+)
+
+// SupportedLocales lists the locales templates can be registered for.
+var SupportedLocales = []string{LocaleFR, LocaleEN}
+
+// Template holds the raw title and body of a notification for one locale.
+// Both use text/template syntax, e.g. "Nouveau message de {{.SenderName}}".
+type Template struct {
+	Title string
+	Body  string
+}
+
+// Message is a rendered notification, ready to be pushed.
+type Message struct {
+	Event  Event
+	Locale string
+	Title  string
+	Body   string
+}
+
+type compiledTemplate struct {
+	title *template.Template
+	body  *template.Template
+}
+
+// Registry stores notification templates keyed by event and locale.
+type Registry struct {
+	mu        sync.RWMutex
+	fallback  string
+	templates map[Event]map[string]compiledTemplate
+}
+
+func NewRegistry(fallback string) *Registry {
+	return &Registry{
+		fallback:  NormalizeLocale(fallback),
+		templates: make(map[Event]map[string]compiledTemplate),
+	}
+}
+
+// Register compiles and stores the template of an event for a locale.
+func (r *Registry) Register(event Event, locale string, tpl Template) error {
+	locale = NormalizeLocale(locale)
+
+	title, err := template.New(string(event) + ".title").Option("missingkey=zero").Parse(tpl.Title)
+	if err != nil {
+		return fmt.Errorf("invalid title template for %s/%s: %w", event, locale, err)
+	}
+	body, err := template.New(string(event) + ".body").Option("missingkey=zero").Parse(tpl.Body)
+	if err != nil {
+		return fmt.Errorf("invalid body template for %s/%s: %w", event, locale, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.templates[event] == nil {
+		r.templates[event] = make(map[string]compiledTemplate)
+	}
+	r.templates[event][locale] = compiledTemplate{title: title, body: body}
+	return nil
+}
+
+// Has reports whether at least one template is registered for the event.
+func (r *Registry) Has(event Event) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.templates[event]) > 0
+}
+
+// Render renders the template of an event in the given locale, falling back
+// to the registry fallback locale when no translation exists.
+func (r *Registry) Render(event Event, locale string, vars map[string]string) (*Message, error) {
+	locale = NormalizeLocale(locale)
+
+	r.mu.RLock()
+	byLocale, ok := r.templates[event]
+	if !ok {
+		r.mu.RUnlock()
+		return nil, fmt.Errorf("no template registered for event %s", event)
+	}
+	tpl, ok := byLocale[locale]
+	if !ok {
+		locale = r.fallback
+		tpl, ok = byLocale[locale]
+	}
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no template registered for event %s in locale %s", event, locale)
+	}
+
+	if vars == nil {
+		vars = map[string]string{}
+	}
+
+	var title, body bytes.Buffer
+	if err := tpl.title.Execute(&title, vars); err != nil {
+		return nil, fmt.Errorf("error rendering title for %s: %w", event, err)
+	}
+	if err := tpl.body.Execute(&body, vars); err != nil {
+		return nil, fmt.Errorf("error rendering body for %s: %w", event, err)
+	}
+
+	return &Message{
+		Event:  event,
+		Locale: locale,
+		Title:  strings.TrimSpace(title.String()),
+		Body:   strings.TrimSpace(body.String()),
+	}, nil
+}
+
+// NormalizeLocale reduces a locale or Accept-Language value ("fr-FR",
+// "en_US", "en-GB,en;q=0.9") to one of the supported locales. Unknown values
+// return the default locale.
+func NormalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, ",;"); i >= 0 {
+		locale = locale[:i]
+	}
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	for _, supported := range SupportedLocales {
+		if locale == supported {
+			return supported
+		}
+	}
+	return DefaultLocale
+}
+
+// Default is the registry used by the handlers, preloaded with the
+// application templates.
+var Default = newDefaultRegistry()
+
+// Render renders an event with the default registry.
+func Render(event Event, locale string, vars map[string]string) (*Message, error) {
+	return Default.Render(event, locale, vars)
+}
+
+func newDefaultRegistry() *Registry {
+	registry := NewRegistry(DefaultLocale)
+	for event, byLocale := range defaultTemplates {
+		for locale, tpl := range byLocale {
+			if err := registry.Register(event, locale, tpl); err != nil {
+				panic(err)
+			}
+		}
+	}
+	return registry
+}
+
+var defaultTemplates = map[Event]map[string]Template{
+	EventNewMessage: {
+		LocaleFR: {Title: "Nouveau message de {{.SenderName}}", Body: "{{.Message}}"},
+		LocaleEN: {Title: "New message from {{.SenderName}}", Body: "{{.Message}}"},
+	},
+	EventAssociationVerified: {
+		LocaleFR: {Title: "Vérification Association", Body: "Votre association {{.AssociationName}} a été vérifiée."},
+		LocaleEN: {Title: "Association verification", Body: "Your association {{.AssociationName}} has been verified."},
+	},
+	EventAssociationRejected: {
+		LocaleFR: {Title: "Vérification Association", Body: "Votre association {{.AssociationName}} n'est plus vérifiée."},
+		LocaleEN: {Title: "Association verification", Body: "Your association {{.AssociationName}} is no longer verified."},
+	},
+	EventSavedSearchMatch: {
+		LocaleFR: {Title: "Nouveau chat pour « {{.SearchName}} »", Body: "{{.CatName}} correspond à votre recherche : {{.AnnonceTitle}}"},
+		LocaleEN: {Title: "New cat for \"{{.SearchName}}\"", Body: "{{.CatName}} matches your search: {{.AnnonceTitle}}"},
+	},
+	EventSavedSearchDigest: {
+		LocaleFR: {Title: "{{.Count}} nouveau(x) chat(s) pour « {{.SearchName}} »", Body: "De nouveaux chats correspondent à votre recherche, dont {{.CatNames}}."},
+		LocaleEN: {Title: "{{.Count}} new cat(s) for \"{{.SearchName}}\"", Body: "New cats match your search, including {{.CatNames}}."},
+	},
+	EventCareReminder: {
+		LocaleFR: {Title: "Rappel de soin pour {{.CatName}}", Body: "{{.CareName}} est à prévoir pour le {{.DueDate}}."},
+		LocaleEN: {Title: "Care reminder for {{.CatName}}", Body: "{{.CareName}} is due on {{.DueDate}}."},
+	},
+	EventAnnonceRenewal: {
+		LocaleFR: {Title: "Votre annonce « {{.AnnonceTitle}} » expire bientôt", Body: "Elle ne sera plus visible après le {{.ExpiresAt}}, pensez à la renouveler."},
+		LocaleEN: {Title: "Your annonce \"{{.AnnonceTitle}}\" expires soon", Body: "It will no longer be listed after {{.ExpiresAt}}, remember to renew it."},
+	},
+	EventAnnonceExpired: {
+		LocaleFR: {Title: "Votre annonce « {{.AnnonceTitle}} » a expiré", Body: "Elle n'est plus visible, renouvelez-la si {{.CatName}} cherche toujours une famille."},
+		LocaleEN: {Title: "Your annonce \"{{.AnnonceTitle}}\" expired", Body: "It is no longer listed, renew it if {{.CatName}} is still looking for a home."},
+	},
+	EventFavoriteCatStatus: {
+		LocaleFR: {
+			Title: "Des nouvelles de {{.CatName}}",
+			Body: `{{if eq .Status "available"}}{{.CatName}} est de nouveau à l'adoption !` +
+				`{{else if eq .Status "reserved"}}{{.CatName}}, que vous suivez, vient d'être réservé.` +
+				`{{else if eq .Status "adopted"}}{{.CatName}}, que vous suivez, a trouvé une famille.` +
+				`{{else if eq .Status "foster"}}{{.CatName}} est parti en famille d'accueil.` +
+				`{{else if eq .Status "deceased"}}Nous avons le regret de vous annoncer que {{.CatName}} nous a quittés.` +
+				`{{else}}{{.CatName}} a changé de situation.{{end}}`,
+		},
+		LocaleEN: {
+			Title: "News from {{.CatName}}",
+			Body: `{{if eq .Status "available"}}{{.CatName}} is up for adoption again!` +
+				`{{else if eq .Status "reserved"}}{{.CatName}}, whom you follow, was just reserved.` +
+				`{{else if eq .Status "adopted"}}{{.CatName}}, whom you follow, found a home.` +
+				`{{else if eq .Status "foster"}}{{.CatName}} went to a foster family.` +
+				`{{else if eq .Status "deceased"}}We are sorry to let you know that {{.CatName}} passed away.` +
+				`{{else}}{{.CatName}}'s situation changed.{{end}}`,
+		},
+	},
+	EventAssociationInvitation: {
+		LocaleFR: {Title: "Invitation à rejoindre {{.AssociationName}}", Body: "{{.InviterName}} vous invite à rejoindre {{.AssociationName}} en tant que {{if eq .Role \"admin\"}}administrateur{{else}}bénévole{{end}}."},
+		LocaleEN: {Title: "Invitation to join {{.AssociationName}}", Body: "{{.InviterName}} invites you to join {{.AssociationName}} as {{if eq .Role \"admin\"}}an admin{{else}}a volunteer{{end}}."},
+	},
+	EventAssociationInvitationEmail: {
+		LocaleFR: {
+			Title: "Invitation à rejoindre {{.AssociationName}} sur Purrfect Match",
+			Body: "Bonjour,\n\n{{.InviterName}} vous invite à rejoindre l'association {{.AssociationName}} sur Purrfect Match en tant que {{if eq .Role \"admin\"}}administrateur{{else}}bénévole{{end}}.\n\n" +
+				"Connectez-vous à l'application avec l'adresse {{.Email}} pour accepter l'invitation avant le {{.ExpiresAt}}.\n\n" +
+				"Si vous ne vous attendiez pas à cette invitation, vous pouvez ignorer ce message.",
+		},
+		LocaleEN: {
+			Title: "Invitation to join {{.AssociationName}} on Purrfect Match",
+			Body: "Hello,\n\n{{.InviterName}} invites you to join the association {{.AssociationName}} on Purrfect Match as {{if eq .Role \"admin\"}}an admin{{else}}a volunteer{{end}}.\n\n" +
+				"Sign in to the app with {{.Email}} to accept the invitation before {{.ExpiresAt}}.\n\n" +
+				"If you were not expecting this invitation, you can ignore this message.",
+		},
+	},
+	EventAssociationInvitationAccepted: {
+		LocaleFR: {Title: "{{.AssociationName}} a un nouveau membre", Body: "{{.MemberName}} a accepté votre invitation à rejoindre {{.AssociationName}}."},
+		LocaleEN: {Title: "{{.AssociationName}} has a new member", Body: "{{.MemberName}} accepted your invitation to join {{.AssociationName}}."},
+	},
+	EventAssociationMemberRemoved: {
+		LocaleFR: {Title: "Vous ne faites plus partie de {{.AssociationName}}", Body: "Vous avez été retiré des membres de {{.AssociationName}}."},
+		LocaleEN: {Title: "You are no longer part of {{.AssociationName}}", Body: "You were removed from the members of {{.AssociationName}}."},
+	},
+	EventAssociationOwnership: {
+		LocaleFR: {Title: "Vous êtes responsable de {{.AssociationName}}", Body: "{{.PreviousOwnerName}} vous a confié la responsabilité de {{.AssociationName}}."},
+		LocaleEN: {Title: "You are now in charge of {{.AssociationName}}", Body: "{{.PreviousOwnerName}} handed the ownership of {{.AssociationName}} over to you."},
+	},
+	EventAssociationReview: {
+		LocaleFR: {
+			Title: "Vérification de {{.AssociationName}}",
+			Body: `{{if eq .Status "in_review"}}Nous examinons les informations et documents de {{.AssociationName}}.` +
+				`{{else if eq .Status "changes_requested"}}Des modifications sont nécessaires pour vérifier {{.AssociationName}}{{if .Note}} : {{.Note}}{{else}}.{{end}}` +
+				`{{else if eq .Status "rejected"}}La vérification de {{.AssociationName}} a été refusée{{if .Note}} : {{.Note}}{{else}}.{{end}}` +
+				`{{else if .Resubmitted}}Les informations de {{.AssociationName}} ont changé, elle sera vérifiée de nouveau.` +
+				`{{else}}{{.AssociationName}} a été soumise à vérification.{{end}}`,
+		},
+		LocaleEN: {
+			Title: "Verification of {{.AssociationName}}",
+			Body: `{{if eq .Status "in_review"}}We are reviewing the information and documents of {{.AssociationName}}.` +
+				`{{else if eq .Status "changes_requested"}}Changes are needed to verify {{.AssociationName}}{{if .Note}}: {{.Note}}{{else}}.{{end}}` +
+				`{{else if eq .Status "rejected"}}The verification of {{.AssociationName}} was declined{{if .Note}}: {{.Note}}{{else}}.{{end}}` +
+				`{{else if .Resubmitted}}The information of {{.AssociationName}} changed, it will be verified again.` +
+				`{{else}}{{.AssociationName}} was submitted for verification.{{end}}`,
+		},
+	},
+	EventAdoptionApplication: {
+		LocaleFR: {
+			Title: "Votre demande d'adoption de {{.CatNames}}",
+			Body: `{{if eq .Status "accepted"}}Bonne nouvelle, votre demande a été acceptée, {{.CatNames}} vous attend !` +
+				`{{else}}Votre demande n'a pas été retenue{{if .OtherFamily}}, une autre famille a été choisie{{end}}.{{end}}`,
+		},
+		LocaleEN: {
+			Title: "Your application to adopt {{.CatNames}}",
+			Body: `{{if eq .Status "accepted"}}Good news, your application was accepted, {{.CatNames}} is waiting for you!` +
+				`{{else}}Your application was not accepted{{if .OtherFamily}}, another family was chosen{{end}}.{{end}}`,
+		},
+	},
+	EventFosterRequest: {
+		LocaleFR: {Title: "Accueillir {{.CatName}} ?", Body: "{{.AssociationName}} vous propose d'accueillir {{.CatName}} à partir du {{.StartDate}}."},
+		LocaleEN: {Title: "Foster {{.CatName}}?", Body: "{{.AssociationName}} asks whether you can foster {{.CatName}} from {{.StartDate}}."},
+	},
+	EventFosterResponse: {
+		LocaleFR: {
+			Title: "Accueil de {{.CatName}}",
+			Body:  `{{.FosterName}} {{if eq .Status "declined"}}ne peut pas accueillir{{else}}accueille{{end}} {{.CatName}}.`,
+		},
+		LocaleEN: {
+			Title: "Fostering {{.CatName}}",
+			Body:  `{{.FosterName}} {{if eq .Status "declined"}}cannot foster{{else}}is fostering{{end}} {{.CatName}}.`,
+		},
+	},
+	EventFosterClosed: {
+		LocaleFR: {
+			Title: "Accueil de {{.CatName}}",
+			Body: `{{if eq .Status "cancelled"}}{{.AssociationName}} a annulé l'accueil de {{.CatName}}.` +
+				`{{else}}L'accueil de {{.CatName}} est terminé, merci de lui avoir ouvert votre porte !{{end}}`,
+		},
+		LocaleEN: {
+			Title: "Fostering {{.CatName}}",
+			Body: `{{if eq .Status "cancelled"}}{{.AssociationName}} cancelled the fostering of {{.CatName}}.` +
+				`{{else}}The fostering of {{.CatName}} is over, thank you for opening your door to them!{{end}}`,
+		},
+	},
+}
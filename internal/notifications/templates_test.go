@@ -0,0 +1,110 @@
+package notifications
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLocale(t *testing.T) {
+	assert.Equal(t, LocaleEN, NormalizeLocale("en-GB,en;q=0.9"))
+	assert.Equal(t, LocaleFR, NormalizeLocale("fr_FR"))
+	assert.Equal(t, DefaultLocale, NormalizeLocale("de"))
+	assert.Equal(t, DefaultLocale, NormalizeLocale(""))
+}
+
+func TestRenderSubstitutesVariables(t *testing.T) {
+	message, err := Render(EventNewMessage, "en", map[string]string{"SenderName": "Alice", "Message": "Hello"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "New message from Alice", message.Title)
+	assert.Equal(t, "Hello", message.Body)
+	assert.Equal(t, LocaleEN, message.Locale)
+}
+
+func TestRenderFallsBackToDefaultLocale(t *testing.T) {
+	registry := NewRegistry(LocaleFR)
+	assert.NoError(t, registry.Register("welcome", LocaleFR, Template{Title: "Bienvenue", Body: "Bonjour {{.Name}}"}))
+
+	message, err := registry.Render("welcome", LocaleEN, map[string]string{"Name": "Bob"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, LocaleFR, message.Locale)
+	assert.Equal(t, "Bonjour Bob", message.Body)
+}
+
+func TestRenderUnknownEvent(t *testing.T) {
+	_, err := Render("unknown", LocaleFR, nil)
+
+	assert.Error(t, err)
+}
+
+func TestRenderFavoriteCatStatus(t *testing.T) {
+	message, err := Render(EventFavoriteCatStatus, LocaleFR, map[string]string{"CatName": "Félix", "Status": "adopted"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Des nouvelles de Félix", message.Title)
+	assert.Equal(t, "Félix, que vous suivez, a trouvé une famille.", message.Body)
+
+	message, err = Render(EventFavoriteCatStatus, LocaleEN, map[string]string{"CatName": "Félix", "Status": "returned"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Félix's situation changed.", message.Body)
+}
+
+func TestRenderAssociationInvitation(t *testing.T) {
+	vars := map[string]string{"InviterName": "Jeanne", "AssociationName": "Les Chats Libres", "Role": "volunteer"}
+	message, err := Render(EventAssociationInvitation, LocaleFR, vars)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Jeanne vous invite à rejoindre Les Chats Libres en tant que bénévole.", message.Body)
+
+	vars["Role"] = "admin"
+	message, err = Render(EventAssociationInvitation, LocaleEN, vars)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Jeanne invites you to join Les Chats Libres as an admin.", message.Body)
+}
+
+func TestRenderAssociationReview(t *testing.T) {
+	vars := map[string]string{"AssociationName": "Les Chats Libres", "Status": "changes_requested", "Note": "le KBIS est illisible"}
+	message, err := Render(EventAssociationReview, LocaleFR, vars)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Des modifications sont nécessaires pour vérifier Les Chats Libres : le KBIS est illisible", message.Body)
+
+	message, err = Render(EventAssociationReview, LocaleEN, map[string]string{"AssociationName": "Les Chats Libres", "Status": "submitted", "Resubmitted": "true"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "The information of Les Chats Libres changed, it will be verified again.", message.Body)
+}
+
+func TestRenderFosterResponse(t *testing.T) {
+	vars := map[string]string{"FosterName": "Jeanne", "CatName": "Félix", "Status": "declined"}
+	message, err := Render(EventFosterResponse, LocaleFR, vars)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Jeanne ne peut pas accueillir Félix.", message.Body)
+
+	vars["Status"] = "active"
+	message, err = Render(EventFosterResponse, LocaleEN, vars)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Jeanne is fostering Félix.", message.Body)
+}
+
+func TestRenderAdoptionApplication(t *testing.T) {
+	vars := map[string]string{"CatNames": "Félix", "Status": "accepted"}
+	message, err := Render(EventAdoptionApplication, LocaleFR, vars)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Votre demande d'adoption de Félix", message.Title)
+	assert.Equal(t, "Bonne nouvelle, votre demande a été acceptée, Félix vous attend !", message.Body)
+
+	vars["Status"] = "rejected"
+	vars["OtherFamily"] = "true"
+	message, err = Render(EventAdoptionApplication, LocaleEN, vars)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Your application was not accepted, another family was chosen.", message.Body)
+}
@@ -0,0 +1,115 @@
+// Package policy decides who may write the resources owned by users and
+// associations.
+package policy
+
+import (
+	"go-challenge/internal/models"
+)
+
+// Subject is the authenticated user a request acts for.
+type Subject struct {
+	UserID string
+	Role   models.RoleName
+}
+
+func (s Subject) IsAdmin() bool {
+	return s.Role == models.AdminRole
+}
+
+// AssociationRole returns the role of a user in an association, and false
+// when they are not a member. The owner of an association is its owner even
+// before their membership is recorded.
+func AssociationRole(association *models.Association, userID string) (models.AssociationRole, bool) {
+	if association == nil || userID == "" {
+		return "", false
+	}
+	if association.OwnerID == userID {
+		return models.AssociationOwner, true
+	}
+	for _, member := range association.Members {
+		if member.UserID == userID {
+			return member.Role, true
+		}
+	}
+	return "", false
+}
+
+// IsAssociationMember reports whether a user is the owner or a member of an
+// association.
+func IsAssociationMember(association *models.Association, userID string) bool {
+	_, ok := AssociationRole(association, userID)
+	return ok
+}
+
+// CanPublishAs reports whether a subject may publish cats as an association
+// once it is verified: admins, and its owner and admins. Volunteers may not.
+func CanPublishAs(subject Subject, association *models.Association) bool {
+	if association == nil || !association.IsVerified() {
+		return false
+	}
+	return CanManageMembers(subject, association)
+}
+
+// CanManageMembers reports whether a subject may see the members and
+// invitations of an association, and submit it for verification: admins,
+// and its owner and admins.
+func CanManageMembers(subject Subject, association *models.Association) bool {
+	if subject.IsAdmin() {
+		return true
+	}
+	role, ok := AssociationRole(association, subject.UserID)
+	return ok && role.CanPublish()
+}
+
+// CanManageRole reports whether a subject may invite, remove, or give or take
+// away a role in an association: admins and the owner for any role but
+// owner, which only changes hands through a transfer, and the admins of the
+// association for volunteers.
+func CanManageRole(subject Subject, association *models.Association, role models.AssociationRole) bool {
+	if association == nil || role == models.AssociationOwner {
+		return false
+	}
+	if subject.IsAdmin() {
+		return true
+	}
+	switch own, _ := AssociationRole(association, subject.UserID); own {
+	case models.AssociationOwner:
+		return true
+	case models.AssociationAdmin:
+		return role == models.AssociationVolunteer
+	}
+	return false
+}
+
+// CanWriteCat reports whether a subject may edit or delete a cat: admins, its
+// owner, and the owner and members of the association it is published as.
+// association is the one named by cat.PublishedAs, nil when there is none.
+func CanWriteCat(subject Subject, cat *models.Cats, association *models.Association) bool {
+	if subject.UserID == "" {
+		return false
+	}
+	return subject.IsAdmin() || cat.UserID == subject.UserID || IsAssociationMember(association, subject.UserID)
+}
+
+// CanWriteAnnonce reports whether a subject may edit or delete an annonce: its
+// author, and whoever may write the cat it is about. cat is nil when the cat
+// no longer exists.
+func CanWriteAnnonce(subject Subject, annonce *models.Annonce, cat *models.Cats, association *models.Association) bool {
+	if subject.UserID == "" {
+		return false
+	}
+	if subject.IsAdmin() || annonce.UserID == subject.UserID {
+		return true
+	}
+	return cat != nil && CanWriteCat(subject, cat, association)
+}
+
+// CanWriteLitter reports whether a subject may edit or delete a litter, with
+// the same rules as for cats. association is the one named by
+// litter.PublishedAs, nil when there is none.
+func CanWriteLitter(subject Subject, litter *models.Litter, association *models.Association) bool {
+	if subject.UserID == "" {
+		return false
+	}
+	return subject.IsAdmin() || litter.UserID == subject.UserID || IsAssociationMember(association, subject.UserID)
+}
@@ -0,0 +1,101 @@
+package policy
+
+import (
+	"testing"
+
+	"go-challenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanWriteCat(t *testing.T) {
+	association := &models.Association{OwnerID: "asso-owner", Members: []models.AssociationMember{{UserID: "member", Role: models.AssociationAdmin}}}
+	cat := &models.Cats{UserID: "owner", PublishedAs: "1"}
+
+	assert.True(t, CanWriteCat(Subject{UserID: "owner", Role: models.UserRole}, cat, association))
+	assert.True(t, CanWriteCat(Subject{UserID: "asso-owner", Role: models.AssoRole}, cat, association))
+	assert.True(t, CanWriteCat(Subject{UserID: "member", Role: models.UserRole}, cat, association))
+	assert.True(t, CanWriteCat(Subject{UserID: "admin", Role: models.AdminRole}, cat, association))
+
+	assert.False(t, CanWriteCat(Subject{UserID: "stranger", Role: models.UserRole}, cat, association))
+	assert.False(t, CanWriteCat(Subject{UserID: "member", Role: models.UserRole}, cat, nil))
+	assert.False(t, CanWriteCat(Subject{}, &models.Cats{}, nil))
+}
+
+func TestCanWriteAnnonce(t *testing.T) {
+	association := &models.Association{OwnerID: "asso-owner", Members: []models.AssociationMember{{UserID: "member", Role: models.AssociationAdmin}}}
+	cat := &models.Cats{UserID: "owner", PublishedAs: "1"}
+	annonce := &models.Annonce{UserID: "author"}
+
+	assert.True(t, CanWriteAnnonce(Subject{UserID: "author"}, annonce, nil, nil))
+	assert.True(t, CanWriteAnnonce(Subject{UserID: "member"}, annonce, cat, association))
+	assert.True(t, CanWriteAnnonce(Subject{UserID: "admin", Role: models.AdminRole}, annonce, nil, nil))
+
+	assert.False(t, CanWriteAnnonce(Subject{UserID: "member"}, annonce, nil, nil))
+	assert.False(t, CanWriteAnnonce(Subject{UserID: "stranger"}, annonce, cat, association))
+}
+
+func TestCanPublishAs(t *testing.T) {
+	association := &models.Association{OwnerID: "asso-owner", Members: []models.AssociationMember{{UserID: "member", Role: models.AssociationAdmin}}, VerificationStatus: models.AssociationVerified}
+
+	assert.True(t, CanPublishAs(Subject{UserID: "member"}, association))
+	assert.True(t, CanPublishAs(Subject{UserID: "admin", Role: models.AdminRole}, association))
+	assert.False(t, CanPublishAs(Subject{UserID: "stranger"}, association))
+	assert.False(t, CanPublishAs(Subject{UserID: "stranger"}, nil))
+
+	association.Members = append(association.Members, models.AssociationMember{UserID: "volunteer", Role: models.AssociationVolunteer})
+	assert.True(t, CanPublishAs(Subject{UserID: "asso-owner"}, association))
+	assert.False(t, CanPublishAs(Subject{UserID: "volunteer"}, association))
+
+	association.VerificationStatus = models.AssociationChangesRequested
+	assert.False(t, CanPublishAs(Subject{UserID: "asso-owner"}, association))
+	assert.False(t, CanPublishAs(Subject{UserID: "admin", Role: models.AdminRole}, association))
+}
+
+func TestAssociationRole(t *testing.T) {
+	association := &models.Association{OwnerID: "asso-owner", Members: []models.AssociationMember{
+		{UserID: "member", Role: models.AssociationAdmin},
+		{UserID: "volunteer", Role: models.AssociationVolunteer},
+	}}
+
+	role, ok := AssociationRole(association, "asso-owner")
+	assert.True(t, ok)
+	assert.Equal(t, models.AssociationOwner, role)
+	role, ok = AssociationRole(association, "volunteer")
+	assert.True(t, ok)
+	assert.Equal(t, models.AssociationVolunteer, role)
+	_, ok = AssociationRole(association, "stranger")
+	assert.False(t, ok)
+	_, ok = AssociationRole(nil, "asso-owner")
+	assert.False(t, ok)
+}
+
+func TestCanManageRole(t *testing.T) {
+	association := &models.Association{OwnerID: "asso-owner", Members: []models.AssociationMember{
+		{UserID: "member", Role: models.AssociationAdmin},
+		{UserID: "volunteer", Role: models.AssociationVolunteer},
+	}}
+
+	assert.True(t, CanManageRole(Subject{UserID: "asso-owner"}, association, models.AssociationAdmin))
+	assert.True(t, CanManageRole(Subject{UserID: "asso-owner"}, association, models.AssociationVolunteer))
+	assert.True(t, CanManageRole(Subject{UserID: "member"}, association, models.AssociationVolunteer))
+	assert.True(t, CanManageRole(Subject{UserID: "admin", Role: models.AdminRole}, association, models.AssociationAdmin))
+
+	assert.False(t, CanManageRole(Subject{UserID: "member"}, association, models.AssociationAdmin))
+	assert.False(t, CanManageRole(Subject{UserID: "volunteer"}, association, models.AssociationVolunteer))
+	assert.False(t, CanManageRole(Subject{UserID: "stranger"}, association, models.AssociationVolunteer))
+	assert.False(t, CanManageRole(Subject{UserID: "asso-owner"}, association, models.AssociationOwner))
+	assert.False(t, CanManageRole(Subject{UserID: "admin", Role: models.AdminRole}, nil, models.AssociationVolunteer))
+}
+
+func TestCanWriteLitter(t *testing.T) {
+	association := &models.Association{OwnerID: "asso-owner", Members: []models.AssociationMember{{UserID: "member", Role: models.AssociationAdmin}}}
+	litter := &models.Litter{UserID: "owner", PublishedAs: "1"}
+
+	assert.True(t, CanWriteLitter(Subject{UserID: "owner"}, litter, nil))
+	assert.True(t, CanWriteLitter(Subject{UserID: "member"}, litter, association))
+	assert.True(t, CanWriteLitter(Subject{UserID: "admin", Role: models.AdminRole}, litter, nil))
+
+	assert.False(t, CanWriteLitter(Subject{UserID: "stranger"}, litter, association))
+	assert.False(t, CanWriteLitter(Subject{}, &models.Litter{}, nil))
+}
@@ -0,0 +1,101 @@
+// Package scheduler runs background jobs at fixed intervals.
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go-challenge/internal/utils"
+)
+
+// Job is a named function run at a fixed interval. It receives the time of
+// the tick it runs for.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(now time.Time)
+}
+
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    []Job
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	started bool
+}
+
+func New() *Scheduler {
+	return &Scheduler{stop: make(chan struct{})}
+}
+
+// Every registers a job. Jobs registered after Start are started right away.
+func (s *Scheduler) Every(name string, interval time.Duration, run func(now time.Time)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := Job{Name: name, Interval: interval, Run: run}
+	s.jobs = append(s.jobs, job)
+	if s.started {
+		s.startJob(job)
+	}
+}
+
+// Start runs every registered job in its own goroutine. A job first runs one
+// interval after Start, then at each interval; a run that lasts longer than
+// the interval delays the next one instead of overlapping it.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+	for _, job := range s.jobs {
+		s.startJob(job)
+	}
+}
+
+// Stop stops the jobs and waits for the runs in progress to finish.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return
+	}
+	s.started = false
+	close(s.stop)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Scheduler) startJob(job Job) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(job.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case now := <-ticker.C:
+				runJob(job, now)
+			}
+		}
+	}()
+}
+
+// runJob runs one tick of a job, so that a panicking job does not bring the
+// server down with it.
+func runJob(job Job, now time.Time) {
+	defer func() {
+		if err := recover(); err != nil {
+			utils.Logger("error", "Scheduler:", fmt.Sprintf("Job %s panicked", job.Name), fmt.Sprintf("Error: %v", err))
+		}
+	}()
+	job.Run(now)
+}
@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulerRunsJobsUntilStopped(t *testing.T) {
+	var runs int32
+	s := New()
+	s.Every("count", 5*time.Millisecond, func(time.Time) {
+		atomic.AddInt32(&runs, 1)
+	})
+
+	s.Start()
+	time.Sleep(40 * time.Millisecond)
+	s.Stop()
+	stopped := atomic.LoadInt32(&runs)
+	time.Sleep(20 * time.Millisecond)
+
+	assert.GreaterOrEqual(t, stopped, int32(2))
+	assert.Equal(t, stopped, atomic.LoadInt32(&runs))
+}
+
+func TestSchedulerRecoversFromPanickingJob(t *testing.T) {
+	var runs int32
+	s := New()
+	s.Every("panic", 5*time.Millisecond, func(time.Time) {
+		atomic.AddInt32(&runs, 1)
+		panic("boom")
+	})
+
+	s.Start()
+	time.Sleep(30 * time.Millisecond)
+	s.Stop()
+
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&runs), int32(2))
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"time"
+
+	"go-challenge/internal/handlers"
+	"go-challenge/internal/scheduler"
+)
+
+// startJobs starts the background jobs of the server.
+func (s *Server) startJobs() *scheduler.Scheduler {
+	jobs := scheduler.New()
+	jobs.Every("saved-search-digests", time.Hour, func(now time.Time) {
+		handlers.RunSavedSearchDigests(s.dbService, now)
+	})
+	jobs.Every("care-reminders", 6*time.Hour, func(now time.Time) {
+		handlers.RunCareReminders(s.dbService, now)
+	})
+	jobs.Every("annonce-lifecycle", time.Hour, func(now time.Time) {
+		handlers.RunAnnonceLifecycle(s.dbService, now)
+	})
+	jobs.Every("foster-placements", time.Hour, func(now time.Time) {
+		handlers.RunFosterPlacements(s.dbService, now)
+	})
+	jobs.Every("notification-campaigns", time.Minute, func(now time.Time) {
+		handlers.RunNotificationCampaigns(s.dbService, now)
+	})
+	jobs.Every("photo-hashes", time.Hour, handlers.NewPhotoHashBackfill(s.dbService, s.store))
+	jobs.Start()
+	return jobs
+}
@@ -8,6 +8,7 @@ import (
 
 	"go-challenge/internal/auth"
 	"go-challenge/internal/handlers"
+	"go-challenge/internal/storage"
 	"go-challenge/internal/utils"
 
 	_ "go-challenge/docs"
@@ -25,21 +26,35 @@ func (s *Server) RegisterRoutes() http.Handler {
 	r.Use(middleware.Logger)
 
 	r.Handle("/assets/*", http.StripPrefix("/assets/", http.FileServer(http.Dir("assets"))))
-	r.Handle("/uploads/", http.StripPrefix("/uploads/", http.FileServer(http.Dir("/internal/uploads/"))))
+	if local, ok := s.store.(*storage.Local); ok {
+		r.Handle("/uploads/*", http.StripPrefix("/uploads", local.Handler()))
+	}
 	r.Handle("/.well-known/*", http.StripPrefix("/.well-known/", http.FileServer(http.Dir("assets"))))
 
 	authHandler := handlers.NewAuthHandler(s.dbService)
-	userHandler := handlers.NewUserHandler(s.dbService, s.uploadcareClient)
+	userHandler := handlers.NewUserHandler(s.dbService, s.store)
 	annonceHandler := handlers.NewAnnonceHandler(s.dbService, s.dbService, s.dbService)
-	catHandler := handlers.NewCatHandler(s.dbService, s.uploadcareClient)
+	catHandler := handlers.NewCatHandler(s.dbService, s.store)
 	favoriteHandler := handlers.NewFavoriteHandler(s.dbService, s.dbService)
-	raceHandler := handlers.NewRaceHandler(s.dbService, s.uploadcareClient)
-	associationHandler := handlers.NewAssociationHandler(s.dbService, s.uploadcareClient)
+	raceHandler := handlers.NewRaceHandler(s.dbService, s.store)
+	associationHandler := handlers.NewAssociationHandler(s.dbService, s.store)
+	associationMemberHandler := handlers.NewAssociationMemberHandler(s.dbService, s.mailer)
 	ratingHandler := handlers.NewRatingHandler(s.dbService, s.dbService)
 	roomHandler := handlers.NewRoomHandler(s.dbService)
 	reportsHandler := handlers.NewReportsHandler(s.dbService)
 	notificationTokenHandler := handlers.NewNotificationTokenHandler(s.dbService)
 	featureFlagHandler := handlers.NewFeatureFlagHandler(s.dbService)
+	notificationCampaignHandler := handlers.NewNotificationCampaignHandler(s.dbService)
+	inboxHandler := handlers.NewInboxHandler(s.dbService)
+	savedSearchHandler := handlers.NewSavedSearchHandler(s.dbService)
+	recommendationHandler := handlers.NewRecommendationHandler(s.dbService)
+	medicalRecordHandler := handlers.NewMedicalRecordHandler(s.dbService)
+	careHandler := handlers.NewCareHandler(s.dbService)
+	catStatusHandler := handlers.NewCatStatusHandler(s.dbService)
+	catPhotoHandler := handlers.NewCatPhotoHandler(s.dbService, s.store)
+	familyHandler := handlers.NewFamilyHandler(s.dbService)
+	adoptionApplicationHandler := handlers.NewAdoptionApplicationHandler(s.dbService)
+	fosterHandler := handlers.NewFosterHandler(s.dbService)
 
 	roomHandler.LoadRooms()
 
@@ -54,6 +69,30 @@ func (s *Server) RegisterRoutes() http.Handler {
 			// Admin specific routes
 			r.Get("/reports", reportsHandler.GetAllReports)
 
+			//** Notification broadcast routes
+			r.Post("/notifications/campaigns", notificationCampaignHandler.CreateNotificationCampaignHandler)
+			r.Get("/notifications/campaigns", notificationCampaignHandler.GetNotificationCampaignsHandler)
+			r.Get("/notifications/campaigns/{id}/report", notificationCampaignHandler.GetNotificationCampaignReportHandler)
+			r.Post("/notifications/audiences", notificationCampaignHandler.CreateNotificationAudienceHandler)
+			r.Get("/notifications/audiences", notificationCampaignHandler.GetNotificationAudiencesHandler)
+			r.Delete("/notifications/audiences/{id}", notificationCampaignHandler.DeleteNotificationAudienceHandler)
+
+			//** Race catalogue routes
+			r.Get("/races/export", raceHandler.ExportRacesHandler)
+			r.Post("/races/import", raceHandler.ImportRacesHandler)
+			r.Put("/races/{id}/photo", raceHandler.UploadRacePhotoHandler)
+
+			//** Care protocol routes
+			r.Post("/care-protocols", careHandler.CreateCareProtocolHandler)
+			r.Get("/care-protocols", careHandler.GetCareProtocolsHandler)
+			r.Put("/care-protocols/{id}", careHandler.UpdateCareProtocolHandler)
+			r.Delete("/care-protocols/{id}", careHandler.DeleteCareProtocolHandler)
+
+			//** Association review routes
+			r.Get("/associations/reviews", associationHandler.GetAssociationsToReviewHandler)
+			r.Put("/associations/{id}/verification", associationHandler.ReviewAssociationHandler)
+			r.Put("/associations/{id}/verify", associationHandler.UpdateAssociationVerifyStatusHandler)
+
 		})
 
 		r.Group(func(r chi.Router) {
@@ -84,10 +123,14 @@ func (s *Server) RegisterRoutes() http.Handler {
 
 		//**	Annonces routes
 		r.Get("/annonces", annonceHandler.GetAllAnnoncesHandler)
+		r.Get("/annonces/search", annonceHandler.SearchAnnoncesHandler)
 		r.Get("/annonces/{id}", annonceHandler.GetAnnonceByIDHandler)
 		r.Post("/annonces", annonceHandler.AnnonceCreationHandler)
 		r.Put("/annonces/{id}", annonceHandler.ModifyAnnonceHandler)
 		r.Delete("/annonces/{id}", annonceHandler.DeleteAnnonceHandler)
+		r.Put("/annonces/{id}/status", annonceHandler.ChangeAnnonceStatusHandler)
+		r.Post("/annonces/{id}/renew", annonceHandler.RenewAnnonceHandler)
+		r.Get("/annonces/{id}/analytics", annonceHandler.GetAnnonceAnalyticsHandler)
 		r.Get("/annonces/cats/{catID}", annonceHandler.FetchAnnonceByCatIDHandler)
 		//	r.Get("annonce/address/{id}", annonceHandler.GetAddressFromUserID)
 
@@ -98,8 +141,53 @@ func (s *Server) RegisterRoutes() http.Handler {
 		r.Post("/cats", catHandler.CatCreationHandler)
 		r.Delete("/cats/{id}", catHandler.DeleteCatHandler)
 		r.Get("/cats/", catHandler.FindCatsByFilterHandler)
+		r.Get("/cats/search", catHandler.SearchCatsHandler)
 		r.Get("/cats/user/{userID}", catHandler.GetCatsByUserHandler)
 		r.Get("/cats/{id}/annonces", catHandler.GetAnnoncesByCatIDHandler)
+		r.Post("/cats/{id}/transfer", medicalRecordHandler.TransferCatHandler)
+		r.Put("/cats/{id}/status", catStatusHandler.ChangeCatStatusHandler)
+		r.Get("/cats/{id}/status-history", catStatusHandler.GetCatStatusHistoryHandler)
+		r.Get("/cats/{id}/photos", catPhotoHandler.GetCatPhotosHandler)
+		r.Post("/cats/{id}/photos", catPhotoHandler.AddCatPhotosHandler)
+		r.Put("/cats/{id}/photos/order", catPhotoHandler.ReorderCatPhotosHandler)
+		r.Put("/cats/{id}/photos/{photoID}", catPhotoHandler.UpdateCatPhotoHandler)
+		r.Delete("/cats/{id}/photos/{photoID}", catPhotoHandler.DeleteCatPhotoHandler)
+		r.Put("/cats/{id}/photos/{photoID}/cover", catPhotoHandler.SetCatCoverPhotoHandler)
+		r.Get("/cats/{id}/family", familyHandler.GetCatFamilyHandler)
+		r.Put("/cats/{id}/parents", familyHandler.SetCatParentsHandler)
+
+		//** Litter routes
+		r.Get("/litters", familyHandler.GetAvailableLittersHandler)
+		r.Post("/litters", familyHandler.CreateLitterHandler)
+		r.Get("/litters/{id}", familyHandler.GetLitterHandler)
+		r.Put("/litters/{id}", familyHandler.UpdateLitterHandler)
+		r.Delete("/litters/{id}", familyHandler.DeleteLitterHandler)
+
+		//** Adoption application routes
+		r.Post("/adoption-applications", adoptionApplicationHandler.CreateAdoptionApplicationHandler)
+		r.Get("/adoption-applications", adoptionApplicationHandler.GetMyAdoptionApplicationsHandler)
+		r.Put("/adoption-applications/{id}/status", adoptionApplicationHandler.DecideAdoptionApplicationHandler)
+		r.Get("/cats/{id}/adoption-applications", adoptionApplicationHandler.GetCatAdoptionApplicationsHandler)
+
+		//** Foster family routes
+		r.Get("/me/foster-profile", fosterHandler.GetMyFosterProfileHandler)
+		r.Put("/me/foster-profile", fosterHandler.SaveMyFosterProfileHandler)
+		r.Delete("/me/foster-profile", fosterHandler.DeleteMyFosterProfileHandler)
+		r.Get("/me/foster-placements", fosterHandler.GetMyFosterPlacementsHandler)
+		r.Get("/fosters", fosterHandler.GetFosterProfilesHandler)
+		r.Post("/cats/{id}/foster-placements", fosterHandler.RequestFosterPlacementHandler)
+		r.Get("/cats/{id}/foster-placements", fosterHandler.GetCatFosterPlacementsHandler)
+		r.Get("/associations/{id}/foster-placements", fosterHandler.GetAssociationFosterPlacementsHandler)
+		r.Put("/foster-placements/{id}/status", fosterHandler.ChangeFosterPlacementStatusHandler)
+
+		//** Medical record routes
+		r.Get("/cats/{id}/medical", medicalRecordHandler.GetMedicalRecordHandler)
+		r.Post("/cats/{id}/medical/entries", medicalRecordHandler.CreateMedicalEntryHandler)
+		r.Put("/cats/{id}/medical/entries/{entryID}", medicalRecordHandler.UpdateMedicalEntryHandler)
+		r.Delete("/cats/{id}/medical/entries/{entryID}", medicalRecordHandler.DeleteMedicalEntryHandler)
+		r.Put("/cats/{id}/medical/identification", medicalRecordHandler.UpdateCatIdentificationHandler)
+		r.Get("/annonces/{id}/medical", medicalRecordHandler.GetAnnonceMedicalSummaryHandler)
+		r.Get("/cats/{id}/care-schedule", careHandler.GetCareScheduleHandler)
 
 		//** Race routes
 		r.Get("/races", raceHandler.GetAllRaceHandler)
@@ -122,6 +210,11 @@ func (s *Server) RegisterRoutes() http.Handler {
 		r.Post("/favorites", favoriteHandler.FavoriteCreationHandler)
 		r.Get("/favorites/users/{userID}", favoriteHandler.GetFavoritesByUserHandler)
 		r.Delete("/favorites/{favoriteID}", favoriteHandler.DeleteFavoriteByIDHandler) // Nouvelle ligne ajoutée
+		r.Put("/favorites/{favoriteID}/collection", favoriteHandler.MoveFavoriteHandler)
+		r.Post("/favorites/collections", favoriteHandler.CreateFavoriteCollectionHandler)
+		r.Get("/favorites/collections", favoriteHandler.GetFavoriteCollectionsHandler)
+		r.Put("/favorites/collections/{id}", favoriteHandler.UpdateFavoriteCollectionHandler)
+		r.Delete("/favorites/collections/{id}", favoriteHandler.DeleteFavoriteCollectionHandler)
 
 		//** Auth routes
 		r.Get("/logout/{provider}", authHandler.LogoutProvider)
@@ -134,7 +227,21 @@ func (s *Server) RegisterRoutes() http.Handler {
 		r.Get("/associations/{id}", associationHandler.GetAssociationByIdHandler)
 		r.Delete("/associations/{id}", associationHandler.DeleteAssociationHandler)
 		r.Put("/associations/{id}", associationHandler.UpdateAssociationHandler)
-		r.Put("/associations/{id}/verify", associationHandler.UpdateAssociationVerifyStatusHandler)
+		r.Get("/associations/{id}/verification", associationHandler.GetAssociationVerificationHandler)
+		r.Post("/associations/{id}/verification/submit", associationHandler.SubmitAssociationVerificationHandler)
+		r.Post("/associations/{id}/documents", associationHandler.UploadAssociationDocumentHandler)
+		r.Delete("/associations/{id}/documents/{documentID}", associationHandler.DeleteAssociationDocumentHandler)
+		r.Put("/associations/{id}/profile", associationHandler.UpdateAssociationProfileHandler)
+		r.Put("/associations/{id}/logo", associationHandler.UploadAssociationLogoHandler)
+		r.Get("/associations/{id}/status-history", catStatusHandler.GetAssociationStatusHistoryHandler)
+		r.Get("/associations/{id}/analytics", annonceHandler.GetAssociationAnalyticsHandler)
+		r.Get("/associations/{id}/members", associationMemberHandler.GetAssociationMembersHandler)
+		r.Put("/associations/{id}/members/{userID}", associationMemberHandler.UpdateAssociationMemberHandler)
+		r.Delete("/associations/{id}/members/{userID}", associationMemberHandler.RemoveAssociationMemberHandler)
+		r.Post("/associations/{id}/transfer", associationMemberHandler.TransferAssociationOwnershipHandler)
+		r.Post("/associations/{id}/invitations", associationMemberHandler.InviteAssociationMemberHandler)
+		r.Get("/associations/{id}/invitations", associationMemberHandler.GetAssociationInvitationsHandler)
+		r.Delete("/associations/{id}/invitations/{invitationID}", associationMemberHandler.RevokeAssociationInvitationHandler)
 
 		//** Chat routes
 		r.Get("/rooms", roomHandler.GetUserRooms)
@@ -153,7 +260,26 @@ func (s *Server) RegisterRoutes() http.Handler {
 		//** Notification routes
 		r.Post("/notifications", notificationTokenHandler.CreateNotificationTokenHandler)
 		r.Delete("/notifications/{id}", notificationTokenHandler.DeleteNotificationTokenHandler)
-		r.Post("/notifications/send", notificationTokenHandler.SendNotificationHandler)
+		r.Get("/me/notifications", inboxHandler.GetInboxHandler)
+		r.Put("/me/notifications/{id}/read", inboxHandler.MarkInboxNotificationReadHandler)
+		r.Get("/me/invitations", associationMemberHandler.GetMyAssociationInvitationsHandler)
+		r.Post("/me/invitations/{id}/accept", associationMemberHandler.AcceptAssociationInvitationHandler)
+		r.Post("/me/invitations/{id}/decline", associationMemberHandler.DeclineAssociationInvitationHandler)
+
+		//** Saved search routes
+		r.Post("/me/searches", savedSearchHandler.CreateSavedSearchHandler)
+		r.Get("/me/searches", savedSearchHandler.GetSavedSearchesHandler)
+		r.Put("/me/searches/{id}", savedSearchHandler.UpdateSavedSearchHandler)
+		r.Delete("/me/searches/{id}", savedSearchHandler.DeleteSavedSearchHandler)
+		r.Post("/me/searches/{id}/unsubscribe", savedSearchHandler.UnsubscribeSavedSearchHandler)
+		r.Get("/me/searches/{id}/matches", savedSearchHandler.GetSavedSearchMatchesHandler)
+
+		//** Recommendation routes
+		r.Get("/me/lifestyle", recommendationHandler.GetLifestyleProfileHandler)
+		r.Put("/me/lifestyle", recommendationHandler.UpdateLifestyleProfileHandler)
+		r.Get("/me/recommendations", recommendationHandler.GetRecommendationsHandler)
+		r.Post("/me/recommendations/{catID}/dismiss", recommendationHandler.DismissCatHandler)
+		r.Delete("/me/recommendations/{catID}/dismiss", recommendationHandler.UndoCatDismissalHandler)
 
 		//** Feature flag routes
 		r.Put("/feature-flags/{id}", featureFlagHandler.UpdateFeatureFlagStatusHandler)
@@ -173,8 +299,11 @@ func (s *Server) RegisterRoutes() http.Handler {
 		httpSwagger.URL(os.Getenv("SERVER_URL")+"/swagger/doc.json"),
 	))
 	r.Get("/feature-flags", featureFlagHandler.GetAllFeatureFlagsHandler)
+	r.Get("/a/{slug}", annonceHandler.GetAnnoncePageHandler)
+	r.Get("/associations/{id}/profile", associationHandler.GetAssociationProfileHandler)
+	r.Get("/associations/{id}/profile/cats", associationHandler.GetAssociationCatalogueCatsHandler)
+	r.Get("/associations/{id}/profile/annonces", associationHandler.GetAssociationCatalogueAnnoncesHandler)
 	r.Get("/reportSocket", reportsHandler.HandleWebSocket)
-	r.Get("/notifications/test", notificationTokenHandler.TestSendNotificationHandler)
 
 	return r
 }
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-challenge/internal/auth"
+	"go-challenge/internal/database"
+	"go-challenge/internal/database/dbtest"
+	"go-challenge/internal/database/queries"
+	"go-challenge/internal/storage"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRoutes(t *testing.T) http.Handler {
+	auth.TokenAuth = jwtauth.New("HS256", []byte("secret"), nil)
+	store, err := storage.NewLocal(t.TempDir(), "http://localhost:8080/uploads", []byte("secret"))
+	require.NoError(t, err)
+	db, _ := dbtest.New()
+	s := &Server{store: store, dbService: queries.NewQueriesService(&database.Service{Db: db})}
+	return s.RegisterRoutes()
+}
+
+func TestAssociationReviewRoutesAreAdminOnly(t *testing.T) {
+	routes := newTestRoutes(t)
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: http.MethodGet, path: "/associations/reviews"},
+		{method: http.MethodPut, path: "/associations/3/verification", body: `{"status":"verified"}`},
+		{method: http.MethodPut, path: "/associations/3/verify", body: `{"verified":true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			for role, code := range map[string]int{"USER": http.StatusForbidden, "ASSO": http.StatusForbidden, "": http.StatusUnauthorized} {
+				r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+				if role != "" {
+					r.Header.Set("Authorization", "Bearer "+auth.MakeToken("user", role))
+				}
+				w := httptest.NewRecorder()
+
+				routes.ServeHTTP(w, r)
+
+				assert.Equal(t, code, w.Code, role)
+			}
+		})
+	}
+}
+
+func TestAssociationReviewRoutesLetAdminsIn(t *testing.T) {
+	routes := newTestRoutes(t)
+	r := httptest.NewRequest(http.MethodGet, "/associations/reviews", nil)
+	r.Header.Set("Authorization", "Bearer "+auth.MakeToken("admin", "ADMIN"))
+	w := httptest.NewRecorder()
+
+	routes.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
@@ -8,18 +8,21 @@ import (
 	"time"
 
 	_ "github.com/joho/godotenv/autoload"
-	"github.com/uploadcare/uploadcare-go/ucare"
 
-	"go-challenge/internal/api"
 	"go-challenge/internal/database"
 	"go-challenge/internal/database/queries"
+	"go-challenge/internal/mailer"
+	"go-challenge/internal/scheduler"
+	"go-challenge/internal/storage"
 )
 
 type Server struct {
-	port             int
-	db               *database.Service
-	uploadcareClient ucare.Client
-	dbService        *queries.DatabaseService
+	port      int
+	db        *database.Service
+	store     storage.BlobStore
+	mailer    mailer.Mailer
+	dbService *queries.DatabaseService
+	jobs      *scheduler.Scheduler
 }
 
 func NewServer() (*http.Server, error) {
@@ -28,18 +31,20 @@ func NewServer() (*http.Server, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create database: %v", err)
 	}
-	ucClient, err := api.CreateUCClient()
+	store, err := storage.NewFromEnv()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create uploadcare client: %v", err)
+		return nil, fmt.Errorf("failed to create storage: %v", err)
 	}
 	dbService := queries.NewQueriesService(db)
 
 	newServer := &Server{
-		port:             port,
-		db:               db,
-		uploadcareClient: ucClient,
-		dbService:        dbService,
+		port:      port,
+		db:        db,
+		store:     store,
+		mailer:    mailer.NewFromEnv(),
+		dbService: dbService,
 	}
+	newServer.jobs = newServer.startJobs()
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", newServer.port),
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local keeps files in a directory of the server, served under /uploads/. It
+// is meant for development and tests: its files are public, and signatures
+// are only checked on the URLs that carry one.
+type Local struct {
+	dir        string
+	baseURL    string
+	signingKey []byte
+	now        func() time.Time
+}
+
+func NewLocal(dir, baseURL string, signingKey []byte) (*Local, error) {
+	if len(signingKey) == 0 {
+		return nil, errors.New("a signing key is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create upload directory: %v", err)
+	}
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	return &Local{dir: dir, baseURL: baseURL, signingKey: signingKey, now: time.Now}, nil
+}
+
+func (l *Local) Put(ctx context.Context, name, contentType string, data []byte) (string, error) {
+	key := newKey(name)
+	if err := os.WriteFile(filepath.Join(l.dir, key), data, 0o644); err != nil {
+		return "", fmt.Errorf("could not write file: %v", err)
+	}
+	return key, nil
+}
+
+func (l *Local) Get(ctx context.Context, key string) ([]byte, error) {
+	if !validKey(key) {
+		return nil, fmt.Errorf("invalid key %q", key)
+	}
+	data, err := os.ReadFile(filepath.Join(l.dir, key))
+	if err != nil {
+		return nil, fmt.Errorf("could not read file: %v", err)
+	}
+	return data, nil
+}
+
+func (l *Local) URL(key string) string {
+	return l.baseURL + key
+}
+
+func (l *Local) SignedURL(key string, expires time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	expiresAt := strconv.FormatInt(l.now().Add(expires).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expiresAt)
+	query.Set("signature", l.sign(key, expiresAt))
+	return l.URL(key) + "?" + query.Encode(), nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return fmt.Errorf("invalid key %q", key)
+	}
+	if err := os.Remove(filepath.Join(l.dir, key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not delete file: %v", err)
+	}
+	return nil
+}
+
+func (l *Local) Key(fileURL string) (string, bool) {
+	if !strings.HasPrefix(fileURL, l.baseURL) {
+		return "", false
+	}
+	key, _, _ := strings.Cut(strings.TrimPrefix(fileURL, l.baseURL), "?")
+	return key, validKey(key)
+}
+
+// Verify checks the signature of a signed URL of a file.
+func (l *Local) Verify(key, expiresAt, signature string) error {
+	expires, err := strconv.ParseInt(expiresAt, 10, 64)
+	if err != nil || l.now().Unix() > expires {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(l.sign(key, expiresAt))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Handler serves the files of the store. It expects the request path to be
+// the key, with the /uploads/ prefix stripped.
+func (l *Local) Handler() http.Handler {
+	files := http.FileServer(http.Dir(l.dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		if !validKey(key) {
+			http.NotFound(w, r)
+			return
+		}
+		query := r.URL.Query()
+		if query.Has("signature") {
+			if err := l.Verify(key, query.Get("expires"), query.Get("signature")); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+func (l *Local) sign(key, expiresAt string) string {
+	mac := hmac.New(sha256.New, l.signingKey)
+	mac.Write([]byte(key + "\n" + expiresAt))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLocal(t *testing.T) *Local {
+	store, err := NewLocal(t.TempDir(), "http://localhost:8080/uploads", []byte("secret"))
+	require.NoError(t, err)
+	return store
+}
+
+func serve(store *Local, fileURL string) *httptest.ResponseRecorder {
+	path := strings.TrimPrefix(fileURL, "http://localhost:8080/uploads")
+	recorder := httptest.NewRecorder()
+	store.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder
+}
+
+func TestLocalPutServeAndDelete(t *testing.T) {
+	store := newTestLocal(t)
+
+	key, err := store.Put(context.Background(), "kbis.pdf", "application/pdf", []byte("%PDF-1.4"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(key, ".pdf"))
+
+	fileURL := store.URL(key)
+	assert.Equal(t, "http://localhost:8080/uploads/"+key, fileURL)
+	found, ok := store.Key(fileURL)
+	assert.True(t, ok)
+	assert.Equal(t, key, found)
+
+	recorder := serve(store, fileURL)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "%PDF-1.4", recorder.Body.String())
+	data, err := store.Get(context.Background(), key)
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4", string(data))
+
+	require.NoError(t, store.Delete(context.Background(), key))
+	assert.Equal(t, http.StatusNotFound, serve(store, fileURL).Code)
+	assert.NoError(t, store.Delete(context.Background(), key))
+}
+
+func TestLocalSignedURL(t *testing.T) {
+	store := newTestLocal(t)
+	key, err := store.Put(context.Background(), "cat.png", "image/png", []byte("picture"))
+	require.NoError(t, err)
+
+	signed, err := store.SignedURL(key, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, serve(store, signed).Code)
+
+	assert.Equal(t, http.StatusForbidden, serve(store, strings.Replace(signed, "signature=", "signature=0", 1)).Code)
+
+	store.now = func() time.Time { return time.Now().Add(time.Hour) }
+	assert.Equal(t, http.StatusForbidden, serve(store, signed).Code)
+}
+
+func TestLocalRejectsPaths(t *testing.T) {
+	store := newTestLocal(t)
+
+	_, ok := store.Key("http://localhost:8080/uploads/../../etc/passwd")
+	assert.False(t, ok)
+	_, ok = store.Key("https://ucarecdn.com/abc/")
+	assert.False(t, ok)
+	assert.Error(t, store.Delete(context.Background(), "../go.mod"))
+	_, err := store.Get(context.Background(), "../go.mod")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, serve(store, "http://localhost:8080/uploads/sub/file").Code)
+}
+
+func TestLocalRequiresSigningKey(t *testing.T) {
+	_, err := NewLocal(t.TempDir(), "http://localhost:8080/uploads", nil)
+	assert.Error(t, err)
+
+	t.Setenv("STORAGE_BACKEND", BackendLocal)
+	t.Setenv("STORAGE_LOCAL_DIR", t.TempDir())
+	t.Setenv("STORAGE_SIGNING_KEY", "")
+	t.Setenv("JWT_SECRET", "secret")
+	_, err = NewFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	amzDateFormat   = "20060102T150405Z"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// S3Config configures an S3 compatible store.
+type S3Config struct {
+	// Endpoint is the URL of the service, such as https://s3.eu-west-3.amazonaws.com
+	// or http://localhost:9000 for a local MinIO.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle puts the bucket in the path rather than in the host name, as
+	// MinIO expects by default.
+	PathStyle bool
+	// PublicURL is the base URL files are served from when it is not the
+	// bucket itself, such as a CDN.
+	PublicURL string
+}
+
+// S3 keeps files in a bucket of an S3 compatible store. Requests are signed
+// with AWS Signature Version 4.
+type S3 struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewS3(config S3Config) (*S3, error) {
+	if config.Bucket == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("S3 storage needs a bucket, an access key ID and a secret access key")
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", config.Endpoint)
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	config.PublicURL = strings.TrimSuffix(config.PublicURL, "/")
+	return &S3{config: config, endpoint: endpoint, client: &http.Client{Timeout: 30 * time.Second}, now: time.Now}, nil
+}
+
+func (s *S3) Put(ctx context.Context, name, contentType string, data []byte) (string, error) {
+	key := newKey(name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	s.signRequest(req, data)
+
+	if err := s.do(req); err != nil {
+		return "", fmt.Errorf("could not upload file: %v", err)
+	}
+	return key, nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+	if !validKey(key) {
+		return nil, fmt.Errorf("invalid key %q", key)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.signRequest(req, nil)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not download file: %v", err)
+	}
+	defer res.Body.Close()
+	if err := checkStatus(res); err != nil {
+		return nil, fmt.Errorf("could not download file: %v", err)
+	}
+	return io.ReadAll(res.Body)
+}
+
+func (s *S3) URL(key string) string {
+	if s.config.PublicURL != "" {
+		return s.config.PublicURL + "/" + key
+	}
+	return s.objectURL(key).String()
+}
+
+// SignedURL returns a presigned GET URL, which works on private buckets.
+func (s *S3) SignedURL(key string, expires time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	now := s.now().UTC()
+	objectURL := s.objectURL(key)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.config.AccessKeyID+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format(amzDateFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	headers := map[string]string{"host": objectURL.Host}
+	signature := s.signature(http.MethodGet, objectURL.EscapedPath(), query, headers, unsignedPayload, now)
+	query.Set("X-Amz-Signature", signature)
+
+	objectURL.RawQuery = canonicalQuery(query)
+	return objectURL.String(), nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return fmt.Errorf("invalid key %q", key)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+	s.signRequest(req, nil)
+
+	if err := s.do(req); err != nil {
+		return fmt.Errorf("could not delete file: %v", err)
+	}
+	return nil
+}
+
+func (s *S3) Key(fileURL string) (string, bool) {
+	base := strings.TrimSuffix(s.URL(""), "/") + "/"
+	if !strings.HasPrefix(fileURL, base) {
+		return "", false
+	}
+	key, _, _ := strings.Cut(strings.TrimPrefix(fileURL, base), "?")
+	return key, validKey(key)
+}
+
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	basePath := strings.TrimSuffix(u.Path, "/")
+	if s.config.PathStyle {
+		u.Path = basePath + "/" + s.config.Bucket + "/" + key
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path = basePath + "/" + key
+	}
+	return &u
+}
+
+func (s *S3) do(req *http.Request) error {
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound && req.Method == http.MethodDelete {
+		return nil
+	}
+	return checkStatus(res)
+}
+
+// checkStatus turns an unsuccessful response into an error carrying the
+// start of its body.
+func checkStatus(res *http.Response) error {
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// signRequest adds the AWS Signature Version 4 authorization header to a
+// request with the given payload.
+func (s *S3) signRequest(req *http.Request, payload []byte) {
+	now := s.now().UTC()
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           now.Format(amzDateFormat),
+	}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = contentType
+	}
+
+	signature := s.signature(req.Method, req.URL.EscapedPath(), req.URL.Query(), headers, payloadHash, now)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, s.scope(now), signedHeaders(headers), signature))
+}
+
+// signature computes the signature of a request from its canonical form.
+func (s *S3) signature(method, path string, query url.Values, headers map[string]string, payloadHash string, now time.Time) string {
+	names := headerNames(headers)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		canonicalQuery(query),
+		canonicalHeaders.String(),
+		strings.Join(names, ";"),
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format(amzDateFormat),
+		s.scope(now),
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func (s *S3) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.config.Region + "/s3/aws4_request"
+}
+
+func headerNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func signedHeaders(headers map[string]string) string {
+	return strings.Join(headerNames(headers), ";")
+}
+
+// canonicalQuery encodes a query string the way Signature Version 4 expects:
+// sorted by name, with spaces as %20.
+func canonicalQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
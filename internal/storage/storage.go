@@ -0,0 +1,107 @@
+// Package storage keeps uploaded files in a blob store chosen by
+// configuration: Uploadcare, the local filesystem or an S3 compatible store
+// such as MinIO.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-challenge/internal/api"
+
+	"github.com/google/uuid"
+)
+
+const (
+	BackendUploadcare = "uploadcare"
+	BackendLocal      = "local"
+	BackendS3         = "s3"
+)
+
+// ErrInvalidSignature is returned when a signed URL was tampered with or has
+// expired.
+var ErrInvalidSignature = errors.New("invalid or expired signature")
+
+// BlobStore stores uploaded files. Files are identified by a key chosen by the
+// store; the URL of a file is what the API hands out and keeps in the
+// database.
+type BlobStore interface {
+	// Put stores a file and returns its key. The name is only used for its
+	// extension.
+	Put(ctx context.Context, name, contentType string, data []byte) (string, error)
+	// Get returns the content of a file.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// URL returns the public URL of a file.
+	URL(key string) string
+	// SignedURL returns a URL giving access to a file until it expires.
+	SignedURL(key string, expires time.Duration) (string, error)
+	// Delete removes a file. Deleting a missing file is not an error.
+	Delete(ctx context.Context, key string) error
+	// Key returns the key of a file from its URL, and false when the URL does
+	// not belong to the store.
+	Key(fileURL string) (string, bool)
+}
+
+// NewFromEnv creates the store selected by STORAGE_BACKEND. Without it,
+// Uploadcare is used when its keys are set and the local filesystem
+// otherwise, so that the server starts without any credentials.
+func NewFromEnv() (BlobStore, error) {
+	backend := os.Getenv("STORAGE_BACKEND")
+	if backend == "" {
+		backend = BackendLocal
+		if os.Getenv("UPLOAD_CARE_PUBLIC_KEY") != "" {
+			backend = BackendUploadcare
+		}
+	}
+
+	switch backend {
+	case BackendUploadcare:
+		client, err := api.CreateUCClient()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create uploadcare client: %v", err)
+		}
+		return NewUploadcare(client), nil
+	case BackendLocal:
+		signingKey := os.Getenv("STORAGE_SIGNING_KEY")
+		if signingKey == "" {
+			return nil, errors.New("STORAGE_SIGNING_KEY is required to sign the URLs of the local storage")
+		}
+		baseURL := strings.TrimSuffix(os.Getenv("SERVER_URL"), "/") + "/uploads/"
+		return NewLocal(envOr("STORAGE_LOCAL_DIR", "uploads"), baseURL, []byte(signingKey))
+	case BackendS3:
+		pathStyle, _ := strconv.ParseBool(os.Getenv("S3_PATH_STYLE"))
+		return NewS3(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          envOr("S3_REGION", "us-east-1"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PathStyle:       pathStyle,
+			PublicURL:       os.Getenv("S3_PUBLIC_URL"),
+		})
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", backend)
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// newKey makes a unique key keeping the extension of the file name.
+func newKey(name string) string {
+	return uuid.New().String() + strings.ToLower(filepath.Ext(name))
+}
+
+// validKey rejects keys that could escape the store, such as paths.
+func validKey(key string) bool {
+	return key != "" && !strings.ContainsAny(key, `/\`) && key != "." && key != ".."
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go-challenge/internal/api"
+
+	"github.com/uploadcare/uploadcare-go/ucare"
+)
+
+// Uploadcare keeps files on Uploadcare, keyed by their file ID.
+type Uploadcare struct {
+	client ucare.Client
+	cdn    *http.Client
+}
+
+func NewUploadcare(client ucare.Client) *Uploadcare {
+	return &Uploadcare{client: client, cdn: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (u *Uploadcare) Put(ctx context.Context, name, contentType string, data []byte) (string, error) {
+	_, fileID, err := api.UploadData(u.client, name, contentType, data)
+	return fileID, err
+}
+
+// Get downloads the file from the CDN, where files are public.
+func (u *Uploadcare) Get(ctx context.Context, key string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.URL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := u.cdn.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not download file: %v", err)
+	}
+	defer res.Body.Close()
+	if err := checkStatus(res); err != nil {
+		return nil, fmt.Errorf("could not download file: %v", err)
+	}
+	return io.ReadAll(res.Body)
+}
+
+func (u *Uploadcare) URL(key string) string {
+	return api.FileURL(key)
+}
+
+// SignedURL returns the CDN URL of the file: the project does not use
+// Uploadcare secure delivery, its files are public.
+func (u *Uploadcare) SignedURL(key string, expires time.Duration) (string, error) {
+	return u.URL(key), nil
+}
+
+func (u *Uploadcare) Delete(ctx context.Context, key string) error {
+	return api.DeleteFile(u.client, key)
+}
+
+func (u *Uploadcare) Key(fileURL string) (string, bool) {
+	return api.FileIDFromURL(fileURL)
+}